@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+)
+
+const backfillEventsUsage = `usage: tasks backfill-events [-dry-run]
+
+record a TaskCreated event for every task that has no events yet, such as
+tasks written before events.enabled was turned on, tasks that already have
+events are left alone, requires events.enabled, exits with 1 on errors
+`
+
+// runBackfillEvents 執行 backfill-events 子命令，回傳 exit code
+func runBackfillEvents(args []string) int {
+	flags := flag.NewFlagSet("backfill-events", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, backfillEventsUsage) }
+	dryRun := flags.Bool("dry-run", false, "only count the tasks that would be backfilled")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	logger, _ := zap.NewProduction()
+	conf := initConfig()
+	db := openStorage(conf)
+	defer db.Close()
+	taskService, err := newTaskService(conf, db, logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	backfilled, err := taskService.BackfillEvents(context.Background(), *dryRun)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	verb := "backfilled"
+	if *dryRun {
+		verb = "would backfill"
+	}
+	fmt.Printf("%s events for %d tasks\n", verb, backfilled)
+	return 0
+}
@@ -0,0 +1,6 @@
+package config
+
+type Admin struct {
+	// Token 呼叫 /admin API 時帶入的 bearer token，為空時不開放
+	Token string `mapstructure:"token" yaml:"token"`
+}
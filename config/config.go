@@ -1,6 +1,45 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/viper"
+)
+
 type Config struct {
 	Server Server `mapstructure:"server" yaml:"server"`
 	DB     DB     `mapstructure:"db" yaml:"db"`
+	Trash  Trash  `mapstructure:"trash" yaml:"trash"`
+
+	Idempotency Idempotency `mapstructure:"idempotency" yaml:"idempotency"`
+	Reminder    Reminder    `mapstructure:"reminder" yaml:"reminder"`
+	Admin       Admin       `mapstructure:"admin" yaml:"admin"`
+	Events      Events      `mapstructure:"events" yaml:"events"`
+	Webhook     Webhook     `mapstructure:"webhook" yaml:"webhook"`
+}
+
+// Load 讀取設定檔，struct tag 中的 default 不會套用，缺少必要的設定時回傳錯誤
+func Load(path string) (Config, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return Config{}, err
+	}
+	var conf Config
+	if err := v.Unmarshal(&conf); err != nil {
+		return Config{}, fmt.Errorf("unmarshal config: %w", err)
+	}
+	if err := conf.validate(); err != nil {
+		return Config{}, err
+	}
+	return conf, nil
+}
+
+func (c Config) validate() error {
+	// lease 為 0 時處理中的 key 會立即被重試的 request 接手，重複建立 task
+	if c.Idempotency.Lease <= 0 {
+		return errors.New("idempotency.lease must be greater than 0")
+	}
+	return nil
 }
@@ -0,0 +1,29 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("shipped config", func(t *testing.T) {
+		conf, err := Load("../config.yaml")
+
+		require.NoError(t, err)
+		assert.Greater(t, conf.Idempotency.Lease, time.Duration(0))
+	})
+
+	t.Run("missing idempotency lease", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("idempotency:\n    ttl: 24h\n"), 0o600))
+
+		_, err := Load(path)
+
+		assert.EqualError(t, err, "idempotency.lease must be greater than 0")
+	})
+}
@@ -1,7 +1,13 @@
 package config
 
+import "time"
+
 type DB struct {
 	Driver  string `mapstructure:"driver" yaml:"driver" default:"mysql"`
 	Dsn     string `mapstructure:"dsn" yaml:"dsn" default:"file:test.db?cache=shared&mode=memory"`
 	MaxOpen int    `mapstructure:"max_open" yaml:"max_open" default:"10"`
+	// Timeout 每個資料庫操作的預設逾時，0 表示不設逾時
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout" default:"5s"`
+	// Timeouts 以操作名稱覆寫 Timeout，例如 list_tasks
+	Timeouts map[string]time.Duration `mapstructure:"timeouts" yaml:"timeouts"`
 }
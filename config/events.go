@@ -0,0 +1,8 @@
+package config
+
+type Events struct {
+	// Enabled 以 event sourcing 模式寫入 task，tasks 表為事件的 projection
+	Enabled bool `mapstructure:"enabled" yaml:"enabled"`
+	// SnapshotInterval 每個 task 每累積幾筆事件保存一次 snapshot，0 表示不保存
+	SnapshotInterval int `mapstructure:"snapshot_interval" yaml:"snapshot_interval" default:"50"`
+}
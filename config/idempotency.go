@@ -0,0 +1,10 @@
+package config
+
+import "time"
+
+type Idempotency struct {
+	TTL           time.Duration `mapstructure:"ttl" yaml:"ttl" default:"24h"`
+	PurgeInterval time.Duration `mapstructure:"purge_interval" yaml:"purge_interval" default:"1h"`
+	// Lease 處理中的 key 超過此時間仍未完成時，可由相同的 request 接手
+	Lease time.Duration `mapstructure:"lease" yaml:"lease" default:"30s"`
+}
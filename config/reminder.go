@@ -0,0 +1,7 @@
+package config
+
+import "time"
+
+type Reminder struct {
+	Interval time.Duration `mapstructure:"interval" yaml:"interval" default:"1m"`
+}
@@ -0,0 +1,8 @@
+package config
+
+import "time"
+
+type Trash struct {
+	Retention     time.Duration `mapstructure:"retention" yaml:"retention" default:"720h"`
+	PurgeInterval time.Duration `mapstructure:"purge_interval" yaml:"purge_interval" default:"1h"`
+}
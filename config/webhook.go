@@ -0,0 +1,15 @@
+package config
+
+import "time"
+
+type Webhook struct {
+	// Interval dispatcher 檢查 outbox 的間隔，0 表示停用投遞
+	Interval time.Duration `mapstructure:"interval" yaml:"interval" default:"5s"`
+	// Timeout 每次投遞等待接收端回應的時間
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout" default:"10s"`
+	// MaxAttempts 包含第一次投遞的次數上限，用盡後標記為 dead
+	MaxAttempts int `mapstructure:"max_attempts" yaml:"max_attempts" default:"8"`
+	// Backoff 第 n 次失敗後等待 backoff * 2^(n-1)，最多等待 MaxBackoff
+	Backoff    time.Duration `mapstructure:"backoff" yaml:"backoff" default:"30s"`
+	MaxBackoff time.Duration `mapstructure:"max_backoff" yaml:"max_backoff" default:"1h"`
+}
@@ -1,8 +1,202 @@
 package constants
 
+// Status 為 workflow 中狀態的 id，可用的狀態與轉換定義在 workflow_states 與 workflow_transitions，
+// 0 與 1 保留給 todo 與 done，done 是唯一視為完成的狀態
 type Status int
 
 const (
 	Incomplete Status = iota
 	Complete
 )
+
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+func (p Priority) IsValid() bool {
+	return p >= PriorityLow && p <= PriorityUrgent
+}
+
+type SortField string
+
+const (
+	SortByCreatedAt SortField = "created_at"
+	SortByName      SortField = "name"
+	SortByStatus    SortField = "status"
+	SortByPriority  SortField = "priority"
+	SortByPosition  SortField = "position"
+)
+
+func (f SortField) IsValid() bool {
+	switch f {
+	case SortByCreatedAt, SortByName, SortByStatus, SortByPriority, SortByPosition:
+		return true
+	}
+	return false
+}
+
+type SortOrder string
+
+const (
+	OrderAsc  SortOrder = "asc"
+	OrderDesc SortOrder = "desc"
+)
+
+func (o SortOrder) IsValid() bool {
+	return o == OrderAsc || o == OrderDesc
+}
+
+type PatchType string
+
+const (
+	MergePatch PatchType = "application/merge-patch+json"
+	JSONPatch  PatchType = "application/json-patch+json"
+)
+
+type BatchOp string
+
+const (
+	BatchCreate BatchOp = "create"
+	BatchUpdate BatchOp = "update"
+	BatchDelete BatchOp = "delete"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate  AuditAction = "create"
+	AuditUpdate  AuditAction = "update"
+	AuditDelete  AuditAction = "delete"
+	AuditRestore AuditAction = "restore"
+	AuditPurge   AuditAction = "purge"
+)
+
+func (a AuditAction) IsValid() bool {
+	switch a {
+	case AuditCreate, AuditUpdate, AuditDelete, AuditRestore, AuditPurge:
+		return true
+	}
+	return false
+}
+
+// AuditBreakReason 驗證 audit log hash chain 時斷開的原因
+type AuditBreakReason string
+
+const (
+	// AuditPrevHashMismatch 紀錄的 prev hash 與前一筆的 hash 不符，表示前面有紀錄被刪除、插入或調換順序
+	AuditPrevHashMismatch AuditBreakReason = "prev_hash_mismatch"
+	// AuditHashMismatch 依內容重算的 hash 與紀錄的 hash 不符，表示紀錄被修改
+	AuditHashMismatch AuditBreakReason = "hash_mismatch"
+	// AuditHeadMismatch chain 的筆數或最後一筆與 head 不符，表示結尾的紀錄被刪除
+	AuditHeadMismatch AuditBreakReason = "head_mismatch"
+	// AuditUnchained 紀錄不在 chain 中，例如直接寫入資料庫，或 SealAudit 尚未串接的舊紀錄
+	AuditUnchained AuditBreakReason = "unchained"
+)
+
+// TaskEventType event sourcing 模式下 task 的事件類型
+type TaskEventType string
+
+const (
+	TaskCreated        TaskEventType = "TaskCreated"
+	TaskRenamed        TaskEventType = "TaskRenamed"
+	TaskStatusChanged  TaskEventType = "TaskStatusChanged"
+	TaskDetailsChanged TaskEventType = "TaskDetailsChanged"
+	TaskDeleted        TaskEventType = "TaskDeleted"
+	TaskRestored       TaskEventType = "TaskRestored"
+	TaskPurged         TaskEventType = "TaskPurged"
+)
+
+// WebhookEvent webhook 可訂閱的事件
+type WebhookEvent string
+
+const (
+	WebhookTaskCreated WebhookEvent = "task.created"
+	WebhookTaskUpdated WebhookEvent = "task.updated"
+	WebhookTaskDeleted WebhookEvent = "task.deleted"
+)
+
+func (e WebhookEvent) IsValid() bool {
+	switch e {
+	case WebhookTaskCreated, WebhookTaskUpdated, WebhookTaskDeleted:
+		return true
+	}
+	return false
+}
+
+// WebhookEventFor 對應 audit action 的 webhook 事件，移到垃圾桶與永久刪除都是 task.deleted
+func WebhookEventFor(action AuditAction) WebhookEvent {
+	switch action {
+	case AuditCreate:
+		return WebhookTaskCreated
+	case AuditDelete, AuditPurge:
+		return WebhookTaskDeleted
+	}
+	return WebhookTaskUpdated
+}
+
+// DeliveryStatus webhook outbox 中一筆投遞的狀態
+type DeliveryStatus string
+
+const (
+	// DeliveryPending 等待投遞或重試
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	// DeliveryDead 重試次數用盡，只能手動重送
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+func (s DeliveryStatus) IsValid() bool {
+	switch s {
+	case DeliveryPending, DeliveryDelivered, DeliveryDead:
+		return true
+	}
+	return false
+}
+
+type BatchMode string
+
+const (
+	BatchAtomic     BatchMode = "atomic"
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+// MaxTaskDepth sub-task 最深的層數，最上層 task 的深度為 0
+const MaxTaskDepth = 10
+
+// MaxOccurrences 預覽 recurring task 時最多列出的出現次數，DefaultOccurrences 為未指定時列出的次數
+const (
+	MaxOccurrences     = 100
+	DefaultOccurrences = 5
+)
+
+// DefaultSearchSize 搜尋未指定 size 時每頁的筆數，MaxSearchSize 為上限
+const (
+	DefaultSearchSize = 20
+	MaxSearchSize     = 100
+)
+
+// DefaultAuditSize 查詢 audit log 未指定 size 時每頁的筆數，MaxAuditSize 為上限
+const (
+	DefaultAuditSize = 50
+	MaxAuditSize     = 200
+)
+
+// DefaultEventSize 查詢 task 事件未指定 size 時每頁的筆數，MaxEventSize 為上限
+const (
+	DefaultEventSize = 100
+	MaxEventSize     = 500
+)
+
+// DefaultDeliverySize 查詢 webhook 投遞紀錄未指定 size 時每頁的筆數，MaxDeliverySize 為上限
+const (
+	DefaultDeliverySize = 50
+	MaxDeliverySize     = 200
+)
+
+// AuditVerifyBatchSize 驗證 audit log hash chain 時每次讀取的筆數
+const AuditVerifyBatchSize = 500
@@ -15,30 +15,65 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/tasks": {
+        "/admin/audit/verify": {
             "get": {
-                "description": "Get tasks",
-                "consumes": [
+                "description": "Recompute the SHA-256 hash chain of the audit log from the oldest entry and report the first broken link. Requires the admin token as a bearer token",
+                "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Verify audit log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.AuditVerification"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid authorization",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/admin/projection/rebuild": {
+            "post": {
+                "description": "Replay the task events and overwrite the tasks table with the result. Only available when event sourcing is enabled. Requires the admin token as a bearer token",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "tasks"
+                    "admin"
                 ],
-                "summary": "Get tasks",
+                "summary": "Rebuild task projection",
                 "parameters": [
                     {
-                        "type": "integer",
-                        "description": "size",
-                        "name": "size",
-                        "in": "query"
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
                     },
                     {
-                        "type": "integer",
-                        "description": "offset",
-                        "name": "offset",
+                        "type": "boolean",
+                        "description": "only count the rows that would change",
+                        "name": "dry_run",
                         "in": "query"
                     }
                 ],
@@ -46,39 +81,95 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/entities.Tasks"
+                            "$ref": "#/definitions/entities.ProjectionRebuild"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "401": {
+                        "description": "invalid authorization",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    },
+                    "501": {
+                        "description": "event sourcing is not enabled",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tags": {
+            "get": {
+                "description": "Get all tags ordered by name",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Get tags",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Tags"
                         }
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
                     }
                 }
             },
             "post": {
-                "description": "Create a new task with a name and status",
+                "description": "Create a tag with a unique name and an optional hex color",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "tasks"
+                    "tags"
                 ],
-                "summary": "Create a new task",
+                "summary": "Create tag",
                 "parameters": [
                     {
-                        "description": "Task information",
-                        "name": "task",
+                        "description": "tag",
+                        "name": "tag",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/views.CreateTaskReq"
+                            "$ref": "#/definitions/views.CreateTagReq"
                         }
                     }
                 ],
                 "responses": {
                     "201": {
-                        "description": "Created"
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Tag"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the created tag"
+                            }
+                        }
                     },
                     "400": {
                         "description": "request is invalid",
                         "schema": {}
                     },
+                    "409": {
+                        "description": "tag name already exists",
+                        "schema": {}
+                    },
                     "500": {
                         "description": "server internal error",
                         "schema": {}
@@ -86,44 +177,89 @@ const docTemplate = `{
                 }
             }
         },
-        "/tasks/{id}": {
+        "/tags/{id}": {
+            "get": {
+                "description": "Get a single tag by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tags"
+                ],
+                "summary": "Get tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "tag id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Tag"
+                        }
+                    },
+                    "404": {
+                        "description": "tag not found",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            },
             "put": {
-                "description": "Update task",
+                "description": "Replace the name and color of a tag, tasks with the tag see the change immediately",
                 "consumes": [
                     "application/json"
                 ],
+                "produces": [
+                    "application/json"
+                ],
                 "tags": [
-                    "tasks"
+                    "tags"
                 ],
-                "summary": "Update task",
+                "summary": "Replace tag",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "task id",
+                        "description": "tag id",
                         "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "description": "task",
-                        "name": "task",
+                        "description": "tag",
+                        "name": "tag",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/views.UpdateTaskReq"
+                            "$ref": "#/definitions/views.UpdateTagReq"
                         }
                     }
                 ],
                 "responses": {
-                    "204": {
-                        "description": "No Content"
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Tag"
+                        }
                     },
                     "400": {
                         "description": "request is invalid",
                         "schema": {}
                     },
                     "404": {
-                        "description": "task not found",
+                        "description": "tag not found",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "tag name already exists",
                         "schema": {}
                     },
                     "500": {
@@ -133,18 +269,15 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete task",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Delete a tag and detach it from every task",
                 "tags": [
-                    "tasks"
+                    "tags"
                 ],
-                "summary": "Delete task",
+                "summary": "Delete tag",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "task id",
+                        "description": "tag id",
                         "name": "id",
                         "in": "path",
                         "required": true
@@ -154,12 +287,8 @@ const docTemplate = `{
                     "204": {
                         "description": "No Content"
                     },
-                    "400": {
-                        "description": "request is invalid",
-                        "schema": {}
-                    },
                     "404": {
-                        "description": "task not found",
+                        "description": "tag not found",
                         "schema": {}
                     },
                     "500": {
@@ -168,50 +297,2768 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "constants.Status": {
-            "type": "integer",
-            "enum": [
-                0,
-                1
-            ],
-            "x-enum-varnames": [
-                "Incomplete",
-                "Complete"
-            ]
-        },
-        "entities.Task": {
-            "type": "object",
-            "properties": {
-                "id": {
-                    "type": "string"
-                },
-                "name": {
-                    "type": "string"
-                },
-                "status": {
-                    "$ref": "#/definitions/constants.Status"
-                }
-            }
         },
-        "entities.Tasks": {
-            "type": "object",
-            "properties": {
-                "page": {
-                    "type": "integer"
-                },
-                "size": {
-                    "type": "integer"
-                },
-                "tasks": {
-                    "type": "array",
-                    "items": {
-                        "$ref": "#/definitions/entities.Task"
+        "/tasks": {
+            "get": {
+                "description": "Get tasks with optional status, priority, tag and parent filters, name keyword search, created_at and due_at ranges, overdue filter and sorting, ordered by position by default",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get tasks",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "status filter, repeatable",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "priority filter, repeatable",
+                        "name": "priority",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "name keyword",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "created at or after (RFC3339)",
+                        "name": "created_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "created before (RFC3339)",
+                        "name": "created_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "only incomplete tasks past due_at when true, everything else when false",
+                        "name": "overdue",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "due at or after (RFC3339)",
+                        "name": "due_after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "due before (RFC3339)",
+                        "name": "due_before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "tasks with any of these tag names, repeatable",
+                        "name": "tags_any",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "tasks with all of these tag names, repeatable",
+                        "name": "tags_all",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only the direct subtasks of this task",
+                        "name": "parent_id",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "position",
+                            "created_at",
+                            "name",
+                            "status",
+                            "priority"
+                        ],
+                        "type": "string",
+                        "description": "sort field",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "sort order",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor or prev_cursor from a previous response, takes precedence over page",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "include the total number of matching tasks",
+                        "name": "with_total",
+                        "in": "query"
                     }
-                }
-            }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Tasks"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    }
+                }
+            },
+            "post": {
+                "description": "Create a new task with a name and an optional parent_id, priority, due_at and remind_at, the task is placed at the end of the list",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Create a new task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "unique key for safely retrying the request, a retry with the same key replays the original response",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Task information",
+                        "name": "task",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.CreateTaskReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "task version"
+                            },
+                            "Idempotent-Replayed": {
+                                "type": "string",
+                                "description": "true when the response is replayed for an Idempotency-Key"
+                            },
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the created task"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "a request with this idempotency key is in progress, or the position was taken by concurrent creates",
+                        "schema": {}
+                    },
+                    "422": {
+                        "description": "idempotency key was used with a different request",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/audit": {
+            "get": {
+                "description": "List the changes of all tasks with the actor, version and the fields before and after, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get audit log",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "only changes of this task",
+                        "name": "task_id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "only changes by this actor",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "create, update, delete, restore or purge, repeatable",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "changed at or after (RFC3339)",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "changed before (RFC3339)",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "next_before of the previous page",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 200,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "size, defaults to 50",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.AuditLog"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/search": {
+            "get": {
+                "description": "Full-text search on task names, every term must match, \"quoted phrase\" matches words in order and a trailing * matches a prefix, ranked by bm25 with highlighted snippets, the snippet is HTML-escaped and only \u003cmark\u003e tags are added. Without full-text support terms are matched as case-insensitive substrings ordered by position and rank is 0",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Search tasks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "search query, e.g. \\",
+                        "name": "q",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "size, defaults to 20",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "page, defaults to 1",
+                        "name": "page",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.SearchResults"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/trash": {
+            "get": {
+                "description": "Get soft-deleted tasks, accepts the same filters, sorting and paging as GET /tasks",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "trash"
+                ],
+                "summary": "Get trash",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "size",
+                        "name": "size",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "page",
+                        "name": "page",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "status filter, repeatable",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "priority filter, repeatable",
+                        "name": "priority",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "name keyword",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "tasks with any of these tag names, repeatable",
+                        "name": "tags_any",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "tasks with all of these tag names, repeatable",
+                        "name": "tags_all",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "position",
+                            "created_at",
+                            "name",
+                            "status",
+                            "priority"
+                        ],
+                        "type": "string",
+                        "description": "sort field",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "sort order",
+                        "name": "order",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor or prev_cursor from a previous response",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "include the total number of matching tasks",
+                        "name": "with_total",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Tasks"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/trash/{id}": {
+            "delete": {
+                "description": "Permanently delete a task that is in the trash together with its subtasks",
+                "tags": [
+                    "trash"
+                ],
+                "summary": "Purge task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/workflow": {
+            "get": {
+                "description": "List the statuses a task can have and the statuses each one can move to, 0 is todo and 1 is done",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get workflow",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Workflow"
+                        }
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/{id}": {
+            "get": {
+                "description": "Get a single task by id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the parent_id, name, status, priority, due_at and remind_at of a task, a completed task completes its parent once all of the parent's subtasks are complete, send Prefer: return=representation to receive the updated task",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Replace task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from a previous read, the update is rejected when the task has changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "return=representation or return=minimal (default)",
+                        "name": "Prefer",
+                        "in": "header"
+                    },
+                    {
+                        "description": "task",
+                        "name": "task",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.UpdateTaskReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "task version"
+                            }
+                        }
+                    },
+                    "204": {
+                        "description": "No Content",
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "task was modified concurrently, is blocked, or the status transition is not allowed, error.details lists the allowed states",
+                        "schema": {}
+                    },
+                    "412": {
+                        "description": "task version does not match",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            },
+            "delete": {
+                "description": "Move a task to the trash, it can be restored until it is purged, a task with subtasks can only be deleted together with them",
+                "consumes": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Delete task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "also move all subtasks to the trash",
+                        "name": "cascade",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from a previous read, the delete is rejected when the task has changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "task has subtasks",
+                        "schema": {}
+                    },
+                    "412": {
+                        "description": "task version does not match",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            },
+            "patch": {
+                "description": "Partially update a task with a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902) document, only changed fields are written",
+                "consumes": [
+                    "application/merge-patch+json",
+                    "application/json-patch+json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Patch task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from a previous read, the patch is rejected when the task has changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "merge patch object or json patch operation array",
+                        "name": "patch",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "object"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "task was modified concurrently, is blocked, or the status transition is not allowed, error.details lists the allowed states",
+                        "schema": {}
+                    },
+                    "412": {
+                        "description": "task version does not match",
+                        "schema": {}
+                    },
+                    "415": {
+                        "description": "unsupported media type",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/as-of": {
+            "get": {
+                "description": "Replay the events of a task up to a version or a point in time. Tags and blockers are not included. Only available when event sourcing is enabled",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get task as of a version or time",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "the last state with this version",
+                        "name": "version",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "the state at this time (RFC3339)",
+                        "name": "at",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Task"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task did not exist at that point",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    },
+                    "501": {
+                        "description": "event sourcing is not enabled",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/blockers": {
+            "post": {
+                "description": "Mark a task as blocked by other tasks, a task with incomplete blockers can't be completed, existing blockers are skipped",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Add blockers",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "ids of the blocking tasks",
+                        "name": "blockers",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.AddBlockersReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task or blocker not found",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "dependency would form a cycle",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/blockers/{blocker_id}": {
+            "delete": {
+                "description": "Remove a blocker from a task, removing a task that is not a blocker does nothing",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Remove blocker",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "blocking task id",
+                        "name": "blocker_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/events": {
+            "get": {
+                "description": "List the events of a task in the order they happened. The events are kept after the task is purged. Only available when event sourcing is enabled",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get task events",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "next_after of the previous page",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 500,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "size, defaults to 100",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.TaskEvents"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    },
+                    "501": {
+                        "description": "event sourcing is not enabled",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/history": {
+            "get": {
+                "description": "List the changes of a task with the actor, version and the fields before and after, newest first. The history is kept after the task is purged",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get task history",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "only changes by this actor",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "create, update, delete, restore or purge, repeatable",
+                        "name": "action",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "changed at or after (RFC3339)",
+                        "name": "since",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "changed before (RFC3339)",
+                        "name": "until",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "next_before of the previous page",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 200,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "size, defaults to 50",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.AuditLog"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/move": {
+            "post": {
+                "description": "Move a task right before or after another task, only the moved task is rewritten",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Move task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag from a previous read, the move is rejected when the task has changed since",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "id of the task to move before or after, exactly one of before and after",
+                        "name": "move",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.MoveTaskReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "task was modified concurrently, or the position was taken by concurrent moves",
+                        "schema": {}
+                    },
+                    "412": {
+                        "description": "task version does not match",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/occurrences": {
+            "get": {
+                "description": "List the next occurrence dates of a recurring task, starting with its due_at",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Preview occurrences",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 100,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "number of occurrences, defaults to 5",
+                        "name": "count",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Occurrences"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid or task is not recurring",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/restore": {
+            "post": {
+                "description": "Move a soft-deleted task out of the trash together with the subtasks that were deleted with it",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "trash"
+                ],
+                "summary": "Restore task",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Task"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "parent task is deleted",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/subtree": {
+            "get": {
+                "description": "Get a task with its subtasks nested under children, down to depth levels below the task",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Get subtree",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "maximum": 10,
+                        "minimum": 0,
+                        "type": "integer",
+                        "description": "number of levels below the task, 0 returns only the task, defaults to all levels",
+                        "name": "depth",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.TaskNode"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/tags": {
+            "post": {
+                "description": "Attach tags to a task, tags that are already attached are skipped",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Attach tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "ids of the tags to attach",
+                        "name": "tags",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.AttachTagsReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task or tag not found",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks/{id}/tags/{tag_id}": {
+            "delete": {
+                "description": "Detach a tag from a task, detaching a tag that is not attached does nothing",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Detach tag",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "task id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "tag id",
+                        "name": "tag_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Task"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "task version"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks:batch": {
+            "post": {
+                "description": "Run a list of create/update/delete operations, atomically in one transaction or in best-effort mode",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Batch tasks",
+                "parameters": [
+                    {
+                        "description": "batch operations",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.BatchTaskReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.BatchResults"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/tasks:toposort": {
+            "post": {
+                "description": "Return the tasks in topological order, blockers come before the tasks they block, only dependencies between the given tasks are considered",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "tasks"
+                ],
+                "summary": "Sort tasks by dependency",
+                "parameters": [
+                    {
+                        "description": "ids of the tasks to sort",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.SortTasksReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Tasks"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "task not found",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "description": "Get all webhooks ordered by creation time, secrets are not returned. Requires the admin token as a bearer token",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get webhooks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Webhooks"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid authorization",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            },
+            "post": {
+                "description": "Register an endpoint for task events. Every delivery is signed with the secret, which is only returned in this response. Requires the admin token as a bearer token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Create webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "webhook",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.CreateWebhookReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Webhook"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "URL of the created webhook"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "401": {
+                        "description": "invalid authorization",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "get": {
+                "description": "Get a single webhook by id, the secret is not returned. Requires the admin token as a bearer token",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Webhook"
+                        }
+                    },
+                    "401": {
+                        "description": "invalid authorization",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "webhook not found",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            },
+            "put": {
+                "description": "Replace the url, events and active state of a webhook. An inactive webhook keeps its pending deliveries until it is activated again. The secret is returned only when it is changed. Requires the admin token as a bearer token",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Replace webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "webhook",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/views.UpdateWebhookReq"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "401": {
+                        "description": "invalid authorization",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "webhook not found",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete a webhook with its deliveries, pending events are not delivered. Requires the admin token as a bearer token",
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete webhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "invalid authorization",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "webhook not found",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "List the deliveries of a webhook from the newest, with the payload, attempts and the last error. Requires the admin token as a bearer token",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "delivered",
+                            "dead"
+                        ],
+                        "type": "string",
+                        "description": "delivery status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "next_before of the previous page",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "maximum": 200,
+                        "minimum": 1,
+                        "type": "integer",
+                        "description": "size, defaults to 50",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/entities.WebhookDeliveries"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "401": {
+                        "description": "invalid authorization",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "webhook not found",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries/{delivery_id}/redeliver": {
+            "post": {
+                "description": "Queue a delivered or dead delivery again with the original payload and event id, the attempts start over. Requires the admin token as a bearer token",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Redeliver webhook delivery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Bearer admin token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "webhook id",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "delivery id",
+                        "name": "delivery_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "$ref": "#/definitions/entities.WebhookDelivery"
+                        }
+                    },
+                    "400": {
+                        "description": "request is invalid",
+                        "schema": {}
+                    },
+                    "401": {
+                        "description": "invalid authorization",
+                        "schema": {}
+                    },
+                    "404": {
+                        "description": "delivery not found",
+                        "schema": {}
+                    },
+                    "409": {
+                        "description": "delivery is pending",
+                        "schema": {}
+                    },
+                    "500": {
+                        "description": "server internal error",
+                        "schema": {}
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "constants.AuditAction": {
+            "type": "string",
+            "enum": [
+                "create",
+                "update",
+                "delete",
+                "restore",
+                "purge"
+            ],
+            "x-enum-varnames": [
+                "AuditCreate",
+                "AuditUpdate",
+                "AuditDelete",
+                "AuditRestore",
+                "AuditPurge"
+            ]
+        },
+        "constants.AuditBreakReason": {
+            "type": "string",
+            "enum": [
+                "prev_hash_mismatch",
+                "hash_mismatch",
+                "head_mismatch",
+                "unchained"
+            ],
+            "x-enum-varnames": [
+                "AuditPrevHashMismatch",
+                "AuditHashMismatch",
+                "AuditHeadMismatch",
+                "AuditUnchained"
+            ]
+        },
+        "constants.BatchMode": {
+            "type": "string",
+            "enum": [
+                "atomic",
+                "best_effort"
+            ],
+            "x-enum-varnames": [
+                "BatchAtomic",
+                "BatchBestEffort"
+            ]
+        },
+        "constants.BatchOp": {
+            "type": "string",
+            "enum": [
+                "create",
+                "update",
+                "delete"
+            ],
+            "x-enum-varnames": [
+                "BatchCreate",
+                "BatchUpdate",
+                "BatchDelete"
+            ]
+        },
+        "constants.DeliveryStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "delivered",
+                "dead"
+            ],
+            "x-enum-varnames": [
+                "DeliveryPending",
+                "DeliveryDelivered",
+                "DeliveryDead"
+            ]
+        },
+        "constants.Priority": {
+            "type": "integer",
+            "enum": [
+                0,
+                1,
+                2,
+                3
+            ],
+            "x-enum-varnames": [
+                "PriorityLow",
+                "PriorityMedium",
+                "PriorityHigh",
+                "PriorityUrgent"
+            ]
+        },
+        "constants.Status": {
+            "type": "integer",
+            "enum": [
+                0,
+                1
+            ],
+            "x-enum-varnames": [
+                "Incomplete",
+                "Complete"
+            ]
+        },
+        "constants.TaskEventType": {
+            "type": "string",
+            "enum": [
+                "TaskCreated",
+                "TaskRenamed",
+                "TaskStatusChanged",
+                "TaskDetailsChanged",
+                "TaskDeleted",
+                "TaskRestored",
+                "TaskPurged"
+            ],
+            "x-enum-varnames": [
+                "TaskCreated",
+                "TaskRenamed",
+                "TaskStatusChanged",
+                "TaskDetailsChanged",
+                "TaskDeleted",
+                "TaskRestored",
+                "TaskPurged"
+            ]
+        },
+        "constants.WebhookEvent": {
+            "type": "string",
+            "enum": [
+                "task.created",
+                "task.updated",
+                "task.deleted"
+            ],
+            "x-enum-varnames": [
+                "WebhookTaskCreated",
+                "WebhookTaskUpdated",
+                "WebhookTaskDeleted"
+            ]
+        },
+        "entities.AuditBreak": {
+            "type": "object",
+            "properties": {
+                "actual": {
+                    "type": "string"
+                },
+                "expected": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "reason": {
+                    "$ref": "#/definitions/constants.AuditBreakReason"
+                },
+                "task_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.AuditEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/constants.AuditAction"
+                },
+                "actor": {
+                    "type": "string"
+                },
+                "changes": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.FieldChange"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "hash": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "task_id": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.AuditLog": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.AuditEntry"
+                    }
+                },
+                "next_before": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.AuditVerification": {
+            "type": "object",
+            "properties": {
+                "broken": {
+                    "$ref": "#/definitions/entities.AuditBreak"
+                },
+                "checked": {
+                    "type": "integer"
+                },
+                "head": {
+                    "type": "string"
+                },
+                "valid": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "entities.BatchError": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "integer"
+                },
+                "details": {},
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.BatchResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/entities.BatchError"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "index": {
+                    "type": "integer"
+                },
+                "op": {
+                    "$ref": "#/definitions/constants.BatchOp"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "task": {
+                    "$ref": "#/definitions/entities.Task"
+                }
+            }
+        },
+        "entities.BatchResults": {
+            "type": "object",
+            "properties": {
+                "committed": {
+                    "type": "boolean"
+                },
+                "mode": {
+                    "$ref": "#/definitions/constants.BatchMode"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.BatchResult"
+                    }
+                }
+            }
+        },
+        "entities.FieldChange": {
+            "type": "object",
+            "properties": {
+                "after": {},
+                "before": {},
+                "field": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.Occurrences": {
+            "type": "object",
+            "properties": {
+                "occurrences": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "recurrence": {
+                    "type": "string"
+                },
+                "task_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.ProjectionRebuild": {
+            "type": "object",
+            "properties": {
+                "deleted": {
+                    "type": "integer"
+                },
+                "dry_run": {
+                    "type": "boolean"
+                },
+                "inserted": {
+                    "type": "integer"
+                },
+                "tasks": {
+                    "type": "integer"
+                },
+                "updated": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.SearchResult": {
+            "type": "object",
+            "properties": {
+                "rank": {
+                    "type": "number"
+                },
+                "snippet": {
+                    "type": "string"
+                },
+                "task": {
+                    "$ref": "#/definitions/entities.Task"
+                }
+            }
+        },
+        "entities.SearchResults": {
+            "type": "object",
+            "properties": {
+                "page": {
+                    "type": "integer"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.SearchResult"
+                    }
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.Tag": {
+            "type": "object",
+            "properties": {
+                "color": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.Tags": {
+            "type": "object",
+            "properties": {
+                "tags": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.Tag"
+                    }
+                }
+            }
+        },
+        "entities.Task": {
+            "type": "object",
+            "properties": {
+                "blocked": {
+                    "description": "Blocked 有未完成且不在垃圾桶中的 blocker，讀取時計算",
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "due_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "overdue": {
+                    "description": "Overdue 依讀取當下的時間計算，不會寫入資料庫",
+                    "type": "boolean"
+                },
+                "parent_id": {
+                    "description": "ParentID 為 nil 時是最上層的 task",
+                    "type": "string"
+                },
+                "position": {
+                    "description": "Position 手動排序用的 key，以字典序排序，只能透過 move 修改",
+                    "type": "string"
+                },
+                "priority": {
+                    "$ref": "#/definitions/constants.Priority"
+                },
+                "recurrence": {
+                    "description": "Recurrence RRULE，第一次出現為 due_at，完成後會建立下一次的 task 並把規則移過去",
+                    "type": "string"
+                },
+                "remind_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/constants.Status"
+                },
+                "tags": {
+                    "description": "Tags 依名稱排序，只能透過 tag 的 attach 與 detach 修改",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.Tag"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.TaskEvent": {
+            "type": "object",
+            "properties": {
+                "actor": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "object"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "seq": {
+                    "type": "integer"
+                },
+                "task_id": {
+                    "type": "string"
+                },
+                "type": {
+                    "$ref": "#/definitions/constants.TaskEventType"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.TaskEvents": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.TaskEvent"
+                    }
+                },
+                "next_after": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.TaskNode": {
+            "type": "object",
+            "properties": {
+                "blocked": {
+                    "description": "Blocked 有未完成且不在垃圾桶中的 blocker，讀取時計算",
+                    "type": "boolean"
+                },
+                "children": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.TaskNode"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "string"
+                },
+                "depth": {
+                    "type": "integer"
+                },
+                "due_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "overdue": {
+                    "description": "Overdue 依讀取當下的時間計算，不會寫入資料庫",
+                    "type": "boolean"
+                },
+                "parent_id": {
+                    "description": "ParentID 為 nil 時是最上層的 task",
+                    "type": "string"
+                },
+                "position": {
+                    "description": "Position 手動排序用的 key，以字典序排序，只能透過 move 修改",
+                    "type": "string"
+                },
+                "priority": {
+                    "$ref": "#/definitions/constants.Priority"
+                },
+                "recurrence": {
+                    "description": "Recurrence RRULE，第一次出現為 due_at，完成後會建立下一次的 task 並把規則移過去",
+                    "type": "string"
+                },
+                "remind_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/constants.Status"
+                },
+                "tags": {
+                    "description": "Tags 依名稱排序，只能透過 tag 的 attach 與 detach 修改",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.Tag"
+                    }
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.Tasks": {
+            "type": "object",
+            "properties": {
+                "next_cursor": {
+                    "type": "string"
+                },
+                "page": {
+                    "type": "integer"
+                },
+                "prev_cursor": {
+                    "type": "string"
+                },
+                "size": {
+                    "type": "integer"
+                },
+                "tasks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.Task"
+                    }
+                },
+                "total": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.Webhook": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/constants.WebhookEvent"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "updated_at": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.WebhookDeliveries": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.WebhookDelivery"
+                    }
+                },
+                "next_before": {
+                    "type": "integer"
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "entities.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "type": "string"
+                },
+                "event": {
+                    "$ref": "#/definitions/constants.WebhookEvent"
+                },
+                "event_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_status_code": {
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "payload": {
+                    "type": "object"
+                },
+                "status": {
+                    "$ref": "#/definitions/constants.DeliveryStatus"
+                },
+                "task_id": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "entities.Webhooks": {
+            "type": "object",
+            "properties": {
+                "webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.Webhook"
+                    }
+                }
+            }
+        },
+        "entities.Workflow": {
+            "type": "object",
+            "properties": {
+                "states": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/entities.WorkflowState"
+                    }
+                }
+            }
+        },
+        "entities.WorkflowState": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "$ref": "#/definitions/constants.Status"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "transitions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/constants.Status"
+                    }
+                }
+            }
+        },
+        "views.AddBlockersReq": {
+            "type": "object",
+            "required": [
+                "blocker_ids"
+            ],
+            "properties": {
+                "blocker_ids": {
+                    "type": "array",
+                    "maxItems": 50,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "views.AttachTagsReq": {
+            "type": "object",
+            "required": [
+                "tag_ids"
+            ],
+            "properties": {
+                "tag_ids": {
+                    "type": "array",
+                    "maxItems": 50,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "views.BatchOperationReq": {
+            "type": "object",
+            "required": [
+                "op"
+            ],
+            "properties": {
+                "due_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "op": {
+                    "type": "string",
+                    "enum": [
+                        "create",
+                        "update",
+                        "delete"
+                    ]
+                },
+                "parent_id": {
+                    "type": "string"
+                },
+                "priority": {
+                    "enum": [
+                        0,
+                        1,
+                        2,
+                        3
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/constants.Priority"
+                        }
+                    ]
+                },
+                "recurrence": {
+                    "type": "string"
+                },
+                "remind_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/constants.Status"
+                        }
+                    ],
+                    "example": 2
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "views.BatchTaskReq": {
+            "type": "object",
+            "required": [
+                "operations"
+            ],
+            "properties": {
+                "mode": {
+                    "type": "string",
+                    "enum": [
+                        "atomic",
+                        "best_effort"
+                    ]
+                },
+                "operations": {
+                    "type": "array",
+                    "maxItems": 100,
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/views.BatchOperationReq"
+                    }
+                }
+            }
+        },
+        "views.CreateTagReq": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "color": {
+                    "type": "string",
+                    "example": "#1e90ff"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
         },
         "views.CreateTaskReq": {
             "type": "object",
@@ -219,22 +3066,191 @@ const docTemplate = `{
                 "name"
             ],
             "properties": {
+                "due_at": {
+                    "type": "string"
+                },
                 "name": {
                     "type": "string"
+                },
+                "parent_id": {
+                    "type": "string"
+                },
+                "priority": {
+                    "enum": [
+                        0,
+                        1,
+                        2,
+                        3
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/constants.Priority"
+                        }
+                    ]
+                },
+                "recurrence": {
+                    "description": "Recurrence RRULE，例如 FREQ=WEEKLY;BYDAY=MO，需要同時設定 due_at",
+                    "type": "string",
+                    "example": "FREQ=WEEKLY;BYDAY=MO"
+                },
+                "remind_at": {
+                    "type": "string"
+                }
+            }
+        },
+        "views.CreateWebhookReq": {
+            "type": "object",
+            "required": [
+                "events",
+                "url"
+            ],
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/constants.WebhookEvent"
+                    },
+                    "example": [
+                        "task.created",
+                        "task.updated",
+                        "task.deleted"
+                    ]
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/tasks"
+                }
+            }
+        },
+        "views.MoveTaskReq": {
+            "type": "object",
+            "properties": {
+                "after": {
+                    "type": "string"
+                },
+                "before": {
+                    "type": "string"
+                }
+            }
+        },
+        "views.SortTasksReq": {
+            "type": "object",
+            "required": [
+                "task_ids"
+            ],
+            "properties": {
+                "task_ids": {
+                    "type": "array",
+                    "maxItems": 100,
+                    "minItems": 1,
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "views.UpdateTagReq": {
+            "type": "object",
+            "required": [
+                "name"
+            ],
+            "properties": {
+                "color": {
+                    "type": "string",
+                    "example": "#1e90ff"
+                },
+                "name": {
+                    "type": "string",
+                    "minLength": 1
                 }
             }
         },
         "views.UpdateTaskReq": {
             "type": "object",
+            "required": [
+                "name",
+                "status"
+            ],
             "properties": {
+                "due_at": {
+                    "type": "string"
+                },
                 "id": {
                     "type": "string"
                 },
                 "name": {
+                    "type": "string",
+                    "minLength": 1
+                },
+                "parent_id": {
+                    "type": "string"
+                },
+                "priority": {
+                    "enum": [
+                        0,
+                        1,
+                        2,
+                        3
+                    ],
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/constants.Priority"
+                        }
+                    ]
+                },
+                "recurrence": {
+                    "type": "string",
+                    "example": "FREQ=WEEKLY;BYDAY=MO"
+                },
+                "remind_at": {
                     "type": "string"
                 },
                 "status": {
-                    "$ref": "#/definitions/constants.Status"
+                    "description": "Status workflow 中狀態的 id，0 為 todo，1 為 done，其他狀態見 GET /tasks/workflow",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/constants.Status"
+                        }
+                    ],
+                    "example": 2
+                }
+            }
+        },
+        "views.UpdateWebhookReq": {
+            "type": "object",
+            "required": [
+                "events",
+                "url"
+            ],
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "events": {
+                    "type": "array",
+                    "minItems": 1,
+                    "items": {
+                        "$ref": "#/definitions/constants.WebhookEvent"
+                    },
+                    "example": [
+                        "task.created",
+                        "task.updated",
+                        "task.deleted"
+                    ]
+                },
+                "rotate_secret": {
+                    "type": "boolean"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string",
+                    "example": "https://example.com/hooks/tasks"
                 }
             }
         }
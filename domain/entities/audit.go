@@ -0,0 +1,67 @@
+package entities
+
+import (
+	"tasks/constants"
+	"time"
+)
+
+// FieldChange 欄位變更前後的值，新增時 Before 為 null，永久刪除時 After 為 null
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditEntry task 的一次變更，Version 為變更後的 version
+type AuditEntry struct {
+	ID        int64                 `json:"id"`
+	TaskID    string                `json:"task_id"`
+	Action    constants.AuditAction `json:"action"`
+	Actor     string                `json:"actor"`
+	Version   int                   `json:"version"`
+	Changes   []FieldChange         `json:"changes"`
+	CreatedAt time.Time             `json:"created_at"`
+	Hash      string                `json:"hash"`
+}
+
+// AuditQueryParam 條件為空時不過濾，Before 不為 0 時只列出 id 小於 Before 的紀錄
+type AuditQueryParam struct {
+	TaskID  string
+	Actor   string
+	Actions []constants.AuditAction
+	Since   *time.Time
+	Until   *time.Time
+	Before  int64
+	Size    int
+}
+
+// AuditLog 由新到舊排列，NextBefore 為下一頁的 before
+type AuditLog struct {
+	Entries    []AuditEntry `json:"entries"`
+	Size       int          `json:"size"`
+	NextBefore int64        `json:"next_before,omitempty"`
+}
+
+// AuditBreak hash chain 第一個斷開的位置，Expected 與 Actual 為 hash，chain 的筆數與 head 不符時為筆數
+type AuditBreak struct {
+	ID       int64                      `json:"id"`
+	TaskID   string                     `json:"task_id,omitempty"`
+	Reason   constants.AuditBreakReason `json:"reason"`
+	Expected string                     `json:"expected"`
+	Actual   string                     `json:"actual"`
+}
+
+// AuditVerification Checked 為驗證過的筆數，Broken 為 nil 表示 chain 完整
+type AuditVerification struct {
+	Valid   bool        `json:"valid"`
+	Checked int64       `json:"checked"`
+	Head    string      `json:"head"`
+	Broken  *AuditBreak `json:"broken,omitempty"`
+}
+
+// Break 記錄斷開的位置並將驗證結果標示為失敗
+func (v *AuditVerification) Break(id int64, taskID string, reason constants.AuditBreakReason, expected, actual string) *AuditVerification {
+	v.Valid = false
+	v.Broken = &AuditBreak{ID: id, TaskID: taskID, Reason: reason, Expected: expected, Actual: actual}
+	return v
+}
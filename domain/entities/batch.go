@@ -0,0 +1,40 @@
+package entities
+
+import (
+	"tasks/constants"
+	"time"
+)
+
+type BatchOperation struct {
+	Op         constants.BatchOp
+	ID         string
+	ParentID   *string
+	Name       *string
+	Status     *constants.Status
+	Priority   *constants.Priority
+	DueAt      *time.Time
+	RemindAt   *time.Time
+	Recurrence *string
+	Version    *int
+}
+
+type BatchError struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+type BatchResult struct {
+	Index  int               `json:"index"`
+	Op     constants.BatchOp `json:"op"`
+	ID     string            `json:"id,omitempty"`
+	Status int               `json:"status"`
+	Task   *Task             `json:"task,omitempty"`
+	Error  *BatchError       `json:"error,omitempty"`
+}
+
+type BatchResults struct {
+	Mode      constants.BatchMode `json:"mode"`
+	Committed bool                `json:"committed"`
+	Results   []BatchResult       `json:"results"`
+}
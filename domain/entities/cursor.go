@@ -0,0 +1,41 @@
+package entities
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"tasks/constants"
+)
+
+// Cursor 列表分頁游標，以排序欄位值與 id 作為 keyset
+type Cursor struct {
+	SortBy   constants.SortField `json:"s"`
+	Order    constants.SortOrder `json:"o"`
+	Value    interface{}         `json:"v"`
+	ID       string              `json:"id"`
+	Backward bool                `json:"b,omitempty"`
+}
+
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func DecodeCursor(token string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var cursor Cursor
+	if err = decoder.Decode(&cursor); err != nil {
+		return nil, err
+	}
+	if number, ok := cursor.Value.(json.Number); ok {
+		if cursor.Value, err = number.Int64(); err != nil {
+			return nil, err
+		}
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,41 @@
+package entities
+
+import (
+	"encoding/json"
+	"tasks/constants"
+	"time"
+)
+
+// TaskEvent Version 為事件發生後 task 的 version
+type TaskEvent struct {
+	ID        int64                   `json:"id"`
+	TaskID    string                  `json:"task_id"`
+	Seq       int64                   `json:"seq"`
+	Type      constants.TaskEventType `json:"type"`
+	Version   int                     `json:"version"`
+	Data      json.RawMessage         `json:"data" swaggertype:"object"`
+	Actor     string                  `json:"actor"`
+	CreatedAt time.Time               `json:"created_at"`
+}
+
+// TaskEvents 依 seq 由舊到新排列，NextAfter 為下一頁的 after
+type TaskEvents struct {
+	Events    []TaskEvent `json:"events"`
+	Size      int         `json:"size"`
+	NextAfter int64       `json:"next_after,omitempty"`
+}
+
+// AsOf 只能指定其中一個條件，Version 取該 version 最後的狀態，At 取該時間點的狀態
+type AsOf struct {
+	Version *int
+	At      *time.Time
+}
+
+// ProjectionRebuild 由事件重建 tasks 表的結果，Tasks 為事件中未永久刪除的 task 數，其餘為與事件不一致而修正的筆數
+type ProjectionRebuild struct {
+	Tasks    int  `json:"tasks"`
+	Inserted int  `json:"inserted"`
+	Updated  int  `json:"updated"`
+	Deleted  int  `json:"deleted"`
+	DryRun   bool `json:"dry_run"`
+}
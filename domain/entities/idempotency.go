@@ -0,0 +1,13 @@
+package entities
+
+// IdempotentResponse 以 Idempotency-Key 保存的原始回應
+type IdempotentResponse struct {
+	StatusCode int
+	Body       []byte
+}
+
+// IdempotencyLease 處理中的 Idempotency-Key，token 用來確認 lease 未被其他 request 接手
+type IdempotencyLease struct {
+	Key   string
+	Token string
+}
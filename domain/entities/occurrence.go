@@ -0,0 +1,10 @@
+package entities
+
+import "time"
+
+// Occurrences recurring task 接下來出現的時間，第一次為 task 的 due_at
+type Occurrences struct {
+	TaskID      string      `json:"task_id"`
+	Recurrence  string      `json:"recurrence"`
+	Occurrences []time.Time `json:"occurrences"`
+}
@@ -0,0 +1,13 @@
+package entities
+
+import "time"
+
+// Reminder task 到達提醒時間時送出的事件
+type Reminder struct {
+	TaskID   string     `json:"task_id"`
+	Name     string     `json:"name"`
+	DueAt    *time.Time `json:"due_at,omitempty"`
+	RemindAt *time.Time `json:"remind_at,omitempty"`
+	Overdue  bool       `json:"overdue"`
+	SentAt   time.Time  `json:"sent_at"`
+}
@@ -0,0 +1,14 @@
+package entities
+
+// SearchResult snippet 為 HTML escape 後的名稱，只以 <mark> 標示符合的文字，rank 越小越符合，不支援全文檢索時為 0
+type SearchResult struct {
+	Task    Task    `json:"task"`
+	Snippet string  `json:"snippet"`
+	Rank    float64 `json:"rank"`
+}
+
+type SearchResults struct {
+	Results []SearchResult `json:"results"`
+	Size    int            `json:"size"`
+	Page    int            `json:"page"`
+}
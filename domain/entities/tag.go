@@ -0,0 +1,30 @@
+package entities
+
+import (
+	"github.com/google/uuid"
+	"time"
+)
+
+type Tag struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// NewTag 建立一個新的 tag
+func NewTag(name, color string) Tag {
+	now := time.Now().UTC()
+	return Tag{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Color:     color,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+type Tags struct {
+	Tags []Tag `json:"tags"`
+}
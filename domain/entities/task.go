@@ -1,25 +1,109 @@
 package entities
 
 import (
+	"github.com/google/uuid"
 	"tasks/constants"
 	"time"
 )
 
 type Task struct {
-	ID        string           `json:"id"`
-	Name      string           `json:"name"`
-	Status    constants.Status `json:"status"`
-	Version   int              `json:"-"`
-	CreatedAt time.Time        `json:"-"`
+	ID       string             `json:"id"`
+	Name     string             `json:"name"`
+	Status   constants.Status   `json:"status"`
+	Priority constants.Priority `json:"priority"`
+	// ParentID 為 nil 時是最上層的 task
+	ParentID *string `json:"parent_id,omitempty"`
+	// Position 手動排序用的 key，以字典序排序，只能透過 move 修改
+	Position  string     `json:"position"`
+	Version   int        `json:"version"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DueAt     *time.Time `json:"due_at,omitempty"`
+	RemindAt  *time.Time `json:"remind_at,omitempty"`
+	// Recurrence RRULE，第一次出現為 due_at，完成後會建立下一次的 task 並把規則移過去
+	Recurrence *string `json:"recurrence,omitempty"`
+	// Overdue 依讀取當下的時間計算，不會寫入資料庫
+	Overdue bool `json:"overdue,omitempty"`
+	// Blocked 有未完成且不在垃圾桶中的 blocker，讀取時計算
+	Blocked bool `json:"blocked,omitempty"`
+	// Tags 依名稱排序，只能透過 tag 的 attach 與 detach 修改
+	Tags []Tag `json:"tags,omitempty"`
+}
+
+// NewTask 建立一個新的未完成 task
+func NewTask(name string) Task {
+	now := time.Now().UTC()
+	return Task{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Status:    constants.Incomplete,
+		Priority:  constants.PriorityMedium,
+		Version:   0,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// IsOverdue 未完成且已超過 due_at
+func (t Task) IsOverdue(now time.Time) bool {
+	return t.Status != constants.Complete && t.DueAt != nil && t.DueAt.Before(now)
+}
+
+// TaskPatch 部分更新，只有非 nil 的欄位會寫入，ParentID、DueAt、RemindAt 與 Recurrence 指向 nil 時清除該欄位
+type TaskPatch struct {
+	ID         string
+	ParentID   **string
+	Name       *string
+	Status     *constants.Status
+	Priority   *constants.Priority
+	Position   *string
+	DueAt      **time.Time
+	RemindAt   **time.Time
+	Recurrence **string
+}
+
+// TaskMove 將 task 移到 TargetID 之前，After 為 true 時移到之後
+type TaskMove struct {
+	TargetID string
+	After    bool
+}
+
+// TaskNode subtree 中的 task，Depth 為相對於 subtree 根節點的深度
+type TaskNode struct {
+	Task
+	Depth    int         `json:"depth"`
+	Children []*TaskNode `json:"children,omitempty"`
 }
 
 type Tasks struct {
-	Tasks []Task `json:"tasks"`
-	Size  int    `json:"size"`
-	Page  int    `json:"page"`
+	Tasks      []Task `json:"tasks"`
+	Size       int    `json:"size"`
+	Page       int    `json:"page,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
 }
 
 type TaskQueryParam struct {
-	Size   int `json:"size"`
-	Offset int `json:"offset"`
+	Size          int                  `json:"size"`
+	Offset        int                  `json:"offset"`
+	Statuses      []constants.Status   `json:"statuses"`
+	Priorities    []constants.Priority `json:"priorities"`
+	Keyword       string               `json:"keyword"`
+	CreatedAfter  *time.Time           `json:"created_after"`
+	CreatedBefore *time.Time           `json:"created_before"`
+	SortBy        constants.SortField  `json:"sort_by"`
+	Order         constants.SortOrder  `json:"order"`
+	Cursor        *Cursor              `json:"cursor"`
+	WithTotal     bool                 `json:"with_total"`
+	Trashed       bool                 `json:"trashed"`
+	Overdue       *bool                `json:"overdue"`
+	DueAfter      *time.Time           `json:"due_after"`
+	DueBefore     *time.Time           `json:"due_before"`
+	// TagsAny 有任一個 tag 的 task，TagsAll 所有 tag 都有的 task，皆以 tag 名稱比對
+	TagsAny []string `json:"tags_any"`
+	TagsAll []string `json:"tags_all"`
+	// ParentID 只列出這個 task 的直接子 task
+	ParentID *string `json:"parent_id"`
 }
@@ -0,0 +1,92 @@
+package entities
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/google/uuid"
+	"tasks/constants"
+	"time"
+)
+
+// Webhook Secret 只在建立或更換時回傳
+type Webhook struct {
+	ID        string                   `json:"id"`
+	URL       string                   `json:"url"`
+	Events    []constants.WebhookEvent `json:"events"`
+	Active    bool                     `json:"active"`
+	Secret    string                   `json:"secret,omitempty"`
+	CreatedAt time.Time                `json:"created_at"`
+	UpdatedAt time.Time                `json:"updated_at"`
+}
+
+// NewWebhook 建立一個啟用中的 webhook，未指定 secret 時隨機產生
+func NewWebhook(url string, events []constants.WebhookEvent, secret string) Webhook {
+	now := time.Now().UTC()
+	if secret == "" {
+		secret = NewWebhookSecret()
+	}
+	return Webhook{
+		ID:        uuid.New().String(),
+		URL:       url,
+		Events:    events,
+		Active:    true,
+		Secret:    secret,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// NewWebhookSecret 32 bytes 的隨機值
+func NewWebhookSecret() string {
+	secret := make([]byte, 32)
+	_, _ = rand.Read(secret)
+	return hex.EncodeToString(secret)
+}
+
+type Webhooks struct {
+	Webhooks []Webhook `json:"webhooks"`
+}
+
+// WebhookPayload 送給 webhook 的事件內容，Task 為變更後的 task，永久刪除時為刪除前的 task
+type WebhookPayload struct {
+	ID         string                 `json:"id"`
+	Event      constants.WebhookEvent `json:"event"`
+	Action     constants.AuditAction  `json:"action"`
+	Actor      string                 `json:"actor"`
+	TaskID     string                 `json:"task_id"`
+	Version    int                    `json:"version"`
+	Task       json.RawMessage        `json:"task" swaggertype:"object"`
+	Changes    []FieldChange          `json:"changes"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+type WebhookDelivery struct {
+	ID             int64                    `json:"id"`
+	EventID        string                   `json:"event_id"`
+	WebhookID      string                   `json:"webhook_id"`
+	Event          constants.WebhookEvent   `json:"event"`
+	TaskID         string                   `json:"task_id"`
+	Status         constants.DeliveryStatus `json:"status"`
+	Attempts       int                      `json:"attempts"`
+	NextAttemptAt  *time.Time               `json:"next_attempt_at,omitempty"`
+	LastStatusCode *int                     `json:"last_status_code,omitempty"`
+	LastError      string                   `json:"last_error,omitempty"`
+	Payload        json.RawMessage          `json:"payload" swaggertype:"object"`
+	CreatedAt      time.Time                `json:"created_at"`
+	DeliveredAt    *time.Time               `json:"delivered_at,omitempty"`
+}
+
+// WebhookDeliveries 依 id 由新到舊排列，NextBefore 為下一頁的 before
+type WebhookDeliveries struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Size       int               `json:"size"`
+	NextBefore int64             `json:"next_before,omitempty"`
+}
+
+type DeliveryQueryParam struct {
+	WebhookID string
+	Status    constants.DeliveryStatus
+	Before    int64
+	Size      int
+}
@@ -0,0 +1,54 @@
+package entities
+
+import "tasks/constants"
+
+// WorkflowState task 可以處於的狀態，ID 即為 task 的 status，Transitions 為可以改為的狀態
+type WorkflowState struct {
+	ID          constants.Status   `json:"id"`
+	Name        string             `json:"name"`
+	Transitions []constants.Status `json:"transitions"`
+}
+
+type Workflow struct {
+	States []WorkflowState `json:"states"`
+}
+
+// State 找不到 status 時 ok 為 false
+func (w Workflow) State(status constants.Status) (state WorkflowState, ok bool) {
+	for _, state = range w.States {
+		if state.ID == status {
+			return state, true
+		}
+	}
+	return WorkflowState{}, false
+}
+
+// Allows 狀態不變時一律允許
+func (w Workflow) Allows(from, to constants.Status) bool {
+	if from == to {
+		return true
+	}
+	state, ok := w.State(from)
+	if !ok {
+		return false
+	}
+	for _, next := range state.Transitions {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// StateRef workflow 中的一個狀態，不含其轉換
+type StateRef struct {
+	ID   constants.Status `json:"id"`
+	Name string           `json:"name,omitempty"`
+}
+
+// TransitionDetails TransitionNotAllowed 錯誤回應的 details，Allowed 為目前狀態可以改為的狀態
+type TransitionDetails struct {
+	From    StateRef   `json:"from"`
+	To      StateRef   `json:"to"`
+	Allowed []StateRef `json:"allowed"`
+}
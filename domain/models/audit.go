@@ -0,0 +1,21 @@
+package models
+
+// AuditEntry Changes 為 entities.FieldChange 陣列的 JSON
+type AuditEntry struct {
+	ID        int64
+	TaskID    string
+	Action    string
+	Actor     string
+	Version   int
+	Changes   string
+	CreatedAt string
+	PrevHash  string
+	Hash      string
+}
+
+// AuditHead audit log hash chain 的最後一筆，Seq 為 chain 中的筆數
+type AuditHead struct {
+	Seq    int64
+	LastID int64
+	Hash   string
+}
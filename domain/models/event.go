@@ -0,0 +1,22 @@
+package models
+
+// TaskEvent Seq 為事件在同一個 task 中的序號，由 1 開始，Data 為事件內容的 JSON
+type TaskEvent struct {
+	ID        int64
+	TaskID    string
+	Seq       int64
+	Type      string
+	Version   int
+	Data      string
+	Actor     string
+	CreatedAt string
+}
+
+// TaskSnapshot 套用到 Seq 為止的事件後的 task，State 為 Task 的 JSON
+type TaskSnapshot struct {
+	TaskID    string
+	Seq       int64
+	Version   int
+	State     string
+	CreatedAt string
+}
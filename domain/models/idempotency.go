@@ -0,0 +1,10 @@
+package models
+
+type IdempotencyKey struct {
+	Key         string  `json:"idempotency_key"`
+	RequestHash string  `json:"request_hash"`
+	StatusCode  int     `json:"status_code"`
+	Response    *string `json:"response"`
+	CreatedAt   string  `json:"created_at"`
+	ExpiresAt   string  `json:"expires_at"`
+}
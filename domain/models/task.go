@@ -1,12 +1,34 @@
 package models
 
 type Task struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Status    int    `json:"status"`
-	Version   int    `json:"version"`
-	CreatedAt string `json:"created_at"`
+	ID         string  `json:"id"`
+	ParentID   *string `json:"parent_id"`
+	Name       string  `json:"name"`
+	Status     int     `json:"status"`
+	Priority   int     `json:"priority"`
+	Position   string  `json:"position"`
+	Version    int     `json:"version"`
+	CreatedAt  string  `json:"created_at"`
+	UpdatedAt  string  `json:"updated_at"`
+	DeletedAt  *string `json:"deleted_at"`
+	DueAt      *string `json:"due_at"`
+	RemindAt   *string `json:"remind_at"`
+	Recurrence *string `json:"recurrence"`
+	Blocked    bool    `json:"blocked"`
+}
+
+// TaskNode subtree 查詢的結果，Depth 為相對於 subtree 根節點的深度
+type TaskNode struct {
+	Task
+	Depth int
 }
 
 type TaskQueryParam struct {
 }
+
+// SearchResult Rank 為 bm25 的分數，越小越符合，不支援全文檢索時為 0
+type SearchResult struct {
+	Task
+	Snippet string
+	Rank    float64
+}
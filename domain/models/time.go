@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// timeLayouts 資料庫時間欄位可能的文字格式，sqlite driver 寫入時使用第一種，
+// postgres 與開啟 parseTime 的 mysql 讀出 time.Time 後由 database/sql 轉為 RFC3339
+var timeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// ParseTime 解析資料庫讀出的時間字串，無法解析時回傳零值
+func ParseTime(value string) time.Time {
+	for _, layout := range timeLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed.UTC()
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,36 @@
+package models
+
+// Webhook Events 為以逗號分隔的事件名稱
+type Webhook struct {
+	ID        string
+	URL       string
+	Secret    string
+	Events    string
+	Active    bool
+	CreatedAt string
+	UpdatedAt string
+}
+
+// WebhookDelivery webhook outbox 中的一筆投遞，同一次變更送往各 webhook 的投遞有相同的 EventID
+type WebhookDelivery struct {
+	ID             int64
+	EventID        string
+	WebhookID      string
+	Event          string
+	TaskID         string
+	Payload        string
+	Status         string
+	Attempts       int
+	NextAttemptAt  string
+	LastStatusCode *int
+	LastError      *string
+	CreatedAt      string
+	DeliveredAt    *string
+}
+
+// DueDelivery 到期的投遞與其 webhook 的位置及 secret
+type DueDelivery struct {
+	WebhookDelivery
+	URL    string
+	Secret string
+}
@@ -0,0 +1,17 @@
+package models
+
+type WorkflowState struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type WorkflowTransition struct {
+	From int `json:"from"`
+	To   int `json:"to"`
+}
+
+// Workflow 狀態依 position 排序
+type Workflow struct {
+	States      []*WorkflowState
+	Transitions []*WorkflowTransition
+}
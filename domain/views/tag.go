@@ -0,0 +1,16 @@
+package views
+
+type CreateTagReq struct {
+	Name  string  `json:"name" binding:"required"`
+	Color *string `json:"color,omitempty" example:"#1e90ff"`
+}
+
+// UpdateTagReq 整筆取代，未帶 color 會清除該欄位
+type UpdateTagReq struct {
+	Name  *string `json:"name" binding:"required,min=1"`
+	Color *string `json:"color" example:"#1e90ff"`
+}
+
+type AttachTagsReq struct {
+	TagIDs []string `json:"tag_ids" binding:"required,min=1,max=50"`
+}
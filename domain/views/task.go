@@ -1,24 +1,130 @@
 package views
 
-import "tasks/constants"
+import (
+	"tasks/constants"
+	"time"
+)
 
 type GetTasksReq struct {
-	Size   *int    `json:"size"`
-	Page   *int    `json:"page" `
-	SortBy *string `json:"sort"`
-	Order  *string `json:"order"`
+	Size          *int                 `json:"size" form:"size"`
+	Page          *int                 `json:"page" form:"page"`
+	SortBy        *string              `json:"sort" form:"sort"`
+	Order         *string              `json:"order" form:"order"`
+	Status        []constants.Status   `json:"status" form:"status"`
+	Priority      []constants.Priority `json:"priority" form:"priority"`
+	Keyword       *string              `json:"q" form:"q"`
+	CreatedAfter  *time.Time           `json:"created_after" form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore *time.Time           `json:"created_before" form:"created_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	Cursor        *string              `json:"cursor" form:"cursor"`
+	WithTotal     bool                 `json:"with_total" form:"with_total"`
+	Overdue       *bool                `json:"overdue" form:"overdue"`
+	DueAfter      *time.Time           `json:"due_after" form:"due_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	DueBefore     *time.Time           `json:"due_before" form:"due_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	TagsAny       []string             `json:"tags_any" form:"tags_any"`
+	TagsAll       []string             `json:"tags_all" form:"tags_all"`
+	ParentID      *string              `json:"parent_id" form:"parent_id"`
 }
 
 type CreateTaskReq struct {
-	Name string `json:"name" binding:"required"`
+	Name     string              `json:"name" binding:"required"`
+	ParentID *string             `json:"parent_id,omitempty"`
+	Priority *constants.Priority `json:"priority,omitempty" enums:"0,1,2,3"`
+	DueAt    *time.Time          `json:"due_at,omitempty"`
+	RemindAt *time.Time          `json:"remind_at,omitempty"`
+	// Recurrence RRULE，例如 FREQ=WEEKLY;BYDAY=MO，需要同時設定 due_at
+	Recurrence *string `json:"recurrence,omitempty" example:"FREQ=WEEKLY;BYDAY=MO"`
 }
 
+// UpdateTaskReq 整筆取代，未帶 parent_id、due_at、remind_at 或 recurrence 會清除該欄位，未帶 priority 時為預設值
 type UpdateTaskReq struct {
-	ID     string           `json:"id" uri:"id"`
-	Name   *string          `json:"name"`
-	Status constants.Status `json:"status" enum:"0,1"`
+	ID       string  `json:"id" uri:"id"`
+	ParentID *string `json:"parent_id"`
+	Name     *string `json:"name" binding:"required,min=1"`
+	// Status workflow 中狀態的 id，0 為 todo，1 為 done，其他狀態見 GET /tasks/workflow
+	Status     *constants.Status   `json:"status" binding:"required" example:"2"`
+	Priority   *constants.Priority `json:"priority" enums:"0,1,2,3"`
+	DueAt      *time.Time          `json:"due_at"`
+	RemindAt   *time.Time          `json:"remind_at"`
+	Recurrence *string             `json:"recurrence" example:"FREQ=WEEKLY;BYDAY=MO"`
+}
+
+// MoveTaskReq before 與 after 只能擇一
+type MoveTaskReq struct {
+	Before *string `json:"before"`
+	After  *string `json:"after"`
+}
+
+// GetSubtreeReq 未帶 depth 時回傳所有層
+type GetSubtreeReq struct {
+	Depth *int `json:"depth" form:"depth"`
+}
+
+// GetOccurrencesReq 未帶 count 時列出 5 次
+type GetOccurrencesReq struct {
+	Count *int `json:"count" form:"count"`
+}
+
+// GetAuditReq 未帶 size 時每頁 50 筆，before 為上一頁回傳的 next_before
+type GetAuditReq struct {
+	TaskID *string                 `json:"task_id" form:"task_id"`
+	Actor  *string                 `json:"actor" form:"actor"`
+	Action []constants.AuditAction `json:"action" form:"action"`
+	Since  *time.Time              `json:"since" form:"since" time_format:"2006-01-02T15:04:05Z07:00"`
+	Until  *time.Time              `json:"until" form:"until" time_format:"2006-01-02T15:04:05Z07:00"`
+	Before *int64                  `json:"before" form:"before"`
+	Size   *int                    `json:"size" form:"size"`
+}
+
+// GetEventsReq 未帶 size 時每頁 100 筆，after 為上一頁回傳的 next_after
+type GetEventsReq struct {
+	After *int64 `json:"after" form:"after"`
+	Size  *int   `json:"size" form:"size"`
+}
+
+// GetTaskAsOfReq version 與 at 只能指定其中一個
+type GetTaskAsOfReq struct {
+	Version *int       `json:"version" form:"version"`
+	At      *time.Time `json:"at" form:"at" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+// RebuildProjectionReq dry_run 時只回傳會修正的筆數
+type RebuildProjectionReq struct {
+	DryRun bool `json:"dry_run" form:"dry_run"`
+}
+
+// SearchTasksReq 未帶 size 時每頁 20 筆，page 從 1 開始
+type SearchTasksReq struct {
+	Query string `json:"q" form:"q" binding:"required"`
+	Size  *int   `json:"size" form:"size"`
+	Page  *int   `json:"page" form:"page"`
+}
+
+type AddBlockersReq struct {
+	BlockerIDs []string `json:"blocker_ids" binding:"required,min=1,max=50"`
+}
+
+type SortTasksReq struct {
+	TaskIDs []string `json:"task_ids" binding:"required,min=1,max=100"`
 }
 
 type DeleteTaskReq struct {
 	ID string `json:"id" uri:"id" binding:"required"`
 }
+
+type BatchTaskReq struct {
+	Mode       string              `json:"mode" enums:"atomic,best_effort"`
+	Operations []BatchOperationReq `json:"operations" binding:"required,min=1,max=100,dive"`
+}
+
+type BatchOperationReq struct {
+	Op         string              `json:"op" binding:"required,oneof=create update delete" enums:"create,update,delete"`
+	ID         string              `json:"id"`
+	ParentID   *string             `json:"parent_id"`
+	Name       *string             `json:"name"`
+	Status     *constants.Status   `json:"status" example:"2"`
+	Priority   *constants.Priority `json:"priority" enums:"0,1,2,3"`
+	DueAt      *time.Time          `json:"due_at"`
+	RemindAt   *time.Time          `json:"remind_at"`
+	Recurrence *string             `json:"recurrence"`
+	Version    *int                `json:"version"`
+}
@@ -0,0 +1,26 @@
+package views
+
+import "tasks/constants"
+
+// CreateWebhookReq 未帶 secret 時由伺服器產生，只在回應中出現一次
+type CreateWebhookReq struct {
+	URL    string                   `json:"url" binding:"required" example:"https://example.com/hooks/tasks"`
+	Events []constants.WebhookEvent `json:"events" binding:"required,min=1" example:"task.created,task.updated,task.deleted"`
+	Secret *string                  `json:"secret,omitempty"`
+}
+
+// UpdateWebhookReq 整筆取代，未帶 active 時為啟用；帶 secret 或 rotate_secret 時更換 secret
+type UpdateWebhookReq struct {
+	URL          string                   `json:"url" binding:"required" example:"https://example.com/hooks/tasks"`
+	Events       []constants.WebhookEvent `json:"events" binding:"required,min=1" example:"task.created,task.updated,task.deleted"`
+	Active       *bool                    `json:"active"`
+	Secret       *string                  `json:"secret,omitempty"`
+	RotateSecret bool                     `json:"rotate_secret"`
+}
+
+// GetDeliveriesReq 未帶 size 時每頁 50 筆，before 為上一頁回傳的 next_before
+type GetDeliveriesReq struct {
+	Status constants.DeliveryStatus `json:"status" form:"status" enums:"pending,delivered,dead"`
+	Before *int64                   `json:"before" form:"before"`
+	Size   *int                     `json:"size" form:"size"`
+}
@@ -13,6 +13,31 @@ var (
 	InternalServerPanic = NewCustomError(559201000, StatusInternalServerError, "internal server panic")
 	InternalServerError = NewCustomError(559201001, StatusInternalServerError, "internal server error")
 	TaskNotFound        = NewCustomError(559201003, StatusNotFound, "task not found")
+	TaskVersionMismatch = NewCustomError(559201004, StatusPreconditionFailed, "task version does not match")
+	TaskVersionConflict = NewCustomError(559201005, StatusConflict, "task was modified concurrently")
+	BatchAborted        = NewCustomError(559201006, StatusFailedDependency, "batch aborted, operation was rolled back")
+
+	IdempotencyKeyReused     = NewCustomError(559201007, StatusUnprocessableEntity, "idempotency key was used with a different request")
+	IdempotencyKeyInProgress = NewCustomError(559201008, StatusConflict, "a request with this idempotency key is in progress")
+
+	TagNotFound     = NewCustomError(559201009, StatusNotFound, "tag not found")
+	TagNameConflict = NewCustomError(559201010, StatusConflict, "tag name already exists")
+
+	TaskHasSubtasks   = NewCustomError(559201011, StatusConflict, "task has subtasks")
+	TaskParentDeleted = NewCustomError(559201012, StatusConflict, "parent task is deleted")
+
+	TaskBlocked     = NewCustomError(559201013, StatusConflict, "task is blocked by incomplete tasks")
+	DependencyCycle = NewCustomError(559201020, StatusConflict, "dependency would form a cycle")
+
+	TransitionNotAllowed = NewCustomError(559201014, StatusConflict, "status transition is not allowed")
+
+	EventSourcingDisabled = NewCustomError(559201015, StatusNotImplemented, "event sourcing is not enabled")
+
+	WebhookNotFound  = NewCustomError(559201016, StatusNotFound, "webhook not found")
+	DeliveryNotFound = NewCustomError(559201017, StatusNotFound, "webhook delivery not found")
+	DeliveryPending  = NewCustomError(559201018, StatusConflict, "webhook delivery is still pending")
+
+	TaskPositionConflict = NewCustomError(559201019, StatusConflict, "task position was taken by a concurrent write")
 )
 
 type CustomError struct {
@@ -21,6 +46,7 @@ type CustomError struct {
 	message      string
 	errorMessage string
 	cause        error
+	details      interface{}
 }
 
 func NewCustomError(code int, status Status, message string) CustomError {
@@ -31,6 +57,12 @@ func NewCustomError(code int, status Status, message string) CustomError {
 	}
 }
 
+// WithDetails 附加對外回應的 details，例如允許的狀態轉換
+func (e CustomError) WithDetails(details interface{}) CustomError {
+	e.details = details
+	return e
+}
+
 func (e CustomError) New(msg string) error {
 	e.cause = errors.New(msg)
 	return errors.WithStack(e)
@@ -97,8 +129,14 @@ func (e CustomError) Status() Status {
 	return e.status
 }
 
+// Details 取得對外回應的 details，沒有時為 nil
+func (e CustomError) Details() interface{} {
+	return e.details
+}
+
+// IsEmpty details 可能是無法比較的型別，只比較其他欄位
 func (e CustomError) IsEmpty() bool {
-	return e == CustomError{}
+	return e.code == 0 && e.status == "" && e.message == "" && e.errorMessage == "" && e.cause == nil && e.details == nil
 }
 
 func (e CustomError) Is(err error) bool {
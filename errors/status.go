@@ -11,9 +11,15 @@ const (
 	StatusUnauthorized        Status = "Unauthorized"
 	StatusForbidden           Status = "Forbidden"
 	StatusNotFound            Status = "NotFound"
+	StatusConflict            Status = "Conflict"
+	StatusPreconditionFailed  Status = "PreconditionFailed"
+	StatusUnsupportedMedia    Status = "UnsupportedMediaType"
+	StatusUnprocessableEntity Status = "UnprocessableEntity"
+	StatusFailedDependency    Status = "FailedDependency"
 	StatusTooManyRequests     Status = "TooManyRequests"
 	StatusBadGateway          Status = "BadGateway"
 	StatusInternalServerError Status = "InternalServerError"
+	StatusNotImplemented      Status = "NotImplemented"
 	StatusServiceUnavailable  Status = "ServiceUnavailable"
 	StatusGatewayTimeout      Status = "GatewayTimeout"
 )
@@ -28,12 +34,24 @@ func (s Status) ToHTTPStatus() int {
 		return http.StatusForbidden
 	case StatusNotFound:
 		return http.StatusNotFound
+	case StatusConflict:
+		return http.StatusConflict
+	case StatusPreconditionFailed:
+		return http.StatusPreconditionFailed
+	case StatusUnsupportedMedia:
+		return http.StatusUnsupportedMediaType
+	case StatusUnprocessableEntity:
+		return http.StatusUnprocessableEntity
+	case StatusFailedDependency:
+		return http.StatusFailedDependency
 	case StatusTooManyRequests:
 		return http.StatusTooManyRequests
 	case StatusBadGateway:
 		return http.StatusBadGateway
 	case StatusInternalServerError:
 		return http.StatusInternalServerError
+	case StatusNotImplemented:
+		return http.StatusNotImplemented
 	case StatusServiceUnavailable:
 		return http.StatusServiceUnavailable
 	case StatusGatewayTimeout:
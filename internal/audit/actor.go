@@ -0,0 +1,21 @@
+package audit
+
+import "context"
+
+// SystemActor 沒有請求者時使用，例如背景工作清除垃圾桶
+const SystemActor = "system"
+
+type actorKey struct{}
+
+// WithActor 讓同一個 context 中的寫入都記錄為 actor 所做的變更
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// Actor 取得 context 中的 actor，未設定時回傳 SystemActor
+func Actor(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
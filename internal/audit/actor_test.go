@@ -0,0 +1,15 @@
+package audit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestActor(t *testing.T) {
+	ctx := context.Background()
+
+	assert.Equal(t, SystemActor, Actor(ctx))
+	assert.Equal(t, "alice", Actor(WithActor(ctx, "alice")))
+	assert.Equal(t, SystemActor, Actor(WithActor(ctx, "")))
+}
@@ -0,0 +1,39 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"time"
+)
+
+// Record 一筆 audit log 中納入 hash 的內容，id 由資料庫產生所以不納入，順序由 prev hash 保證
+type Record struct {
+	TaskID    string
+	Action    string
+	Actor     string
+	Version   int
+	Changes   string
+	CreatedAt time.Time
+}
+
+// Now 資料庫只保存到微秒，寫入前先截斷，讀回時才能算出相同的 hash
+func Now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
+}
+
+// Hash 以 SHA-256 串接前一筆的 hash 與本筆內容，第一筆的 prevHash 為空字串
+func Hash(prevHash string, record Record) string {
+	// JSON 陣列保留欄位邊界，避免不同欄位內容串接後相同
+	content, _ := json.Marshal([]interface{}{
+		prevHash,
+		record.TaskID,
+		record.Action,
+		record.Actor,
+		record.Version,
+		record.Changes,
+		record.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
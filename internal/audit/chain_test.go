@@ -0,0 +1,62 @@
+package audit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHash(t *testing.T) {
+	record := Record{
+		TaskID:    "task-1",
+		Action:    "update",
+		Actor:     "alice",
+		Version:   2,
+		Changes:   `[{"field":"name","before":"Draft","after":"Final"}]`,
+		CreatedAt: time.Date(2024, 1, 1, 9, 0, 0, 123456000, time.UTC),
+	}
+	hash := Hash("", record)
+
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, Hash("", record))
+
+	t.Run("same instant in another zone", func(t *testing.T) {
+		moved := record
+		moved.CreatedAt = record.CreatedAt.In(time.FixedZone("UTC+8", 8*60*60))
+		assert.Equal(t, hash, Hash("", moved))
+	})
+
+	t.Run("depends on previous hash", func(t *testing.T) {
+		assert.NotEqual(t, hash, Hash(hash, record))
+	})
+
+	t.Run("depends on every field", func(t *testing.T) {
+		changed := []func(r *Record){
+			func(r *Record) { r.TaskID = "task-2" },
+			func(r *Record) { r.Action = "delete" },
+			func(r *Record) { r.Actor = "bob" },
+			func(r *Record) { r.Version = 3 },
+			func(r *Record) { r.Changes = "[]" },
+			func(r *Record) { r.CreatedAt = r.CreatedAt.Add(time.Microsecond) },
+		}
+		for _, change := range changed {
+			modified := record
+			change(&modified)
+			assert.NotEqual(t, hash, Hash("", modified))
+		}
+	})
+
+	t.Run("field boundaries", func(t *testing.T) {
+		a, b := record, record
+		a.TaskID, a.Action = "task-1u", "pdate"
+		b.TaskID, b.Action = "task-1", "update"
+		assert.NotEqual(t, Hash("", a), Hash("", b))
+	})
+}
+
+func TestNow(t *testing.T) {
+	now := Now()
+
+	assert.Equal(t, time.UTC, now.Location())
+	assert.Zero(t, now.Nanosecond()%1000)
+}
@@ -0,0 +1,180 @@
+package dialect
+
+import (
+	"errors"
+	"fmt"
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"strconv"
+	"strings"
+)
+
+// Dialect 各資料庫 SQL 語法的差異，repository 一律以 ? 作為 placeholder 撰寫 SQL
+type Dialect interface {
+	Name() string
+	// Rebind 將 ? placeholder 轉為資料庫使用的格式
+	Rebind(query string) string
+	// TimestampType 時間欄位的型別
+	TimestampType() string
+	// TransactionalDDL DDL 能否在 transaction 中回滾，不能時失敗的 migration 可能只套用了一部分
+	TransactionalDDL() bool
+	// Contains 不分大小寫的子字串比對條件，使用一個 placeholder，以 ! 作為跳脫字元
+	Contains(column string) string
+	// Upsert 產生 INSERT 語法，key 衝突且 when 成立時以新資料覆寫 update 欄位
+	Upsert(table string, columns []string, key string, update []string, when Condition) string
+	// IsUniqueViolation 判斷寫入錯誤是否因為違反 unique index 或 primary key
+	IsUniqueViolation(err error) bool
+}
+
+// Condition 組出 upsert 的覆寫條件，existing 與 incoming 分別取得既有資料列與新資料列的欄位
+type Condition func(existing, incoming func(column string) string) string
+
+var dialects = map[string]Dialect{
+	"sqlite3":  SQLite,
+	"postgres": Postgres,
+	"mysql":    MySQL,
+}
+
+var (
+	SQLite   Dialect = sqlite{}
+	Postgres Dialect = postgres{}
+	MySQL    Dialect = mysql{}
+)
+
+// ForDriver 依 db.driver 取得對應的 dialect
+func ForDriver(driver string) (Dialect, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("driver %s not supported", driver)
+	}
+	return d, nil
+}
+
+type sqlite struct{}
+
+func (sqlite) Name() string { return "sqlite3" }
+
+func (sqlite) Rebind(query string) string { return query }
+
+func (sqlite) TimestampType() string { return "TEXT" }
+
+func (sqlite) TransactionalDDL() bool { return true }
+
+func (sqlite) Contains(column string) string {
+	return column + " LIKE ? ESCAPE '!'"
+}
+
+func (sqlite) Upsert(table string, columns []string, key string, update []string, when Condition) string {
+	return onConflict(table, columns, key, update, when)
+}
+
+func (sqlite) IsUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+type postgres struct{}
+
+func (postgres) Name() string { return "postgres" }
+
+func (postgres) Rebind(query string) string {
+	var (
+		result   strings.Builder
+		index    int
+		inString bool
+	)
+	result.Grow(len(query) + 8)
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			inString = !inString
+		case r == '?' && !inString:
+			index++
+			result.WriteString("$" + strconv.Itoa(index))
+			continue
+		}
+		result.WriteRune(r)
+	}
+	return result.String()
+}
+
+func (postgres) TimestampType() string { return "TIMESTAMPTZ" }
+
+func (postgres) TransactionalDDL() bool { return true }
+
+func (postgres) Contains(column string) string {
+	return column + " ILIKE ? ESCAPE '!'"
+}
+
+func (postgres) Upsert(table string, columns []string, key string, update []string, when Condition) string {
+	return onConflict(table, columns, key, update, when)
+}
+
+func (postgres) IsUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// onConflict sqlite 與 postgres 共用的 ON CONFLICT 語法
+func onConflict(table string, columns []string, key string, update []string, when Condition) string {
+	assignments := make([]string, 0, len(update))
+	for _, column := range update {
+		assignments = append(assignments, column+" = excluded."+column)
+	}
+	query := insert(table, columns) + " ON CONFLICT(" + key + ") DO UPDATE SET " + strings.Join(assignments, ", ")
+	if when != nil {
+		query += " WHERE " + when(
+			func(column string) string { return table + "." + column },
+			func(column string) string { return "excluded." + column },
+		)
+	}
+	return query
+}
+
+type mysql struct{}
+
+func (mysql) Name() string { return "mysql" }
+
+func (mysql) Rebind(query string) string { return query }
+
+func (mysql) TimestampType() string { return "DATETIME(6)" }
+
+// TransactionalDDL MySQL 執行 DDL 前會隱含 commit，migration 無法整個回滾
+func (mysql) TransactionalDDL() bool { return false }
+
+// Contains 大小寫是否相符取決於欄位 collation，統一轉小寫比對
+func (mysql) Contains(column string) string {
+	return "LOWER(" + column + ") LIKE LOWER(?) ESCAPE '!'"
+}
+
+// Upsert MySQL 沒有 ON CONFLICT ... WHERE，改以 IF 判斷每個欄位，
+// 欄位依序更新，判斷條件用到的欄位必須放在 update 的最後
+func (mysql) Upsert(table string, columns []string, key string, update []string, when Condition) string {
+	assignments := make([]string, 0, len(update))
+	for _, column := range update {
+		incoming := "VALUES(" + column + ")"
+		if when == nil {
+			assignments = append(assignments, column+" = "+incoming)
+			continue
+		}
+		condition := when(
+			func(column string) string { return column },
+			func(column string) string { return "VALUES(" + column + ")" },
+		)
+		assignments = append(assignments, fmt.Sprintf("%s = IF(%s, %s, %s)", column, condition, incoming, column))
+	}
+	return insert(table, columns) + " ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", ")
+}
+
+// IsUniqueViolation 1062 為 ER_DUP_ENTRY
+func (mysql) IsUniqueViolation(err error) bool {
+	var mysqlErr *mysqlDriver.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+func insert(table string, columns []string) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	return "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES (" + placeholders + ")"
+}
@@ -0,0 +1,70 @@
+package dialect
+
+import (
+	"errors"
+	"fmt"
+	mysqlDriver "github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func expiredCondition(existing, incoming func(string) string) string {
+	return existing("expires_at") + " < " + incoming("created_at")
+}
+
+func TestForDriver(t *testing.T) {
+	d, err := ForDriver("postgres")
+	assert.NoError(t, err)
+	assert.Equal(t, Postgres, d)
+
+	_, err = ForDriver("oracle")
+	assert.Error(t, err)
+}
+
+func TestRebind(t *testing.T) {
+	query := `SELECT id FROM tasks WHERE name LIKE ? ESCAPE '!' AND note = 'why?' AND id > ? LIMIT ?`
+
+	assert.Equal(t, query, SQLite.Rebind(query))
+	assert.Equal(t, query, MySQL.Rebind(query))
+	assert.Equal(t, `SELECT id FROM tasks WHERE name LIKE $1 ESCAPE '!' AND note = 'why?' AND id > $2 LIMIT $3`, Postgres.Rebind(query))
+}
+
+func TestUpsert(t *testing.T) {
+	columns := []string{"k", "created_at", "expires_at"}
+	update := []string{"created_at", "expires_at"}
+
+	t.Run("on conflict", func(t *testing.T) {
+		expected := "INSERT INTO keys (k, created_at, expires_at) VALUES (?, ?, ?) ON CONFLICT(k) DO UPDATE SET " +
+			"created_at = excluded.created_at, expires_at = excluded.expires_at WHERE keys.expires_at < excluded.created_at"
+		assert.Equal(t, expected, SQLite.Upsert("keys", columns, "k", update, expiredCondition))
+		assert.Equal(t, expected, Postgres.Upsert("keys", columns, "k", update, expiredCondition))
+	})
+
+	t.Run("on duplicate key", func(t *testing.T) {
+		expected := "INSERT INTO keys (k, created_at, expires_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE " +
+			"created_at = IF(expires_at < VALUES(created_at), VALUES(created_at), created_at), " +
+			"expires_at = IF(expires_at < VALUES(created_at), VALUES(expires_at), expires_at)"
+		assert.Equal(t, expected, MySQL.Upsert("keys", columns, "k", update, expiredCondition))
+	})
+
+	t.Run("without condition", func(t *testing.T) {
+		assert.Equal(t, "INSERT INTO keys (k, created_at, expires_at) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE "+
+			"created_at = VALUES(created_at), expires_at = VALUES(expires_at)", MySQL.Upsert("keys", columns, "k", update, nil))
+	})
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	other := errors.New("connection refused")
+
+	assert.True(t, SQLite.IsUniqueViolation(fmt.Errorf("insert: %w", sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique})))
+	assert.False(t, SQLite.IsUniqueViolation(sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintNotNull}))
+	assert.True(t, Postgres.IsUniqueViolation(&pq.Error{Code: "23505"}))
+	assert.False(t, Postgres.IsUniqueViolation(&pq.Error{Code: "23502"}))
+	assert.True(t, MySQL.IsUniqueViolation(&mysqlDriver.MySQLError{Number: 1062}))
+	assert.False(t, MySQL.IsUniqueViolation(&mysqlDriver.MySQLError{Number: 1048}))
+	for _, d := range []Dialect{SQLite, Postgres, MySQL} {
+		assert.False(t, d.IsUniqueViolation(other), d.Name())
+	}
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"tasks/domain/views"
+	customError "tasks/errors"
+	"tasks/internal/service"
+)
+
+type adminHandler struct {
+	taskService service.TaskService
+}
+
+func NewAdminHandler(taskService service.TaskService) AdminHandler {
+	return &adminHandler{taskService: taskService}
+}
+
+// VerifyAudit godoc
+// @Summary Verify audit log
+// @Description Recompute the SHA-256 hash chain of the audit log from the oldest entry and report the first broken link. Requires the admin token as a bearer token
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Bearer admin token"
+// @Success 200 {object} entities.AuditVerification
+// @Failure 401 {object} error "invalid authorization"
+// @Failure 500 {object} error "server internal error"
+// @Router /admin/audit/verify [get]
+func (h *adminHandler) VerifyAudit(ginCtx *gin.Context) {
+	ctx := ginCtx.Request.Context()
+	verification, err := h.taskService.VerifyAudit(ctx)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, verification)
+}
+
+// RebuildProjection godoc
+// @Summary Rebuild task projection
+// @Description Replay the task events and overwrite the tasks table with the result. Only available when event sourcing is enabled. Requires the admin token as a bearer token
+// @Tags admin
+// @Produce json
+// @Param Authorization header string true "Bearer admin token"
+// @Param dry_run query bool false "only count the rows that would change"
+// @Success 200 {object} entities.ProjectionRebuild
+// @Failure 400 {object} error "request is invalid"
+// @Failure 401 {object} error "invalid authorization"
+// @Failure 500 {object} error "server internal error"
+// @Failure 501 {object} error "event sourcing is not enabled"
+// @Router /admin/projection/rebuild [post]
+func (h *adminHandler) RebuildProjection(ginCtx *gin.Context) {
+	var req views.RebuildProjectionReq
+	if err := ginCtx.ShouldBindQuery(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind query error"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	rebuild, err := h.taskService.RebuildProjection(ctx, req.DryRun)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, rebuild)
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"testing"
+)
+
+func Test_adminHandler_VerifyAudit(t *testing.T) {
+	t.Run("broken chain", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := NewAdminHandler(mockTaskService)
+		mockTaskService.On("VerifyAudit", mock.Anything).Return(&entities.AuditVerification{
+			Checked: 2,
+			Head:    "h9",
+			Broken:  &entities.AuditBreak{ID: 2, TaskID: "task-1", Reason: constants.AuditHashMismatch, Expected: "h2", Actual: "x2"},
+		}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/admin/audit/verify", nil)
+
+		h.VerifyAudit(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"valid":false,"checked":2,"head":"h9","broken":{"id":2,"task_id":"task-1","reason":"hash_mismatch","expected":"h2","actual":"x2"}}`, w.Body.String())
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("service error", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := NewAdminHandler(mockTaskService)
+		mockTaskService.On("VerifyAudit", mock.Anything).Return(nil, errors.New("db error"))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/admin/audit/verify", nil)
+
+		h.VerifyAudit(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.EqualError(t, c.Errors[0].Err, "db error")
+	})
+}
+
+func Test_adminHandler_RebuildProjection(t *testing.T) {
+	t.Run("dry run", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := NewAdminHandler(mockTaskService)
+		mockTaskService.On("RebuildProjection", mock.Anything, true).Return(&entities.ProjectionRebuild{Tasks: 3, Updated: 1, DryRun: true}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/admin/projection/rebuild?dry_run=true", nil)
+
+		h.RebuildProjection(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"tasks":3,"inserted":0,"updated":1,"deleted":0,"dry_run":true}`, w.Body.String())
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("service error", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := NewAdminHandler(mockTaskService)
+		mockTaskService.On("RebuildProjection", mock.Anything, false).Return(nil, errors.New("db error"))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/admin/projection/rebuild", nil)
+
+		h.RebuildProjection(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.EqualError(t, c.Errors[0].Err, "db error")
+	})
+}
@@ -3,8 +3,51 @@ package handler
 import "github.com/gin-gonic/gin"
 
 type TaskHandler interface {
+	GetTask(ginCtx *gin.Context)
+	GetSubtree(ginCtx *gin.Context)
+	GetOccurrences(ginCtx *gin.Context)
 	GetTasks(ginCtx *gin.Context)
 	CreateTask(ginCtx *gin.Context)
 	UpdateTask(ginCtx *gin.Context)
+	PatchTask(ginCtx *gin.Context)
 	DeleteTask(ginCtx *gin.Context)
+	GetTrash(ginCtx *gin.Context)
+	RestoreTask(ginCtx *gin.Context)
+	MoveTask(ginCtx *gin.Context)
+	PurgeTask(ginCtx *gin.Context)
+	BatchTasks(ginCtx *gin.Context)
+	AttachTags(ginCtx *gin.Context)
+	DetachTag(ginCtx *gin.Context)
+	AddBlockers(ginCtx *gin.Context)
+	RemoveBlocker(ginCtx *gin.Context)
+	SortTasks(ginCtx *gin.Context)
+	GetWorkflow(ginCtx *gin.Context)
+	SearchTasks(ginCtx *gin.Context)
+	GetHistory(ginCtx *gin.Context)
+	GetAuditLog(ginCtx *gin.Context)
+	GetEvents(ginCtx *gin.Context)
+	GetTaskAsOf(ginCtx *gin.Context)
+}
+
+type TagHandler interface {
+	GetTag(ginCtx *gin.Context)
+	GetTags(ginCtx *gin.Context)
+	CreateTag(ginCtx *gin.Context)
+	UpdateTag(ginCtx *gin.Context)
+	DeleteTag(ginCtx *gin.Context)
+}
+
+type AdminHandler interface {
+	VerifyAudit(ginCtx *gin.Context)
+	RebuildProjection(ginCtx *gin.Context)
+}
+
+type WebhookHandler interface {
+	GetWebhook(ginCtx *gin.Context)
+	GetWebhooks(ginCtx *gin.Context)
+	CreateWebhook(ginCtx *gin.Context)
+	UpdateWebhook(ginCtx *gin.Context)
+	DeleteWebhook(ginCtx *gin.Context)
+	GetDeliveries(ginCtx *gin.Context)
+	Redeliver(ginCtx *gin.Context)
 }
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"tasks/domain/entities"
+	"tasks/domain/views"
+	customError "tasks/errors"
+	"tasks/internal/service"
+)
+
+type tagHandler struct {
+	tagService service.TagService
+}
+
+func NewTagHandler(tagService service.TagService) TagHandler {
+	return &tagHandler{tagService: tagService}
+}
+
+// GetTag godoc
+// @Summary Get tag
+// @Description Get a single tag by id
+// @Tags tags
+// @Produce json
+// @Param id path string true "tag id"
+// @Success 200 {object} entities.Tag
+// @Failure 404 {object} error "tag not found"
+// @Failure 500 {object} error "server internal error"
+// @Router /tags/{id} [get]
+func (h *tagHandler) GetTag(ginCtx *gin.Context) {
+	tagId := ginCtx.Param("id")
+	if tagId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("tag id is required"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	tag, err := h.tagService.GetTag(ctx, tagId)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, tag)
+}
+
+// GetTags godoc
+// @Summary Get tags
+// @Description Get all tags ordered by name
+// @Tags tags
+// @Produce json
+// @Success 200 {object} entities.Tags
+// @Failure 500 {object} error "server internal error"
+// @Router /tags [get]
+func (h *tagHandler) GetTags(ginCtx *gin.Context) {
+	ctx := ginCtx.Request.Context()
+	tags, err := h.tagService.GetTags(ctx)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, tags)
+}
+
+// CreateTag godoc
+// @Summary Create tag
+// @Description Create a tag with a unique name and an optional hex color
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param tag body views.CreateTagReq true "tag"
+// @Success 201 {object} entities.Tag
+// @Header 201 {string} Location "URL of the created tag"
+// @Failure 400 {object} error "request is invalid"
+// @Failure 409 {object} error "tag name already exists"
+// @Failure 500 {object} error "server internal error"
+// @Router /tags [post]
+func (h *tagHandler) CreateTag(ginCtx *gin.Context) {
+	var req views.CreateTagReq
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind json error"))
+		return
+	}
+	color := ""
+	if req.Color != nil {
+		color = *req.Color
+	}
+	ctx := ginCtx.Request.Context()
+	created, err := h.tagService.CreateTag(ctx, entities.NewTag(req.Name, color))
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.Header("Location", "/tags/"+created.ID)
+	ginCtx.JSON(http.StatusCreated, created)
+}
+
+// UpdateTag godoc
+// @Summary Replace tag
+// @Description Replace the name and color of a tag, tasks with the tag see the change immediately
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path string true "tag id"
+// @Param tag body views.UpdateTagReq true "tag"
+// @Success 200 {object} entities.Tag
+// @Failure 400 {object} error "request is invalid"
+// @Failure 404 {object} error "tag not found"
+// @Failure 409 {object} error "tag name already exists"
+// @Failure 500 {object} error "server internal error"
+// @Router /tags/{id} [put]
+func (h *tagHandler) UpdateTag(ginCtx *gin.Context) {
+	tagId := ginCtx.Param("id")
+	if tagId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("tag id is required"))
+		return
+	}
+	var req views.UpdateTagReq
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind json error"))
+		return
+	}
+	tag := entities.Tag{ID: tagId, Name: *req.Name}
+	if req.Color != nil {
+		tag.Color = *req.Color
+	}
+	ctx := ginCtx.Request.Context()
+	updated, err := h.tagService.UpdateTag(ctx, tag)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, updated)
+}
+
+// DeleteTag godoc
+// @Summary Delete tag
+// @Description Delete a tag and detach it from every task
+// @Tags tags
+// @Param id path string true "tag id"
+// @Success 204
+// @Failure 404 {object} error "tag not found"
+// @Failure 500 {object} error "server internal error"
+// @Router /tags/{id} [delete]
+func (h *tagHandler) DeleteTag(ginCtx *gin.Context) {
+	tagId := ginCtx.Param("id")
+	if tagId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("tag id is required"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	if err := h.tagService.DeleteTag(ctx, tagId); err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.AbortWithStatus(http.StatusNoContent)
+}
@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"tasks/domain/entities"
+	customError "tasks/errors"
+	"testing"
+	"time"
+)
+
+// MockTagService 模擬 TagService
+type MockTagService struct {
+	mock.Mock
+}
+
+func (m *MockTagService) CreateTag(ctx context.Context, tag entities.Tag) (*entities.Tag, error) {
+	args := m.Called(ctx, tag)
+	if created, ok := args.Get(0).(*entities.Tag); ok {
+		return created, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTagService) UpdateTag(ctx context.Context, tag entities.Tag) (*entities.Tag, error) {
+	args := m.Called(ctx, tag)
+	if updated, ok := args.Get(0).(*entities.Tag); ok {
+		return updated, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTagService) DeleteTag(ctx context.Context, tagId string) error {
+	args := m.Called(ctx, tagId)
+	return args.Error(0)
+}
+
+func (m *MockTagService) GetTag(ctx context.Context, tagId string) (*entities.Tag, error) {
+	args := m.Called(ctx, tagId)
+	if tag, ok := args.Get(0).(*entities.Tag); ok {
+		return tag, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTagService) GetTags(ctx context.Context) (*entities.Tags, error) {
+	args := m.Called(ctx)
+	if tags, ok := args.Get(0).(*entities.Tags); ok {
+		return tags, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func Test_tagHandler_GetTags(t *testing.T) {
+	mockTagService := new(MockTagService)
+	h := &tagHandler{tagService: mockTagService}
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockTagService.On("GetTags", mock.Anything).Return(&entities.Tags{Tags: []entities.Tag{
+		{ID: "tag-1", Name: "work", Color: "#1e90ff", CreatedAt: createdAt, UpdatedAt: createdAt},
+	}}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/tags", nil)
+
+	h.GetTags(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	expected := `{"tags":[{"id":"tag-1","name":"work","color":"#1e90ff","created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z"}]}`
+	assert.JSONEq(t, expected, w.Body.String())
+}
+
+func Test_tagHandler_GetTag(t *testing.T) {
+	t.Run("tag not found", func(t *testing.T) {
+		mockTagService := new(MockTagService)
+		h := &tagHandler{tagService: mockTagService}
+		mockTagService.On("GetTag", mock.Anything, "tag-404").Return(nil, customError.TagNotFound.New("tag not found"))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/tags/tag-404", nil)
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "tag-404"}}
+
+		h.GetTag(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.TagNotFound))
+	})
+}
+
+func Test_tagHandler_CreateTag(t *testing.T) {
+	t.Run("create tag", func(t *testing.T) {
+		mockTagService := new(MockTagService)
+		h := &tagHandler{tagService: mockTagService}
+		mockTagService.On("CreateTag", mock.Anything, mock.MatchedBy(func(tag entities.Tag) bool {
+			return tag.ID != "" && tag.Name == "work" && tag.Color == "#1e90ff" && !tag.CreatedAt.IsZero()
+		})).Return(&entities.Tag{ID: "tag-1", Name: "work", Color: "#1e90ff"}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/tags", bytes.NewBufferString(`{"name": "work", "color": "#1e90ff"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		h.CreateTag(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "/tags/tag-1", w.Header().Get("Location"))
+		mockTagService.AssertExpectations(t)
+	})
+
+	t.Run("name is required", func(t *testing.T) {
+		mockTagService := new(MockTagService)
+		h := &tagHandler{tagService: mockTagService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/tags", bytes.NewBufferString(`{"color": "#1e90ff"}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		h.CreateTag(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+		mockTagService.AssertNotCalled(t, "CreateTag", mock.Anything, mock.Anything)
+	})
+}
+
+func Test_tagHandler_UpdateTag(t *testing.T) {
+	mockTagService := new(MockTagService)
+	h := &tagHandler{tagService: mockTagService}
+	mockTagService.On("UpdateTag", mock.Anything, entities.Tag{ID: "tag-1", Name: "home"}).
+		Return(&entities.Tag{ID: "tag-1", Name: "home"}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("PUT", "/tags/tag-1", bytes.NewBufferString(`{"name": "home"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "tag-1"}}
+
+	h.UpdateTag(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockTagService.AssertExpectations(t)
+}
+
+func Test_tagHandler_DeleteTag(t *testing.T) {
+	mockTagService := new(MockTagService)
+	h := &tagHandler{tagService: mockTagService}
+	mockTagService.On("DeleteTag", mock.Anything, "tag-1").Return(nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("DELETE", "/tags/tag-1", nil)
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "tag-1"}}
+
+	h.DeleteTag(c)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	mockTagService.AssertExpectations(t)
+}
@@ -2,10 +2,11 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"net/http"
 	"strconv"
+	"strings"
 	"tasks/constants"
 	"tasks/domain/entities"
 	"tasks/domain/views"
@@ -15,28 +16,161 @@ import (
 )
 
 type taskHandler struct {
-	taskService service.TaskService
+	taskService        service.TaskService
+	idempotencyService service.IdempotencyService
+	searchService      service.SearchService
 }
 
-func NewTaskHandler(taskService service.TaskService) TaskHandler {
+func NewTaskHandler(taskService service.TaskService, idempotencyService service.IdempotencyService, searchService service.SearchService) TaskHandler {
 	return &taskHandler{
-		taskService: taskService,
+		taskService:        taskService,
+		idempotencyService: idempotencyService,
+		searchService:      searchService,
 	}
 }
 
+// maxIdempotencyKeyLength Idempotency-Key 的長度上限
+const maxIdempotencyKeyLength = 255
+
+// GetTask godoc
+// @Summary Get task
+// @Description Get a single task by id
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "task id"
+// @Success 200 {object} entities.Task
+// @Header 200 {string} ETag "task version"
+// @Failure 400 {object} error "request is invalid"
+// @Failure 404 {object} error "task not found"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks/{id} [get]
+func (h *taskHandler) GetTask(ginCtx *gin.Context) {
+	taskId := ginCtx.Param("id")
+	if taskId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("task id is required"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	task, err := h.taskService.GetTask(ctx, taskId)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.Header("ETag", formatETag(task.Version))
+	ginCtx.JSON(http.StatusOK, task)
+}
+
+// GetSubtree godoc
+// @Summary Get subtree
+// @Description Get a task with its subtasks nested under children, down to depth levels below the task
+// @Tags tasks
+// @Produce json
+// @Param id path string true "task id"
+// @Param depth query int false "number of levels below the task, 0 returns only the task, defaults to all levels" minimum(0) maximum(10)
+// @Success 200 {object} entities.TaskNode
+// @Failure 400 {object} error "request is invalid"
+// @Failure 404 {object} error "task not found"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks/{id}/subtree [get]
+func (h *taskHandler) GetSubtree(ginCtx *gin.Context) {
+	taskId := ginCtx.Param("id")
+	if taskId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("task id is required"))
+		return
+	}
+	var req views.GetSubtreeReq
+	if err := ginCtx.ShouldBindQuery(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind query error"))
+		return
+	}
+	depth := constants.MaxTaskDepth
+	if req.Depth != nil {
+		depth = *req.Depth
+	}
+	ctx := ginCtx.Request.Context()
+	tree, err := h.taskService.GetSubtree(ctx, taskId, depth)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, tree)
+}
+
+// GetOccurrences godoc
+// @Summary Preview occurrences
+// @Description List the next occurrence dates of a recurring task, starting with its due_at
+// @Tags tasks
+// @Produce json
+// @Param id path string true "task id"
+// @Param count query int false "number of occurrences, defaults to 5" minimum(1) maximum(100)
+// @Success 200 {object} entities.Occurrences
+// @Failure 400 {object} error "request is invalid or task is not recurring"
+// @Failure 404 {object} error "task not found"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks/{id}/occurrences [get]
+func (h *taskHandler) GetOccurrences(ginCtx *gin.Context) {
+	taskId := ginCtx.Param("id")
+	if taskId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("task id is required"))
+		return
+	}
+	var req views.GetOccurrencesReq
+	if err := ginCtx.ShouldBindQuery(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind query error"))
+		return
+	}
+	count := constants.DefaultOccurrences
+	if req.Count != nil {
+		count = *req.Count
+	}
+	ctx := ginCtx.Request.Context()
+	occurrences, err := h.taskService.GetOccurrences(ctx, taskId, count)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, occurrences)
+}
+
 // GetTasks godoc
 // @Summary Get tasks
-// @Description Get tasks
+// @Description Get tasks with optional status, priority, tag and parent filters, name keyword search, created_at and due_at ranges, overdue filter and sorting, ordered by position by default
 // @Tags tasks
 // @Accept json
 // @Produce json
 // @Param size query int false "size"
-// @Param offset query int false "offset"
+// @Param page query int false "page"
+// @Param status query []int false "status filter, repeatable" collectionFormat(multi)
+// @Param priority query []int false "priority filter, repeatable" collectionFormat(multi)
+// @Param q query string false "name keyword"
+// @Param created_after query string false "created at or after (RFC3339)"
+// @Param created_before query string false "created before (RFC3339)"
+// @Param overdue query bool false "only incomplete tasks past due_at when true, everything else when false"
+// @Param due_after query string false "due at or after (RFC3339)"
+// @Param due_before query string false "due before (RFC3339)"
+// @Param tags_any query []string false "tasks with any of these tag names, repeatable" collectionFormat(multi)
+// @Param tags_all query []string false "tasks with all of these tag names, repeatable" collectionFormat(multi)
+// @Param parent_id query string false "only the direct subtasks of this task"
+// @Param sort query string false "sort field" Enums(position, created_at, name, status, priority)
+// @Param order query string false "sort order" Enums(asc, desc)
+// @Param cursor query string false "next_cursor or prev_cursor from a previous response, takes precedence over page"
+// @Param with_total query bool false "include the total number of matching tasks"
 // @Success 200 {object} entities.Tasks
+// @Failure 400 {object} error "request is invalid"
 // @Router /tasks [get]
 func (h *taskHandler) GetTasks(ginCtx *gin.Context) {
-	ctx := context.Background()
-	query := formatQuery(ginCtx.Query("size"), ginCtx.Query("page"))
+	var req views.GetTasksReq
+	if err := ginCtx.ShouldBindQuery(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind query error"))
+		return
+	}
+	query, err := formatQuery(req)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ctx := ginCtx.Request.Context()
 	tasks, err := h.taskService.GetTasks(ctx, query)
 	if err != nil {
 		_ = ginCtx.Error(err)
@@ -45,31 +179,242 @@ func (h *taskHandler) GetTasks(ginCtx *gin.Context) {
 	ginCtx.JSON(http.StatusOK, tasks)
 }
 
-func formatQuery(size, page string) entities.TaskQueryParam {
+// GetTrash godoc
+// @Summary Get trash
+// @Description Get soft-deleted tasks, accepts the same filters, sorting and paging as GET /tasks
+// @Tags trash
+// @Accept json
+// @Produce json
+// @Param size query int false "size"
+// @Param page query int false "page"
+// @Param status query []int false "status filter, repeatable" collectionFormat(multi)
+// @Param priority query []int false "priority filter, repeatable" collectionFormat(multi)
+// @Param q query string false "name keyword"
+// @Param tags_any query []string false "tasks with any of these tag names, repeatable" collectionFormat(multi)
+// @Param tags_all query []string false "tasks with all of these tag names, repeatable" collectionFormat(multi)
+// @Param sort query string false "sort field" Enums(position, created_at, name, status, priority)
+// @Param order query string false "sort order" Enums(asc, desc)
+// @Param cursor query string false "next_cursor or prev_cursor from a previous response"
+// @Param with_total query bool false "include the total number of matching tasks"
+// @Success 200 {object} entities.Tasks
+// @Failure 400 {object} error "request is invalid"
+// @Router /tasks/trash [get]
+func (h *taskHandler) GetTrash(ginCtx *gin.Context) {
+	var req views.GetTasksReq
+	if err := ginCtx.ShouldBindQuery(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind query error"))
+		return
+	}
+	query, err := formatQuery(req)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	tasks, err := h.taskService.GetTrash(ctx, query)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, tasks)
+}
+
+// RestoreTask godoc
+// @Summary Restore task
+// @Description Move a soft-deleted task out of the trash together with the subtasks that were deleted with it
+// @Tags trash
+// @Produce json
+// @Param id path string true "task id"
+// @Success 200 {object} entities.Task
+// @Failure 400 {object} error "request is invalid"
+// @Failure 404 {object} error "task not found"
+// @Failure 409 {object} error "parent task is deleted"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks/{id}/restore [post]
+func (h *taskHandler) RestoreTask(ginCtx *gin.Context) {
+	taskId := ginCtx.Param("id")
+	if taskId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("task id is required"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	task, err := h.taskService.RestoreTask(ctx, taskId)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.Header("ETag", formatETag(task.Version))
+	ginCtx.JSON(http.StatusOK, task)
+}
+
+// PurgeTask godoc
+// @Summary Purge task
+// @Description Permanently delete a task that is in the trash together with its subtasks
+// @Tags trash
+// @Param id path string true "task id"
+// @Success 204
+// @Failure 400 {object} error "request is invalid"
+// @Failure 404 {object} error "task not found"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks/trash/{id} [delete]
+func (h *taskHandler) PurgeTask(ginCtx *gin.Context) {
+	taskId := ginCtx.Param("id")
+	if taskId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("task id is required"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	if err := h.taskService.PurgeTask(ctx, taskId); err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.AbortWithStatus(http.StatusNoContent)
+}
+
+// BatchTasks godoc
+// @Summary Batch tasks
+// @Description Run a list of create/update/delete operations, atomically in one transaction or in best-effort mode
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param request body views.BatchTaskReq true "batch operations"
+// @Success 200 {object} entities.BatchResults
+// @Failure 400 {object} error "request is invalid"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks:batch [post]
+func (h *taskHandler) BatchTasks(ginCtx *gin.Context) {
+	var req views.BatchTaskReq
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind json error"))
+		return
+	}
+	mode := constants.BatchAtomic
+	if req.Mode != "" {
+		mode = constants.BatchMode(req.Mode)
+	}
+	if mode != constants.BatchAtomic && mode != constants.BatchBestEffort {
+		_ = ginCtx.Error(customError.InvalidRequest.Errorf("mode %s not supported", req.Mode))
+		return
+	}
+	operations := make([]entities.BatchOperation, 0, len(req.Operations))
+	for _, operation := range req.Operations {
+		operations = append(operations, entities.BatchOperation{
+			Op:         constants.BatchOp(operation.Op),
+			ID:         operation.ID,
+			ParentID:   operation.ParentID,
+			Name:       operation.Name,
+			Status:     operation.Status,
+			Priority:   operation.Priority,
+			DueAt:      toUTC(operation.DueAt),
+			RemindAt:   toUTC(operation.RemindAt),
+			Recurrence: operation.Recurrence,
+			Version:    operation.Version,
+		})
+	}
+	ctx := ginCtx.Request.Context()
+	result, err := h.taskService.BatchTasks(ctx, mode, operations)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, result)
+}
+
+func formatQuery(req views.GetTasksReq) (entities.TaskQueryParam, error) {
 	defaultSize := 10
 	result := entities.TaskQueryParam{
 		Size:   defaultSize,
 		Offset: 0,
+		SortBy: constants.SortByPosition,
+		Order:  constants.OrderAsc,
 	}
-	s, err := strconv.Atoi(size)
-	if err == nil && s > 0 {
-		result.Size = s
+	if req.Size != nil && *req.Size > 0 {
+		result.Size = *req.Size
 	}
-	p, err := strconv.Atoi(page)
-	if err == nil && p > 0 {
-		result.Offset = (p - 1) * result.Size
+	if req.Page != nil && *req.Page > 0 {
+		result.Offset = (*req.Page - 1) * result.Size
 	}
-	return result
+	for _, status := range req.Status {
+		if status < 0 {
+			return result, customError.InvalidRequest.Errorf("status %d not supported", status)
+		}
+		result.Statuses = append(result.Statuses, status)
+	}
+	for _, priority := range req.Priority {
+		if !priority.IsValid() {
+			return result, customError.InvalidRequest.Errorf("priority %d not supported", priority)
+		}
+		result.Priorities = append(result.Priorities, priority)
+	}
+	if req.Keyword != nil {
+		result.Keyword = strings.TrimSpace(*req.Keyword)
+	}
+	if req.CreatedAfter != nil {
+		createdAfter := req.CreatedAfter.UTC()
+		result.CreatedAfter = &createdAfter
+	}
+	if req.CreatedBefore != nil {
+		createdBefore := req.CreatedBefore.UTC()
+		result.CreatedBefore = &createdBefore
+	}
+	if result.CreatedAfter != nil && result.CreatedBefore != nil && !result.CreatedAfter.Before(*result.CreatedBefore) {
+		return result, customError.InvalidRequest.New("created_after must be before created_before")
+	}
+	result.DueAfter = toUTC(req.DueAfter)
+	result.DueBefore = toUTC(req.DueBefore)
+	if result.DueAfter != nil && result.DueBefore != nil && !result.DueAfter.Before(*result.DueBefore) {
+		return result, customError.InvalidRequest.New("due_after must be before due_before")
+	}
+	result.Overdue = req.Overdue
+	result.TagsAny = tagNames(req.TagsAny)
+	result.TagsAll = tagNames(req.TagsAll)
+	if req.ParentID != nil && *req.ParentID != "" {
+		result.ParentID = req.ParentID
+	}
+	if req.SortBy != nil && *req.SortBy != "" {
+		result.SortBy = constants.SortField(*req.SortBy)
+		if !result.SortBy.IsValid() {
+			return result, customError.InvalidRequest.Errorf("sort field %q not supported", *req.SortBy)
+		}
+	}
+	if req.Order != nil && *req.Order != "" {
+		result.Order = constants.SortOrder(strings.ToLower(*req.Order))
+		if !result.Order.IsValid() {
+			return result, customError.InvalidRequest.Errorf("order %q not supported", *req.Order)
+		}
+	}
+	if req.Cursor != nil && *req.Cursor != "" {
+		cursor, err := entities.DecodeCursor(*req.Cursor)
+		if err != nil {
+			return result, customError.InvalidRequest.Wrap(err, "decode cursor error")
+		}
+		if !cursor.SortBy.IsValid() || !cursor.Order.IsValid() || cursor.ID == "" {
+			return result, customError.InvalidRequest.New("cursor is invalid")
+		}
+		result.Cursor = cursor
+		result.SortBy = cursor.SortBy
+		result.Order = cursor.Order
+		result.Offset = 0
+	}
+	result.WithTotal = req.WithTotal
+	return result, nil
 }
 
 // CreateTask godoc
 // @Summary Create a new task
-// @Description Create a new task with a name and status
+// @Description Create a new task with a name and an optional parent_id, priority, due_at and remind_at, the task is placed at the end of the list
 // @Tags tasks
 // @Accept json
+// @Produce json
+// @Param Idempotency-Key header string false "unique key for safely retrying the request, a retry with the same key replays the original response"
 // @Param task body views.CreateTaskReq true "Task information"
-// @Success 201
+// @Success 201 {object} entities.Task
+// @Header 201 {string} Location "URL of the created task"
+// @Header 201 {string} ETag "task version"
+// @Header 201 {string} Idempotent-Replayed "true when the response is replayed for an Idempotency-Key"
 // @Failure 400 {object} error "request is invalid"
+// @Failure 409 {object} error "a request with this idempotency key is in progress, or the position was taken by concurrent creates"
+// @Failure 422 {object} error "idempotency key was used with a different request"
 // @Failure 500 {object} error "server internal error"
 // @Router /tasks [post]
 func (h *taskHandler) CreateTask(ginCtx *gin.Context) {
@@ -78,32 +423,82 @@ func (h *taskHandler) CreateTask(ginCtx *gin.Context) {
 		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind json error"))
 		return
 	}
-	ctx := context.Background()
-	task := entities.Task{
-		ID:        uuid.New().String(),
-		Name:      req.Name,
-		Status:    constants.Incomplete,
-		Version:   0,
-		CreatedAt: time.Now().UTC(),
+	if req.Priority != nil && !req.Priority.IsValid() {
+		_ = ginCtx.Error(customError.InvalidRequest.New("priority not supported"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	var lease *entities.IdempotencyLease
+	if idempotencyKey := ginCtx.GetHeader("Idempotency-Key"); idempotencyKey != "" {
+		if len(idempotencyKey) > maxIdempotencyKeyLength {
+			_ = ginCtx.Error(customError.InvalidRequest.Errorf("idempotency key must be at most %d characters", maxIdempotencyKeyLength))
+			return
+		}
+		// 以重新編碼後的 request 計算 hash，忽略空白與欄位順序的差異
+		request, err := json.Marshal(req)
+		if err != nil {
+			_ = ginCtx.Error(err)
+			return
+		}
+		var replay *entities.IdempotentResponse
+		lease, replay, err = h.idempotencyService.Begin(ctx, idempotencyKey, request)
+		if err != nil {
+			_ = ginCtx.Error(err)
+			return
+		}
+		if replay != nil {
+			var replayed entities.Task
+			if err = json.Unmarshal(replay.Body, &replayed); err != nil {
+				_ = ginCtx.Error(err)
+				return
+			}
+			ginCtx.Header("Location", "/tasks/"+replayed.ID)
+			ginCtx.Header("ETag", formatETag(replayed.Version))
+			ginCtx.Header("Idempotent-Replayed", "true")
+			ginCtx.Data(replay.StatusCode, "application/json; charset=utf-8", replay.Body)
+			return
+		}
+	}
+	task := entities.NewTask(req.Name)
+	task.ParentID = req.ParentID
+	if req.Priority != nil {
+		task.Priority = *req.Priority
 	}
-	err := h.taskService.CreateTask(ctx, task)
+	task.DueAt = toUTC(req.DueAt)
+	task.RemindAt = toUTC(req.RemindAt)
+	task.Recurrence = req.Recurrence
+	// 回應與 task 在同一個 transaction 中保存
+	created, err := h.taskService.CreateTask(ctx, task, lease)
 	if err != nil {
+		if lease != nil {
+			// client 中斷連線時仍需釋放 key，逾時由 repository 依 release_idempotency_key 設定
+			_ = h.idempotencyService.Release(context.WithoutCancel(ctx), *lease)
+		}
 		_ = ginCtx.Error(err)
 		return
 	}
-	ginCtx.AbortWithStatus(http.StatusCreated)
+	ginCtx.Header("Location", "/tasks/"+created.ID)
+	ginCtx.Header("ETag", formatETag(created.Version))
+	ginCtx.JSON(http.StatusCreated, created)
 }
 
 // UpdateTask godoc
-// @Summary Update task
-// @Description Update task
+// @Summary Replace task
+// @Description Replace the parent_id, name, status, priority, due_at and remind_at of a task, a completed task completes its parent once all of the parent's subtasks are complete, send Prefer: return=representation to receive the updated task
 // @Tags tasks
 // @Accept json
+// @Produce json
 // @Param id path string true "task id"
+// @Param If-Match header string false "ETag from a previous read, the update is rejected when the task has changed since"
+// @Param Prefer header string false "return=representation or return=minimal (default)"
 // @Param task body views.UpdateTaskReq true "task"
+// @Success 200 {object} entities.Task
 // @Success 204
+// @Header 200,204 {string} ETag "task version"
 // @Failure 400 {object} error "request is invalid"
 // @Failure 404 {object} error "task not found"
+// @Failure 409 {object} error "task was modified concurrently, is blocked, or the status transition is not allowed, error.details lists the allowed states"
+// @Failure 412 {object} error "task version does not match"
 // @Failure 500 {object} error "server internal error"
 // @Router /tasks/{id} [put]
 func (h *taskHandler) UpdateTask(ginCtx *gin.Context) {
@@ -117,36 +512,327 @@ func (h *taskHandler) UpdateTask(ginCtx *gin.Context) {
 		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind json error"))
 		return
 	}
-	if req.Status != constants.Complete && req.Status != constants.Incomplete {
+	if *req.Status < 0 {
 		_ = ginCtx.Error(customError.InvalidRequest.New("status not supported"))
 		return
 	}
-	ctx := context.Background()
+	priority := constants.PriorityMedium
+	if req.Priority != nil {
+		priority = *req.Priority
+	}
+	if !priority.IsValid() {
+		_ = ginCtx.Error(customError.InvalidRequest.New("priority not supported"))
+		return
+	}
+	expectedVersion, err := parseIfMatch(ginCtx.GetHeader("If-Match"))
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ctx := ginCtx.Request.Context()
 
 	task := entities.Task{
-		ID:     taskId,
-		Status: req.Status,
-	}
-	if req.Name != nil {
-		task.Name = *req.Name
+		ID:         taskId,
+		ParentID:   req.ParentID,
+		Name:       *req.Name,
+		Status:     *req.Status,
+		Priority:   priority,
+		DueAt:      toUTC(req.DueAt),
+		RemindAt:   toUTC(req.RemindAt),
+		Recurrence: req.Recurrence,
 	}
-	err := h.taskService.UpdateTask(ctx, task)
+	updated, err := h.taskService.UpdateTask(ctx, task, expectedVersion)
 	if err != nil {
 		_ = ginCtx.Error(err)
 		return
 	}
+	ginCtx.Header("ETag", formatETag(updated.Version))
+	if prefersRepresentation(ginCtx.GetHeader("Prefer")) {
+		ginCtx.Header("Preference-Applied", "return=representation")
+		ginCtx.JSON(http.StatusOK, updated)
+		return
+	}
 	ginCtx.AbortWithStatus(http.StatusNoContent)
 }
 
+// PatchTask godoc
+// @Summary Patch task
+// @Description Partially update a task with a JSON Merge Patch (RFC 7396) or JSON Patch (RFC 6902) document, only changed fields are written
+// @Tags tasks
+// @Accept application/merge-patch+json,application/json-patch+json
+// @Produce json
+// @Param id path string true "task id"
+// @Param If-Match header string false "ETag from a previous read, the patch is rejected when the task has changed since"
+// @Param patch body object true "merge patch object or json patch operation array"
+// @Success 200 {object} entities.Task
+// @Header 200 {string} ETag "task version"
+// @Failure 400 {object} error "request is invalid"
+// @Failure 404 {object} error "task not found"
+// @Failure 409 {object} error "task was modified concurrently, is blocked, or the status transition is not allowed, error.details lists the allowed states"
+// @Failure 412 {object} error "task version does not match"
+// @Failure 415 {object} error "unsupported media type"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks/{id} [patch]
+func (h *taskHandler) PatchTask(ginCtx *gin.Context) {
+	taskId := ginCtx.Param("id")
+	if taskId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("task id is required"))
+		return
+	}
+	patchType := constants.PatchType(ginCtx.ContentType())
+	if patchType != constants.MergePatch && patchType != constants.JSONPatch {
+		_ = ginCtx.Error(customError.UnsupportedMedia.Errorf("content type %q not supported", ginCtx.ContentType()))
+		return
+	}
+	expectedVersion, err := parseIfMatch(ginCtx.GetHeader("If-Match"))
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	patch, err := ginCtx.GetRawData()
+	if err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "read body error"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	patched, err := h.taskService.PatchTask(ctx, taskId, patchType, patch, expectedVersion)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.Header("ETag", formatETag(patched.Version))
+	ginCtx.JSON(http.StatusOK, patched)
+}
+
+// MoveTask godoc
+// @Summary Move task
+// @Description Move a task right before or after another task, only the moved task is rewritten
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "task id"
+// @Param If-Match header string false "ETag from a previous read, the move is rejected when the task has changed since"
+// @Param move body views.MoveTaskReq true "id of the task to move before or after, exactly one of before and after"
+// @Success 200 {object} entities.Task
+// @Header 200 {string} ETag "task version"
+// @Failure 400 {object} error "request is invalid"
+// @Failure 404 {object} error "task not found"
+// @Failure 409 {object} error "task was modified concurrently, or the position was taken by concurrent moves"
+// @Failure 412 {object} error "task version does not match"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks/{id}/move [post]
+func (h *taskHandler) MoveTask(ginCtx *gin.Context) {
+	taskId := ginCtx.Param("id")
+	if taskId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("task id is required"))
+		return
+	}
+	var req views.MoveTaskReq
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind json error"))
+		return
+	}
+	if (req.Before == nil) == (req.After == nil) {
+		_ = ginCtx.Error(customError.InvalidRequest.New("exactly one of before and after is required"))
+		return
+	}
+	move := entities.TaskMove{}
+	if req.After != nil {
+		move.TargetID = *req.After
+		move.After = true
+	} else {
+		move.TargetID = *req.Before
+	}
+	if move.TargetID == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("target task id is required"))
+		return
+	}
+	expectedVersion, err := parseIfMatch(ginCtx.GetHeader("If-Match"))
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	moved, err := h.taskService.MoveTask(ctx, taskId, move, expectedVersion)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.Header("ETag", formatETag(moved.Version))
+	ginCtx.JSON(http.StatusOK, moved)
+}
+
+// AttachTags godoc
+// @Summary Attach tags
+// @Description Attach tags to a task, tags that are already attached are skipped
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "task id"
+// @Param tags body views.AttachTagsReq true "ids of the tags to attach"
+// @Success 200 {object} entities.Task
+// @Header 200 {string} ETag "task version"
+// @Failure 400 {object} error "request is invalid"
+// @Failure 404 {object} error "task or tag not found"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks/{id}/tags [post]
+func (h *taskHandler) AttachTags(ginCtx *gin.Context) {
+	taskId := ginCtx.Param("id")
+	if taskId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("task id is required"))
+		return
+	}
+	var req views.AttachTagsReq
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind json error"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	task, err := h.taskService.AttachTags(ctx, taskId, req.TagIDs)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.Header("ETag", formatETag(task.Version))
+	ginCtx.JSON(http.StatusOK, task)
+}
+
+// DetachTag godoc
+// @Summary Detach tag
+// @Description Detach a tag from a task, detaching a tag that is not attached does nothing
+// @Tags tasks
+// @Produce json
+// @Param id path string true "task id"
+// @Param tag_id path string true "tag id"
+// @Success 200 {object} entities.Task
+// @Header 200 {string} ETag "task version"
+// @Failure 400 {object} error "request is invalid"
+// @Failure 404 {object} error "task not found"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks/{id}/tags/{tag_id} [delete]
+func (h *taskHandler) DetachTag(ginCtx *gin.Context) {
+	taskId := ginCtx.Param("id")
+	tagId := ginCtx.Param("tag_id")
+	if taskId == "" || tagId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("task id and tag id are required"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	task, err := h.taskService.DetachTags(ctx, taskId, []string{tagId})
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.Header("ETag", formatETag(task.Version))
+	ginCtx.JSON(http.StatusOK, task)
+}
+
+// AddBlockers godoc
+// @Summary Add blockers
+// @Description Mark a task as blocked by other tasks, a task with incomplete blockers can't be completed, existing blockers are skipped
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "task id"
+// @Param blockers body views.AddBlockersReq true "ids of the blocking tasks"
+// @Success 200 {object} entities.Task
+// @Header 200 {string} ETag "task version"
+// @Failure 400 {object} error "request is invalid"
+// @Failure 404 {object} error "task or blocker not found"
+// @Failure 409 {object} error "dependency would form a cycle"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks/{id}/blockers [post]
+func (h *taskHandler) AddBlockers(ginCtx *gin.Context) {
+	taskId := ginCtx.Param("id")
+	if taskId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("task id is required"))
+		return
+	}
+	var req views.AddBlockersReq
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind json error"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	task, err := h.taskService.AddBlockers(ctx, taskId, req.BlockerIDs)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.Header("ETag", formatETag(task.Version))
+	ginCtx.JSON(http.StatusOK, task)
+}
+
+// RemoveBlocker godoc
+// @Summary Remove blocker
+// @Description Remove a blocker from a task, removing a task that is not a blocker does nothing
+// @Tags tasks
+// @Produce json
+// @Param id path string true "task id"
+// @Param blocker_id path string true "blocking task id"
+// @Success 200 {object} entities.Task
+// @Header 200 {string} ETag "task version"
+// @Failure 400 {object} error "request is invalid"
+// @Failure 404 {object} error "task not found"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks/{id}/blockers/{blocker_id} [delete]
+func (h *taskHandler) RemoveBlocker(ginCtx *gin.Context) {
+	taskId := ginCtx.Param("id")
+	blockerId := ginCtx.Param("blocker_id")
+	if taskId == "" || blockerId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("task id and blocker id are required"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	task, err := h.taskService.RemoveBlockers(ctx, taskId, []string{blockerId})
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.Header("ETag", formatETag(task.Version))
+	ginCtx.JSON(http.StatusOK, task)
+}
+
+// SortTasks godoc
+// @Summary Sort tasks by dependency
+// @Description Return the tasks in topological order, blockers come before the tasks they block, only dependencies between the given tasks are considered
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param request body views.SortTasksReq true "ids of the tasks to sort"
+// @Success 200 {object} entities.Tasks
+// @Failure 400 {object} error "request is invalid"
+// @Failure 404 {object} error "task not found"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks:toposort [post]
+func (h *taskHandler) SortTasks(ginCtx *gin.Context) {
+	var req views.SortTasksReq
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind json error"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	result, err := h.taskService.SortTasks(ctx, req.TaskIDs)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, result)
+}
+
 // DeleteTask godoc
 // @Summary Delete task
-// @Description Delete task
+// @Description Move a task to the trash, it can be restored until it is purged, a task with subtasks can only be deleted together with them
 // @Tags tasks
 // @Accept json
 // @Param id path string true "task id"
+// @Param cascade query bool false "also move all subtasks to the trash"
+// @Param If-Match header string false "ETag from a previous read, the delete is rejected when the task has changed since"
 // @Success 204
 // @Failure 400 {object} error "request is invalid"
 // @Failure 404 {object} error "task not found"
+// @Failure 409 {object} error "task has subtasks"
+// @Failure 412 {object} error "task version does not match"
 // @Failure 500 {object} error "server internal error"
 // @Router /tasks/{id} [delete]
 func (h *taskHandler) DeleteTask(ginCtx *gin.Context) {
@@ -155,8 +841,18 @@ func (h *taskHandler) DeleteTask(ginCtx *gin.Context) {
 		_ = ginCtx.Error(customError.InvalidRequest.New("task id is required"))
 		return
 	}
-	ctx := context.Background()
-	err := h.taskService.DeleteTask(ctx, taskId)
+	cascade, err := strconv.ParseBool(ginCtx.DefaultQuery("cascade", "false"))
+	if err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "invalid cascade"))
+		return
+	}
+	expectedVersion, err := parseIfMatch(ginCtx.GetHeader("If-Match"))
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	err = h.taskService.DeleteTask(ctx, taskId, expectedVersion, cascade)
 
 	if err != nil {
 		_ = ginCtx.Error(err)
@@ -164,3 +860,277 @@ func (h *taskHandler) DeleteTask(ginCtx *gin.Context) {
 	}
 	ginCtx.AbortWithStatus(http.StatusNoContent)
 }
+
+// tagNames 去除空白，略過空的 tag 名稱
+func tagNames(values []string) []string {
+	var names []string
+	for _, value := range values {
+		if name := strings.TrimSpace(value); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func toUTC(value *time.Time) *time.Time {
+	if value == nil {
+		return nil
+	}
+	utc := value.UTC()
+	return &utc
+}
+
+// formatETag 以 task version 作為 strong ETag
+func formatETag(version int) string {
+	return strconv.Quote(strconv.Itoa(version))
+}
+
+// parseIfMatch 解析 If-Match header，未帶或為 * 時不檢查版本
+func parseIfMatch(header string) (*int, error) {
+	header = strings.TrimSpace(header)
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+	if strings.Contains(header, ",") {
+		return nil, customError.InvalidRequest.New("multiple entity tags in If-Match are not supported")
+	}
+	tag := strings.TrimPrefix(header, "W/")
+	value, err := strconv.Unquote(tag)
+	if err != nil {
+		return nil, customError.InvalidRequest.Wrapf(err, "invalid If-Match %s", header)
+	}
+	version, err := strconv.Atoi(value)
+	if err != nil {
+		return nil, customError.InvalidRequest.Wrapf(err, "invalid If-Match %s", header)
+	}
+	return &version, nil
+}
+
+// prefersRepresentation 判斷 Prefer header 是否要求回傳更新後的資源 (RFC 7240)
+func prefersRepresentation(header string) bool {
+	for _, preference := range strings.Split(header, ",") {
+		if strings.EqualFold(strings.TrimSpace(preference), "return=representation") {
+			return true
+		}
+	}
+	return false
+}
+
+// GetWorkflow godoc
+// @Summary Get workflow
+// @Description List the statuses a task can have and the statuses each one can move to, 0 is todo and 1 is done
+// @Tags tasks
+// @Produce json
+// @Success 200 {object} entities.Workflow
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks/workflow [get]
+func (h *taskHandler) GetWorkflow(ginCtx *gin.Context) {
+	ctx := ginCtx.Request.Context()
+	workflow, err := h.taskService.GetWorkflow(ctx)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, workflow)
+}
+
+// SearchTasks godoc
+// @Summary Search tasks
+// @Description Full-text search on task names, every term must match, "quoted phrase" matches words in order and a trailing * matches a prefix, ranked by bm25 with highlighted snippets, the snippet is HTML-escaped and only <mark> tags are added. Without full-text support terms are matched as case-insensitive substrings ordered by position and rank is 0
+// @Tags tasks
+// @Produce json
+// @Param q query string true "search query, e.g. \"weekly report\" plan*"
+// @Param size query int false "size, defaults to 20" minimum(1) maximum(100)
+// @Param page query int false "page, defaults to 1" minimum(1)
+// @Success 200 {object} entities.SearchResults
+// @Failure 400 {object} error "request is invalid"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks/search [get]
+func (h *taskHandler) SearchTasks(ginCtx *gin.Context) {
+	var req views.SearchTasksReq
+	if err := ginCtx.ShouldBindQuery(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind query error"))
+		return
+	}
+	size, page := constants.DefaultSearchSize, 1
+	if req.Size != nil {
+		size = *req.Size
+	}
+	if req.Page != nil {
+		page = *req.Page
+	}
+	ctx := ginCtx.Request.Context()
+	results, err := h.searchService.Search(ctx, req.Query, size, page)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, results)
+}
+
+// GetHistory godoc
+// @Summary Get task history
+// @Description List the changes of a task with the actor, version and the fields before and after, newest first. The history is kept after the task is purged
+// @Tags tasks
+// @Produce json
+// @Param id path string true "task id"
+// @Param actor query string false "only changes by this actor"
+// @Param action query []string false "create, update, delete, restore or purge, repeatable" collectionFormat(multi)
+// @Param since query string false "changed at or after (RFC3339)"
+// @Param until query string false "changed before (RFC3339)"
+// @Param before query int false "next_before of the previous page"
+// @Param size query int false "size, defaults to 50" minimum(1) maximum(200)
+// @Success 200 {object} entities.AuditLog
+// @Failure 400 {object} error "request is invalid"
+// @Failure 404 {object} error "task not found"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks/{id}/history [get]
+func (h *taskHandler) GetHistory(ginCtx *gin.Context) {
+	taskId := ginCtx.Param("id")
+	if taskId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("task id is required"))
+		return
+	}
+	var req views.GetAuditReq
+	if err := ginCtx.ShouldBindQuery(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind query error"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	history, err := h.taskService.GetHistory(ctx, taskId, formatAuditQuery(req))
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, history)
+}
+
+// GetAuditLog godoc
+// @Summary Get audit log
+// @Description List the changes of all tasks with the actor, version and the fields before and after, newest first
+// @Tags tasks
+// @Produce json
+// @Param task_id query string false "only changes of this task"
+// @Param actor query string false "only changes by this actor"
+// @Param action query []string false "create, update, delete, restore or purge, repeatable" collectionFormat(multi)
+// @Param since query string false "changed at or after (RFC3339)"
+// @Param until query string false "changed before (RFC3339)"
+// @Param before query int false "next_before of the previous page"
+// @Param size query int false "size, defaults to 50" minimum(1) maximum(200)
+// @Success 200 {object} entities.AuditLog
+// @Failure 400 {object} error "request is invalid"
+// @Failure 500 {object} error "server internal error"
+// @Router /tasks/audit [get]
+func (h *taskHandler) GetAuditLog(ginCtx *gin.Context) {
+	var req views.GetAuditReq
+	if err := ginCtx.ShouldBindQuery(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind query error"))
+		return
+	}
+	param := formatAuditQuery(req)
+	if req.TaskID != nil {
+		param.TaskID = *req.TaskID
+	}
+	ctx := ginCtx.Request.Context()
+	log, err := h.taskService.GetAuditLog(ctx, param)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, log)
+}
+
+// GetEvents godoc
+// @Summary Get task events
+// @Description List the events of a task in the order they happened. The events are kept after the task is purged. Only available when event sourcing is enabled
+// @Tags tasks
+// @Produce json
+// @Param id path string true "task id"
+// @Param after query int false "next_after of the previous page"
+// @Param size query int false "size, defaults to 100" minimum(1) maximum(500)
+// @Success 200 {object} entities.TaskEvents
+// @Failure 400 {object} error "request is invalid"
+// @Failure 404 {object} error "task not found"
+// @Failure 500 {object} error "server internal error"
+// @Failure 501 {object} error "event sourcing is not enabled"
+// @Router /tasks/{id}/events [get]
+func (h *taskHandler) GetEvents(ginCtx *gin.Context) {
+	taskId := ginCtx.Param("id")
+	if taskId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("task id is required"))
+		return
+	}
+	var req views.GetEventsReq
+	if err := ginCtx.ShouldBindQuery(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind query error"))
+		return
+	}
+	var after int64
+	size := constants.DefaultEventSize
+	if req.After != nil {
+		after = *req.After
+	}
+	if req.Size != nil {
+		size = *req.Size
+	}
+	ctx := ginCtx.Request.Context()
+	events, err := h.taskService.GetEvents(ctx, taskId, after, size)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, events)
+}
+
+// GetTaskAsOf godoc
+// @Summary Get task as of a version or time
+// @Description Replay the events of a task up to a version or a point in time. Tags and blockers are not included. Only available when event sourcing is enabled
+// @Tags tasks
+// @Produce json
+// @Param id path string true "task id"
+// @Param version query int false "the last state with this version"
+// @Param at query string false "the state at this time (RFC3339)"
+// @Success 200 {object} entities.Task
+// @Failure 400 {object} error "request is invalid"
+// @Failure 404 {object} error "task did not exist at that point"
+// @Failure 500 {object} error "server internal error"
+// @Failure 501 {object} error "event sourcing is not enabled"
+// @Router /tasks/{id}/as-of [get]
+func (h *taskHandler) GetTaskAsOf(ginCtx *gin.Context) {
+	taskId := ginCtx.Param("id")
+	if taskId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("task id is required"))
+		return
+	}
+	var req views.GetTaskAsOfReq
+	if err := ginCtx.ShouldBindQuery(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind query error"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	task, err := h.taskService.GetTaskAsOf(ctx, taskId, entities.AsOf{Version: req.Version, At: toUTC(req.At)})
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, task)
+}
+
+func formatAuditQuery(req views.GetAuditReq) entities.AuditQueryParam {
+	result := entities.AuditQueryParam{
+		Actions: req.Action,
+		Since:   toUTC(req.Since),
+		Until:   toUTC(req.Until),
+		Size:    constants.DefaultAuditSize,
+	}
+	if req.Actor != nil {
+		result.Actor = *req.Actor
+	}
+	if req.Before != nil {
+		result.Before = *req.Before
+	}
+	if req.Size != nil {
+		result.Size = *req.Size
+	}
+	return result
+}
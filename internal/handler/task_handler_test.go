@@ -3,6 +3,9 @@ package handler
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -10,22 +13,143 @@ import (
 	"net/http/httptest"
 	"tasks/constants"
 	"tasks/domain/entities"
+	"tasks/domain/views"
+	customError "tasks/errors"
+	"tasks/internal/service"
 	"testing"
+	"time"
 )
 
+// MockSearchService 模擬 SearchService
+type MockSearchService struct {
+	mock.Mock
+}
+
+func (m *MockSearchService) Search(ctx context.Context, value string, size, page int) (*entities.SearchResults, error) {
+	args := m.Called(ctx, value, size, page)
+	if results, ok := args.Get(0).(*entities.SearchResults); ok {
+		return results, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 // MockTaskService 模擬 TaskService
 type MockTaskService struct {
 	mock.Mock
 }
 
-func (m *MockTaskService) UpdateTask(ctx context.Context, param entities.Task) error {
-	args := m.Called(ctx, param)
+func (m *MockTaskService) UpdateTask(ctx context.Context, param entities.Task, expectedVersion *int) (*entities.Task, error) {
+	args := m.Called(ctx, param, expectedVersion)
+	if task, ok := args.Get(0).(*entities.Task); ok {
+		return task, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) PatchTask(ctx context.Context, taskId string, patchType constants.PatchType, patch []byte, expectedVersion *int) (*entities.Task, error) {
+	args := m.Called(ctx, taskId, patchType, patch, expectedVersion)
+	if task, ok := args.Get(0).(*entities.Task); ok {
+		return task, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) MoveTask(ctx context.Context, taskId string, move entities.TaskMove, expectedVersion *int) (*entities.Task, error) {
+	args := m.Called(ctx, taskId, move, expectedVersion)
+	if task, ok := args.Get(0).(*entities.Task); ok {
+		return task, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) DeleteTask(ctx context.Context, taskId string, expectedVersion *int, cascade bool) error {
+	args := m.Called(ctx, taskId, expectedVersion, cascade)
 	return args.Error(0)
 }
 
-func (m *MockTaskService) DeleteTask(ctx context.Context, taskId string) error {
+func (m *MockTaskService) GetSubtree(ctx context.Context, taskId string, depth int) (*entities.TaskNode, error) {
+	args := m.Called(ctx, taskId, depth)
+	if node, ok := args.Get(0).(*entities.TaskNode); ok {
+		return node, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) GetOccurrences(ctx context.Context, taskId string, count int) (*entities.Occurrences, error) {
+	args := m.Called(ctx, taskId, count)
+	if occurrences, ok := args.Get(0).(*entities.Occurrences); ok {
+		return occurrences, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) GetWorkflow(ctx context.Context) (*entities.Workflow, error) {
+	args := m.Called(ctx)
+	if workflow, ok := args.Get(0).(*entities.Workflow); ok {
+		return workflow, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) GetHistory(ctx context.Context, taskId string, param entities.AuditQueryParam) (*entities.AuditLog, error) {
+	args := m.Called(ctx, taskId, param)
+	if log, ok := args.Get(0).(*entities.AuditLog); ok {
+		return log, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) GetAuditLog(ctx context.Context, param entities.AuditQueryParam) (*entities.AuditLog, error) {
+	args := m.Called(ctx, param)
+	if log, ok := args.Get(0).(*entities.AuditLog); ok {
+		return log, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) VerifyAudit(ctx context.Context) (*entities.AuditVerification, error) {
+	args := m.Called(ctx)
+	if verification, ok := args.Get(0).(*entities.AuditVerification); ok {
+		return verification, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) GetEvents(ctx context.Context, taskId string, after int64, size int) (*entities.TaskEvents, error) {
+	args := m.Called(ctx, taskId, after, size)
+	if events, ok := args.Get(0).(*entities.TaskEvents); ok {
+		return events, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) GetTaskAsOf(ctx context.Context, taskId string, asOf entities.AsOf) (*entities.Task, error) {
+	args := m.Called(ctx, taskId, asOf)
+	if task, ok := args.Get(0).(*entities.Task); ok {
+		return task, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) RebuildProjection(ctx context.Context, dryRun bool) (*entities.ProjectionRebuild, error) {
+	args := m.Called(ctx, dryRun)
+	if rebuild, ok := args.Get(0).(*entities.ProjectionRebuild); ok {
+		return rebuild, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) BackfillEvents(ctx context.Context, dryRun bool) (int, error) {
+	args := m.Called(ctx, dryRun)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTaskService) GetTask(ctx context.Context, taskId string) (*entities.Task, error) {
 	args := m.Called(ctx, taskId)
-	return args.Error(0)
+	if task, ok := args.Get(0).(*entities.Task); ok {
+		return task, args.Error(1)
+	}
+	return nil, args.Error(1)
 }
 
 func (m *MockTaskService) GetTasks(ctx context.Context, param entities.TaskQueryParam) (*entities.Tasks, error) {
@@ -33,19 +157,202 @@ func (m *MockTaskService) GetTasks(ctx context.Context, param entities.TaskQuery
 	return args.Get(0).(*entities.Tasks), args.Error(1)
 }
 
-func (m *MockTaskService) CreateTask(ctx context.Context, task entities.Task) error {
-	args := m.Called(ctx, task)
+func (m *MockTaskService) CreateTask(ctx context.Context, task entities.Task, lease *entities.IdempotencyLease) (*entities.Task, error) {
+	args := m.Called(ctx, task, lease)
+	if created, ok := args.Get(0).(*entities.Task); ok {
+		return created, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) GetTrash(ctx context.Context, param entities.TaskQueryParam) (*entities.Tasks, error) {
+	args := m.Called(ctx, param)
+	return args.Get(0).(*entities.Tasks), args.Error(1)
+}
+
+func (m *MockTaskService) RestoreTask(ctx context.Context, taskId string) (*entities.Task, error) {
+	args := m.Called(ctx, taskId)
+	if task, ok := args.Get(0).(*entities.Task); ok {
+		return task, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) PurgeTask(ctx context.Context, taskId string) error {
+	args := m.Called(ctx, taskId)
+	return args.Error(0)
+}
+
+func (m *MockTaskService) PurgeExpiredTasks(ctx context.Context, retention time.Duration) (int64, error) {
+	args := m.Called(ctx, retention)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaskService) BatchTasks(ctx context.Context, mode constants.BatchMode, operations []entities.BatchOperation) (*entities.BatchResults, error) {
+	args := m.Called(ctx, mode, operations)
+	if result, ok := args.Get(0).(*entities.BatchResults); ok {
+		return result, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) SendDueReminders(ctx context.Context, notifier service.ReminderNotifier) (int, error) {
+	args := m.Called(ctx, notifier)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTaskService) AttachTags(ctx context.Context, taskId string, tagIds []string) (*entities.Task, error) {
+	args := m.Called(ctx, taskId, tagIds)
+	if task, ok := args.Get(0).(*entities.Task); ok {
+		return task, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) DetachTags(ctx context.Context, taskId string, tagIds []string) (*entities.Task, error) {
+	args := m.Called(ctx, taskId, tagIds)
+	if task, ok := args.Get(0).(*entities.Task); ok {
+		return task, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) AddBlockers(ctx context.Context, taskId string, blockerIds []string) (*entities.Task, error) {
+	args := m.Called(ctx, taskId, blockerIds)
+	if task, ok := args.Get(0).(*entities.Task); ok {
+		return task, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) RemoveBlockers(ctx context.Context, taskId string, blockerIds []string) (*entities.Task, error) {
+	args := m.Called(ctx, taskId, blockerIds)
+	if task, ok := args.Get(0).(*entities.Task); ok {
+		return task, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskService) SortTasks(ctx context.Context, taskIds []string) (*entities.Tasks, error) {
+	args := m.Called(ctx, taskIds)
+	if tasks, ok := args.Get(0).(*entities.Tasks); ok {
+		return tasks, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+// MockIdempotencyService 模擬 IdempotencyService
+type MockIdempotencyService struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyService) Begin(ctx context.Context, key string, request []byte) (*entities.IdempotencyLease, *entities.IdempotentResponse, error) {
+	args := m.Called(ctx, key, request)
+	lease, _ := args.Get(0).(*entities.IdempotencyLease)
+	response, _ := args.Get(1).(*entities.IdempotentResponse)
+	return lease, response, args.Error(2)
+}
+
+func (m *MockIdempotencyService) Release(ctx context.Context, lease entities.IdempotencyLease) error {
+	args := m.Called(ctx, lease)
 	return args.Error(0)
 }
 
+func (m *MockIdempotencyService) PurgeExpiredKeys(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func Test_taskHandler_GetTask(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	task := &entities.Task{
+		ID:        "task-1",
+		Name:      "Task 1",
+		Status:    constants.Complete,
+		Priority:  constants.PriorityHigh,
+		Position:  "00000001",
+		Version:   3,
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt.Add(time.Hour),
+	}
+
+	t.Run("successful task retrieval", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("GetTask", mock.Anything, "task-1").Return(task, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetTask(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+		expected := `{"id":"task-1","name":"Task 1","status":1,"priority":2,"position":"00000001","version":3,"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T01:00:00Z"}`
+		assert.JSONEq(t, expected, w.Body.String())
+
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("task not found", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("GetTask", mock.Anything, "task-2").Return(nil, customError.TaskNotFound.New("task not found"))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-2", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-2"}}
+
+		h.GetTask(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.TaskNotFound))
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("request context is passed to service", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		reqCtx, cancel := context.WithCancel(context.Background())
+		cancel()
+		mockTaskService.On("GetTask", mock.MatchedBy(func(ctx context.Context) bool {
+			return errors.Is(ctx.Err(), context.Canceled)
+		}), "task-1").Return(nil, customError.Timeout.New("canceled"))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequestWithContext(reqCtx, "GET", "/tasks/task-1", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetTask(c)
+
+		assert.Len(t, c.Errors, 1)
+		mockTaskService.AssertExpectations(t)
+	})
+}
+
 func Test_taskHandler_GetTasks(t *testing.T) {
 
 	tasks := &entities.Tasks{
 		Tasks: []entities.Task{
 			{
-				ID:     "task-1",
-				Name:   "Task 1",
-				Status: constants.Incomplete,
+				ID:       "task-1",
+				Name:     "Task 1",
+				Status:   constants.Incomplete,
+				Priority: constants.PriorityMedium,
+				Position: "00000001",
 			},
 		},
 		Page: 1,
@@ -66,79 +373,1662 @@ func Test_taskHandler_GetTasks(t *testing.T) {
 		h.GetTasks(c)
 
 		assert.Equal(t, http.StatusOK, w.Code)
-		expected := `{"tasks":[{"id":"task-1","name":"Task 1","status":0}],"page":1,"size":1}`
+		expected := `{"tasks":[{"id":"task-1","name":"Task 1","status":0,"priority":1,"position":"00000001","version":0,"created_at":"0001-01-01T00:00:00Z","updated_at":"0001-01-01T00:00:00Z"}],"page":1,"size":1}`
 		assert.JSONEq(t, expected, w.Body.String())
 
 		mockTaskService.AssertExpectations(t)
 	})
+
+	t.Run("filters and sorting are passed to service", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		expected := entities.TaskQueryParam{
+			Size:         20,
+			Offset:       20,
+			Statuses:     []constants.Status{constants.Complete},
+			Keyword:      "report",
+			CreatedAfter: &createdAfter,
+			SortBy:       constants.SortByName,
+			Order:        constants.OrderDesc,
+		}
+		mockTaskService.On("GetTasks", mock.Anything, expected).Return(tasks, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks?size=20&page=2&status=1&q=report&created_after=2024-01-01T08:00:00%2B08:00&sort=name&order=DESC", nil)
+		c.Request = req
+
+		h.GetTasks(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("tag filters are passed to service", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("GetTasks", mock.Anything, mock.MatchedBy(func(param entities.TaskQueryParam) bool {
+			return assert.ObjectsAreEqual([]string{"work", "home"}, param.TagsAny) && assert.ObjectsAreEqual([]string{"urgent"}, param.TagsAll)
+		})).Return(tasks, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks?tags_any=work&tags_any=home&tags_all=urgent", nil)
+		c.Request = req
+
+		h.GetTasks(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("unsupported sort field", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks?sort=version", nil)
+		c.Request = req
+
+		h.GetTasks(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+		mockTaskService.AssertNotCalled(t, "GetTasks", mock.Anything, mock.Anything)
+	})
+}
+
+func Test_formatQuery(t *testing.T) {
+	t.Run("defaults to position order", func(t *testing.T) {
+		param, err := formatQuery(views.GetTasksReq{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, constants.SortByPosition, param.SortBy)
+		assert.Equal(t, constants.OrderAsc, param.Order)
+	})
+
+	t.Run("priority filter", func(t *testing.T) {
+		param, err := formatQuery(views.GetTasksReq{Priority: []constants.Priority{constants.PriorityHigh, constants.PriorityUrgent}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []constants.Priority{constants.PriorityHigh, constants.PriorityUrgent}, param.Priorities)
+
+		_, err = formatQuery(views.GetTasksReq{Priority: []constants.Priority{9}})
+
+		assert.True(t, errors.Is(err, customError.InvalidRequest))
+	})
+
+	t.Run("blank tag names are ignored", func(t *testing.T) {
+		param, err := formatQuery(views.GetTasksReq{TagsAny: []string{" work ", ""}, TagsAll: []string{" "}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"work"}, param.TagsAny)
+		assert.Nil(t, param.TagsAll)
+	})
+
+	t.Run("parent filter", func(t *testing.T) {
+		parentID, empty := "task-1", ""
+
+		param, err := formatQuery(views.GetTasksReq{ParentID: &parentID})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "task-1", *param.ParentID)
+
+		param, err = formatQuery(views.GetTasksReq{ParentID: &empty})
+
+		assert.NoError(t, err)
+		assert.Nil(t, param.ParentID)
+	})
+
+	t.Run("cursor overrides sort and page", func(t *testing.T) {
+		token := entities.Cursor{
+			SortBy: constants.SortByName,
+			Order:  constants.OrderDesc,
+			Value:  "Task 1",
+			ID:     "task-1",
+		}.Encode()
+		page, sort := 3, "status"
+
+		param, err := formatQuery(views.GetTasksReq{Page: &page, SortBy: &sort, Cursor: &token, WithTotal: true})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, param.Offset)
+		assert.Equal(t, constants.SortByName, param.SortBy)
+		assert.Equal(t, constants.OrderDesc, param.Order)
+		assert.Equal(t, "task-1", param.Cursor.ID)
+		assert.True(t, param.WithTotal)
+	})
+
+	t.Run("malformed cursor", func(t *testing.T) {
+		token := "not-a-cursor"
+
+		_, err := formatQuery(views.GetTasksReq{Cursor: &token})
+
+		assert.True(t, errors.Is(err, customError.InvalidRequest))
+	})
+
+	t.Run("due date filters", func(t *testing.T) {
+		overdue := true
+		after := time.Date(2024, 1, 1, 8, 0, 0, 0, time.FixedZone("UTC+8", 8*60*60))
+		before := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+		param, err := formatQuery(views.GetTasksReq{Overdue: &overdue, DueAfter: &after, DueBefore: &before})
+
+		assert.NoError(t, err)
+		assert.True(t, *param.Overdue)
+		assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), *param.DueAfter)
+		assert.Equal(t, before, *param.DueBefore)
+	})
+
+	t.Run("due_after must be before due_before", func(t *testing.T) {
+		after := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+		before := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		_, err := formatQuery(views.GetTasksReq{DueAfter: &after, DueBefore: &before})
+
+		assert.True(t, errors.Is(err, customError.InvalidRequest))
+	})
 }
 
 func Test_taskHandler_CreateTask(t *testing.T) {
-	t.Run("successful task creation", func(t *testing.T) {
+	t.Run("priority is passed to the service", func(t *testing.T) {
 		mockTaskService := new(MockTaskService)
 		h := &taskHandler{
 			taskService: mockTaskService,
 		}
-		mockTaskService.On("CreateTask", mock.Anything, mock.Anything).Return(nil)
+		mockTaskService.On("CreateTask", mock.Anything, mock.MatchedBy(func(task entities.Task) bool {
+			return task.Priority == constants.PriorityUrgent
+		}), (*entities.IdempotencyLease)(nil)).Return(&entities.Task{ID: "task-1", Name: "Test Task", Priority: constants.PriorityUrgent}, nil)
 
-		body := `{"name": "Test Task"}`
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
-		req, _ := http.NewRequest("POST", "/tasks", bytes.NewBufferString(body))
+		req, _ := http.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"name": "Test Task", "priority": 3}`))
 		req.Header.Set("Content-Type", "application/json")
 		c.Request = req
 
 		h.CreateTask(c)
 
 		assert.Equal(t, http.StatusCreated, w.Code)
-
 		mockTaskService.AssertExpectations(t)
 	})
-}
 
-func Test_taskHandler_UpdateTask(t *testing.T) {
-	t.Run("successful task update", func(t *testing.T) {
+	t.Run("parent_id is passed to the service", func(t *testing.T) {
 		mockTaskService := new(MockTaskService)
 		h := &taskHandler{
 			taskService: mockTaskService,
 		}
+		mockTaskService.On("CreateTask", mock.Anything, mock.MatchedBy(func(task entities.Task) bool {
+			return task.ParentID != nil && *task.ParentID == "task-1"
+		}), (*entities.IdempotencyLease)(nil)).Return(&entities.Task{ID: "task-2", Name: "Subtask"}, nil)
 
-		mockTaskService.On("UpdateTask", mock.Anything, mock.Anything).Return(nil)
-
-		body := `{"name":"task-test","status": 1}`
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
-		req, _ := http.NewRequest("PUT", "/tasks/task-1", bytes.NewBufferString(body))
+		req, _ := http.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"name": "Subtask", "parent_id": "task-1"}`))
 		req.Header.Set("Content-Type", "application/json")
 		c.Request = req
-		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
-
-		h.UpdateTask(c)
 
-		assert.Equal(t, http.StatusNoContent, w.Code)
+		h.CreateTask(c)
 
+		assert.Equal(t, http.StatusCreated, w.Code)
 		mockTaskService.AssertExpectations(t)
 	})
-}
 
-func Test_taskHandler_DeleteTask(t *testing.T) {
-	t.Run("successful task deletion", func(t *testing.T) {
+	t.Run("invalid priority", func(t *testing.T) {
 		mockTaskService := new(MockTaskService)
 		h := &taskHandler{
 			taskService: mockTaskService,
 		}
-		mockTaskService.On("DeleteTask", mock.Anything, "task-1").Return(nil)
 
 		w := httptest.NewRecorder()
 		c, _ := gin.CreateTestContext(w)
-		req, _ := http.NewRequest("DELETE", "/tasks/task-1", nil)
+		req, _ := http.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"name": "Test Task", "priority": 7}`))
+		req.Header.Set("Content-Type", "application/json")
 		c.Request = req
-		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
 
-		h.DeleteTask(c)
+		h.CreateTask(c)
 
-		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+		mockTaskService.AssertNotCalled(t, "CreateTask", mock.Anything, mock.Anything, mock.Anything)
+	})
 
-		mockTaskService.AssertExpectations(t)
+	t.Run("due dates are passed in UTC", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		dueAt := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+		mockTaskService.On("CreateTask", mock.Anything, mock.MatchedBy(func(task entities.Task) bool {
+			return task.DueAt != nil && task.DueAt.Equal(dueAt) && task.DueAt.Location() == time.UTC && task.RemindAt == nil
+		}), (*entities.IdempotencyLease)(nil)).Return(&entities.Task{ID: "task-1", Name: "Test Task", DueAt: &dueAt}, nil)
+
+		body := `{"name": "Test Task", "due_at": "2024-01-01T09:00:00+08:00"}`
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("POST", "/tasks", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+
+		h.CreateTask(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("successful task creation", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("CreateTask", mock.Anything, mock.MatchedBy(func(task entities.Task) bool {
+			return task.ID != "" && task.Name == "Test Task" && task.Status == constants.Incomplete
+		}), (*entities.IdempotencyLease)(nil)).Return(&entities.Task{ID: "task-1", Name: "Test Task"}, nil)
+
+		body := `{"name": "Test Task"}`
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("POST", "/tasks", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+
+		h.CreateTask(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		var created entities.Task
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		assert.Equal(t, "task-1", created.ID)
+		assert.Equal(t, "/tasks/task-1", w.Header().Get("Location"))
+		assert.Equal(t, `"0"`, w.Header().Get("ETag"))
+
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("stores the response for an idempotency key", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		mockIdempotencyService := new(MockIdempotencyService)
+		h := &taskHandler{
+			taskService:        mockTaskService,
+			idempotencyService: mockIdempotencyService,
+		}
+		lease := &entities.IdempotencyLease{Key: "key-1", Token: "token-1"}
+		mockIdempotencyService.On("Begin", mock.Anything, "key-1", []byte(`{"name":"Test Task"}`)).Return(lease, nil, nil)
+		mockTaskService.On("CreateTask", mock.Anything, mock.Anything, lease).Return(&entities.Task{ID: "task-1", Name: "Test Task"}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("POST", "/tasks", bytes.NewBufferString(`{ "name" : "Test Task" }`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "key-1")
+		c.Request = req
+
+		h.CreateTask(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Empty(t, w.Header().Get("Idempotent-Replayed"))
+		mockTaskService.AssertExpectations(t)
+		mockIdempotencyService.AssertExpectations(t)
+	})
+
+	t.Run("replays the stored response", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		mockIdempotencyService := new(MockIdempotencyService)
+		h := &taskHandler{
+			taskService:        mockTaskService,
+			idempotencyService: mockIdempotencyService,
+		}
+		stored := []byte(`{"id":"task-1","name":"Test Task","status":0,"version":0}`)
+		mockIdempotencyService.On("Begin", mock.Anything, "key-1", mock.Anything).
+			Return(nil, &entities.IdempotentResponse{StatusCode: http.StatusCreated, Body: stored}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"name": "Test Task"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "key-1")
+		c.Request = req
+
+		h.CreateTask(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, stored, w.Body.Bytes())
+		assert.Equal(t, "/tasks/task-1", w.Header().Get("Location"))
+		assert.Equal(t, "true", w.Header().Get("Idempotent-Replayed"))
+		mockTaskService.AssertNotCalled(t, "CreateTask", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("key reused with a different body", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		mockIdempotencyService := new(MockIdempotencyService)
+		h := &taskHandler{
+			taskService:        mockTaskService,
+			idempotencyService: mockIdempotencyService,
+		}
+		mockIdempotencyService.On("Begin", mock.Anything, "key-1", mock.Anything).
+			Return(nil, nil, customError.IdempotencyKeyReused.New("idempotency key reused"))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"name": "Other Task"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "key-1")
+		c.Request = req
+
+		h.CreateTask(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.IdempotencyKeyReused))
+		mockTaskService.AssertNotCalled(t, "CreateTask", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("releases the key when creation fails", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		mockIdempotencyService := new(MockIdempotencyService)
+		h := &taskHandler{
+			taskService:        mockTaskService,
+			idempotencyService: mockIdempotencyService,
+		}
+		lease := &entities.IdempotencyLease{Key: "key-1", Token: "token-1"}
+		mockIdempotencyService.On("Begin", mock.Anything, "key-1", mock.Anything).Return(lease, nil, nil)
+		mockTaskService.On("CreateTask", mock.Anything, mock.Anything, lease).Return(nil, customError.Internal.New("db error"))
+		mockIdempotencyService.On("Release", mock.Anything, *lease).Return(nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("POST", "/tasks", bytes.NewBufferString(`{"name": "Test Task"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "key-1")
+		c.Request = req
+
+		h.CreateTask(c)
+
+		assert.Len(t, c.Errors, 1)
+		mockIdempotencyService.AssertExpectations(t)
+	})
+
+	t.Run("releases the key after the client disconnects", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		mockIdempotencyService := new(MockIdempotencyService)
+		h := &taskHandler{
+			taskService:        mockTaskService,
+			idempotencyService: mockIdempotencyService,
+		}
+		lease := &entities.IdempotencyLease{Key: "key-1", Token: "token-1"}
+		mockIdempotencyService.On("Begin", mock.Anything, "key-1", mock.Anything).Return(lease, nil, nil)
+		mockTaskService.On("CreateTask", mock.Anything, mock.Anything, lease).Return(nil, context.Canceled)
+		mockIdempotencyService.On("Release", mock.MatchedBy(func(ctx context.Context) bool {
+			return ctx.Err() == nil
+		}), *lease).Return(nil)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequestWithContext(ctx, "POST", "/tasks", bytes.NewBufferString(`{"name": "Test Task"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Idempotency-Key", "key-1")
+		c.Request = req
+
+		h.CreateTask(c)
+
+		mockIdempotencyService.AssertExpectations(t)
+	})
+}
+
+func Test_taskHandler_UpdateTask(t *testing.T) {
+	updated := &entities.Task{
+		ID:      "task-1",
+		Name:    "task-test",
+		Status:  constants.Complete,
+		Version: 2,
+	}
+	t.Run("successful task update", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+
+		mockTaskService.On("UpdateTask", mock.Anything, mock.Anything, (*int)(nil)).Return(updated, nil)
+
+		body := `{"name":"task-test","status": 1}`
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("PUT", "/tasks/task-1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.UpdateTask(c)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+		assert.Empty(t, w.Body.String())
+
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("workflow status is passed to service", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		isReview := func(task entities.Task) bool { return task.Status == constants.Status(3) }
+		mockTaskService.On("UpdateTask", mock.Anything, mock.MatchedBy(isReview), (*int)(nil)).Return(nil, customError.TransitionNotAllowed.New("task task-1 cannot move from todo to review"))
+
+		body := `{"name":"task-test","status": 3}`
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("PUT", "/tasks/task-1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.UpdateTask(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.TransitionNotAllowed))
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("return representation when preferred", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+
+		mockTaskService.On("UpdateTask", mock.Anything, mock.Anything, (*int)(nil)).Return(updated, nil)
+
+		body := `{"name":"task-test","status": 1}`
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("PUT", "/tasks/task-1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Prefer", "handling=strict, return=representation")
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.UpdateTask(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "return=representation", w.Header().Get("Preference-Applied"))
+		var task entities.Task
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &task))
+		assert.Equal(t, *updated, task)
+
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("stale If-Match is passed as expected version", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		version := 2
+		mockTaskService.On("UpdateTask", mock.Anything, mock.Anything, &version).
+			Return(nil, customError.TaskVersionMismatch.New("task version is 3, expected 2"))
+
+		body := `{"name":"task-test","status": 1}`
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("PUT", "/tasks/task-1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"2"`)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.UpdateTask(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.TaskVersionMismatch))
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("full replacement requires status", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+
+		body := `{"name":"task-test"}`
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("PUT", "/tasks/task-1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.UpdateTask(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+		mockTaskService.AssertNotCalled(t, "UpdateTask", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("malformed If-Match", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+
+		body := `{"name":"task-test","status": 1}`
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("PUT", "/tasks/task-1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", "abc")
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.UpdateTask(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+		mockTaskService.AssertNotCalled(t, "UpdateTask", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func Test_taskHandler_PatchTask(t *testing.T) {
+	patched := &entities.Task{
+		ID:      "task-1",
+		Name:    "Renamed",
+		Status:  constants.Complete,
+		Version: 4,
+	}
+
+	t.Run("successful merge patch", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		body := `{"name":"Renamed"}`
+		version := 3
+		mockTaskService.On("PatchTask", mock.Anything, "task-1", constants.MergePatch, []byte(body), &version).Return(patched, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("PATCH", "/tasks/task-1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+		req.Header.Set("If-Match", `"3"`)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.PatchTask(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("successful json patch", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		body := `[{"op":"replace","path":"/status","value":1}]`
+		mockTaskService.On("PatchTask", mock.Anything, "task-1", constants.JSONPatch, []byte(body), (*int)(nil)).Return(patched, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("PATCH", "/tasks/task-1", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json-patch+json; charset=utf-8")
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.PatchTask(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("PATCH", "/tasks/task-1", bytes.NewBufferString(`{"name":"Renamed"}`))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.PatchTask(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.UnsupportedMedia))
+		mockTaskService.AssertNotCalled(t, "PatchTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func Test_taskHandler_MoveTask(t *testing.T) {
+	t.Run("move after another task", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		version := 3
+		mockTaskService.On("MoveTask", mock.Anything, "task-1", entities.TaskMove{TargetID: "task-2", After: true}, &version).
+			Return(&entities.Task{ID: "task-1", Position: "00000005i", Version: 4}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("POST", "/tasks/task-1/move", bytes.NewBufferString(`{"after": "task-2"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", `"3"`)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.MoveTask(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"4"`, w.Header().Get("ETag"))
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("exactly one of before and after", func(t *testing.T) {
+		for _, body := range []string{`{}`, `{"before": "task-2", "after": "task-3"}`, `{"before": ""}`} {
+			mockTaskService := new(MockTaskService)
+			h := &taskHandler{
+				taskService: mockTaskService,
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			req, _ := http.NewRequest("POST", "/tasks/task-1/move", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req
+			c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+			h.MoveTask(c)
+
+			assert.Len(t, c.Errors, 1, body)
+			assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest), body)
+			mockTaskService.AssertNotCalled(t, "MoveTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+}
+
+func Test_taskHandler_AttachTags(t *testing.T) {
+	t.Run("attach tags", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mockTaskService.On("AttachTags", mock.Anything, "task-1", []string{"tag-1"}).Return(&entities.Task{
+			ID:        "task-1",
+			Name:      "Task 1",
+			Position:  "00000001",
+			Version:   2,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+			Tags:      []entities.Tag{{ID: "tag-1", Name: "work", Color: "#1e90ff", CreatedAt: createdAt, UpdatedAt: createdAt}},
+		}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("POST", "/tasks/task-1/tags", bytes.NewBufferString(`{"tag_ids": ["tag-1"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.AttachTags(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+		expected := `{"id":"task-1","name":"Task 1","status":0,"priority":0,"position":"00000001","version":2,"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z",` +
+			`"tags":[{"id":"tag-1","name":"work","color":"#1e90ff","created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z"}]}`
+		assert.JSONEq(t, expected, w.Body.String())
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("tag ids are required", func(t *testing.T) {
+		for _, body := range []string{`{}`, `{"tag_ids": []}`} {
+			mockTaskService := new(MockTaskService)
+			h := &taskHandler{
+				taskService: mockTaskService,
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			req, _ := http.NewRequest("POST", "/tasks/task-1/tags", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req
+			c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+			h.AttachTags(c)
+
+			assert.Len(t, c.Errors, 1, body)
+			assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest), body)
+			mockTaskService.AssertNotCalled(t, "AttachTags", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+}
+
+func Test_taskHandler_DetachTag(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	h := &taskHandler{
+		taskService: mockTaskService,
+	}
+	mockTaskService.On("DetachTags", mock.Anything, "task-1", []string{"tag-1"}).Return(&entities.Task{ID: "task-1", Version: 3}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	req, _ := http.NewRequest("DELETE", "/tasks/task-1/tags/tag-1", nil)
+	c.Request = req
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}, gin.Param{Key: "tag_id", Value: "tag-1"}}
+
+	h.DetachTag(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"3"`, w.Header().Get("ETag"))
+	mockTaskService.AssertExpectations(t)
+}
+
+func Test_taskHandler_DeleteTask(t *testing.T) {
+	t.Run("successful task deletion", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("DeleteTask", mock.Anything, "task-1", (*int)(nil), false).Return(nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("DELETE", "/tasks/task-1", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.DeleteTask(c)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("delete with If-Match", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		version := 5
+		mockTaskService.On("DeleteTask", mock.Anything, "task-1", &version, false).Return(nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("DELETE", "/tasks/task-1", nil)
+		req.Header.Set("If-Match", `"5"`)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.DeleteTask(c)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("cascade delete", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("DeleteTask", mock.Anything, "task-1", (*int)(nil), true).Return(nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("DELETE", "/tasks/task-1?cascade=true", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.DeleteTask(c)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("invalid cascade", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("DELETE", "/tasks/task-1?cascade=maybe", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.DeleteTask(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+		mockTaskService.AssertNotCalled(t, "DeleteTask", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func Test_taskHandler_GetSubtree(t *testing.T) {
+	parentID := "task-1"
+	tree := &entities.TaskNode{
+		Task: entities.Task{ID: "task-1", Name: "epic"},
+		Children: []*entities.TaskNode{
+			{Task: entities.Task{ID: "task-2", ParentID: &parentID, Name: "story"}, Depth: 1},
+		},
+	}
+
+	t.Run("defaults to all levels", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("GetSubtree", mock.Anything, "task-1", constants.MaxTaskDepth).Return(tree, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1/subtree", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetSubtree(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, "task-1", body["id"])
+		assert.Equal(t, float64(0), body["depth"])
+		child := body["children"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "task-1", child["parent_id"])
+		assert.Equal(t, float64(1), child["depth"])
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("depth is passed to service", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("GetSubtree", mock.Anything, "task-1", 1).Return(tree, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1/subtree?depth=1", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetSubtree(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("invalid depth", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1/subtree?depth=deep", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetSubtree(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+	})
+}
+
+func Test_taskHandler_GetWorkflow(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	h := &taskHandler{
+		taskService: mockTaskService,
+	}
+	workflow := &entities.Workflow{States: []entities.WorkflowState{
+		{ID: 0, Name: "todo", Transitions: []constants.Status{1}},
+		{ID: 1, Name: "done", Transitions: []constants.Status{0}},
+	}}
+	mockTaskService.On("GetWorkflow", mock.Anything).Return(workflow, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	req, _ := http.NewRequest("GET", "/tasks/workflow", nil)
+	c.Request = req
+
+	h.GetWorkflow(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"states":[{"id":0,"name":"todo","transitions":[1]},{"id":1,"name":"done","transitions":[0]}]}`, w.Body.String())
+	mockTaskService.AssertExpectations(t)
+}
+
+func Test_taskHandler_GetOccurrences(t *testing.T) {
+	occurrences := &entities.Occurrences{
+		TaskID:      "task-1",
+		Recurrence:  "FREQ=DAILY",
+		Occurrences: []time.Time{time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)},
+	}
+
+	t.Run("defaults to five occurrences", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("GetOccurrences", mock.Anything, "task-1", constants.DefaultOccurrences).Return(occurrences, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1/occurrences", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetOccurrences(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		assert.Equal(t, "FREQ=DAILY", body["recurrence"])
+		assert.Equal(t, []interface{}{"2024-01-01T09:00:00Z", "2024-01-02T09:00:00Z"}, body["occurrences"])
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("count is passed to service", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("GetOccurrences", mock.Anything, "task-1", 2).Return(occurrences, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1/occurrences?count=2", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetOccurrences(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("invalid count", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1/occurrences?count=many", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetOccurrences(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+	})
+}
+
+func Test_taskHandler_SearchTasks(t *testing.T) {
+	results := &entities.SearchResults{
+		Results: []entities.SearchResult{{Task: entities.Task{ID: "task-1", Name: "Weekly report"}, Snippet: "<mark>Weekly report</mark>", Rank: -1.2}},
+		Size:    20,
+		Page:    1,
+	}
+
+	t.Run("defaults to the first page of twenty", func(t *testing.T) {
+		mockSearchService := new(MockSearchService)
+		h := &taskHandler{
+			searchService: mockSearchService,
+		}
+		mockSearchService.On("Search", mock.Anything, `"weekly report"`, constants.DefaultSearchSize, 1).Return(results, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/search?q=%22weekly+report%22", nil)
+		c.Request = req
+
+		h.SearchTasks(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var body map[string]interface{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		result := body["results"].([]interface{})[0].(map[string]interface{})
+		assert.Equal(t, "<mark>Weekly report</mark>", result["snippet"])
+		assert.Equal(t, "task-1", result["task"].(map[string]interface{})["id"])
+		mockSearchService.AssertExpectations(t)
+	})
+
+	t.Run("size and page are passed to service", func(t *testing.T) {
+		mockSearchService := new(MockSearchService)
+		h := &taskHandler{
+			searchService: mockSearchService,
+		}
+		mockSearchService.On("Search", mock.Anything, "plan*", 5, 3).Return(&entities.SearchResults{Results: []entities.SearchResult{}, Size: 5, Page: 3}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/search?q=plan*&size=5&page=3", nil)
+		c.Request = req
+
+		h.SearchTasks(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"results":[],"size":5,"page":3}`, w.Body.String())
+		mockSearchService.AssertExpectations(t)
+	})
+
+	t.Run("query is required", func(t *testing.T) {
+		mockSearchService := new(MockSearchService)
+		h := &taskHandler{
+			searchService: mockSearchService,
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/search", nil)
+		c.Request = req
+
+		h.SearchTasks(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+		mockSearchService.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("service error", func(t *testing.T) {
+		mockSearchService := new(MockSearchService)
+		h := &taskHandler{
+			searchService: mockSearchService,
+		}
+		mockSearchService.On("Search", mock.Anything, `"weekly`, constants.DefaultSearchSize, 1).Return(nil, customError.InvalidRequest.New("invalid search query"))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/search?q=%22weekly", nil)
+		c.Request = req
+
+		h.SearchTasks(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+	})
+}
+
+func Test_taskHandler_GetHistory(t *testing.T) {
+	log := &entities.AuditLog{
+		Entries: []entities.AuditEntry{{
+			ID:        4,
+			TaskID:    "task-1",
+			Action:    constants.AuditUpdate,
+			Actor:     "alice",
+			Version:   2,
+			Changes:   []entities.FieldChange{{Field: "name", Before: "Draft", After: "Final"}},
+			CreatedAt: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+			Hash:      "h4",
+		}},
+		Size:       1,
+		NextBefore: 4,
+	}
+
+	t.Run("defaults to fifty entries", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("GetHistory", mock.Anything, "task-1", entities.AuditQueryParam{Size: constants.DefaultAuditSize}).Return(log, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1/history", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetHistory(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"entries":[{"id":4,"task_id":"task-1","action":"update","actor":"alice","version":2,`+
+			`"changes":[{"field":"name","before":"Draft","after":"Final"}],"created_at":"2024-01-01T09:00:00Z","hash":"h4"}],"size":1,"next_before":4}`, w.Body.String())
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("filters and cursor", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mockTaskService.On("GetHistory", mock.Anything, "task-1", entities.AuditQueryParam{
+			Actor:   "alice",
+			Actions: []constants.AuditAction{constants.AuditUpdate, constants.AuditDelete},
+			Since:   &since,
+			Before:  10,
+			Size:    5,
+		}).Return(log, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1/history?actor=alice&action=update&action=delete&since=2024-01-01T08:00:00%2B08:00&before=10&size=5", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetHistory(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("invalid before", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1/history?before=last", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetHistory(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+	})
+}
+
+func Test_taskHandler_GetAuditLog(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	h := &taskHandler{
+		taskService: mockTaskService,
+	}
+	mockTaskService.On("GetAuditLog", mock.Anything, entities.AuditQueryParam{TaskID: "task-1", Actor: "bob", Size: constants.DefaultAuditSize}).
+		Return(&entities.AuditLog{Entries: []entities.AuditEntry{}, Size: constants.DefaultAuditSize}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	req, _ := http.NewRequest("GET", "/tasks/audit?task_id=task-1&actor=bob", nil)
+	c.Request = req
+
+	h.GetAuditLog(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"entries":[],"size":50}`, w.Body.String())
+	mockTaskService.AssertExpectations(t)
+}
+
+func Test_parseIfMatch(t *testing.T) {
+	version := 7
+	tests := []struct {
+		name    string
+		header  string
+		want    *int
+		wantErr bool
+	}{
+		{name: "empty", header: "", want: nil},
+		{name: "wildcard", header: "*", want: nil},
+		{name: "strong tag", header: `"7"`, want: &version},
+		{name: "weak tag", header: `W/"7"`, want: &version},
+		{name: "unquoted", header: "7", wantErr: true},
+		{name: "not a version", header: `"abc"`, wantErr: true},
+		{name: "multiple tags", header: `"6", "7"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseIfMatch(tt.header)
+			if tt.wantErr {
+				assert.True(t, errors.Is(err, customError.InvalidRequest))
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func Test_taskHandler_GetTrash(t *testing.T) {
+	t.Run("successful trash retrieval", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		deletedAt := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+		trash := &entities.Tasks{
+			Tasks: []entities.Task{{ID: "task-1", Name: "Task 1", DeletedAt: &deletedAt}},
+			Size:  1,
+			Page:  1,
+		}
+		mockTaskService.On("GetTrash", mock.Anything, mock.MatchedBy(func(param entities.TaskQueryParam) bool {
+			return param.Size == 5 && param.SortBy == constants.SortByName
+		})).Return(trash, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/trash?size=5&sort=name", nil)
+		c.Request = req
+
+		h.GetTrash(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"deleted_at":"2024-01-03T00:00:00Z"`)
+		mockTaskService.AssertExpectations(t)
+	})
+}
+
+func Test_taskHandler_RestoreTask(t *testing.T) {
+	t.Run("successful task restore", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("RestoreTask", mock.Anything, "task-1").Return(&entities.Task{ID: "task-1", Version: 2}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("POST", "/tasks/task-1/restore", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.RestoreTask(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+		mockTaskService.AssertExpectations(t)
+	})
+}
+
+func Test_taskHandler_PurgeTask(t *testing.T) {
+	t.Run("successful task purge", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("PurgeTask", mock.Anything, "task-1").Return(nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("DELETE", "/tasks/trash/task-1", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.PurgeTask(c)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("task not in trash", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("PurgeTask", mock.Anything, "task-1").Return(customError.TaskNotFound.New("task not found in trash"))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("DELETE", "/tasks/trash/task-1", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.PurgeTask(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.TaskNotFound))
+		mockTaskService.AssertExpectations(t)
+	})
+}
+
+func Test_taskHandler_BatchTasks(t *testing.T) {
+	t.Run("defaults to atomic mode", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		name := "Task 1"
+		operations := []entities.BatchOperation{
+			{Op: constants.BatchCreate, Name: &name},
+			{Op: constants.BatchDelete, ID: "task-2"},
+		}
+		result := &entities.BatchResults{
+			Mode:      constants.BatchAtomic,
+			Committed: true,
+			Results: []entities.BatchResult{
+				{Index: 0, Op: constants.BatchCreate, ID: "task-1", Status: http.StatusCreated},
+				{Index: 1, Op: constants.BatchDelete, ID: "task-2", Status: http.StatusNoContent},
+			},
+		}
+		mockTaskService.On("BatchTasks", mock.Anything, constants.BatchAtomic, operations).Return(result, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		body := `{"operations":[{"op":"create","name":"Task 1"},{"op":"delete","id":"task-2"}]}`
+		req, _ := http.NewRequest("POST", "/tasks:batch", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+
+		h.BatchTasks(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var got entities.BatchResults
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &got))
+		assert.Equal(t, *result, got)
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("invalid mode", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		body := `{"mode":"eventually","operations":[{"op":"delete","id":"task-2"}]}`
+		req, _ := http.NewRequest("POST", "/tasks:batch", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+
+		h.BatchTasks(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+		mockTaskService.AssertNotCalled(t, "BatchTasks", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("empty operations", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("POST", "/tasks:batch", bytes.NewBufferString(`{"operations":[]}`))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+
+		h.BatchTasks(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+	})
+}
+
+func Test_taskHandler_AddBlockers(t *testing.T) {
+	t.Run("add blockers", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mockTaskService.On("AddBlockers", mock.Anything, "task-2", []string{"task-1"}).Return(&entities.Task{
+			ID:        "task-2",
+			Name:      "Build",
+			Position:  "00000002",
+			Version:   1,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+			Blocked:   true,
+		}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("POST", "/tasks/task-2/blockers", bytes.NewBufferString(`{"blocker_ids": ["task-1"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-2"}}
+
+		h.AddBlockers(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, `"1"`, w.Header().Get("ETag"))
+		expected := `{"id":"task-2","name":"Build","status":0,"priority":0,"position":"00000002","version":1,"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z","blocked":true}`
+		assert.JSONEq(t, expected, w.Body.String())
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("blocker ids are required", func(t *testing.T) {
+		for _, body := range []string{`{}`, `{"blocker_ids": []}`} {
+			mockTaskService := new(MockTaskService)
+			h := &taskHandler{
+				taskService: mockTaskService,
+			}
+
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			req, _ := http.NewRequest("POST", "/tasks/task-2/blockers", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			c.Request = req
+			c.Params = gin.Params{gin.Param{Key: "id", Value: "task-2"}}
+
+			h.AddBlockers(c)
+
+			assert.Len(t, c.Errors, 1, body)
+			assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest), body)
+			mockTaskService.AssertNotCalled(t, "AddBlockers", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+}
+
+func Test_taskHandler_RemoveBlocker(t *testing.T) {
+	mockTaskService := new(MockTaskService)
+	h := &taskHandler{
+		taskService: mockTaskService,
+	}
+	mockTaskService.On("RemoveBlockers", mock.Anything, "task-2", []string{"task-1"}).Return(&entities.Task{ID: "task-2", Version: 2}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	req, _ := http.NewRequest("DELETE", "/tasks/task-2/blockers/task-1", nil)
+	c.Request = req
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "task-2"}, gin.Param{Key: "blocker_id", Value: "task-1"}}
+
+	h.RemoveBlocker(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	mockTaskService.AssertExpectations(t)
+}
+
+func Test_taskHandler_SortTasks(t *testing.T) {
+	t.Run("sort tasks", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("SortTasks", mock.Anything, []string{"task-2", "task-1"}).Return(&entities.Tasks{
+			Tasks: []entities.Task{{ID: "task-1", Name: "Design"}, {ID: "task-2", Name: "Build"}},
+			Size:  2,
+		}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("POST", "/tasks:toposort", bytes.NewBufferString(`{"task_ids": ["task-2", "task-1"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+
+		h.SortTasks(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result entities.Tasks
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, "task-1", result.Tasks[0].ID)
+		assert.Equal(t, "task-2", result.Tasks[1].ID)
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("too many task ids", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		ids := make([]string, 101)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("task-%d", i)
+		}
+		body, _ := json.Marshal(views.SortTasksReq{TaskIDs: ids})
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("POST", "/tasks:toposort", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		c.Request = req
+
+		h.SortTasks(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+		mockTaskService.AssertNotCalled(t, "SortTasks", mock.Anything, mock.Anything)
+	})
+}
+
+func Test_taskHandler_GetEvents(t *testing.T) {
+	t.Run("defaults to a hundred events", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("GetEvents", mock.Anything, "task-1", int64(0), constants.DefaultEventSize).Return(&entities.TaskEvents{
+			Events: []entities.TaskEvent{{
+				ID:        7,
+				TaskID:    "task-1",
+				Seq:       2,
+				Type:      constants.TaskRenamed,
+				Version:   1,
+				Data:      json.RawMessage(`{"name":"Final"}`),
+				Actor:     "alice",
+				CreatedAt: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC),
+			}},
+			Size:      1,
+			NextAfter: 2,
+		}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1/events", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetEvents(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"events":[{"id":7,"task_id":"task-1","seq":2,"type":"TaskRenamed","version":1,`+
+			`"data":{"name":"Final"},"actor":"alice","created_at":"2024-01-01T09:00:00Z"}],"size":1,"next_after":2}`, w.Body.String())
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("cursor", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("GetEvents", mock.Anything, "task-1", int64(2), 10).Return(&entities.TaskEvents{Events: []entities.TaskEvent{}, Size: 10}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1/events?after=2&size=10", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetEvents(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"events":[],"size":10}`, w.Body.String())
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("event sourcing disabled", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		mockTaskService.On("GetEvents", mock.Anything, "task-1", int64(0), constants.DefaultEventSize).
+			Return(nil, customError.EventSourcingDisabled.New("disabled"))
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1/events", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetEvents(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.EventSourcingDisabled))
+	})
+}
+
+func Test_taskHandler_GetTaskAsOf(t *testing.T) {
+	task := &entities.Task{ID: "task-1", Name: "Draft", Version: 1}
+
+	t.Run("as of a version", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		version := 1
+		mockTaskService.On("GetTaskAsOf", mock.Anything, "task-1", entities.AsOf{Version: &version}).Return(task, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1/as-of?version=1", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetTaskAsOf(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		var result entities.Task
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, "Draft", result.Name)
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("as of a time in UTC", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+		at := time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC)
+		mockTaskService.On("GetTaskAsOf", mock.Anything, "task-1", entities.AsOf{At: &at}).Return(task, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1/as-of?at=2024-01-01T09:00:00%2B08:00", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetTaskAsOf(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockTaskService.AssertExpectations(t)
+	})
+
+	t.Run("invalid time", func(t *testing.T) {
+		mockTaskService := new(MockTaskService)
+		h := &taskHandler{
+			taskService: mockTaskService,
+		}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		req, _ := http.NewRequest("GET", "/tasks/task-1/as-of?at=yesterday", nil)
+		c.Request = req
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "task-1"}}
+
+		h.GetTaskAsOf(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+		mockTaskService.AssertNotCalled(t, "GetTaskAsOf", mock.Anything, mock.Anything, mock.Anything)
 	})
 }
@@ -0,0 +1,253 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strconv"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/views"
+	customError "tasks/errors"
+	"tasks/internal/service"
+)
+
+type webhookHandler struct {
+	webhookService service.WebhookService
+}
+
+func NewWebhookHandler(webhookService service.WebhookService) WebhookHandler {
+	return &webhookHandler{webhookService: webhookService}
+}
+
+// GetWebhook godoc
+// @Summary Get webhook
+// @Description Get a single webhook by id, the secret is not returned. Requires the admin token as a bearer token
+// @Tags webhooks
+// @Produce json
+// @Param Authorization header string true "Bearer admin token"
+// @Param id path string true "webhook id"
+// @Success 200 {object} entities.Webhook
+// @Failure 401 {object} error "invalid authorization"
+// @Failure 404 {object} error "webhook not found"
+// @Failure 500 {object} error "server internal error"
+// @Router /webhooks/{id} [get]
+func (h *webhookHandler) GetWebhook(ginCtx *gin.Context) {
+	webhookId := ginCtx.Param("id")
+	if webhookId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("webhook id is required"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	hook, err := h.webhookService.GetWebhook(ctx, webhookId)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, hook)
+}
+
+// GetWebhooks godoc
+// @Summary Get webhooks
+// @Description Get all webhooks ordered by creation time, secrets are not returned. Requires the admin token as a bearer token
+// @Tags webhooks
+// @Produce json
+// @Param Authorization header string true "Bearer admin token"
+// @Success 200 {object} entities.Webhooks
+// @Failure 401 {object} error "invalid authorization"
+// @Failure 500 {object} error "server internal error"
+// @Router /webhooks [get]
+func (h *webhookHandler) GetWebhooks(ginCtx *gin.Context) {
+	ctx := ginCtx.Request.Context()
+	hooks, err := h.webhookService.GetWebhooks(ctx)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, hooks)
+}
+
+// CreateWebhook godoc
+// @Summary Create webhook
+// @Description Register an endpoint for task events. Every delivery is signed with the secret, which is only returned in this response. Requires the admin token as a bearer token
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer admin token"
+// @Param webhook body views.CreateWebhookReq true "webhook"
+// @Success 201 {object} entities.Webhook
+// @Header 201 {string} Location "URL of the created webhook"
+// @Failure 400 {object} error "request is invalid"
+// @Failure 401 {object} error "invalid authorization"
+// @Failure 500 {object} error "server internal error"
+// @Router /webhooks [post]
+func (h *webhookHandler) CreateWebhook(ginCtx *gin.Context) {
+	var req views.CreateWebhookReq
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind json error"))
+		return
+	}
+	secret := ""
+	if req.Secret != nil {
+		secret = *req.Secret
+	}
+	ctx := ginCtx.Request.Context()
+	created, err := h.webhookService.CreateWebhook(ctx, entities.NewWebhook(req.URL, req.Events, secret))
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.Header("Location", "/webhooks/"+created.ID)
+	ginCtx.JSON(http.StatusCreated, created)
+}
+
+// UpdateWebhook godoc
+// @Summary Replace webhook
+// @Description Replace the url, events and active state of a webhook. An inactive webhook keeps its pending deliveries until it is activated again. The secret is returned only when it is changed. Requires the admin token as a bearer token
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param Authorization header string true "Bearer admin token"
+// @Param id path string true "webhook id"
+// @Param webhook body views.UpdateWebhookReq true "webhook"
+// @Success 200 {object} entities.Webhook
+// @Failure 400 {object} error "request is invalid"
+// @Failure 401 {object} error "invalid authorization"
+// @Failure 404 {object} error "webhook not found"
+// @Failure 500 {object} error "server internal error"
+// @Router /webhooks/{id} [put]
+func (h *webhookHandler) UpdateWebhook(ginCtx *gin.Context) {
+	webhookId := ginCtx.Param("id")
+	if webhookId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("webhook id is required"))
+		return
+	}
+	var req views.UpdateWebhookReq
+	if err := ginCtx.ShouldBindJSON(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind json error"))
+		return
+	}
+	if req.Secret != nil && req.RotateSecret {
+		_ = ginCtx.Error(customError.InvalidRequest.New("secret and rotate_secret cannot be used together"))
+		return
+	}
+	hook := entities.Webhook{ID: webhookId, URL: req.URL, Events: req.Events, Active: true}
+	if req.Active != nil {
+		hook.Active = *req.Active
+	}
+	if req.Secret != nil {
+		hook.Secret = *req.Secret
+	}
+	if req.RotateSecret {
+		hook.Secret = entities.NewWebhookSecret()
+	}
+	ctx := ginCtx.Request.Context()
+	updated, err := h.webhookService.UpdateWebhook(ctx, hook)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, updated)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete webhook
+// @Description Delete a webhook with its deliveries, pending events are not delivered. Requires the admin token as a bearer token
+// @Tags webhooks
+// @Param Authorization header string true "Bearer admin token"
+// @Param id path string true "webhook id"
+// @Success 204
+// @Failure 401 {object} error "invalid authorization"
+// @Failure 404 {object} error "webhook not found"
+// @Failure 500 {object} error "server internal error"
+// @Router /webhooks/{id} [delete]
+func (h *webhookHandler) DeleteWebhook(ginCtx *gin.Context) {
+	webhookId := ginCtx.Param("id")
+	if webhookId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("webhook id is required"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	if err := h.webhookService.DeleteWebhook(ctx, webhookId); err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.AbortWithStatus(http.StatusNoContent)
+}
+
+// GetDeliveries godoc
+// @Summary Get webhook deliveries
+// @Description List the deliveries of a webhook from the newest, with the payload, attempts and the last error. Requires the admin token as a bearer token
+// @Tags webhooks
+// @Produce json
+// @Param Authorization header string true "Bearer admin token"
+// @Param id path string true "webhook id"
+// @Param status query string false "delivery status" Enums(pending, delivered, dead)
+// @Param before query int false "next_before of the previous page"
+// @Param size query int false "size, defaults to 50" minimum(1) maximum(200)
+// @Success 200 {object} entities.WebhookDeliveries
+// @Failure 400 {object} error "request is invalid"
+// @Failure 401 {object} error "invalid authorization"
+// @Failure 404 {object} error "webhook not found"
+// @Failure 500 {object} error "server internal error"
+// @Router /webhooks/{id}/deliveries [get]
+func (h *webhookHandler) GetDeliveries(ginCtx *gin.Context) {
+	webhookId := ginCtx.Param("id")
+	if webhookId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("webhook id is required"))
+		return
+	}
+	var req views.GetDeliveriesReq
+	if err := ginCtx.ShouldBindQuery(&req); err != nil {
+		_ = ginCtx.Error(customError.InvalidRequest.Wrap(err, "should bind query error"))
+		return
+	}
+	param := entities.DeliveryQueryParam{WebhookID: webhookId, Status: req.Status, Size: constants.DefaultDeliverySize}
+	if req.Before != nil {
+		param.Before = *req.Before
+	}
+	if req.Size != nil {
+		param.Size = *req.Size
+	}
+	ctx := ginCtx.Request.Context()
+	deliveries, err := h.webhookService.GetDeliveries(ctx, param)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusOK, deliveries)
+}
+
+// Redeliver godoc
+// @Summary Redeliver webhook delivery
+// @Description Queue a delivered or dead delivery again with the original payload and event id, the attempts start over. Requires the admin token as a bearer token
+// @Tags webhooks
+// @Produce json
+// @Param Authorization header string true "Bearer admin token"
+// @Param id path string true "webhook id"
+// @Param delivery_id path int true "delivery id"
+// @Success 202 {object} entities.WebhookDelivery
+// @Failure 400 {object} error "request is invalid"
+// @Failure 401 {object} error "invalid authorization"
+// @Failure 404 {object} error "delivery not found"
+// @Failure 409 {object} error "delivery is pending"
+// @Failure 500 {object} error "server internal error"
+// @Router /webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (h *webhookHandler) Redeliver(ginCtx *gin.Context) {
+	webhookId := ginCtx.Param("id")
+	if webhookId == "" {
+		_ = ginCtx.Error(customError.InvalidRequest.New("webhook id is required"))
+		return
+	}
+	deliveryId, err := strconv.ParseInt(ginCtx.Param("delivery_id"), 10, 64)
+	if err != nil || deliveryId < 1 {
+		_ = ginCtx.Error(customError.InvalidRequest.New("delivery id must be a positive integer"))
+		return
+	}
+	ctx := ginCtx.Request.Context()
+	delivery, err := h.webhookService.Redeliver(ctx, webhookId, deliveryId)
+	if err != nil {
+		_ = ginCtx.Error(err)
+		return
+	}
+	ginCtx.JSON(http.StatusAccepted, delivery)
+}
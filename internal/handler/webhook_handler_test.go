@@ -0,0 +1,262 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"tasks/constants"
+	"tasks/domain/entities"
+	customError "tasks/errors"
+	"testing"
+	"time"
+)
+
+// MockWebhookService 模擬 WebhookService
+type MockWebhookService struct {
+	mock.Mock
+}
+
+func (m *MockWebhookService) CreateWebhook(ctx context.Context, hook entities.Webhook) (*entities.Webhook, error) {
+	args := m.Called(ctx, hook)
+	if created, ok := args.Get(0).(*entities.Webhook); ok {
+		return created, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) UpdateWebhook(ctx context.Context, hook entities.Webhook) (*entities.Webhook, error) {
+	args := m.Called(ctx, hook)
+	if updated, ok := args.Get(0).(*entities.Webhook); ok {
+		return updated, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) DeleteWebhook(ctx context.Context, webhookId string) error {
+	args := m.Called(ctx, webhookId)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) GetWebhook(ctx context.Context, webhookId string) (*entities.Webhook, error) {
+	args := m.Called(ctx, webhookId)
+	if hook, ok := args.Get(0).(*entities.Webhook); ok {
+		return hook, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) GetWebhooks(ctx context.Context) (*entities.Webhooks, error) {
+	args := m.Called(ctx)
+	if hooks, ok := args.Get(0).(*entities.Webhooks); ok {
+		return hooks, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) GetDeliveries(ctx context.Context, param entities.DeliveryQueryParam) (*entities.WebhookDeliveries, error) {
+	args := m.Called(ctx, param)
+	if deliveries, ok := args.Get(0).(*entities.WebhookDeliveries); ok {
+		return deliveries, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) Redeliver(ctx context.Context, webhookId string, deliveryId int64) (*entities.WebhookDelivery, error) {
+	args := m.Called(ctx, webhookId, deliveryId)
+	if delivery, ok := args.Get(0).(*entities.WebhookDelivery); ok {
+		return delivery, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) DispatchDue(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func Test_webhookHandler_GetWebhooks(t *testing.T) {
+	mockWebhookService := new(MockWebhookService)
+	h := &webhookHandler{webhookService: mockWebhookService}
+	createdAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockWebhookService.On("GetWebhooks", mock.Anything).Return(&entities.Webhooks{Webhooks: []entities.Webhook{
+		{ID: "hook-1", URL: "https://example.com/hook", Events: []constants.WebhookEvent{constants.WebhookTaskCreated}, Active: true, CreatedAt: createdAt, UpdatedAt: createdAt},
+	}}, nil)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("GET", "/webhooks", nil)
+
+	h.GetWebhooks(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	expected := `{"webhooks":[{"id":"hook-1","url":"https://example.com/hook","events":["task.created"],"active":true,"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z"}]}`
+	assert.JSONEq(t, expected, w.Body.String())
+}
+
+func Test_webhookHandler_CreateWebhook(t *testing.T) {
+	t.Run("create webhook", func(t *testing.T) {
+		mockWebhookService := new(MockWebhookService)
+		h := &webhookHandler{webhookService: mockWebhookService}
+		mockWebhookService.On("CreateWebhook", mock.Anything, mock.MatchedBy(func(hook entities.Webhook) bool {
+			return hook.ID != "" && hook.URL == "https://example.com/hook" && hook.Active && len(hook.Secret) == 64 &&
+				assert.ObjectsAreEqual([]constants.WebhookEvent{constants.WebhookTaskCreated}, hook.Events)
+		})).Return(&entities.Webhook{ID: "hook-1", URL: "https://example.com/hook", Secret: "generated"}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/webhooks", bytes.NewBufferString(`{"url": "https://example.com/hook", "events": ["task.created"]}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		h.CreateWebhook(c)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Equal(t, "/webhooks/hook-1", w.Header().Get("Location"))
+		assert.Contains(t, w.Body.String(), `"secret":"generated"`)
+		mockWebhookService.AssertExpectations(t)
+	})
+
+	t.Run("events are required", func(t *testing.T) {
+		mockWebhookService := new(MockWebhookService)
+		h := &webhookHandler{webhookService: mockWebhookService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/webhooks", bytes.NewBufferString(`{"url": "https://example.com/hook", "events": []}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+
+		h.CreateWebhook(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+		mockWebhookService.AssertNotCalled(t, "CreateWebhook", mock.Anything, mock.Anything)
+	})
+}
+
+func Test_webhookHandler_UpdateWebhook(t *testing.T) {
+	t.Run("pause and rotate the secret", func(t *testing.T) {
+		mockWebhookService := new(MockWebhookService)
+		h := &webhookHandler{webhookService: mockWebhookService}
+		mockWebhookService.On("UpdateWebhook", mock.Anything, mock.MatchedBy(func(hook entities.Webhook) bool {
+			return hook.ID == "hook-1" && !hook.Active && len(hook.Secret) == 64
+		})).Return(&entities.Webhook{ID: "hook-1"}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("PUT", "/webhooks/hook-1", bytes.NewBufferString(`{"url": "https://example.com/hook", "events": ["task.updated"], "active": false, "rotate_secret": true}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "hook-1"}}
+
+		h.UpdateWebhook(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockWebhookService.AssertExpectations(t)
+	})
+
+	t.Run("secret with rotate_secret", func(t *testing.T) {
+		mockWebhookService := new(MockWebhookService)
+		h := &webhookHandler{webhookService: mockWebhookService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("PUT", "/webhooks/hook-1", bytes.NewBufferString(`{"url": "https://example.com/hook", "events": ["task.updated"], "secret": "0123456789abcdef", "rotate_secret": true}`))
+		c.Request.Header.Set("Content-Type", "application/json")
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "hook-1"}}
+
+		h.UpdateWebhook(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+		mockWebhookService.AssertNotCalled(t, "UpdateWebhook", mock.Anything, mock.Anything)
+	})
+}
+
+func Test_webhookHandler_DeleteWebhook(t *testing.T) {
+	mockWebhookService := new(MockWebhookService)
+	h := &webhookHandler{webhookService: mockWebhookService}
+	mockWebhookService.On("DeleteWebhook", mock.Anything, "hook-404").Return(customError.WebhookNotFound.New("webhook not found"))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request, _ = http.NewRequest("DELETE", "/webhooks/hook-404", nil)
+	c.Params = gin.Params{gin.Param{Key: "id", Value: "hook-404"}}
+
+	h.DeleteWebhook(c)
+
+	assert.Len(t, c.Errors, 1)
+	assert.True(t, errors.Is(c.Errors[0].Err, customError.WebhookNotFound))
+}
+
+func Test_webhookHandler_GetDeliveries(t *testing.T) {
+	t.Run("default size", func(t *testing.T) {
+		mockWebhookService := new(MockWebhookService)
+		h := &webhookHandler{webhookService: mockWebhookService}
+		mockWebhookService.On("GetDeliveries", mock.Anything, entities.DeliveryQueryParam{WebhookID: "hook-1", Status: constants.DeliveryDead, Size: constants.DefaultDeliverySize}).
+			Return(&entities.WebhookDeliveries{Deliveries: []entities.WebhookDelivery{}, Size: constants.DefaultDeliverySize}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/webhooks/hook-1/deliveries?status=dead", nil)
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "hook-1"}}
+
+		h.GetDeliveries(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"deliveries":[],"size":50}`, w.Body.String())
+	})
+
+	t.Run("before and size", func(t *testing.T) {
+		mockWebhookService := new(MockWebhookService)
+		h := &webhookHandler{webhookService: mockWebhookService}
+		mockWebhookService.On("GetDeliveries", mock.Anything, entities.DeliveryQueryParam{WebhookID: "hook-1", Before: 30, Size: 10}).
+			Return(&entities.WebhookDeliveries{Deliveries: []entities.WebhookDelivery{}, Size: 10}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("GET", "/webhooks/hook-1/deliveries?before=30&size=10", nil)
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "hook-1"}}
+
+		h.GetDeliveries(c)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockWebhookService.AssertExpectations(t)
+	})
+}
+
+func Test_webhookHandler_Redeliver(t *testing.T) {
+	t.Run("redeliver", func(t *testing.T) {
+		mockWebhookService := new(MockWebhookService)
+		h := &webhookHandler{webhookService: mockWebhookService}
+		mockWebhookService.On("Redeliver", mock.Anything, "hook-1", int64(7)).Return(&entities.WebhookDelivery{ID: 7, Status: constants.DeliveryPending}, nil)
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/webhooks/hook-1/deliveries/7/redeliver", nil)
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "hook-1"}, gin.Param{Key: "delivery_id", Value: "7"}}
+
+		h.Redeliver(c)
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Contains(t, w.Body.String(), `"status":"pending"`)
+	})
+
+	t.Run("invalid delivery id", func(t *testing.T) {
+		mockWebhookService := new(MockWebhookService)
+		h := &webhookHandler{webhookService: mockWebhookService}
+
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request, _ = http.NewRequest("POST", "/webhooks/hook-1/deliveries/abc/redeliver", nil)
+		c.Params = gin.Params{gin.Param{Key: "id", Value: "hook-1"}, gin.Param{Key: "delivery_id", Value: "abc"}}
+
+		h.Redeliver(c)
+
+		assert.Len(t, c.Errors, 1)
+		assert.True(t, errors.Is(c.Errors[0].Err, customError.InvalidRequest))
+		mockWebhookService.AssertNotCalled(t, "Redeliver", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,342 @@
+package migration
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"tasks/internal/dialect"
+	"time"
+)
+
+// fileNamePattern migration 檔名格式，例如 0001_create_tasks.up.sql
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt string
+	// Dirty migration 執行到一半失敗，schema 可能只套用了一部分
+	Dirty bool
+}
+
+// record schema_migrations 中的一筆紀錄
+type record struct {
+	appliedAt string
+	dirty     bool
+}
+
+type Migrator struct {
+	db         *sql.DB
+	dialect    dialect.Dialect
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+func NewMigrator(db *sql.DB, dialect dialect.Dialect, source fs.FS, logger *zap.Logger) (*Migrator, error) {
+	migrations, err := load(source)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, dialect: dialect, migrations: migrations, logger: logger}, nil
+}
+
+// load 讀取 source 根目錄下的 migration 檔案並依版本排序
+func load(source fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(source, ".")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := fs.ReadFile(source, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has conflicting names %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func (m *Migrator) ensureTable() error {
+	_, err := m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations
+		(version INTEGER PRIMARY KEY NOT NULL,
+		name VARCHAR(255) NOT NULL,
+		applied_at ` + m.dialect.TimestampType() + ` NOT NULL,
+		dirty BOOLEAN NOT NULL DEFAULT FALSE
+		)`)
+	return err
+}
+
+// applied 已套用的版本與套用紀錄
+func (m *Migrator) applied() (map[int]record, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	rows, err := m.db.Query("SELECT version,applied_at,dirty FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	result := make(map[int]record)
+	for rows.Next() {
+		var version int
+		var r record
+		if err = rows.Scan(&version, &r.appliedAt, &r.dirty); err != nil {
+			return nil, err
+		}
+		result[version] = r
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// clean 有 dirty 的 migration 時拒絕繼續，必須先修復 schema 並以 Resolve 標記結果
+func (m *Migrator) clean(applied map[int]record) error {
+	for _, migration := range m.migrations {
+		if applied[migration.Version].dirty {
+			return fmt.Errorf("migration %d_%s is dirty, repair the schema and run `migrate resolve -version %d` first",
+				migration.Version, migration.Name, migration.Version)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		r, ok := applied[migration.Version]
+		result = append(result, Status{Migration: migration, Applied: ok, AppliedAt: r.appliedAt, Dirty: r.dirty})
+	}
+	return result, nil
+}
+
+// Up 依序套用尚未套用且版本不大於 target 的 migration，target 為 0 時套用全部
+func (m *Migrator) Up(target int, dryRun bool) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err = m.clean(applied); err != nil {
+		return nil, err
+	}
+	pending := make([]Migration, 0)
+	for _, migration := range m.migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if target > 0 && migration.Version > target {
+			break
+		}
+		pending = append(pending, migration)
+	}
+	if dryRun {
+		return pending, nil
+	}
+	for i, migration := range pending {
+		if err = m.apply(migration); err != nil {
+			return pending[:i], fmt.Errorf("apply migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		m.logger.Info("applied migration", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+	}
+	return pending, nil
+}
+
+// Down 由最新版本開始回滾 steps 個已套用的 migration
+func (m *Migrator) Down(steps int, dryRun bool) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if err = m.clean(applied); err != nil {
+		return nil, err
+	}
+	rollback := make([]Migration, 0, steps)
+	for i := len(m.migrations) - 1; i >= 0 && len(rollback) < steps; i-- {
+		if _, ok := applied[m.migrations[i].Version]; ok {
+			rollback = append(rollback, m.migrations[i])
+		}
+	}
+	for _, migration := range rollback {
+		if migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+		}
+	}
+	if dryRun {
+		return rollback, nil
+	}
+	for i, migration := range rollback {
+		if err = m.revert(migration); err != nil {
+			return rollback[:i], fmt.Errorf("rollback migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		m.logger.Info("rolled back migration", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+	}
+	return rollback, nil
+}
+
+// Baseline 將版本不大於 target 的 migration 記錄為已套用但不執行，
+// 用於 migration 出現前就已由 checkTables 建立 schema 的資料庫，只能在尚未套用任何 migration 時執行
+func (m *Migrator) Baseline(target int) ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) > 0 {
+		return nil, errors.New("baseline requires a database without applied migrations")
+	}
+	baseline := make([]Migration, 0)
+	for _, migration := range m.migrations {
+		if migration.Version > target {
+			break
+		}
+		baseline = append(baseline, migration)
+	}
+	tx, err := m.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	for _, migration := range baseline {
+		_, err = tx.Exec(m.dialect.Rebind("INSERT INTO schema_migrations (version,name,applied_at) VALUES (?, ?, ?)"),
+			migration.Version, migration.Name, time.Now().UTC())
+		if err != nil {
+			_ = tx.Rollback()
+			return nil, err
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+	return baseline, nil
+}
+
+// Unversioned 資料庫已有 table 但沒有任何 migration 紀錄，代表 schema 是在 migration 出現前建立的
+func (m *Migrator) Unversioned(table string) (bool, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return false, err
+	}
+	if len(applied) > 0 {
+		return false, nil
+	}
+	// table 不存在時查詢會失敗，不需要依 dialect 查 catalog
+	rows, err := m.db.Query("SELECT 1 FROM " + table + " WHERE 1 = 0")
+	if err != nil {
+		return false, nil
+	}
+	return true, rows.Close()
+}
+
+// Resolve 清除 dirty 狀態，applied 表示修復後的 schema 已完整套用該 migration，否則移除紀錄視為未套用
+func (m *Migrator) Resolve(version int, applied bool) error {
+	records, err := m.applied()
+	if err != nil {
+		return err
+	}
+	if r, ok := records[version]; !ok || !r.dirty {
+		return fmt.Errorf("migration %d is not dirty", version)
+	}
+	if applied {
+		_, err = m.db.Exec(m.dialect.Rebind("UPDATE schema_migrations SET dirty = ? WHERE version = ?"), false, version)
+	} else {
+		_, err = m.db.Exec(m.dialect.Rebind("DELETE FROM schema_migrations WHERE version = ?"), version)
+	}
+	return err
+}
+
+// apply 先以 dirty 記錄 migration 再執行，成功後清除 dirty，
+// 程序在執行中途結束，或 DDL 無法回滾的資料庫執行失敗時，紀錄會保持 dirty，直到以 Resolve 確認 schema 的狀態
+func (m *Migrator) apply(migration Migration) error {
+	_, err := m.db.Exec(m.dialect.Rebind("INSERT INTO schema_migrations (version,name,applied_at,dirty) VALUES (?, ?, ?, ?)"),
+		migration.Version, migration.Name, time.Now().UTC(), true)
+	if err != nil {
+		return err
+	}
+	err = m.run(migration.Up, "UPDATE schema_migrations SET dirty = ? WHERE version = ?", false, migration.Version)
+	if err != nil && m.dialect.TransactionalDDL() {
+		// transaction 已回滾整個 migration，移除紀錄即可重試
+		if _, resetErr := m.db.Exec(m.dialect.Rebind("DELETE FROM schema_migrations WHERE version = ?"), migration.Version); resetErr != nil {
+			return errors.Join(err, resetErr)
+		}
+	}
+	return err
+}
+
+// revert 先將紀錄標記為 dirty 再回滾，成功時一併移除紀錄
+func (m *Migrator) revert(migration Migration) error {
+	_, err := m.db.Exec(m.dialect.Rebind("UPDATE schema_migrations SET dirty = ? WHERE version = ?"), true, migration.Version)
+	if err != nil {
+		return err
+	}
+	err = m.run(migration.Down, "DELETE FROM schema_migrations WHERE version = ?", migration.Version)
+	if err != nil && m.dialect.TransactionalDDL() {
+		if _, resetErr := m.db.Exec(m.dialect.Rebind("UPDATE schema_migrations SET dirty = ? WHERE version = ?"), false, migration.Version); resetErr != nil {
+			return errors.Join(err, resetErr)
+		}
+	}
+	return err
+}
+
+// run 在同一個 transaction 中執行 migration 並更新 schema_migrations，
+// MySQL 的 DDL 會隱含 commit，失敗時只有 DDL 之後的語句會回滾
+func (m *Migrator) run(script string, record string, args ...interface{}) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err = tx.Exec(script); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if _, err = tx.Exec(m.dialect.Rebind(record), args...); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
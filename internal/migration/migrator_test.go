@@ -0,0 +1,313 @@
+package migration
+
+import (
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"tasks/internal/dialect"
+	"tasks/migrations"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	assert.NoError(t, err)
+	// :memory: 每個連線都是獨立的資料庫
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func testSource() fstest.MapFS {
+	return fstest.MapFS{
+		"0001_create_a.up.sql":   {Data: []byte("CREATE TABLE a (id INTEGER);")},
+		"0001_create_a.down.sql": {Data: []byte("DROP TABLE a;")},
+		"0002_create_b.up.sql":   {Data: []byte("CREATE TABLE b (id INTEGER);")},
+		"0002_create_b.down.sql": {Data: []byte("DROP TABLE b;")},
+		"0003_broken.up.sql":     {Data: []byte("CREATE TABLE c (id INTEGER); INSERT INTO missing VALUES (1);")},
+	}
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	var count int
+	err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", name).Scan(&count)
+	assert.NoError(t, err)
+	return count > 0
+}
+
+// nonTransactional 模擬 DDL 會隱含 commit 的資料庫
+type nonTransactional struct {
+	dialect.Dialect
+}
+
+func (nonTransactional) TransactionalDDL() bool { return false }
+
+func TestMigrator_Up(t *testing.T) {
+	t.Run("applies migrations up to target", func(t *testing.T) {
+		db := newTestDB(t)
+		migrator, err := NewMigrator(db, dialect.SQLite, testSource(), zap.NewNop())
+		assert.NoError(t, err)
+
+		applied, err := migrator.Up(2, false)
+
+		assert.NoError(t, err)
+		assert.Len(t, applied, 2)
+		assert.True(t, tableExists(t, db, "b"))
+		statuses, err := migrator.Status()
+		assert.NoError(t, err)
+		assert.True(t, statuses[1].Applied)
+		assert.False(t, statuses[2].Applied)
+	})
+
+	t.Run("failed migration is rolled back", func(t *testing.T) {
+		db := newTestDB(t)
+		migrator, err := NewMigrator(db, dialect.SQLite, testSource(), zap.NewNop())
+		assert.NoError(t, err)
+
+		applied, err := migrator.Up(0, false)
+
+		assert.Error(t, err)
+		assert.Len(t, applied, 2)
+		assert.False(t, tableExists(t, db, "c"))
+		statuses, err := migrator.Status()
+		assert.NoError(t, err)
+		assert.False(t, statuses[2].Applied)
+	})
+
+	t.Run("failed migration stays dirty without transactional DDL", func(t *testing.T) {
+		db := newTestDB(t)
+		migrator, err := NewMigrator(db, nonTransactional{dialect.SQLite}, testSource(), zap.NewNop())
+		assert.NoError(t, err)
+
+		_, err = migrator.Up(0, false)
+
+		assert.Error(t, err)
+		statuses, err := migrator.Status()
+		assert.NoError(t, err)
+		assert.True(t, statuses[2].Dirty)
+		_, err = migrator.Up(0, false)
+		assert.ErrorContains(t, err, "dirty")
+	})
+
+	t.Run("dry run does not change the schema", func(t *testing.T) {
+		db := newTestDB(t)
+		migrator, err := NewMigrator(db, dialect.SQLite, testSource(), zap.NewNop())
+		assert.NoError(t, err)
+
+		pending, err := migrator.Up(0, true)
+
+		assert.NoError(t, err)
+		assert.Len(t, pending, 3)
+		assert.False(t, tableExists(t, db, "a"))
+	})
+}
+
+func TestMigrator_Down(t *testing.T) {
+	t.Run("rolls back the latest migrations", func(t *testing.T) {
+		db := newTestDB(t)
+		migrator, err := NewMigrator(db, dialect.SQLite, testSource(), zap.NewNop())
+		assert.NoError(t, err)
+		_, err = migrator.Up(2, false)
+		assert.NoError(t, err)
+
+		rolledBack, err := migrator.Down(1, false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, rolledBack[0].Version)
+		assert.False(t, tableExists(t, db, "b"))
+		assert.True(t, tableExists(t, db, "a"))
+	})
+
+	t.Run("missing down file", func(t *testing.T) {
+		db := newTestDB(t)
+		source := testSource()
+		source["0003_broken.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE c (id INTEGER);")}
+		migrator, err := NewMigrator(db, dialect.SQLite, source, zap.NewNop())
+		assert.NoError(t, err)
+		_, err = migrator.Up(0, false)
+		assert.NoError(t, err)
+
+		_, err = migrator.Down(1, false)
+
+		assert.Error(t, err)
+		assert.True(t, tableExists(t, db, "c"))
+	})
+}
+
+func TestNewMigrator(t *testing.T) {
+	t.Run("invalid file name", func(t *testing.T) {
+		_, err := NewMigrator(newTestDB(t), dialect.SQLite, fstest.MapFS{"create_a.sql": {Data: []byte("")}}, zap.NewNop())
+		assert.Error(t, err)
+	})
+
+	t.Run("embedded sqlite migrations apply and roll back", func(t *testing.T) {
+		db := newTestDB(t)
+		source, err := migrations.Source("sqlite3")
+		assert.NoError(t, err)
+		migrator, err := NewMigrator(db, dialect.SQLite, source, zap.NewNop())
+		assert.NoError(t, err)
+
+		applied, err := migrator.Up(0, false)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, applied)
+		assert.True(t, tableExists(t, db, "tasks"))
+
+		_, err = migrator.Down(len(applied), false)
+		assert.NoError(t, err)
+		assert.False(t, tableExists(t, db, "tasks"))
+	})
+	t.Run("existing tasks are positioned in created order", func(t *testing.T) {
+		db := newTestDB(t)
+		source, err := migrations.Source("sqlite3")
+		assert.NoError(t, err)
+		migrator, err := NewMigrator(db, dialect.SQLite, source, zap.NewNop())
+		assert.NoError(t, err)
+		_, err = migrator.Up(5, false)
+		assert.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO tasks (id, name, status, version, created_at) VALUES
+			('task-b', 'B', 0, 0, '2024-01-02 00:00:00+00:00'),
+			('task-a', 'A', 0, 0, '2024-01-01 00:00:00+00:00')`)
+		assert.NoError(t, err)
+
+		_, err = migrator.Up(0, false)
+		assert.NoError(t, err)
+
+		rows, err := db.Query("SELECT id, priority, position FROM tasks ORDER BY position")
+		assert.NoError(t, err)
+		defer rows.Close()
+		var positions []string
+		for rows.Next() {
+			var id, position string
+			var priority int
+			assert.NoError(t, rows.Scan(&id, &priority, &position))
+			assert.Equal(t, 1, priority)
+			positions = append(positions, id+"="+position)
+		}
+		assert.Equal(t, []string{"task-a=00000001i", "task-b=00000002i"}, positions)
+	})
+	t.Run("task positions are unique", func(t *testing.T) {
+		db := newTestDB(t)
+		source, err := migrations.Source("sqlite3")
+		assert.NoError(t, err)
+		migrator, err := NewMigrator(db, dialect.SQLite, source, zap.NewNop())
+		assert.NoError(t, err)
+		_, err = migrator.Up(0, false)
+		assert.NoError(t, err)
+
+		_, err = db.Exec(`INSERT INTO tasks (id, name, status, version, created_at, position) VALUES ('task-a', 'A', 0, 0, '2024-01-01 00:00:00+00:00', '00000001i')`)
+		assert.NoError(t, err)
+		_, err = db.Exec(`INSERT INTO tasks (id, name, status, version, created_at, position) VALUES ('task-b', 'B', 0, 0, '2024-01-01 00:00:00+00:00', '00000001i')`)
+		assert.Error(t, err)
+	})
+}
+
+func TestMigrator_Baseline(t *testing.T) {
+	t.Run("records migrations without running them", func(t *testing.T) {
+		db := newTestDB(t)
+		_, err := db.Exec("CREATE TABLE a (id INTEGER)")
+		assert.NoError(t, err)
+		migrator, err := NewMigrator(db, dialect.SQLite, testSource(), zap.NewNop())
+		assert.NoError(t, err)
+		unversioned, err := migrator.Unversioned("a")
+		assert.NoError(t, err)
+		assert.True(t, unversioned)
+
+		baseline, err := migrator.Baseline(1)
+
+		assert.NoError(t, err)
+		assert.Len(t, baseline, 1)
+		unversioned, err = migrator.Unversioned("a")
+		assert.NoError(t, err)
+		assert.False(t, unversioned)
+		applied, err := migrator.Up(2, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, applied[0].Version)
+	})
+
+	t.Run("refuses a database with applied migrations", func(t *testing.T) {
+		db := newTestDB(t)
+		migrator, err := NewMigrator(db, dialect.SQLite, testSource(), zap.NewNop())
+		assert.NoError(t, err)
+		_, err = migrator.Up(1, false)
+		assert.NoError(t, err)
+
+		_, err = migrator.Baseline(2)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("empty database is not unversioned", func(t *testing.T) {
+		migrator, err := NewMigrator(newTestDB(t), dialect.SQLite, testSource(), zap.NewNop())
+		assert.NoError(t, err)
+
+		unversioned, err := migrator.Unversioned("a")
+
+		assert.NoError(t, err)
+		assert.False(t, unversioned)
+	})
+}
+
+func TestMigrator_Resolve(t *testing.T) {
+	newDirtyMigrator := func(t *testing.T) (*Migrator, *sql.DB) {
+		db := newTestDB(t)
+		migrator, err := NewMigrator(db, dialect.SQLite, testSource(), zap.NewNop())
+		assert.NoError(t, err)
+		_, err = migrator.Up(1, false)
+		assert.NoError(t, err)
+		// 模擬程序在 migration 執行中途結束
+		_, err = db.Exec("CREATE TABLE b (id INTEGER)")
+		assert.NoError(t, err)
+		_, err = db.Exec("INSERT INTO schema_migrations (version, name, applied_at, dirty) VALUES (2, 'create_b', '2024-01-01 00:00:00+00:00', 1)")
+		assert.NoError(t, err)
+		return migrator, db
+	}
+
+	t.Run("dirty migration blocks up and down", func(t *testing.T) {
+		migrator, _ := newDirtyMigrator(t)
+
+		_, upErr := migrator.Up(0, false)
+		_, downErr := migrator.Down(1, false)
+
+		assert.ErrorContains(t, upErr, "dirty")
+		assert.ErrorContains(t, downErr, "dirty")
+		statuses, err := migrator.Status()
+		assert.NoError(t, err)
+		assert.True(t, statuses[1].Dirty)
+	})
+
+	t.Run("resolved as applied", func(t *testing.T) {
+		migrator, _ := newDirtyMigrator(t)
+
+		err := migrator.Resolve(2, true)
+
+		assert.NoError(t, err)
+		statuses, err := migrator.Status()
+		assert.NoError(t, err)
+		assert.True(t, statuses[1].Applied)
+		assert.False(t, statuses[1].Dirty)
+	})
+
+	t.Run("resolved as pending", func(t *testing.T) {
+		migrator, db := newDirtyMigrator(t)
+		_, err := db.Exec("DROP TABLE b")
+		assert.NoError(t, err)
+
+		err = migrator.Resolve(2, false)
+
+		assert.NoError(t, err)
+		applied, err := migrator.Up(2, false)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, applied[0].Version)
+	})
+
+	t.Run("clean migration", func(t *testing.T) {
+		migrator, _ := newDirtyMigrator(t)
+
+		err := migrator.Resolve(1, true)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,103 @@
+package position
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// position 是以字典序排序的 base-36 字串，在兩個 key 之間永遠可以再插入新的 key，
+// 移動 task 時只需要改寫被移動的那一筆。
+// key 的前 headWidth 位視為整數，新增在最後面時遞增整數部分，避免 key 隨新增次數變長。
+
+const (
+	digits    = "0123456789abcdefghijklmnopqrstuvwxyz"
+	headWidth = 8
+)
+
+var ErrInvalidRange = errors.New("position: lower must be less than upper")
+
+// After 回傳排在 last 之後的 key，last 為空表示目前沒有任何 key
+func After(last string) (string, error) {
+	if !valid(last) {
+		return "", ErrInvalidRange
+	}
+	head := last
+	if len(head) > headWidth {
+		head = head[:headWidth]
+	}
+	head += strings.Repeat("0", headWidth-len(head))
+	value, err := strconv.ParseUint(head, len(digits), 64)
+	if err != nil {
+		return "", err
+	}
+	for value++; ; value++ {
+		key := strconv.FormatUint(value, len(digits))
+		if len(key) > headWidth {
+			// 整數部分用完時退回取中間值
+			return Between(last, "")
+		}
+		key = strings.Repeat("0", headWidth-len(key)) + key
+		// 結尾為 0 的 key 與其前綴之間沒有空間
+		if !strings.HasSuffix(key, "0") {
+			return key, nil
+		}
+	}
+}
+
+// Between 回傳嚴格介於 lower 與 upper 之間的 key，lower 為空表示沒有下界，upper 為空表示沒有上界
+func Between(lower, upper string) (string, error) {
+	if !valid(lower) || !valid(upper) || (upper != "" && lower >= upper) {
+		return "", ErrInvalidRange
+	}
+	return midpoint(lower, upper), nil
+}
+
+func midpoint(lower, upper string) string {
+	if upper != "" {
+		// 保留共同前綴，lower 較短時視為補 0
+		n := 0
+		for n < len(upper) && digitAt(lower, n) == upper[n] {
+			n++
+		}
+		if n > 0 {
+			return upper[:n] + midpoint(suffix(lower, n), upper[n:])
+		}
+	}
+	low := strings.IndexByte(digits, digitAt(lower, 0))
+	high := len(digits)
+	if upper != "" {
+		high = strings.IndexByte(digits, upper[0])
+	}
+	if high-low > 1 {
+		return string(digits[(low+high)/2])
+	}
+	// 第一位相鄰時，upper 的第一位本身就介於兩者之間
+	if len(upper) > 1 {
+		return upper[:1]
+	}
+	return string(digits[low]) + midpoint(suffix(lower, 1), "")
+}
+
+func digitAt(key string, i int) byte {
+	if i < len(key) {
+		return key[i]
+	}
+	return digits[0]
+}
+
+func suffix(key string, i int) string {
+	if i < len(key) {
+		return key[i:]
+	}
+	return ""
+}
+
+func valid(key string) bool {
+	for i := 0; i < len(key); i++ {
+		if strings.IndexByte(digits, key[i]) < 0 {
+			return false
+		}
+	}
+	return !strings.HasSuffix(key, "0")
+}
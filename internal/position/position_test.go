@@ -0,0 +1,105 @@
+package position
+
+import (
+	"github.com/stretchr/testify/assert"
+	"math/rand"
+	"testing"
+)
+
+func TestAfter(t *testing.T) {
+	t.Run("first key", func(t *testing.T) {
+		key, err := After("")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "00000001", key)
+	})
+
+	t.Run("increments the head", func(t *testing.T) {
+		key, err := After("00000001i")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "00000002", key)
+	})
+
+	t.Run("skips keys ending with 0", func(t *testing.T) {
+		key, err := After("0000000z")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "00000011", key)
+	})
+
+	t.Run("key length stays fixed while appending", func(t *testing.T) {
+		key := ""
+		for i := 0; i < 10000; i++ {
+			next, err := After(key)
+			assert.NoError(t, err)
+			assert.Greater(t, next, key)
+			key = next
+		}
+		assert.Len(t, key, headWidth)
+	})
+
+	t.Run("head exhausted", func(t *testing.T) {
+		key, err := After("zzzzzzzz")
+
+		assert.NoError(t, err)
+		assert.Greater(t, key, "zzzzzzzz")
+	})
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name  string
+		lower string
+		upper string
+		want  string
+	}{
+		{name: "no bounds", lower: "", upper: "", want: "i"},
+		{name: "before first", lower: "", upper: "00000001", want: "00000000i"},
+		{name: "after last", lower: "00000001", upper: "", want: "i"},
+		{name: "wide gap", lower: "00000001", upper: "00000009", want: "00000005"},
+		{name: "adjacent digits", lower: "00000001", upper: "00000002", want: "00000001i"},
+		{name: "upper prefix", lower: "00000001i", upper: "00000002i", want: "00000002"},
+		{name: "shorter lower", lower: "1", upper: "1001", want: "1000i"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Between(tt.lower, tt.upper)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Greater(t, got, tt.lower)
+			if tt.upper != "" {
+				assert.Less(t, got, tt.upper)
+			}
+		})
+	}
+
+	t.Run("invalid range", func(t *testing.T) {
+		for _, bounds := range [][2]string{{"b", "a"}, {"a", "a"}, {"A", ""}, {"", "10"}} {
+			_, err := Between(bounds[0], bounds[1])
+			assert.ErrorIs(t, err, ErrInvalidRange, bounds)
+		}
+	})
+
+	t.Run("repeated inserts keep keys ordered", func(t *testing.T) {
+		random := rand.New(rand.NewSource(1))
+		keys := []string{}
+		for i := 0; i < 2000; i++ {
+			index := random.Intn(len(keys) + 1)
+			lower, upper := "", ""
+			if index > 0 {
+				lower = keys[index-1]
+			}
+			if index < len(keys) {
+				upper = keys[index]
+			}
+			key, err := Between(lower, upper)
+			assert.NoError(t, err)
+			keys = append(keys[:index], append([]string{key}, keys[index:]...)...)
+		}
+		for i := 1; i < len(keys); i++ {
+			assert.Less(t, keys[i-1], keys[i])
+		}
+	})
+}
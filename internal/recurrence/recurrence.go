@@ -0,0 +1,287 @@
+package recurrence
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 支援 RFC 5545 RRULE 的子集合：FREQ 為 DAILY、WEEKLY 或 MONTHLY，
+// 可搭配 INTERVAL、BYDAY（只限 WEEKLY）、BYMONTHDAY（只限 MONTHLY）以及 COUNT 或 UNTIL。
+// 第一次出現的時間（DTSTART）為 task 的 due_at，之後每次出現都保留 DTSTART 的時分秒，週的第一天為星期一。
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// maxPeriods 找下一次出現時最多往後找的週期數，避免 BYMONTHDAY=31 搭配特定 INTERVAL 時永遠找不到
+const maxPeriods = 1000
+
+const untilLayout = "20060102T150405Z"
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+type Rule struct {
+	Freq     Frequency
+	Interval int
+	// ByDay 依星期一到星期日排序
+	ByDay []time.Weekday
+	// ByMonthDay 負數表示由月底往前數，-1 為每月最後一天
+	ByMonthDay []int
+	// Count 包含 DTSTART 在內總共出現的次數，0 表示不限次數
+	Count int
+	Until *time.Time
+}
+
+// Parse 解析 RRULE 字串，可帶 "RRULE:" 前綴，不分大小寫
+func Parse(value string) (Rule, error) {
+	rule := Rule{Interval: 1}
+	value = strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(value)), "RRULE:")
+	if value == "" {
+		return rule, fmt.Errorf("recurrence: rule is empty")
+	}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(value, ";") {
+		name, raw, ok := strings.Cut(part, "=")
+		if !ok || raw == "" {
+			return rule, fmt.Errorf("recurrence: invalid part %q", part)
+		}
+		if seen[name] {
+			return rule, fmt.Errorf("recurrence: %s is repeated", name)
+		}
+		seen[name] = true
+		var err error
+		switch name {
+		case "FREQ":
+			rule.Freq = Frequency(raw)
+			if rule.Freq != Daily && rule.Freq != Weekly && rule.Freq != Monthly {
+				return rule, fmt.Errorf("recurrence: FREQ %s not supported", raw)
+			}
+		case "INTERVAL":
+			rule.Interval, err = positive(name, raw)
+		case "COUNT":
+			rule.Count, err = positive(name, raw)
+		case "UNTIL":
+			rule.Until, err = parseUntil(raw)
+		case "BYDAY":
+			rule.ByDay, err = parseByDay(raw)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseByMonthDay(raw)
+		default:
+			return rule, fmt.Errorf("recurrence: %s not supported", name)
+		}
+		if err != nil {
+			return rule, err
+		}
+	}
+	if rule.Freq == "" {
+		return rule, fmt.Errorf("recurrence: FREQ is required")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return rule, fmt.Errorf("recurrence: COUNT and UNTIL cannot be used together")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != Weekly {
+		return rule, fmt.Errorf("recurrence: BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != Monthly {
+		return rule, fmt.Errorf("recurrence: BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	return rule, nil
+}
+
+func positive(name, raw string) (int, error) {
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 1 {
+		return 0, fmt.Errorf("recurrence: %s must be a positive integer", name)
+	}
+	return value, nil
+}
+
+// parseUntil 只有日期時視為當天結束前都有效
+func parseUntil(raw string) (*time.Time, error) {
+	until, err := time.Parse(untilLayout, raw)
+	if err != nil {
+		date, dateErr := time.Parse("20060102", raw)
+		if dateErr != nil {
+			return nil, fmt.Errorf("recurrence: UNTIL must look like 20240131 or 20240131T235959Z")
+		}
+		until = date.Add(24*time.Hour - time.Second)
+	}
+	return &until, nil
+}
+
+func parseByDay(raw string) ([]time.Weekday, error) {
+	set := make(map[time.Weekday]bool)
+	for _, name := range strings.Split(raw, ",") {
+		day, ok := weekdays[name]
+		if !ok {
+			return nil, fmt.Errorf("recurrence: BYDAY %s not supported", name)
+		}
+		set[day] = true
+	}
+	days := make([]time.Weekday, 0, len(set))
+	for day := range set {
+		days = append(days, day)
+	}
+	sort.Slice(days, func(i, j int) bool { return weekOffset(days[i]) < weekOffset(days[j]) })
+	return days, nil
+}
+
+func parseByMonthDay(raw string) ([]int, error) {
+	set := make(map[int]bool)
+	for _, value := range strings.Split(raw, ",") {
+		day, err := strconv.Atoi(value)
+		if err != nil || day == 0 || day < -31 || day > 31 {
+			return nil, fmt.Errorf("recurrence: BYMONTHDAY %s must be between 1 and 31 or -31 and -1", value)
+		}
+		set[day] = true
+	}
+	days := make([]int, 0, len(set))
+	for day := range set {
+		days = append(days, day)
+	}
+	sort.Ints(days)
+	return days, nil
+}
+
+// String 以固定的順序輸出 RRULE，不含 "RRULE:" 前綴
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		names := make([]string, 0, len(r.ByDay))
+		for _, day := range r.ByDay {
+			names = append(names, strings.ToUpper(day.String()[:2]))
+		}
+		parts = append(parts, "BYDAY="+strings.Join(names, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, 0, len(r.ByMonthDay))
+		for _, day := range r.ByMonthDay {
+			days = append(days, strconv.Itoa(day))
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(untilLayout))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Occurrences 由 start 開始最多 n 次出現的時間，start 本身是第一次，
+// 超過 COUNT 或 UNTIL 時提早結束
+func (r Rule) Occurrences(start time.Time, n int) []time.Time {
+	start = start.UTC()
+	limit := n
+	if r.Count > 0 && r.Count < limit {
+		limit = r.Count
+	}
+	result := make([]time.Time, 0, limit)
+	add := func(occurrence time.Time) bool {
+		if r.Until != nil && occurrence.After(*r.Until) {
+			return false
+		}
+		result = append(result, occurrence)
+		return len(result) < limit
+	}
+	if limit <= 0 || !add(start) {
+		return result
+	}
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	for period := 0; period < maxPeriods; period++ {
+		for _, candidate := range r.candidates(start, period*interval) {
+			if !candidate.After(start) {
+				continue
+			}
+			if !add(candidate) {
+				return result
+			}
+		}
+	}
+	return result
+}
+
+// Next start 之後的下一次出現時間，以及剩下的規則，COUNT 會扣掉 start 這一次，沒有下一次時 ok 為 false
+func (r Rule) Next(start time.Time) (next time.Time, rest Rule, ok bool) {
+	occurrences := r.Occurrences(start, 2)
+	if len(occurrences) < 2 {
+		return time.Time{}, r, false
+	}
+	rest = r
+	if rest.Count > 0 {
+		rest.Count--
+	}
+	return occurrences[1], rest, true
+}
+
+// candidates start 之後第 offset 個週期內依序可能出現的時間
+func (r Rule) candidates(start time.Time, offset int) []time.Time {
+	clock := start.Sub(truncateDay(start))
+	switch r.Freq {
+	case Weekly:
+		monday := truncateDay(start).AddDate(0, 0, -weekOffset(start.Weekday())+7*offset)
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{start.Weekday()}
+		}
+		result := make([]time.Time, 0, len(days))
+		for _, day := range days {
+			result = append(result, monday.AddDate(0, 0, weekOffset(day)).Add(clock))
+		}
+		return result
+	case Monthly:
+		first := time.Date(start.Year(), start.Month()+time.Month(offset), 1, 0, 0, 0, 0, time.UTC)
+		last := first.AddDate(0, 1, -1).Day()
+		days := r.ByMonthDay
+		if len(days) == 0 {
+			days = []int{start.Day()}
+		}
+		seen := make(map[int]bool, len(days))
+		resolved := make([]int, 0, len(days))
+		for _, day := range days {
+			if day < 0 {
+				day = last + day + 1
+			}
+			// 該月沒有這一天時略過，例如二月的 30 號
+			if day < 1 || day > last || seen[day] {
+				continue
+			}
+			seen[day] = true
+			resolved = append(resolved, day)
+		}
+		sort.Ints(resolved)
+		result := make([]time.Time, 0, len(resolved))
+		for _, day := range resolved {
+			result = append(result, first.AddDate(0, 0, day-1).Add(clock))
+		}
+		return result
+	default:
+		return []time.Time{truncateDay(start).AddDate(0, 0, offset).Add(clock)}
+	}
+}
+
+func truncateDay(value time.Time) time.Time {
+	return time.Date(value.Year(), value.Month(), value.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// weekOffset 以星期一為一週的第一天
+func weekOffset(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
@@ -0,0 +1,161 @@
+package recurrence
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func dates(values ...string) []time.Time {
+	result := make([]time.Time, 0, len(values))
+	for _, value := range values {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			panic(err)
+		}
+		result = append(result, parsed)
+	}
+	return result
+}
+
+func TestParse(t *testing.T) {
+	t.Run("weekly rule with prefix and lower case", func(t *testing.T) {
+		rule, err := Parse("rrule:freq=weekly;byday=fr,mo,mo;interval=2;count=4")
+
+		assert.NoError(t, err)
+		assert.Equal(t, Weekly, rule.Freq)
+		assert.Equal(t, 2, rule.Interval)
+		assert.Equal(t, []time.Weekday{time.Monday, time.Friday}, rule.ByDay)
+		assert.Equal(t, 4, rule.Count)
+		assert.Equal(t, "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR;COUNT=4", rule.String())
+	})
+
+	t.Run("until date covers the whole day", func(t *testing.T) {
+		rule, err := Parse("FREQ=DAILY;UNTIL=20240131")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "FREQ=DAILY;UNTIL=20240131T235959Z", rule.String())
+	})
+
+	t.Run("monthly rule from the end of the month", func(t *testing.T) {
+		rule, err := Parse("FREQ=MONTHLY;BYMONTHDAY=-1,15")
+
+		assert.NoError(t, err)
+		assert.Equal(t, []int{-1, 15}, rule.ByMonthDay)
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		for _, value := range []string{
+			"",
+			"FREQ=YEARLY",
+			"INTERVAL=2",
+			"FREQ=DAILY;INTERVAL=0",
+			"FREQ=DAILY;COUNT=2;UNTIL=20240101",
+			"FREQ=DAILY;BYDAY=MO",
+			"FREQ=WEEKLY;BYDAY=1MO",
+			"FREQ=WEEKLY;BYMONTHDAY=1",
+			"FREQ=MONTHLY;BYMONTHDAY=32",
+			"FREQ=DAILY;BYHOUR=9",
+			"FREQ=DAILY;FREQ=WEEKLY",
+			"FREQ=DAILY;UNTIL=tomorrow",
+		} {
+			_, err := Parse(value)
+			assert.Error(t, err, value)
+		}
+	})
+}
+
+func TestRule_Occurrences(t *testing.T) {
+	t.Run("daily with interval", func(t *testing.T) {
+		rule, _ := Parse("FREQ=DAILY;INTERVAL=3")
+
+		occurrences := rule.Occurrences(dates("2024-01-30T09:00:00Z")[0], 3)
+
+		assert.Equal(t, dates("2024-01-30T09:00:00Z", "2024-02-02T09:00:00Z", "2024-02-05T09:00:00Z"), occurrences)
+	})
+
+	t.Run("weekly by day keeps the time of day", func(t *testing.T) {
+		rule, _ := Parse("FREQ=WEEKLY;BYDAY=MO,WE,FR")
+
+		// 2024-01-03 是星期三
+		occurrences := rule.Occurrences(dates("2024-01-03T08:30:00Z")[0], 5)
+
+		assert.Equal(t, dates("2024-01-03T08:30:00Z", "2024-01-05T08:30:00Z", "2024-01-08T08:30:00Z", "2024-01-10T08:30:00Z", "2024-01-12T08:30:00Z"), occurrences)
+	})
+
+	t.Run("every other week skips the weeks in between", func(t *testing.T) {
+		rule, _ := Parse("FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,SU")
+
+		occurrences := rule.Occurrences(dates("2024-01-02T10:00:00Z")[0], 4)
+
+		assert.Equal(t, dates("2024-01-02T10:00:00Z", "2024-01-07T10:00:00Z", "2024-01-16T10:00:00Z", "2024-01-21T10:00:00Z"), occurrences)
+	})
+
+	t.Run("start outside of by day still counts as the first occurrence", func(t *testing.T) {
+		rule, _ := Parse("FREQ=WEEKLY;BYDAY=MO;COUNT=2")
+
+		occurrences := rule.Occurrences(dates("2024-01-03T00:00:00Z")[0], 10)
+
+		assert.Equal(t, dates("2024-01-03T00:00:00Z", "2024-01-08T00:00:00Z"), occurrences)
+	})
+
+	t.Run("monthly skips months without the day", func(t *testing.T) {
+		rule, _ := Parse("FREQ=MONTHLY;BYMONTHDAY=30")
+
+		occurrences := rule.Occurrences(dates("2024-01-30T00:00:00Z")[0], 3)
+
+		assert.Equal(t, dates("2024-01-30T00:00:00Z", "2024-03-30T00:00:00Z", "2024-04-30T00:00:00Z"), occurrences)
+	})
+
+	t.Run("monthly on the last day", func(t *testing.T) {
+		rule, _ := Parse("FREQ=MONTHLY;BYMONTHDAY=-1")
+
+		occurrences := rule.Occurrences(dates("2024-01-31T00:00:00Z")[0], 3)
+
+		assert.Equal(t, dates("2024-01-31T00:00:00Z", "2024-02-29T00:00:00Z", "2024-03-31T00:00:00Z"), occurrences)
+	})
+
+	t.Run("until stops the series", func(t *testing.T) {
+		rule, _ := Parse("FREQ=DAILY;UNTIL=20240102")
+
+		occurrences := rule.Occurrences(dates("2024-01-01T12:00:00Z")[0], 10)
+
+		assert.Equal(t, dates("2024-01-01T12:00:00Z", "2024-01-02T12:00:00Z"), occurrences)
+	})
+
+	t.Run("day that never occurs ends the search", func(t *testing.T) {
+		rule, _ := Parse("FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=31")
+
+		occurrences := rule.Occurrences(dates("2024-02-01T00:00:00Z")[0], 3)
+
+		assert.Equal(t, dates("2024-02-01T00:00:00Z"), occurrences)
+	})
+}
+
+func TestRule_Next(t *testing.T) {
+	t.Run("count is reduced", func(t *testing.T) {
+		rule, _ := Parse("FREQ=WEEKLY;COUNT=3")
+
+		next, rest, ok := rule.Next(dates("2024-01-01T09:00:00Z")[0])
+
+		assert.True(t, ok)
+		assert.Equal(t, dates("2024-01-08T09:00:00Z")[0], next)
+		assert.Equal(t, "FREQ=WEEKLY;COUNT=2", rest.String())
+	})
+
+	t.Run("last occurrence", func(t *testing.T) {
+		rule, _ := Parse("FREQ=DAILY;COUNT=1")
+
+		_, _, ok := rule.Next(dates("2024-01-01T09:00:00Z")[0])
+
+		assert.False(t, ok)
+	})
+
+	t.Run("next occurrence after until", func(t *testing.T) {
+		rule, _ := Parse("FREQ=MONTHLY;UNTIL=20240215T000000Z")
+
+		_, _, ok := rule.Next(dates("2024-01-20T09:00:00Z")[0])
+
+		assert.False(t, ok)
+	})
+}
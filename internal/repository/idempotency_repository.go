@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go.uber.org/zap"
+	"tasks/domain/models"
+	"tasks/internal/dialect"
+	"time"
+)
+
+type idempotencyRepository struct {
+	conn     dbConn
+	dialect  dialect.Dialect
+	timeouts Timeouts
+	logger   *zap.Logger
+}
+
+func NewIdempotencyRepository(conn *sql.DB, dialect dialect.Dialect, timeouts Timeouts, logger *zap.Logger) IdempotencyRepository {
+	return &idempotencyRepository{
+		conn:     reboundConn{conn: conn, dialect: dialect},
+		dialect:  dialect,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+func (r *idempotencyRepository) Reserve(ctx context.Context, key, requestHash, leaseToken string, now, leaseExpiresAt, expiresAt time.Time) (reserved bool, err error) {
+	ctx, done := r.timeouts.apply(ctx, "reserve_idempotency_key")
+	defer done(&err)
+	// 已過期的 key 視為不存在，直接覆寫，MySQL 依序更新欄位，條件用到的 expires_at 需放在最後
+	query := r.dialect.Upsert("idempotency_keys",
+		[]string{"idempotency_key", "request_hash", "status_code", "response", "created_at", "lease_token", "lease_expires_at", "expires_at"},
+		"idempotency_key",
+		[]string{"request_hash", "status_code", "response", "created_at", "lease_token", "lease_expires_at", "expires_at"},
+		func(existing, incoming func(string) string) string {
+			return existing("expires_at") + " < " + incoming("created_at")
+		})
+	stmt, err := r.conn.PrepareContext(ctx, query)
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.ExecContext(ctx, key, requestHash, 0, nil, now, leaseToken, leaseExpiresAt, expiresAt)
+	if err != nil {
+		r.logger.Error("Execute reserve idempotency key stmt error", zap.String("key", key), zap.Error(err))
+		return false, err
+	}
+	effectRows, err := rows.RowsAffected()
+	if err != nil {
+		r.logger.Error("Execute reserve idempotency key stmt error", zap.String("key", key), zap.Error(err))
+		return false, err
+	}
+	return effectRows > 0, nil
+}
+
+func (r *idempotencyRepository) TakeOver(ctx context.Context, key, requestHash, leaseToken string, now, leaseExpiresAt time.Time) (taken bool, err error) {
+	ctx, done := r.timeouts.apply(ctx, "take_over_idempotency_key")
+	defer done(&err)
+	stmt, err := r.conn.PrepareContext(ctx, "UPDATE idempotency_keys SET lease_token = ?, lease_expires_at = ?"+
+		" WHERE idempotency_key = ? AND request_hash = ? AND response IS NULL AND (lease_expires_at IS NULL OR lease_expires_at < ?)")
+	if err != nil {
+		return false, err
+	}
+	defer stmt.Close()
+	rows, err := stmt.ExecContext(ctx, leaseToken, leaseExpiresAt, key, requestHash, now)
+	if err != nil {
+		r.logger.Error("Execute take over idempotency key stmt error", zap.String("key", key), zap.Error(err))
+		return false, err
+	}
+	effectRows, err := rows.RowsAffected()
+	if err != nil {
+		r.logger.Error("Execute take over idempotency key stmt error", zap.String("key", key), zap.Error(err))
+		return false, err
+	}
+	return effectRows > 0, nil
+}
+
+func (r *idempotencyRepository) Find(ctx context.Context, key string) (record *models.IdempotencyKey, err error) {
+	ctx, done := r.timeouts.apply(ctx, "find_idempotency_key")
+	defer done(&err)
+	record = &models.IdempotencyKey{}
+	row := r.conn.QueryRowContext(ctx, "SELECT idempotency_key,request_hash,status_code,response,created_at,expires_at FROM idempotency_keys WHERE idempotency_key = ?", key)
+	err = row.Scan(&record.Key, &record.RequestHash, &record.StatusCode, &record.Response, &record.CreatedAt, &record.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		r.logger.Error("Find idempotency key error", zap.String("key", key), zap.Error(err))
+		return nil, err
+	}
+	return record, nil
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, key, leaseToken string) (err error) {
+	ctx, done := r.timeouts.apply(ctx, "release_idempotency_key")
+	defer done(&err)
+	stmt, err := r.conn.PrepareContext(ctx, "DELETE FROM idempotency_keys WHERE idempotency_key = ? AND lease_token = ? AND response IS NULL")
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	if _, err = stmt.ExecContext(ctx, key, leaseToken); err != nil {
+		r.logger.Error("Execute release idempotency key stmt error", zap.String("key", key), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *idempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (deleted int64, err error) {
+	ctx, done := r.timeouts.apply(ctx, "delete_expired_idempotency_keys")
+	defer done(&err)
+	rows, err := r.conn.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at < ?", before)
+	if err != nil {
+		r.logger.Error("Execute delete expired idempotency keys error", zap.Error(err))
+		return 0, err
+	}
+	return rows.RowsAffected()
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"tasks/internal/dialect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_idempotencyRepository_Reserve(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewIdempotencyRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	leaseExpiresAt := now.Add(30 * time.Second)
+	expiresAt := now.Add(24 * time.Hour)
+
+	t.Run("reserve new key", func(t *testing.T) {
+		mock.ExpectPrepare("INSERT INTO idempotency_keys").
+			ExpectExec().
+			WithArgs("key-1", "hash-1", 0, nil, now, "token-1", leaseExpiresAt, expiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		reserved, err := repo.Reserve(ctx, "key-1", "hash-1", "token-1", now, leaseExpiresAt, expiresAt)
+		assert.NoError(t, err)
+		assert.True(t, reserved)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("key already reserved", func(t *testing.T) {
+		mock.ExpectPrepare("INSERT INTO idempotency_keys").
+			ExpectExec().
+			WithArgs("key-1", "hash-1", 0, nil, now, "token-1", leaseExpiresAt, expiresAt).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		reserved, err := repo.Reserve(ctx, "key-1", "hash-1", "token-1", now, leaseExpiresAt, expiresAt)
+		assert.NoError(t, err)
+		assert.False(t, reserved)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_idempotencyRepository_TakeOver(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewIdempotencyRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	leaseExpiresAt := now.Add(30 * time.Second)
+	query := regexp.QuoteMeta("UPDATE idempotency_keys SET lease_token = ?, lease_expires_at = ?" +
+		" WHERE idempotency_key = ? AND request_hash = ? AND response IS NULL AND (lease_expires_at IS NULL OR lease_expires_at < ?)")
+
+	t.Run("take over an expired lease", func(t *testing.T) {
+		mock.ExpectPrepare(query).
+			ExpectExec().
+			WithArgs("token-2", leaseExpiresAt, "key-1", "hash-1", now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		taken, err := repo.TakeOver(ctx, "key-1", "hash-1", "token-2", now, leaseExpiresAt)
+		assert.NoError(t, err)
+		assert.True(t, taken)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("lease still held", func(t *testing.T) {
+		mock.ExpectPrepare(query).
+			ExpectExec().
+			WithArgs("token-2", leaseExpiresAt, "key-1", "hash-1", now).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		taken, err := repo.TakeOver(ctx, "key-1", "hash-1", "token-2", now, leaseExpiresAt)
+		assert.NoError(t, err)
+		assert.False(t, taken)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_idempotencyRepository_Find(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewIdempotencyRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	query := regexp.QuoteMeta("SELECT idempotency_key,request_hash,status_code,response,created_at,expires_at FROM idempotency_keys WHERE idempotency_key = ?")
+
+	t.Run("find completed key", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"idempotency_key", "request_hash", "status_code", "response", "created_at", "expires_at"}).
+			AddRow("key-1", "hash-1", 201, `{"id":"task-1"}`, "2024-01-01 00:00:00+00:00", "2024-01-02 00:00:00+00:00")
+		mock.ExpectQuery(query).WithArgs("key-1").WillReturnRows(rows)
+
+		record, err := repo.Find(ctx, "key-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "hash-1", record.RequestHash)
+		assert.Equal(t, 201, record.StatusCode)
+		assert.Equal(t, `{"id":"task-1"}`, *record.Response)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("key not found", func(t *testing.T) {
+		mock.ExpectQuery(query).WithArgs("key-2").WillReturnError(sql.ErrNoRows)
+
+		record, err := repo.Find(ctx, "key-2")
+		assert.NoError(t, err)
+		assert.Nil(t, record)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_idempotencyRepository_ReleaseAndDeleteExpired(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewIdempotencyRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+
+	t.Run("release only removes pending keys holding the lease", func(t *testing.T) {
+		mock.ExpectPrepare(regexp.QuoteMeta("DELETE FROM idempotency_keys WHERE idempotency_key = ? AND lease_token = ? AND response IS NULL")).
+			ExpectExec().
+			WithArgs("key-1", "token-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.Release(ctx, "key-1", "token-1"))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete expired keys", func(t *testing.T) {
+		before := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM idempotency_keys WHERE expires_at < ?")).
+			WithArgs(before).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+
+		deleted, err := repo.DeleteExpired(ctx, before)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), deleted)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -1,14 +1,107 @@
 package repository
 
 import (
+	"context"
+	"tasks/constants"
 	"tasks/domain/entities"
 	"tasks/domain/models"
+	"tasks/internal/search"
+	"time"
 )
 
 type TaskRepository interface {
-	Find(id string) (*models.Task, error)
-	List(param entities.TaskQueryParam) ([]*models.Task, error)
-	Create(task entities.Task) error
-	Update(task entities.Task) error
-	Delete(id string) error
+	Find(ctx context.Context, id string) (*models.Task, error)
+	List(ctx context.Context, param entities.TaskQueryParam) ([]*models.Task, error)
+	Count(ctx context.Context, param entities.TaskQueryParam) (int, error)
+	Create(ctx context.Context, task entities.Task) error
+	Update(ctx context.Context, task entities.Task, expectedVersion *int) error
+	Patch(ctx context.Context, patch entities.TaskPatch, version int) error
+	Delete(ctx context.Context, id string, expectedVersion *int, cascade bool) error
+	Restore(ctx context.Context, id string) error
+	Purge(ctx context.Context, id string) error
+	PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error)
+	// ListSubtree 第一筆為 task 本身，其後依深度排列
+	ListSubtree(ctx context.Context, id string, depth int) ([]*models.TaskNode, error)
+	ListAncestors(ctx context.Context, id string) ([]string, error)
+	CountChildren(ctx context.Context, id string) (total int, completed int, err error)
+	LastPosition(ctx context.Context) (string, error)
+	AdjacentPosition(ctx context.Context, id string, position string, after bool) (string, error)
+	ListDueReminders(ctx context.Context, now time.Time, limit int) ([]*models.Task, error)
+	ClaimReminder(ctx context.Context, id string, now time.Time) (bool, error)
+	ReleaseReminder(ctx context.Context, id string) error
+	// ListTags 一次取得多個 task 的 tag，以 task id 分組
+	ListTags(ctx context.Context, taskIDs []string) (map[string][]*models.Tag, error)
+	AttachTags(ctx context.Context, taskID string, tagIDs []string) error
+	DetachTags(ctx context.Context, taskID string, tagIDs []string) error
+	// ListBlockers 一次取得多個 task 直接的 blocker，以 task id 分組
+	ListBlockers(ctx context.Context, taskIDs []string) (map[string][]string, error)
+	ListAllBlockers(ctx context.Context, taskIDs []string) (map[string]bool, error)
+	// LockDependencies 需在檢查 dependency 是否形成環之前呼叫，直到 transaction 結束
+	LockDependencies(ctx context.Context) error
+	AddBlockers(ctx context.Context, taskID string, blockerIDs []string) error
+	RemoveBlockers(ctx context.Context, taskID string, blockerIDs []string) error
+	FindWorkflow(ctx context.Context) (*models.Workflow, error)
+	// ListAudit 依 id 由新到舊排列，寫入 task 時會在同一個 transaction 中記錄 audit log
+	ListAudit(ctx context.Context, param entities.AuditQueryParam) ([]*models.AuditEntry, error)
+	// ListAuditChain 依 id 由舊到新排列，用來驗證 hash chain
+	ListAuditChain(ctx context.Context, afterID int64, size int) ([]*models.AuditEntry, error)
+	AuditHead(ctx context.Context) (*models.AuditHead, error)
+	SealAudit(ctx context.Context) (int64, error)
+	// CompleteIdempotencyKey 在目前的 transaction 中保存回應，lease 已被接手時回傳 IdempotencyKeyInProgress
+	CompleteIdempotencyKey(ctx context.Context, lease entities.IdempotencyLease, response entities.IdempotentResponse) error
+	WithTx(ctx context.Context, fn func(repo TaskRepository) error) error
+}
+
+// TaskEventRepository event sourcing 模式的 TaskRepository，寫入 task 時同時記錄事件，tasks 表為事件的 projection
+type TaskEventRepository interface {
+	TaskRepository
+	// ListEvents 依 seq 由舊到新排列
+	ListEvents(ctx context.Context, id string, afterSeq int64, size int) ([]*models.TaskEvent, error)
+	// FindAsOf 由 snapshot 與事件還原 task 在過去某個 version 或時間點的狀態
+	FindAsOf(ctx context.Context, id string, asOf entities.AsOf) (*models.Task, error)
+	RebuildProjection(ctx context.Context, dryRun bool) (*entities.ProjectionRebuild, error)
+	// BackfillEvents 為沒有任何事件的 task 補記 TaskCreated，dryRun 時只回傳會補記的筆數
+	BackfillEvents(ctx context.Context, dryRun bool) (int, error)
+}
+
+type TagRepository interface {
+	Find(ctx context.Context, id string) (*models.Tag, error)
+	List(ctx context.Context) ([]*models.Tag, error)
+	Create(ctx context.Context, tag entities.Tag) error
+	Update(ctx context.Context, tag entities.Tag) error
+	Delete(ctx context.Context, id string) error
+}
+
+type WebhookRepository interface {
+	Find(ctx context.Context, id string) (*models.Webhook, error)
+	List(ctx context.Context) ([]*models.Webhook, error)
+	Create(ctx context.Context, webhook entities.Webhook) error
+	Update(ctx context.Context, webhook entities.Webhook) error
+	Delete(ctx context.Context, id string) error
+	// ListDeliveries 依 id 由新到舊排列，寫入 task 時會在同一個 transaction 中寫入 outbox
+	ListDeliveries(ctx context.Context, param entities.DeliveryQueryParam) ([]*models.WebhookDelivery, error)
+	FindDelivery(ctx context.Context, webhookID string, id int64) (*models.WebhookDelivery, error)
+	ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*models.DueDelivery, error)
+	ClaimDelivery(ctx context.Context, id int64, now, until time.Time) (bool, error)
+	CompleteDelivery(ctx context.Context, id int64, statusCode int, now time.Time) error
+	FailDelivery(ctx context.Context, id int64, status constants.DeliveryStatus, statusCode int, lastError string, nextAttemptAt time.Time) error
+	Redeliver(ctx context.Context, webhookID string, id int64, now time.Time) error
+}
+
+type SearchRepository interface {
+	// Prepare 啟動時呼叫一次，決定使用 FTS5 或 LIKE 搜尋
+	Prepare(ctx context.Context) error
+	Search(ctx context.Context, query search.Query, limit, offset int) ([]*models.SearchResult, error)
+}
+
+type IdempotencyRepository interface {
+	// Reserve 保留 key 並取得 lease，key 已存在且未過期時回傳 false
+	Reserve(ctx context.Context, key, requestHash, leaseToken string, now, leaseExpiresAt, expiresAt time.Time) (bool, error)
+	// TakeOver 接手 lease 已過期且尚未完成的 key，request hash 不同時不接手
+	TakeOver(ctx context.Context, key, requestHash, leaseToken string, now, leaseExpiresAt time.Time) (bool, error)
+	// Find 查無 key 時回傳 nil
+	Find(ctx context.Context, key string) (*models.IdempotencyKey, error)
+	// Release 刪除持有 lease 且尚未完成的 key
+	Release(ctx context.Context, key, leaseToken string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
 }
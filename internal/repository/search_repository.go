@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"go.uber.org/zap"
+	"strings"
+	"tasks/domain/models"
+	"tasks/internal/dialect"
+	"tasks/internal/search"
+)
+
+// 以 <mark> 標示 snippet 中符合的文字，其餘文字會先做 HTML escape
+const (
+	markOpen  = "<mark>"
+	markClose = "</mark>"
+)
+
+// ftsTriggers 讓 tasks_fts 與 tasks 的 name 保持同步，tasks_fts 以 tasks 的 rowid 對應
+var ftsTriggers = []string{
+	"CREATE TRIGGER IF NOT EXISTS tasks_fts_insert AFTER INSERT ON tasks BEGIN" +
+		" INSERT INTO tasks_fts (rowid, name) VALUES (new.rowid, new.name); END",
+	"CREATE TRIGGER IF NOT EXISTS tasks_fts_delete AFTER DELETE ON tasks BEGIN" +
+		" INSERT INTO tasks_fts (tasks_fts, rowid, name) VALUES ('delete', old.rowid, old.name); END",
+	"CREATE TRIGGER IF NOT EXISTS tasks_fts_update AFTER UPDATE OF name ON tasks BEGIN" +
+		" INSERT INTO tasks_fts (tasks_fts, rowid, name) VALUES ('delete', old.rowid, old.name);" +
+		" INSERT INTO tasks_fts (rowid, name) VALUES (new.rowid, new.name); END",
+}
+
+type searchRepository struct {
+	db       *sql.DB
+	conn     dbConn
+	dialect  dialect.Dialect
+	timeouts Timeouts
+	logger   *zap.Logger
+	// fts 為 true 時使用 FTS5，否則以 LIKE 比對 name
+	fts bool
+}
+
+func NewSearchRepository(conn *sql.DB, dialect dialect.Dialect, timeouts Timeouts, logger *zap.Logger) SearchRepository {
+	return &searchRepository{
+		db:       conn,
+		conn:     reboundConn{conn: conn, dialect: dialect},
+		dialect:  dialect,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+// Prepare sqlite 支援 FTS5 時建立 tasks_fts 與同步用的 trigger，已存在時略過，索引與 tasks 不一致時重建，
+// 例如 VACUUM 改變了 rowid，不支援時移除 trigger 並改用 LIKE
+func (s *searchRepository) Prepare(ctx context.Context) error {
+	s.fts = false
+	enabled, rebuild, err := s.createIndex(ctx)
+	if err != nil || !enabled {
+		return err
+	}
+	if rebuild {
+		// 重建的時間與 task 數成正比，不套用 prepare_search 的 timeout
+		s.logger.Info("Rebuild fts index")
+		if _, err = s.conn.ExecContext(ctx, "INSERT INTO tasks_fts (tasks_fts) VALUES ('rebuild')"); err != nil {
+			s.logger.Error("Rebuild fts index error", zap.Error(err))
+			return err
+		}
+	}
+	s.fts = true
+	return nil
+}
+
+// createIndex enabled 為 false 時改用 LIKE，rebuild 為 true 時索引需要重建
+func (s *searchRepository) createIndex(ctx context.Context) (enabled, rebuild bool, err error) {
+	ctx, done := s.timeouts.apply(ctx, "prepare_search")
+	defer done(&err)
+	if s.dialect != dialect.SQLite {
+		s.logger.Info("Full-text search is not supported by the dialect, using LIKE", zap.String("dialect", s.dialect.Name()))
+		return false, false, nil
+	}
+	var supported bool
+	if err = s.conn.QueryRowContext(ctx, "SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&supported); err != nil {
+		s.logger.Error("Check fts5 error", zap.Error(err))
+		return false, false, err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		s.logger.Error("Begin tx error", zap.Error(err))
+		return false, false, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				s.logger.Error("Rollback tx error", zap.Error(rollbackErr))
+			}
+		}
+	}()
+	if !supported {
+		// 沒有 FTS5 時 trigger 寫入 tasks_fts 會失敗
+		for _, name := range []string{"tasks_fts_insert", "tasks_fts_delete", "tasks_fts_update"} {
+			if _, err = tx.ExecContext(ctx, "DROP TRIGGER IF EXISTS "+name); err != nil {
+				s.logger.Error("Drop fts trigger error", zap.String("trigger", name), zap.Error(err))
+				return false, false, err
+			}
+		}
+		if err = tx.Commit(); err != nil {
+			s.logger.Error("Commit tx error", zap.Error(err))
+			return false, false, err
+		}
+		s.logger.Warn("SQLite is built without FTS5, using LIKE for search, build with -tags sqlite_fts5 to enable it")
+		return false, false, nil
+	}
+	statements := append([]string{"CREATE VIRTUAL TABLE IF NOT EXISTS tasks_fts USING fts5 (name, content='tasks', content_rowid='rowid')"}, ftsTriggers...)
+	for _, statement := range statements {
+		if _, err = tx.ExecContext(ctx, statement); err != nil {
+			s.logger.Error("Create fts index error", zap.String("statement", statement), zap.Error(err))
+			return false, false, err
+		}
+	}
+	if _, checkErr := tx.ExecContext(ctx, "INSERT INTO tasks_fts (tasks_fts, rank) VALUES ('integrity-check', 1)"); checkErr != nil {
+		s.logger.Info("Fts index is out of sync", zap.NamedError("reason", checkErr))
+		rebuild = true
+	}
+	if err = tx.Commit(); err != nil {
+		s.logger.Error("Commit tx error", zap.Error(err))
+		return false, false, err
+	}
+	return true, rebuild, nil
+}
+
+// Search 不含垃圾桶中的 task，FTS5 依 bm25 排序，LIKE 依 position 排序
+func (s *searchRepository) Search(ctx context.Context, query search.Query, limit, offset int) (result []*models.SearchResult, err error) {
+	ctx, done := s.timeouts.apply(ctx, "search_tasks")
+	defer done(&err)
+	var rows *sql.Rows
+	if s.fts {
+		rows, err = s.conn.QueryContext(ctx, "SELECT "+taskColumns+", m.snippet, m.score FROM tasks JOIN"+
+			" (SELECT rowid, snippet(tasks_fts, 0, '"+search.SnippetOpen+"', '"+search.SnippetClose+"', '...', 16) AS snippet, bm25(tasks_fts) AS score"+
+			" FROM tasks_fts WHERE tasks_fts MATCH ?) m ON m.rowid = tasks.rowid"+
+			" WHERE tasks.deleted_at IS NULL ORDER BY m.score, tasks.position, tasks.id LIMIT ? OFFSET ?", query.Match(), limit, offset)
+	} else {
+		conditions := []string{"deleted_at IS NULL"}
+		args := make([]interface{}, 0, len(query)+2)
+		for _, term := range query {
+			conditions = append(conditions, s.dialect.Contains("name"))
+			args = append(args, "%"+escapeLike(term.Text)+"%")
+		}
+		rows, err = s.conn.QueryContext(ctx, "SELECT "+taskColumns+" FROM tasks WHERE "+strings.Join(conditions, " AND ")+
+			" ORDER BY position, id LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	}
+	if err != nil {
+		s.logger.Error("Search tasks error", zap.String("query", query.Match()), zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			task  *models.Task
+			match models.SearchResult
+		)
+		if s.fts {
+			task, err = scanTask(rows, &match.Snippet, &match.Rank)
+		} else {
+			task, err = scanTask(rows)
+		}
+		if err != nil {
+			s.logger.Error("Scan search result error", zap.Error(err))
+			return nil, err
+		}
+		match.Task = *task
+		if s.fts {
+			match.Snippet = search.MarkSnippet(match.Snippet, markOpen, markClose)
+		} else {
+			match.Snippet = query.Highlight(task.Name, markOpen, markClose)
+		}
+		result = append(result, &match)
+	}
+	if err = rows.Err(); err != nil {
+		s.logger.Error("Iterate search rows error", zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
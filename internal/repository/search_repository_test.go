@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"tasks/internal/dialect"
+	"tasks/internal/search"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_searchRepository_Prepare(t *testing.T) {
+	t.Run("create fts index and rebuild when it is out of sync", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		repo := NewSearchRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT sqlite_compileoption_used('ENABLE_FTS5')")).
+			WillReturnRows(sqlmock.NewRows([]string{"used"}).AddRow(true))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("CREATE VIRTUAL TABLE IF NOT EXISTS tasks_fts")).WillReturnResult(sqlmock.NewResult(0, 0))
+		for range ftsTriggers {
+			mock.ExpectExec("CREATE TRIGGER IF NOT EXISTS tasks_fts_").WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+		mock.ExpectExec(regexp.QuoteMeta("'integrity-check'")).WillReturnError(errors.New("database disk image is malformed"))
+		mock.ExpectCommit()
+		// 重建在 transaction 與 prepare_search 的 timeout 之外執行
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO tasks_fts (tasks_fts) VALUES ('rebuild')")).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.NoError(t, repo.Prepare(context.Background()))
+		assert.True(t, repo.(*searchRepository).fts)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("drop triggers when sqlite is built without fts5", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		repo := NewSearchRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT sqlite_compileoption_used('ENABLE_FTS5')")).
+			WillReturnRows(sqlmock.NewRows([]string{"used"}).AddRow(false))
+		mock.ExpectBegin()
+		for _, name := range []string{"tasks_fts_insert", "tasks_fts_delete", "tasks_fts_update"} {
+			mock.ExpectExec(regexp.QuoteMeta("DROP TRIGGER IF EXISTS " + name)).WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.Prepare(context.Background()))
+		assert.False(t, repo.(*searchRepository).fts)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("other dialects use LIKE", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		repo := NewSearchRepository(db, dialect.Postgres, Timeouts{}, zap.NewNop())
+
+		assert.NoError(t, repo.Prepare(context.Background()))
+		assert.False(t, repo.(*searchRepository).fts)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_searchRepository_Search(t *testing.T) {
+	columns := []string{"id", "name", "status", "version", "created_at", "updated_at", "deleted_at", "due_at", "remind_at", "priority", "position", "parent_id", "recurrence", "blocked"}
+	query, _ := search.Parse(`"weekly report" plan*`)
+	now := time.Now()
+
+	t.Run("fts ranks by bm25", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		repo := &searchRepository{db: db, conn: reboundConn{conn: db, dialect: dialect.SQLite}, dialect: dialect.SQLite, logger: zap.NewNop(), fts: true}
+		mock.ExpectQuery(regexp.QuoteMeta("FROM tasks_fts WHERE tasks_fts MATCH ?) m ON m.rowid = tasks.rowid WHERE tasks.deleted_at IS NULL ORDER BY m.score, tasks.position, tasks.id LIMIT ? OFFSET ?")).
+			WithArgs(`"weekly report" "plan"*`, 20, 40).
+			WillReturnRows(sqlmock.NewRows(append(columns, "snippet", "score")).
+				AddRow("task-1", "Weekly report <b>planning</b>", 0, 1, now, now, nil, nil, nil, 1, "00000001", nil, nil, false, search.SnippetOpen+"Weekly report"+search.SnippetClose+" <b>"+search.SnippetOpen+"planning"+search.SnippetClose+"</b>", -1.5))
+
+		results, err := repo.Search(context.Background(), query, 20, 40)
+
+		assert.NoError(t, err)
+		assert.Len(t, results, 1)
+		assert.Equal(t, "task-1", results[0].ID)
+		// 名稱中的 HTML 會被 escape，只有 <mark> 是標籤
+		assert.Equal(t, "<mark>Weekly report</mark> &lt;b&gt;<mark>planning</mark>&lt;/b&gt;", results[0].Snippet)
+		assert.Equal(t, -1.5, results[0].Rank)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("LIKE requires every term and highlights the name", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		repo := NewSearchRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+		mock.ExpectQuery(regexp.QuoteMeta("FROM tasks WHERE deleted_at IS NULL AND "+dialect.SQLite.Contains("name")+" AND "+dialect.SQLite.Contains("name")+" ORDER BY position, id LIMIT ? OFFSET ?")).
+			WithArgs("%weekly report%", "%plan%", 20, 0).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("task-1", "Weekly report planning", 0, 1, now, now, nil, nil, nil, 1, "00000001", nil, nil, false))
+
+		results, err := repo.Search(context.Background(), query, 20, 0)
+
+		assert.NoError(t, err)
+		assert.Len(t, results, 1)
+		assert.Equal(t, "<mark>Weekly report</mark> <mark>plan</mark>ning", results[0].Snippet)
+		assert.Zero(t, results[0].Rank)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("search error", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		assert.NoError(t, err)
+		defer db.Close()
+		repo := NewSearchRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+		mock.ExpectQuery("FROM tasks WHERE deleted_at IS NULL").WillReturnError(errors.New("db error"))
+
+		results, err := repo.Search(context.Background(), query, 20, 0)
+
+		assert.Nil(t, results)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go.uber.org/zap"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"tasks/internal/dialect"
+)
+
+type tagRepository struct {
+	db       *sql.DB
+	conn     dbConn
+	dialect  dialect.Dialect
+	timeouts Timeouts
+	logger   *zap.Logger
+}
+
+func NewTagRepository(conn *sql.DB, dialect dialect.Dialect, timeouts Timeouts, logger *zap.Logger) TagRepository {
+	return &tagRepository{
+		db:       conn,
+		conn:     reboundConn{conn: conn, dialect: dialect},
+		dialect:  dialect,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+const tagColumns = "id,name,color,created_at,updated_at"
+
+func scanTag(row rowScanner) (*models.Tag, error) {
+	tag := models.Tag{}
+	if err := row.Scan(&tag.ID, &tag.Name, &tag.Color, &tag.CreatedAt, &tag.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// colorArg 空字串時寫入 NULL
+func colorArg(color string) interface{} {
+	if color == "" {
+		return nil
+	}
+	return color
+}
+
+func (r *tagRepository) Find(ctx context.Context, id string) (tag *models.Tag, err error) {
+	ctx, done := r.timeouts.apply(ctx, "find_tag")
+	defer done(&err)
+	tag, err = scanTag(r.conn.QueryRowContext(ctx, "SELECT "+tagColumns+" FROM tags WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.TagNotFound.Wrap(err, "tag not found")
+		}
+		r.logger.Error("Find tag error", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (r *tagRepository) List(ctx context.Context) (result []*models.Tag, err error) {
+	ctx, done := r.timeouts.apply(ctx, "list_tags")
+	defer done(&err)
+	rows, err := r.conn.QueryContext(ctx, "SELECT "+tagColumns+" FROM tags ORDER BY name, id")
+	if err != nil {
+		r.logger.Error("List tag error", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	result = make([]*models.Tag, 0)
+	for rows.Next() {
+		tag, err := scanTag(rows)
+		if err != nil {
+			r.logger.Error("Scan tag error", zap.Error(err))
+			return nil, err
+		}
+		result = append(result, tag)
+	}
+	if err = rows.Err(); err != nil {
+		r.logger.Error("Iterate tag rows error", zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *tagRepository) Create(ctx context.Context, tag entities.Tag) (err error) {
+	ctx, done := r.timeouts.apply(ctx, "create_tag")
+	defer done(&err)
+	_, err = r.conn.ExecContext(ctx, "INSERT INTO tags (id, name, color, created_at, updated_at) VALUES (?, ?, ?, ?, ?)",
+		tag.ID, tag.Name, colorArg(tag.Color), tag.CreatedAt, tag.UpdatedAt)
+	if err != nil {
+		if r.nameTaken(ctx, tag.Name, tag.ID) {
+			return customError.TagNameConflict.Errorf("tag %s already exists", tag.Name)
+		}
+		r.logger.Error("Execute insert tag error", zap.Any("tag", tag), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (r *tagRepository) Update(ctx context.Context, tag entities.Tag) (err error) {
+	ctx, done := r.timeouts.apply(ctx, "update_tag")
+	defer done(&err)
+	rows, err := r.conn.ExecContext(ctx, "UPDATE tags SET name = ?, color = ?, updated_at = ? WHERE id = ?",
+		tag.Name, colorArg(tag.Color), tag.UpdatedAt, tag.ID)
+	if err != nil {
+		if r.nameTaken(ctx, tag.Name, tag.ID) {
+			return customError.TagNameConflict.Errorf("tag %s already exists", tag.Name)
+		}
+		r.logger.Error("Execute update tag error", zap.Any("tag", tag), zap.Error(err))
+		return err
+	}
+	effectRows, err := rows.RowsAffected()
+	if err != nil {
+		r.logger.Error("Execute update tag error", zap.Any("tag", tag), zap.Error(err))
+		return err
+	}
+	if effectRows == 0 {
+		return customError.TagNotFound.New("tag not found")
+	}
+	return nil
+}
+
+// nameTaken 寫入失敗後確認是否因為名稱已被其他 tag 使用，由 unique index 保證不會重複
+func (r *tagRepository) nameTaken(ctx context.Context, name, id string) bool {
+	var count int
+	err := r.conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM tags WHERE name = ? AND id <> ?", name, id).Scan(&count)
+	return err == nil && count > 0
+}
+
+// Delete 刪除 tag 並從所有 task 上移除
+func (r *tagRepository) Delete(ctx context.Context, id string) (err error) {
+	ctx, done := r.timeouts.apply(ctx, "delete_tag")
+	defer done(&err)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Begin tx error", zap.Error(err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+				r.logger.Error("Rollback tx error", zap.Error(rollbackErr))
+			}
+		}
+	}()
+	conn := reboundConn{conn: tx, dialect: r.dialect}
+	rows, err := conn.ExecContext(ctx, "DELETE FROM tags WHERE id = ?", id)
+	if err != nil {
+		r.logger.Error("Execute delete tag error", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	effectRows, err := rows.RowsAffected()
+	if err != nil {
+		r.logger.Error("Execute delete tag error", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	if effectRows == 0 {
+		return customError.TagNotFound.New("tag not found")
+	}
+	if _, err = conn.ExecContext(ctx, "DELETE FROM task_tags WHERE tag_id = ?", id); err != nil {
+		r.logger.Error("Execute delete task tags error", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("Commit tx error", zap.Error(err))
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"tasks/domain/entities"
+	customError "tasks/errors"
+	"tasks/internal/dialect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_tagRepository_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTagRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("empty color is stored as null", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO tags (id, name, color, created_at, updated_at) VALUES (?, ?, ?, ?, ?)")).
+			WithArgs("tag-1", "work", nil, now, now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Create(ctx, entities.Tag{ID: "tag-1", Name: "work", CreatedAt: now, UpdatedAt: now})
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO tags").
+			WillReturnError(errors.New("UNIQUE constraint failed: tags.name"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM tags WHERE name = ? AND id <> ?")).
+			WithArgs("work", "tag-2").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+		err := repo.Create(ctx, entities.Tag{ID: "tag-2", Name: "work", CreatedAt: now, UpdatedAt: now})
+		assert.True(t, errors.Is(err, customError.TagNameConflict))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_tagRepository_Update(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTagRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("tag not found", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE tags SET name = ?, color = ?, updated_at = ? WHERE id = ?")).
+			WithArgs("home", "#ff0000", now, "tag-404").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Update(ctx, entities.Tag{ID: "tag-404", Name: "home", Color: "#ff0000", UpdatedAt: now})
+		assert.True(t, errors.Is(err, customError.TagNotFound))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_tagRepository_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTagRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+
+	t.Run("delete tag and detach it from tasks", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM tags WHERE id = ?")).
+			WithArgs("tag-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM task_tags WHERE tag_id = ?")).
+			WithArgs("tag-1").
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectCommit()
+
+		err := repo.Delete(ctx, "tag-1")
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("tag not found", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM tags WHERE id = ?")).
+			WithArgs("tag-404").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := repo.Delete(ctx, "tag-404")
+		assert.True(t, errors.Is(err, customError.TagNotFound))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,309 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"tasks/internal/audit"
+	"time"
+)
+
+// errAuditSealed 串接舊紀錄時發現其他 instance 已串接，用來 rollback
+var errAuditSealed = errors.New("audit already sealed")
+
+const auditColumns = "id,task_id,action,actor,version,changes,created_at,prev_hash,hash"
+
+// findRecord 不論是否在垃圾桶中都會回傳 task
+func (t *taskRepository) findRecord(ctx context.Context, id string) (*models.Task, error) {
+	task, err := scanTask(t.conn.QueryRowContext(ctx, "SELECT "+taskColumns+" FROM tasks WHERE id = ?", id))
+	if err != nil {
+		t.logger.Error("Find task record error", zap.String("id", id), zap.Error(err))
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.TaskNotFound.Wrap(err, "task not found")
+		}
+		return nil, err
+	}
+	return task, nil
+}
+
+// auditFields 記錄在 audit log 中的欄位，依固定順序排列，version 與 updated_at 等每次都會改變的欄位不記錄
+func auditFields(task *models.Task) [][2]interface{} {
+	if task == nil {
+		return nil
+	}
+	optional := func(value *string) interface{} {
+		if value == nil {
+			return nil
+		}
+		return *value
+	}
+	optionalTime := func(value *string) interface{} {
+		if value == nil {
+			return nil
+		}
+		return models.ParseTime(*value).Format(time.RFC3339Nano)
+	}
+	return [][2]interface{}{
+		{"parent_id", optional(task.ParentID)},
+		{"name", task.Name},
+		{"status", task.Status},
+		{"priority", task.Priority},
+		{"position", task.Position},
+		{"due_at", optionalTime(task.DueAt)},
+		{"remind_at", optionalTime(task.RemindAt)},
+		{"recurrence", optional(task.Recurrence)},
+		{"deleted_at", optionalTime(task.DeletedAt)},
+	}
+}
+
+// diffTask before 為 nil 表示新增，after 為 nil 表示永久刪除，兩者皆為 nil 或空值的欄位不記錄
+func diffTask(before, after *models.Task) []entities.FieldChange {
+	previous, next := auditFields(before), auditFields(after)
+	changes := make([]entities.FieldChange, 0)
+	for i := 0; i < len(previous) || i < len(next); i++ {
+		var field string
+		var from, to interface{}
+		if i < len(previous) {
+			field, from = previous[i][0].(string), previous[i][1]
+		}
+		if i < len(next) {
+			field, to = next[i][0].(string), next[i][1]
+		}
+		if from == to {
+			continue
+		}
+		if (before == nil || after == nil) && (from == "" || to == "") {
+			continue
+		}
+		changes = append(changes, entities.FieldChange{Field: field, Before: from, After: to})
+	}
+	return changes
+}
+
+// setChange 比較 tag 或 blocker 等關聯變更前後的 id
+func setChange(field string, before map[string]bool, added, removed []string) entities.FieldChange {
+	previous := make([]string, 0, len(before))
+	next := make(map[string]bool, len(before)+len(added))
+	for id := range before {
+		previous = append(previous, id)
+		next[id] = true
+	}
+	for _, id := range added {
+		next[id] = true
+	}
+	for _, id := range removed {
+		delete(next, id)
+	}
+	current := make([]string, 0, len(next))
+	for id := range next {
+		current = append(current, id)
+	}
+	sort.Strings(previous)
+	sort.Strings(current)
+	return entities.FieldChange{Field: field, Before: previous, After: current}
+}
+
+// record 記錄 task 的一次變更，寫入 audit log 與 webhook outbox，event sourcing 模式的事件在寫入 tasks 表前已由 emit 寫入
+func (t *taskRepository) record(ctx context.Context, action constants.AuditAction, before, after *models.Task, extra ...entities.FieldChange) error {
+	if err := t.audit(ctx, action, before, after, extra...); err != nil {
+		return err
+	}
+	return t.enqueueWebhooks(ctx, action, before, after, extra)
+}
+
+// audit 在目前的 transaction 中寫入一筆 audit log，actor 取自 ctx，
+// 每筆紀錄以 hash 串接前一筆，task_audit_head 保存 chain 的最後一筆
+func (t *taskRepository) audit(ctx context.Context, action constants.AuditAction, before, after *models.Task, extra ...entities.FieldChange) error {
+	record := after
+	if record == nil {
+		record = before
+	}
+	changes, err := json.Marshal(append(diffTask(before, after), extra...))
+	if err != nil {
+		return err
+	}
+	// 先更新 head 取得 row lock，寫入 audit log 的 transaction 會依序串接，之後讀到的 hash 是最新的
+	if _, err = t.conn.ExecContext(ctx, "UPDATE task_audit_head SET seq = seq + 1 WHERE id = 1"); err != nil {
+		t.logger.Error("Execute lock audit head error", zap.Error(err))
+		return err
+	}
+	var prevHash string
+	if err = t.conn.QueryRowContext(ctx, "SELECT hash FROM task_audit_head WHERE id = 1").Scan(&prevHash); err != nil {
+		t.logger.Error("Find audit head error", zap.Error(err))
+		return err
+	}
+	entry := audit.Record{
+		TaskID:    record.ID,
+		Action:    string(action),
+		Actor:     audit.Actor(ctx),
+		Version:   record.Version,
+		Changes:   string(changes),
+		CreatedAt: audit.Now(),
+	}
+	hash := audit.Hash(prevHash, entry)
+	_, err = t.conn.ExecContext(ctx, "INSERT INTO task_audit (task_id, action, actor, version, changes, created_at, prev_hash, hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		entry.TaskID, entry.Action, entry.Actor, entry.Version, entry.Changes, entry.CreatedAt, prevHash, hash)
+	if err != nil {
+		t.logger.Error("Execute insert audit error", zap.String("id", record.ID), zap.String("action", string(action)), zap.Error(err))
+		return err
+	}
+	// 持有 head 的 lock 時沒有其他 transaction 能寫入，最大的 id 即為剛寫入的紀錄
+	_, err = t.conn.ExecContext(ctx, "UPDATE task_audit_head SET hash = ?, last_id = (SELECT MAX(id) FROM task_audit) WHERE id = 1", hash)
+	if err != nil {
+		t.logger.Error("Execute update audit head error", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ListAudit 依 id 由新到舊排列
+func (t *taskRepository) ListAudit(ctx context.Context, param entities.AuditQueryParam) (result []*models.AuditEntry, err error) {
+	ctx, done := t.timeouts.apply(ctx, "list_audit")
+	defer done(&err)
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	if param.TaskID != "" {
+		conditions = append(conditions, "task_id = ?")
+		args = append(args, param.TaskID)
+	}
+	if param.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, param.Actor)
+	}
+	if len(param.Actions) > 0 {
+		actions := make([]string, 0, len(param.Actions))
+		for _, action := range param.Actions {
+			actions = append(actions, string(action))
+		}
+		in, values := inClause(distinct(actions))
+		conditions = append(conditions, "action IN "+in)
+		args = append(args, values...)
+	}
+	if param.Since != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *param.Since)
+	}
+	if param.Until != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *param.Until)
+	}
+	if param.Before > 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, param.Before)
+	}
+	var query strings.Builder
+	query.WriteString("SELECT " + auditColumns + " FROM task_audit")
+	writeWhere(&query, conditions)
+	query.WriteString(" ORDER BY id DESC LIMIT ?")
+	rows, err := t.conn.QueryContext(ctx, query.String(), append(args, param.Size)...)
+	if err != nil {
+		t.logger.Error("List audit error", zap.Error(err))
+		return nil, err
+	}
+	return t.scanAudit(rows)
+}
+
+// ListAuditChain 依 id 由舊到新排列，只列出 id 大於 afterID 的紀錄
+func (t *taskRepository) ListAuditChain(ctx context.Context, afterID int64, size int) (result []*models.AuditEntry, err error) {
+	ctx, done := t.timeouts.apply(ctx, "list_audit_chain")
+	defer done(&err)
+	rows, err := t.conn.QueryContext(ctx, "SELECT "+auditColumns+" FROM task_audit WHERE id > ? ORDER BY id LIMIT ?", afterID, size)
+	if err != nil {
+		t.logger.Error("List audit chain error", zap.Int64("after_id", afterID), zap.Error(err))
+		return nil, err
+	}
+	return t.scanAudit(rows)
+}
+
+func (t *taskRepository) scanAudit(rows *sql.Rows) ([]*models.AuditEntry, error) {
+	defer rows.Close()
+	result := make([]*models.AuditEntry, 0)
+	for rows.Next() {
+		var entry models.AuditEntry
+		if err := rows.Scan(&entry.ID, &entry.TaskID, &entry.Action, &entry.Actor, &entry.Version, &entry.Changes, &entry.CreatedAt, &entry.PrevHash, &entry.Hash); err != nil {
+			t.logger.Error("Scan audit error", zap.Error(err))
+			return nil, err
+		}
+		result = append(result, &entry)
+	}
+	if err := rows.Err(); err != nil {
+		t.logger.Error("Iterate audit rows error", zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+func (t *taskRepository) AuditHead(ctx context.Context) (head *models.AuditHead, err error) {
+	ctx, done := t.timeouts.apply(ctx, "find_audit_head")
+	defer done(&err)
+	var result models.AuditHead
+	err = t.conn.QueryRowContext(ctx, "SELECT seq, last_id, hash FROM task_audit_head WHERE id = 1").Scan(&result.Seq, &result.LastID, &result.Hash)
+	if err != nil {
+		t.logger.Error("Find audit head error", zap.Error(err))
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SealAudit 將 hash chain 上線前寫入的紀錄依 id 串接成 chain，chain 已有紀錄時不處理，回傳串接的筆數
+func (t *taskRepository) SealAudit(ctx context.Context) (sealed int64, err error) {
+	ctx, done := t.timeouts.apply(ctx, "seal_audit")
+	defer done(&err)
+	err = t.withTx(ctx, func(tx *taskRepository) error {
+		head, err := tx.AuditHead(ctx)
+		if err != nil || head.Seq > 0 {
+			return err
+		}
+		rows, err := tx.conn.QueryContext(ctx, "SELECT "+auditColumns+" FROM task_audit ORDER BY id")
+		if err != nil {
+			t.logger.Error("List unsealed audit error", zap.Error(err))
+			return err
+		}
+		records, err := tx.scanAudit(rows)
+		if err != nil || len(records) == 0 {
+			return err
+		}
+		var hash string
+		for _, record := range records {
+			prevHash := hash
+			hash = audit.Hash(prevHash, audit.Record{
+				TaskID:    record.TaskID,
+				Action:    record.Action,
+				Actor:     record.Actor,
+				Version:   record.Version,
+				Changes:   record.Changes,
+				CreatedAt: models.ParseTime(record.CreatedAt),
+			})
+			if _, err = tx.conn.ExecContext(ctx, "UPDATE task_audit SET prev_hash = ?, hash = ? WHERE id = ?", prevHash, hash, record.ID); err != nil {
+				t.logger.Error("Execute seal audit error", zap.Int64("id", record.ID), zap.Error(err))
+				return err
+			}
+		}
+		// 同時啟動的其他 instance 已串接時 seq 不為 0，放棄這次的串接
+		result, err := tx.conn.ExecContext(ctx, "UPDATE task_audit_head SET seq = ?, last_id = ?, hash = ? WHERE id = 1 AND seq = 0",
+			len(records), records[len(records)-1].ID, hash)
+		if err != nil {
+			t.logger.Error("Execute update audit head error", zap.Error(err))
+			return err
+		}
+		if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+			return errAuditSealed
+		}
+		sealed = int64(len(records))
+		return nil
+	})
+	if errors.Is(err, errAuditSealed) {
+		return 0, nil
+	}
+	return sealed, err
+}
@@ -0,0 +1,315 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	"tasks/internal/audit"
+	"tasks/internal/dialect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// taskColumnNames taskColumns 查詢回傳的欄位
+var taskColumnNames = []string{"id", "name", "status", "version", "created_at", "updated_at", "deleted_at", "due_at", "remind_at", "priority", "position", "parent_id", "recurrence", "blocked"}
+
+// expectRecord 預期 findRecord 讀取 task，不論是否在垃圾桶中
+func expectRecord(mock sqlmock.Sqlmock, id string, version int, deletedAt interface{}) {
+	mock.ExpectQuery(regexp.QuoteMeta(" FROM tasks WHERE id = ?") + "$").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(taskColumnNames).AddRow(id, "Task", 0, version, time.Now(), time.Now(), deletedAt, nil, nil, 1, "00000001", nil, nil, false))
+}
+
+// auditColumnNames auditColumns 查詢回傳的欄位
+var auditColumnNames = []string{"id", "task_id", "action", "actor", "version", "changes", "created_at", "prev_hash", "hash"}
+
+// expectAuditHead 預期鎖定 task_audit_head 並讀取 chain 最後一筆的 hash
+func expectAuditHead(mock sqlmock.Sqlmock, prevHash string) {
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE task_audit_head SET seq = seq + 1 WHERE id = 1")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT hash FROM task_audit_head WHERE id = 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow(prevHash))
+}
+
+// expectAuditTail 預期更新 task_audit_head 為剛寫入的紀錄
+func expectAuditTail(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE task_audit_head SET hash = ?, last_id = (SELECT MAX(id) FROM task_audit) WHERE id = 1")).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+// expectAudit 預期寫入一筆 audit log
+func expectAudit(mock sqlmock.Sqlmock, id string, action constants.AuditAction) {
+	expectAuditHead(mock, "")
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO task_audit (task_id, action, actor, version, changes, created_at, prev_hash, hash) VALUES (?, ?, ?, ?, ?, ?, ?, ?)")).
+		WithArgs(id, string(action), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectAuditTail(mock)
+	expectOutbox(mock)
+}
+
+// expectOutbox 預期查詢訂閱的 webhook，沒有 webhook 時不寫入 outbox
+func expectOutbox(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id, events FROM webhooks WHERE active = 1")).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "events"}))
+}
+
+func Test_diffTask(t *testing.T) {
+	parentID, dueAt := "task-0", "2024-01-02 09:00:00+00:00"
+	before := &models.Task{ID: "task-1", Name: "Draft", Status: 0, Priority: 1, Position: "00000001", Version: 1}
+
+	t.Run("create lists every field that is set", func(t *testing.T) {
+		assert.Equal(t, []entities.FieldChange{
+			{Field: "name", Before: nil, After: "Draft"},
+			{Field: "status", Before: nil, After: 0},
+			{Field: "priority", Before: nil, After: 1},
+			{Field: "position", Before: nil, After: "00000001"},
+		}, diffTask(nil, before))
+	})
+
+	t.Run("update lists changed fields only", func(t *testing.T) {
+		after := *before
+		after.Name, after.Status, after.ParentID, after.DueAt, after.Version = "Final", 1, &parentID, &dueAt, 2
+
+		assert.Equal(t, []entities.FieldChange{
+			{Field: "parent_id", Before: nil, After: "task-0"},
+			{Field: "name", Before: "Draft", After: "Final"},
+			{Field: "status", Before: 0, After: 1},
+			{Field: "due_at", Before: nil, After: "2024-01-02T09:00:00Z"},
+		}, diffTask(before, &after))
+	})
+
+	t.Run("purge lists every field that was set", func(t *testing.T) {
+		changes := diffTask(before, nil)
+
+		assert.Len(t, changes, 4)
+		assert.Equal(t, entities.FieldChange{Field: "name", Before: "Draft", After: nil}, changes[0])
+	})
+}
+
+func Test_setChange(t *testing.T) {
+	existing := map[string]bool{"tag-2": true, "tag-1": true}
+
+	assert.Equal(t, entities.FieldChange{Field: "tags", Before: []string{"tag-1", "tag-2"}, After: []string{"tag-1", "tag-2", "tag-3"}},
+		setChange("tags", existing, []string{"tag-3"}, nil))
+	assert.Equal(t, entities.FieldChange{Field: "tags", Before: []string{"tag-1", "tag-2"}, After: []string{"tag-2"}},
+		setChange("tags", existing, nil, []string{"tag-1", "tag-9"}))
+}
+
+func Test_taskRepository_Audit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := audit.WithActor(context.Background(), "alice")
+
+	t.Run("patch records the actor and the diff", func(t *testing.T) {
+		name := "Renamed"
+		changes, _ := json.Marshal([]entities.FieldChange{{Field: "name", Before: "Task", After: "Renamed"}})
+		mock.ExpectBegin()
+		expectRecord(mock, "task-1", 1, nil)
+		mock.ExpectPrepare(regexp.QuoteMeta("UPDATE tasks SET name = ?, version = ?, updated_at = ? WHERE id = ? and version = ?")).
+			ExpectExec().
+			WithArgs(name, 2, sqlmock.AnyArg(), "task-1", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(" FROM tasks WHERE id = ?") + "$").
+			WithArgs("task-1").
+			WillReturnRows(sqlmock.NewRows(taskColumnNames).AddRow("task-1", name, 0, 2, time.Now(), time.Now(), nil, nil, nil, 1, "00000001", nil, nil, false))
+		expectAuditHead(mock, "prev")
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO task_audit")).
+			WithArgs("task-1", "update", "alice", 2, string(changes), sqlmock.AnyArg(), "prev", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectAuditTail(mock)
+		expectOutbox(mock)
+		mock.ExpectCommit()
+
+		err := repo.Patch(ctx, entities.TaskPatch{ID: "task-1", Name: &name}, 1)
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed audit rolls back the change", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare("INSERT INTO tasks").
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectRecord(mock, "task-1", 0, nil)
+		expectAuditHead(mock, "")
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO task_audit")).
+			WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
+
+		task := entities.NewTask("Task")
+		task.ID = "task-1"
+		err := repo.Create(ctx, task)
+		assert.EqualError(t, err, "db error")
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("list audit with filters", func(t *testing.T) {
+		since := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT "+auditColumns+" FROM task_audit"+
+			" WHERE task_id = ? AND actor = ? AND action IN (?,?) AND created_at >= ? AND id < ? ORDER BY id DESC LIMIT ?")).
+			WithArgs("task-1", "alice", "update", "delete", since, int64(10), 51).
+			WillReturnRows(sqlmock.NewRows(auditColumnNames).
+				AddRow(9, "task-1", "delete", "alice", 3, `[]`, "2024-01-02 00:00:00+00:00", "h4", "h9").
+				AddRow(4, "task-1", "update", "alice", 2, `[{"field":"name","before":"a","after":"b"}]`, "2024-01-01 12:00:00+00:00", "h3", "h4"))
+
+		entries, err := repo.ListAudit(ctx, entities.AuditQueryParam{
+			TaskID:  "task-1",
+			Actor:   "alice",
+			Actions: []constants.AuditAction{constants.AuditUpdate, constants.AuditDelete},
+			Since:   &since,
+			Before:  10,
+			Size:    51,
+		})
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, int64(9), entries[0].ID)
+		assert.Equal(t, "update", entries[1].Action)
+		assert.Equal(t, "h9", entries[0].Hash)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("chain links each entry to the previous hash", func(t *testing.T) {
+		name := "Renamed"
+		var hash string
+		mock.ExpectBegin()
+		expectRecord(mock, "task-1", 1, nil)
+		mock.ExpectPrepare("UPDATE tasks SET").
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectRecord(mock, "task-1", 2, nil)
+		expectAuditHead(mock, "prev")
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO task_audit")).
+			WithArgs("task-1", "update", "alice", 2, sqlmock.AnyArg(), sqlmock.AnyArg(), "prev", hashArg{&hash}).
+			WillReturnResult(sqlmock.NewResult(7, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE task_audit_head SET hash = ?")).
+			WithArgs(hashArg{&hash}).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectOutbox(mock)
+		mock.ExpectCommit()
+
+		err := repo.Patch(ctx, entities.TaskPatch{ID: "task-1", Name: &name}, 1)
+		assert.NoError(t, err)
+		assert.Len(t, hash, 64)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("list audit chain", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT "+auditColumns+" FROM task_audit WHERE id > ? ORDER BY id LIMIT ?")).
+			WithArgs(int64(4), 500).
+			WillReturnRows(sqlmock.NewRows(auditColumnNames).
+				AddRow(5, "task-1", "update", "alice", 3, `[]`, "2024-01-02 00:00:00+00:00", "h4", "h5"))
+
+		entries, err := repo.ListAuditChain(ctx, 4, 500)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+		assert.Equal(t, "h4", entries[0].PrevHash)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// hashArg 記錄第一次比對到的 hash，之後的參數必須相同
+type hashArg struct {
+	hash *string
+}
+
+func (a hashArg) Match(value driver.Value) bool {
+	hash, ok := value.(string)
+	if !ok {
+		return false
+	}
+	if *a.hash == "" {
+		*a.hash = hash
+	}
+	return *a.hash == hash
+}
+
+func Test_taskRepository_SealAudit(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	headQuery := regexp.QuoteMeta("SELECT seq, last_id, hash FROM task_audit_head WHERE id = 1")
+
+	t.Run("chains entries written before the chain existed", func(t *testing.T) {
+		first := audit.Record{TaskID: "task-1", Action: "create", Actor: "alice", Changes: `[]`, CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+		second := audit.Record{TaskID: "task-1", Action: "delete", Actor: "bob", Version: 1, Changes: `[]`, CreatedAt: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}
+		firstHash := audit.Hash("", first)
+		secondHash := audit.Hash(firstHash, second)
+		mock.ExpectBegin()
+		mock.ExpectQuery(headQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"seq", "last_id", "hash"}).AddRow(0, 0, ""))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT " + auditColumns + " FROM task_audit ORDER BY id")).
+			WillReturnRows(sqlmock.NewRows(auditColumnNames).
+				AddRow(1, "task-1", "create", "alice", 0, `[]`, "2024-01-01 00:00:00+00:00", "", "").
+				AddRow(3, "task-1", "delete", "bob", 1, `[]`, "2024-01-02 00:00:00+00:00", "", ""))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE task_audit SET prev_hash = ?, hash = ? WHERE id = ?")).
+			WithArgs("", firstHash, int64(1)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE task_audit SET prev_hash = ?, hash = ? WHERE id = ?")).
+			WithArgs(firstHash, secondHash, int64(3)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE task_audit_head SET seq = ?, last_id = ?, hash = ? WHERE id = 1 AND seq = 0")).
+			WithArgs(2, int64(3), secondHash).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		sealed, err := repo.SealAudit(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), sealed)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("chain already started", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(headQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"seq", "last_id", "hash"}).AddRow(4, 9, "h9"))
+		mock.ExpectCommit()
+
+		sealed, err := repo.SealAudit(ctx)
+		assert.NoError(t, err)
+		assert.Zero(t, sealed)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("sealed by another instance", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(headQuery).
+			WillReturnRows(sqlmock.NewRows([]string{"seq", "last_id", "hash"}).AddRow(0, 0, ""))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT " + auditColumns + " FROM task_audit ORDER BY id")).
+			WillReturnRows(sqlmock.NewRows(auditColumnNames).
+				AddRow(1, "task-1", "create", "alice", 0, `[]`, "2024-01-01 00:00:00+00:00", "", ""))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE task_audit SET prev_hash = ?, hash = ? WHERE id = ?")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE task_audit_head SET seq = ?")).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		sealed, err := repo.SealAudit(ctx)
+		assert.NoError(t, err)
+		assert.Zero(t, sealed)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,850 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"reflect"
+	"sort"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"tasks/internal/audit"
+	"tasks/internal/dialect"
+	"time"
+)
+
+const (
+	eventColumns    = "id,task_id,seq,type,version,data,actor,created_at"
+	snapshotColumns = "task_id,seq,version,state,created_at"
+)
+
+// eventData 事件內容，依事件類型只會有部分欄位，UpdatedAt 為事件發生後 task 的 updated_at
+type eventData struct {
+	UpdatedAt string                 `json:"updated_at,omitempty"`
+	Task      *models.Task           `json:"task,omitempty"`
+	Name      *string                `json:"name,omitempty"`
+	From      *int                   `json:"from,omitempty"`
+	To        *int                   `json:"to,omitempty"`
+	DeletedAt *string                `json:"deleted_at,omitempty"`
+	Changes   []entities.FieldChange `json:"changes,omitempty"`
+}
+
+// taskEvent Version 為事件發生後 task 的 version
+type taskEvent struct {
+	Type    constants.TaskEventType
+	Version int
+	Data    eventData
+}
+
+// eventTaskRepository event sourcing 模式的 TaskRepository，寫入 task 時在同一個 transaction 中
+// 先記錄事件再由事件更新 tasks 表，tasks 表為事件的 projection，可由事件重建
+type eventTaskRepository struct {
+	*taskRepository
+	// snapshotInterval 每個 task 每累積幾筆事件保存一次 snapshot，0 表示不保存
+	snapshotInterval int64
+}
+
+func NewEventTaskRepository(conn *sql.DB, dialect dialect.Dialect, timeouts Timeouts, logger *zap.Logger, snapshotInterval int) TaskEventRepository {
+	return &eventTaskRepository{
+		taskRepository: &taskRepository{
+			db:       conn,
+			conn:     reboundConn{conn: conn, dialect: dialect},
+			dialect:  dialect,
+			timeouts: timeouts,
+			logger:   logger,
+		},
+		snapshotInterval: int64(snapshotInterval),
+	}
+}
+
+// WithTx 傳給 fn 的 repository 同樣以 event sourcing 模式寫入
+func (t *eventTaskRepository) WithTx(ctx context.Context, fn func(repo TaskRepository) error) error {
+	return t.withTx(ctx, func(tx *eventTaskRepository) error {
+		return fn(tx)
+	})
+}
+
+func (t *eventTaskRepository) withTx(ctx context.Context, fn func(tx *eventTaskRepository) error) error {
+	return t.taskRepository.withTx(ctx, func(tx *taskRepository) error {
+		return fn(&eventTaskRepository{taskRepository: tx, snapshotInterval: t.snapshotInterval})
+	})
+}
+
+// canonicalTask 時間統一為 UTC RFC3339Nano 並取到微秒，不同資料庫讀回的 task 才能比較
+func canonicalTask(task *models.Task) *models.Task {
+	if task == nil {
+		return nil
+	}
+	format := func(value string) string {
+		return models.ParseTime(value).Truncate(time.Microsecond).Format(time.RFC3339Nano)
+	}
+	optional := func(value *string) *string {
+		if value == nil {
+			return nil
+		}
+		formatted := format(*value)
+		return &formatted
+	}
+	result := *task
+	result.CreatedAt, result.UpdatedAt = format(task.CreatedAt), format(task.UpdatedAt)
+	result.DeletedAt, result.DueAt, result.RemindAt = optional(task.DeletedAt), optional(task.DueAt), optional(task.RemindAt)
+	// blocked 由 task_dependencies 計算，不屬於 task 本身
+	result.Blocked = false
+	return &result
+}
+
+// eventTime 事件中的時間格式，與 canonicalTask 相同
+func eventTime(value time.Time) string {
+	return value.UTC().Truncate(time.Microsecond).Format(time.RFC3339Nano)
+}
+
+func optionalEventTime(value *time.Time) *string {
+	if value == nil {
+		return nil
+	}
+	formatted := eventTime(*value)
+	return &formatted
+}
+
+// optionalValue 與 auditFields 相同，nil 記錄為 null
+func optionalValue(value *string) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+// createdEvent 新增 task 的事件，事件中的 task 即為寫入 tasks 表的內容
+func createdEvent(task entities.Task) taskEvent {
+	updatedAt := task.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = task.CreatedAt
+	}
+	state := &models.Task{
+		ID:         task.ID,
+		ParentID:   task.ParentID,
+		Name:       task.Name,
+		Status:     int(task.Status),
+		Priority:   int(task.Priority),
+		Position:   task.Position,
+		Version:    task.Version,
+		CreatedAt:  eventTime(task.CreatedAt),
+		UpdatedAt:  eventTime(updatedAt),
+		DueAt:      optionalEventTime(task.DueAt),
+		RemindAt:   optionalEventTime(task.RemindAt),
+		Recurrence: task.Recurrence,
+	}
+	return taskEvent{Type: constants.TaskCreated, Version: task.Version, Data: eventData{Task: state}}
+}
+
+// replacePatch 以整筆更新的 task 覆寫 patch 可修改的所有欄位，position 只能透過 move 修改
+func replacePatch(task entities.Task) entities.TaskPatch {
+	return entities.TaskPatch{
+		ID:         task.ID,
+		ParentID:   &task.ParentID,
+		Name:       &task.Name,
+		Status:     &task.Status,
+		Priority:   &task.Priority,
+		DueAt:      &task.DueAt,
+		RemindAt:   &task.RemindAt,
+		Recurrence: &task.Recurrence,
+	}
+}
+
+// patchEvents 由 patch 產生事件，只記錄與 state 不同的欄位，state 為事件還原的目前狀態
+func patchEvents(state *models.Task, patch entities.TaskPatch, version int, updatedAt time.Time) []taskEvent {
+	var (
+		events  []taskEvent
+		details []entities.FieldChange
+	)
+	data := func() eventData { return eventData{UpdatedAt: eventTime(updatedAt)} }
+	if patch.Name != nil && *patch.Name != state.Name {
+		event := taskEvent{Type: constants.TaskRenamed, Version: version, Data: data()}
+		event.Data.Name = patch.Name
+		events = append(events, event)
+	}
+	if patch.Status != nil && int(*patch.Status) != state.Status {
+		from, to := state.Status, int(*patch.Status)
+		event := taskEvent{Type: constants.TaskStatusChanged, Version: version, Data: data()}
+		event.Data.From, event.Data.To = &from, &to
+		events = append(events, event)
+	}
+	change := func(field string, before, after interface{}) {
+		if before != after {
+			details = append(details, entities.FieldChange{Field: field, Before: before, After: after})
+		}
+	}
+	if patch.ParentID != nil {
+		change("parent_id", optionalValue(state.ParentID), optionalValue(*patch.ParentID))
+	}
+	if patch.Priority != nil {
+		change("priority", state.Priority, int(*patch.Priority))
+	}
+	if patch.Position != nil {
+		change("position", state.Position, *patch.Position)
+	}
+	if patch.DueAt != nil {
+		change("due_at", optionalValue(state.DueAt), optionalValue(optionalEventTime(*patch.DueAt)))
+	}
+	if patch.RemindAt != nil {
+		change("remind_at", optionalValue(state.RemindAt), optionalValue(optionalEventTime(*patch.RemindAt)))
+	}
+	if patch.Recurrence != nil {
+		change("recurrence", optionalValue(state.Recurrence), optionalValue(*patch.Recurrence))
+	}
+	// 沒有改變任何欄位的寫入仍會更新 version，記錄下來重建時 version 與 updated_at 才會一致
+	if len(details) > 0 || len(events) == 0 {
+		event := taskEvent{Type: constants.TaskDetailsChanged, Version: version, Data: data()}
+		event.Data.Changes = details
+		events = append(events, event)
+	}
+	return events
+}
+
+// applyEvent 將事件套用到 state，state 為 nil 表示 task 尚未建立，回傳 nil 表示 task 已永久刪除
+func applyEvent(state *models.Task, event *models.TaskEvent) (*models.Task, error) {
+	var data eventData
+	if err := json.Unmarshal([]byte(event.Data), &data); err != nil {
+		return nil, fmt.Errorf("decode event %d of task %s: %w", event.Seq, event.TaskID, err)
+	}
+	eventType := constants.TaskEventType(event.Type)
+	if eventType == constants.TaskCreated {
+		if data.Task == nil {
+			return nil, fmt.Errorf("event %d of task %s has no task", event.Seq, event.TaskID)
+		}
+		return data.Task, nil
+	}
+	if state == nil {
+		return nil, fmt.Errorf("event %d of task %s %s before the task was created", event.Seq, event.TaskID, event.Type)
+	}
+	result := *state
+	switch eventType {
+	case constants.TaskRenamed:
+		if data.Name != nil {
+			result.Name = *data.Name
+		}
+	case constants.TaskStatusChanged:
+		if data.To != nil {
+			result.Status = *data.To
+		}
+	case constants.TaskDeleted:
+		result.DeletedAt = data.DeletedAt
+	case constants.TaskRestored:
+		result.DeletedAt = nil
+	case constants.TaskDetailsChanged:
+		for _, change := range data.Changes {
+			applyChange(&result, change)
+		}
+	case constants.TaskPurged:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("event %d of task %s has unknown type %s", event.Seq, event.TaskID, event.Type)
+	}
+	result.Version = event.Version
+	if data.UpdatedAt != "" {
+		result.UpdatedAt = data.UpdatedAt
+	}
+	return &result, nil
+}
+
+// applyChange 只套用 tasks 表中的欄位，tags 與 blockers 等關聯只供查閱
+func applyChange(task *models.Task, change entities.FieldChange) {
+	optional := func(value interface{}) *string {
+		if text, ok := value.(string); ok {
+			return &text
+		}
+		return nil
+	}
+	switch change.Field {
+	case "parent_id":
+		task.ParentID = optional(change.After)
+	case "priority":
+		// JSON 解碼後數字為 float64
+		if priority, ok := change.After.(float64); ok {
+			task.Priority = int(priority)
+		}
+	case "position":
+		if position := optional(change.After); position != nil {
+			task.Position = *position
+		}
+	case "due_at":
+		task.DueAt = optional(change.After)
+	case "remind_at":
+		task.RemindAt = optional(change.After)
+	case "recurrence":
+		task.Recurrence = optional(change.After)
+	}
+}
+
+// Create 記錄 TaskCreated 後由事件寫入 tasks 表
+func (t *eventTaskRepository) Create(ctx context.Context, task entities.Task) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "create_task")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *eventTaskRepository) error {
+		err := tx.emit(ctx, task.ID, nil, func(*models.Task) []taskEvent {
+			return []taskEvent{createdEvent(task)}
+		})
+		if err != nil {
+			return err
+		}
+		record, err := tx.findRecord(ctx, task.ID)
+		if err != nil {
+			return err
+		}
+		return tx.record(ctx, constants.AuditCreate, nil, record)
+	})
+}
+
+func (t *eventTaskRepository) Update(ctx context.Context, task entities.Task, expectedVersion *int) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "update_task")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *eventTaskRepository) error {
+		record, err := tx.Find(ctx, task.ID)
+		if err != nil {
+			return err
+		}
+		if expectedVersion != nil && *expectedVersion != record.Version {
+			return customError.TaskVersionMismatch.Errorf("task %s version is %d, expected %d", task.ID, record.Version, *expectedVersion)
+		}
+		err = tx.emit(ctx, task.ID, &record.Version, func(state *models.Task) []taskEvent {
+			return patchEvents(state, replacePatch(task), record.Version+1, audit.Now())
+		})
+		if err != nil {
+			return err
+		}
+		if !sameTime(record.DueAt, task.DueAt) || !sameTime(record.RemindAt, task.RemindAt) {
+			if err = tx.resetReminder(ctx, task.ID); err != nil {
+				return err
+			}
+		}
+		updated, err := tx.findRecord(ctx, task.ID)
+		if err != nil {
+			return err
+		}
+		return tx.record(ctx, constants.AuditUpdate, record, updated)
+	})
+}
+
+func (t *eventTaskRepository) Patch(ctx context.Context, patch entities.TaskPatch, version int) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "patch_task")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *eventTaskRepository) error {
+		// 與 taskRepository 相同，task 不存在時回傳 TaskVersionConflict
+		record, err := tx.findRecord(ctx, patch.ID)
+		if err != nil {
+			if customError.TaskNotFound.Is(customError.CauseCustomError(err)) {
+				return customError.TaskVersionConflict.Errorf("task %s version %d is stale", patch.ID, version)
+			}
+			return err
+		}
+		err = tx.emit(ctx, patch.ID, &version, func(state *models.Task) []taskEvent {
+			return patchEvents(state, patch, version+1, audit.Now())
+		})
+		if err != nil {
+			return err
+		}
+		if patch.DueAt != nil || patch.RemindAt != nil {
+			if err = tx.resetReminder(ctx, patch.ID); err != nil {
+				return err
+			}
+		}
+		updated, err := tx.findRecord(ctx, patch.ID)
+		if err != nil {
+			return err
+		}
+		return tx.record(ctx, constants.AuditUpdate, record, updated)
+	})
+}
+
+// resetReminder 提醒時間改變後需要重新提醒，reminded_at 不屬於事件，直接更新 tasks 表
+func (t *eventTaskRepository) resetReminder(ctx context.Context, id string) error {
+	if _, err := t.conn.ExecContext(ctx, "UPDATE tasks SET reminded_at = NULL WHERE id = ?", id); err != nil {
+		t.logger.Error("Execute reset reminder error", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Delete 為 task 與一起移到垃圾桶的子孫 task 各記錄一筆 TaskDeleted
+func (t *eventTaskRepository) Delete(ctx context.Context, id string, expectedVersion *int, cascade bool) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "delete_task")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *eventTaskRepository) error {
+		record, err := tx.Find(ctx, id)
+		if err != nil {
+			return err
+		}
+		if expectedVersion != nil && *expectedVersion != record.Version {
+			return customError.TaskVersionMismatch.Errorf("task %s version is %d, expected %d", id, record.Version, *expectedVersion)
+		}
+		nodes, err := tx.deleteTargets(ctx, id, cascade)
+		if err != nil {
+			return err
+		}
+		// 同一次刪除的 task 有相同的 deleted_at，還原時以此找出一起被刪除的子孫 task
+		deletedAt := audit.Now().Format(time.RFC3339Nano)
+		for _, node := range nodes {
+			err = tx.emit(ctx, node.ID, &node.Version, func(*models.Task) []taskEvent {
+				event := taskEvent{Type: constants.TaskDeleted, Version: node.Version}
+				event.Data.DeletedAt = &deletedAt
+				return []taskEvent{event}
+			})
+			if err != nil {
+				return err
+			}
+			after := node.Task
+			after.DeletedAt = &deletedAt
+			if err = tx.record(ctx, constants.AuditDelete, &node.Task, &after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (t *eventTaskRepository) Restore(ctx context.Context, id string) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "restore_task")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *eventTaskRepository) error {
+		nodes, err := tx.restoreTargets(ctx, id)
+		if err != nil {
+			return err
+		}
+		now := audit.Now()
+		for _, node := range nodes {
+			err = tx.emit(ctx, node.ID, &node.Version, func(*models.Task) []taskEvent {
+				return []taskEvent{{Type: constants.TaskRestored, Version: node.Version, Data: eventData{UpdatedAt: eventTime(now)}}}
+			})
+			if err != nil {
+				return err
+			}
+			after := node.Task
+			after.DeletedAt, after.UpdatedAt = nil, now.Format(time.RFC3339Nano)
+			if err = tx.record(ctx, constants.AuditRestore, &node.Task, &after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Purge 記錄 TaskPurged，由 project 刪除 task 與其 tag 與 dependency 關聯
+func (t *eventTaskRepository) Purge(ctx context.Context, id string) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "purge_task")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *eventTaskRepository) error {
+		nodes, err := tx.trashedSubtree(ctx, id)
+		if err != nil {
+			return err
+		}
+		for _, node := range nodes {
+			if err = tx.purge(ctx, &node.Task); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (t *eventTaskRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (purged int64, err error) {
+	ctx, done := t.timeouts.apply(ctx, "purge_deleted_tasks")
+	defer done(&err)
+	err = t.withTx(ctx, func(tx *eventTaskRepository) error {
+		expired, err := tx.queryTasks(ctx, "SELECT "+taskColumns+" FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?", before)
+		if err != nil {
+			return err
+		}
+		for _, task := range expired {
+			if err = tx.purge(ctx, task); err != nil {
+				return err
+			}
+		}
+		purged = int64(len(expired))
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+func (t *eventTaskRepository) purge(ctx context.Context, task *models.Task) error {
+	err := t.emit(ctx, task.ID, &task.Version, func(*models.Task) []taskEvent {
+		return []taskEvent{{Type: constants.TaskPurged, Version: task.Version}}
+	})
+	if err != nil {
+		return err
+	}
+	return t.record(ctx, constants.AuditPurge, task, nil)
+}
+
+func (t *eventTaskRepository) AttachTags(ctx context.Context, taskID string, tagIDs []string) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "attach_tags")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *eventTaskRepository) error {
+		return tx.attachTags(ctx, taskID, tagIDs, tx.touch)
+	})
+}
+
+func (t *eventTaskRepository) DetachTags(ctx context.Context, taskID string, tagIDs []string) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "detach_tags")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *eventTaskRepository) error {
+		return tx.detachTags(ctx, taskID, tagIDs, tx.touch)
+	})
+}
+
+func (t *eventTaskRepository) AddBlockers(ctx context.Context, taskID string, blockerIDs []string) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "add_blockers")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *eventTaskRepository) error {
+		return tx.addBlockers(ctx, taskID, blockerIDs, tx.touch)
+	})
+}
+
+func (t *eventTaskRepository) RemoveBlockers(ctx context.Context, taskID string, blockerIDs []string) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "remove_blockers")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *eventTaskRepository) error {
+		return tx.removeBlockers(ctx, taskID, blockerIDs, tx.touch)
+	})
+}
+
+// touch 以 TaskDetailsChanged 記錄 tag 或 blocker 的變更
+func (t *eventTaskRepository) touch(ctx context.Context, record *models.Task, now time.Time, change entities.FieldChange) error {
+	// 與 taskRepository.touch 相同取到微秒，記錄的 updated_at 才會與事件中的相同
+	now = now.UTC().Truncate(time.Microsecond)
+	err := t.emit(ctx, record.ID, &record.Version, func(*models.Task) []taskEvent {
+		event := taskEvent{Type: constants.TaskDetailsChanged, Version: record.Version + 1, Data: eventData{UpdatedAt: eventTime(now)}}
+		event.Data.Changes = []entities.FieldChange{change}
+		return []taskEvent{event}
+	})
+	if err != nil {
+		return err
+	}
+	return t.recordTouched(ctx, record, now, change)
+}
+
+// emit 先寫入事件再由事件更新 tasks 表，build 依事件還原的目前狀態產生事件，
+// version 為 nil 表示新增 task，否則須與目前狀態的 version 相同
+func (t *eventTaskRepository) emit(ctx context.Context, id string, version *int, build func(state *models.Task) []taskEvent) error {
+	state, found, err := t.loadState(ctx, id, entities.AsOf{})
+	if err != nil {
+		return err
+	}
+	switch {
+	case version == nil && found:
+		return fmt.Errorf("task %s already has events", id)
+	case version == nil:
+	case !found:
+		return fmt.Errorf("task %s has no events, run backfill-events to record tasks written without event sourcing", id)
+	case state == nil:
+		return customError.TaskNotFound.Errorf("task %s was purged", id)
+	case state.Version != *version:
+		return customError.TaskVersionConflict.Errorf("task %s version %d is stale", id, *version)
+	}
+	if state, err = t.appendEvents(ctx, id, state, build(state)); err != nil {
+		return err
+	}
+	if err = t.project(ctx, id, version != nil, state); err != nil {
+		// id 為 uuid，違反 unique index 只會是 position 被其他 task 佔用
+		if state != nil && t.dialect.IsUniqueViolation(err) {
+			return customError.TaskPositionConflict.Wrapf(err, "position %s of task %s is taken", state.Position, id)
+		}
+		return err
+	}
+	return nil
+}
+
+// appendEvents 將事件依序寫入 task_events 並套用到 state，累積的事件數跨過 snapshotInterval 的倍數時保存 snapshot
+func (t *eventTaskRepository) appendEvents(ctx context.Context, id string, state *models.Task, events []taskEvent) (*models.Task, error) {
+	var seq int64
+	if err := t.conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(seq), 0) FROM task_events WHERE task_id = ?", id).Scan(&seq); err != nil {
+		t.logger.Error("Find task event seq error", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	first, now, actor := seq+1, audit.Now(), audit.Actor(ctx)
+	for _, event := range events {
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			return nil, err
+		}
+		seq++
+		stored := models.TaskEvent{TaskID: id, Seq: seq, Type: string(event.Type), Version: event.Version, Data: string(data), Actor: actor}
+		_, err = t.conn.ExecContext(ctx, "INSERT INTO task_events (task_id, seq, type, version, data, actor, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+			stored.TaskID, stored.Seq, stored.Type, stored.Version, stored.Data, stored.Actor, now)
+		if err != nil {
+			// 同一個 task 的 seq 不能重複，同時寫入時其中一個會因 unique index 失敗
+			if t.dialect.IsUniqueViolation(err) {
+				return nil, customError.TaskVersionConflict.Wrapf(err, "task %s was changed concurrently", id)
+			}
+			t.logger.Error("Execute insert task event error", zap.String("id", id), zap.String("type", stored.Type), zap.Error(err))
+			return nil, err
+		}
+		if state, err = applyEvent(state, &stored); err != nil {
+			return nil, err
+		}
+	}
+	interval := t.snapshotInterval
+	if interval <= 0 || state == nil || seq/interval == (first-1)/interval {
+		return state, nil
+	}
+	return state, t.saveSnapshot(ctx, state, seq, now)
+}
+
+func (t *eventTaskRepository) saveSnapshot(ctx context.Context, state *models.Task, seq int64, now time.Time) error {
+	encoded, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	_, err = t.conn.ExecContext(ctx, "INSERT INTO task_snapshots (task_id, seq, version, state, created_at) VALUES (?, ?, ?, ?, ?)",
+		state.ID, seq, state.Version, string(encoded), now)
+	if err != nil {
+		t.logger.Error("Execute insert task snapshot error", zap.String("id", state.ID), zap.Int64("seq", seq), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// asOfCondition 依 AsOf 過濾 task_events 與 task_snapshots，兩者的欄位名稱相同
+func asOfCondition(asOf entities.AsOf) (string, []interface{}) {
+	switch {
+	case asOf.Version != nil:
+		return " AND version <= ?", []interface{}{*asOf.Version}
+	case asOf.At != nil:
+		return " AND created_at <= ?", []interface{}{asOf.At.UTC()}
+	}
+	return "", nil
+}
+
+// loadState 由最近的 snapshot 與其後的事件還原 task，found 為 false 表示沒有符合條件的事件，
+// state 為 nil 且 found 為 true 表示 task 已永久刪除
+func (t *eventTaskRepository) loadState(ctx context.Context, id string, asOf entities.AsOf) (state *models.Task, found bool, err error) {
+	condition, args := asOfCondition(asOf)
+	var snapshot models.TaskSnapshot
+	err = t.conn.QueryRowContext(ctx, "SELECT "+snapshotColumns+" FROM task_snapshots WHERE task_id = ?"+condition+" ORDER BY seq DESC LIMIT 1",
+		append([]interface{}{id}, args...)...).
+		Scan(&snapshot.TaskID, &snapshot.Seq, &snapshot.Version, &snapshot.State, &snapshot.CreatedAt)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+	case err != nil:
+		t.logger.Error("Find task snapshot error", zap.String("id", id), zap.Error(err))
+		return nil, false, err
+	default:
+		if err = json.Unmarshal([]byte(snapshot.State), &state); err != nil {
+			return nil, false, fmt.Errorf("decode snapshot %d of task %s: %w", snapshot.Seq, id, err)
+		}
+		found = true
+	}
+	events, err := t.queryEvents(ctx, "SELECT "+eventColumns+" FROM task_events WHERE task_id = ? AND seq > ?"+condition+" ORDER BY seq",
+		append([]interface{}{id, snapshot.Seq}, args...)...)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, event := range events {
+		if state, err = applyEvent(state, event); err != nil {
+			return nil, false, err
+		}
+		found = true
+	}
+	return state, found, nil
+}
+
+func (t *eventTaskRepository) queryEvents(ctx context.Context, query string, args ...interface{}) ([]*models.TaskEvent, error) {
+	rows, err := t.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		t.logger.Error("Query task events error", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	result := make([]*models.TaskEvent, 0)
+	for rows.Next() {
+		var event models.TaskEvent
+		if err = rows.Scan(&event.ID, &event.TaskID, &event.Seq, &event.Type, &event.Version, &event.Data, &event.Actor, &event.CreatedAt); err != nil {
+			t.logger.Error("Scan task event error", zap.Error(err))
+			return nil, err
+		}
+		result = append(result, &event)
+	}
+	if err = rows.Err(); err != nil {
+		t.logger.Error("Iterate task event rows error", zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+// ListEvents 依 seq 由舊到新排列，只列出 seq 大於 afterSeq 的事件
+func (t *eventTaskRepository) ListEvents(ctx context.Context, id string, afterSeq int64, size int) (result []*models.TaskEvent, err error) {
+	ctx, done := t.timeouts.apply(ctx, "list_task_events")
+	defer done(&err)
+	return t.queryEvents(ctx, "SELECT "+eventColumns+" FROM task_events WHERE task_id = ? AND seq > ? ORDER BY seq LIMIT ?", id, afterSeq, size)
+}
+
+func (t *eventTaskRepository) FindAsOf(ctx context.Context, id string, asOf entities.AsOf) (task *models.Task, err error) {
+	ctx, done := t.timeouts.apply(ctx, "find_task_as_of")
+	defer done(&err)
+	state, found, err := t.loadState(ctx, id, asOf)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, customError.TaskNotFound.Errorf("task %s has no events at the requested point", id)
+	}
+	if state == nil {
+		return nil, customError.TaskNotFound.Errorf("task %s was purged at the requested point", id)
+	}
+	return state, nil
+}
+
+// projectionState 讀取 tasks 表與事件還原的所有 task，以 id 對應
+func (t *eventTaskRepository) projectionState(ctx context.Context) (rows map[string]*models.Task, states map[string]*models.Task, err error) {
+	projected, err := t.queryTasks(ctx, "SELECT "+taskColumns+" FROM tasks")
+	if err != nil {
+		return nil, nil, err
+	}
+	rows = make(map[string]*models.Task, len(projected))
+	for _, task := range projected {
+		rows[task.ID] = canonicalTask(task)
+	}
+	ids, err := t.queryStrings(ctx, "SELECT DISTINCT task_id FROM task_events")
+	if err != nil {
+		return nil, nil, err
+	}
+	states = make(map[string]*models.Task, len(ids))
+	for id := range ids {
+		state, _, err := t.loadState(ctx, id, entities.AsOf{})
+		if err != nil {
+			return nil, nil, err
+		}
+		states[id] = state
+	}
+	return rows, states, nil
+}
+
+// sortedIDs 依 id 排序，讓重建的順序固定
+func sortedIDs(maps ...map[string]*models.Task) []string {
+	seen := make(map[string]bool)
+	for _, values := range maps {
+		for id := range values {
+			seen[id] = true
+		}
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// RebuildProjection 以事件還原的狀態覆寫 tasks 表，沒有事件的 task 會被刪除，reminded_at 等不屬於事件的欄位保留原值
+func (t *eventTaskRepository) RebuildProjection(ctx context.Context, dryRun bool) (result *entities.ProjectionRebuild, err error) {
+	ctx, done := t.timeouts.apply(ctx, "rebuild_projection")
+	defer done(&err)
+	result = &entities.ProjectionRebuild{DryRun: dryRun}
+	err = t.withTx(ctx, func(tx *eventTaskRepository) error {
+		rows, states, err := tx.projectionState(ctx)
+		if err != nil {
+			return err
+		}
+		for _, id := range sortedIDs(rows, states) {
+			row, state := rows[id], states[id]
+			if state != nil {
+				result.Tasks++
+			}
+			switch {
+			case state == nil && row == nil:
+				continue
+			case state == nil:
+				result.Deleted++
+			case row == nil:
+				result.Inserted++
+			case reflect.DeepEqual(row, state):
+				continue
+			default:
+				result.Updated++
+			}
+			if dryRun {
+				continue
+			}
+			if err = tx.project(ctx, id, row != nil, state); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// project 將 state 寫入 tasks 表，state 為 nil 時刪除 task 與其關聯
+func (t *eventTaskRepository) project(ctx context.Context, id string, exists bool, state *models.Task) error {
+	timeArg := func(value *string) interface{} {
+		if value == nil {
+			return nil
+		}
+		return models.ParseTime(*value)
+	}
+	var err error
+	switch {
+	case state == nil:
+		if _, err = t.conn.ExecContext(ctx, "DELETE FROM task_tags WHERE task_id = ?", id); err != nil {
+			break
+		}
+		if _, err = t.conn.ExecContext(ctx, "DELETE FROM task_dependencies WHERE task_id = ? OR blocker_id = ?", id, id); err != nil {
+			break
+		}
+		_, err = t.conn.ExecContext(ctx, "DELETE FROM tasks WHERE id = ?", id)
+	case exists:
+		_, err = t.conn.ExecContext(ctx, "UPDATE tasks SET name = ?, status = ?, version = ?, created_at = ?, updated_at = ?, deleted_at = ?,"+
+			" due_at = ?, remind_at = ?, priority = ?, position = ?, parent_id = ?, recurrence = ? WHERE id = ?",
+			state.Name, state.Status, state.Version, timeArg(&state.CreatedAt), timeArg(&state.UpdatedAt), timeArg(state.DeletedAt),
+			timeArg(state.DueAt), timeArg(state.RemindAt), state.Priority, positionArg(state.Position), state.ParentID, state.Recurrence, id)
+	default:
+		_, err = t.conn.ExecContext(ctx, "INSERT INTO tasks (id, name, status, version, created_at, updated_at, deleted_at,"+
+			" due_at, remind_at, priority, position, parent_id, recurrence) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			id, state.Name, state.Status, state.Version, timeArg(&state.CreatedAt), timeArg(&state.UpdatedAt), timeArg(state.DeletedAt),
+			timeArg(state.DueAt), timeArg(state.RemindAt), state.Priority, positionArg(state.Position), state.ParentID, state.Recurrence)
+	}
+	if err != nil {
+		t.logger.Error("Execute project task error", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// BackfillEvents 為沒有任何事件的 task 補記 TaskCreated，例如啟用 event sourcing 前已存在的 task，
+// 已有事件的 task 不會變更，回傳補記的 task 數
+func (t *eventTaskRepository) BackfillEvents(ctx context.Context, dryRun bool) (backfilled int, err error) {
+	ctx, done := t.timeouts.apply(ctx, "backfill_task_events")
+	defer done(&err)
+	err = t.withTx(ctx, func(tx *eventTaskRepository) error {
+		tasks, err := tx.queryTasks(ctx, "SELECT "+taskColumns+" FROM tasks WHERE NOT EXISTS (SELECT 1 FROM task_events WHERE task_events.task_id = tasks.id) ORDER BY id")
+		if err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			backfilled++
+			if dryRun {
+				continue
+			}
+			seed := canonicalTask(task)
+			event := taskEvent{Type: constants.TaskCreated, Version: seed.Version, Data: eventData{Task: seed}}
+			if _, err = tx.appendEvents(ctx, seed.ID, nil, []taskEvent{event}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return backfilled, nil
+}
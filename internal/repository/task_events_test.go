@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"tasks/internal/audit"
+	"tasks/internal/dialect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// eventColumnNames eventColumns 查詢回傳的欄位
+var eventColumnNames = []string{"id", "task_id", "seq", "type", "version", "data", "actor", "created_at"}
+
+// replay 依序套用事件
+func replay(t *testing.T, state *models.Task, events []taskEvent) *models.Task {
+	for i, event := range events {
+		data, err := json.Marshal(event.Data)
+		assert.NoError(t, err)
+		state, err = applyEvent(state, &models.TaskEvent{TaskID: "task-1", Seq: int64(i + 1), Type: string(event.Type), Version: event.Version, Data: string(data)})
+		assert.NoError(t, err)
+	}
+	return state
+}
+
+func Test_canonicalTask(t *testing.T) {
+	deletedAt := "2024-01-02 09:00:00.1234567+08:00"
+	task := canonicalTask(&models.Task{ID: "task-1", CreatedAt: "2024-01-01 08:00:00+00:00", UpdatedAt: "2024-01-01T08:00:00.5Z", DeletedAt: &deletedAt, Blocked: true})
+
+	assert.Equal(t, "2024-01-01T08:00:00Z", task.CreatedAt)
+	assert.Equal(t, "2024-01-01T08:00:00.5Z", task.UpdatedAt)
+	assert.Equal(t, "2024-01-02T01:00:00.123456Z", *task.DeletedAt)
+	assert.False(t, task.Blocked)
+	assert.Nil(t, canonicalTask(nil))
+}
+
+func Test_createdEvent(t *testing.T) {
+	created := time.Date(2024, 1, 1, 8, 0, 0, 123456789, time.FixedZone("", 8*60*60))
+	task := entities.Task{ID: "task-1", Name: "Draft", Priority: constants.PriorityHigh, Position: "00000001", CreatedAt: created}
+
+	event := createdEvent(task)
+
+	assert.Equal(t, constants.TaskCreated, event.Type)
+	assert.Equal(t, &models.Task{ID: "task-1", Name: "Draft", Priority: int(constants.PriorityHigh), Position: "00000001",
+		CreatedAt: "2024-01-01T00:00:00.123456Z", UpdatedAt: "2024-01-01T00:00:00.123456Z"}, replay(t, nil, []taskEvent{event}))
+}
+
+func Test_patchEvents(t *testing.T) {
+	parentID := "task-0"
+	state := &models.Task{ID: "task-1", Name: "Draft", Status: 0, Priority: 1, Position: "00000001", Version: 1,
+		CreatedAt: "2024-01-01T00:00:00Z", UpdatedAt: "2024-01-01T00:00:00Z"}
+	now := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	types := func(events []taskEvent) []constants.TaskEventType {
+		result := make([]constants.TaskEventType, 0, len(events))
+		for _, event := range events {
+			result = append(result, event.Type)
+		}
+		return result
+	}
+
+	t.Run("patch splits name, status and details", func(t *testing.T) {
+		name, status, priority, parent := "Final", constants.Complete, constants.PriorityHigh, &parentID
+		events := patchEvents(state, entities.TaskPatch{ID: "task-1", Name: &name, Status: &status, Priority: &priority, ParentID: &parent}, 2, now)
+
+		assert.Equal(t, []constants.TaskEventType{constants.TaskRenamed, constants.TaskStatusChanged, constants.TaskDetailsChanged}, types(events))
+		assert.Equal(t, 0, *events[1].Data.From)
+		expected := *state
+		expected.Name, expected.Status, expected.Priority, expected.ParentID, expected.Version, expected.UpdatedAt = "Final", 1, int(constants.PriorityHigh), &parentID, 2, "2024-01-02T00:00:00Z"
+		assert.Equal(t, &expected, replay(t, state, events))
+	})
+
+	t.Run("unchanged fields are left out", func(t *testing.T) {
+		name, due := "Draft", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+		dueAt := &due
+		events := patchEvents(state, entities.TaskPatch{ID: "task-1", Name: &name, DueAt: &dueAt}, 2, now)
+
+		assert.Equal(t, []constants.TaskEventType{constants.TaskDetailsChanged}, types(events))
+		assert.Equal(t, []entities.FieldChange{{Field: "due_at", Before: nil, After: "2024-02-01T00:00:00Z"}}, events[0].Data.Changes)
+	})
+
+	t.Run("a write without changes still records the version", func(t *testing.T) {
+		task := entities.Task{ID: "task-1", Name: "Draft", Priority: 1}
+		events := patchEvents(state, replacePatch(task), 2, now)
+
+		assert.Equal(t, []constants.TaskEventType{constants.TaskDetailsChanged}, types(events))
+		assert.Empty(t, events[0].Data.Changes)
+		expected := *state
+		expected.Version, expected.UpdatedAt = 2, "2024-01-02T00:00:00Z"
+		assert.Equal(t, &expected, replay(t, state, events))
+	})
+}
+
+func Test_applyEvent(t *testing.T) {
+	t.Run("event before creation", func(t *testing.T) {
+		_, err := applyEvent(nil, &models.TaskEvent{TaskID: "task-1", Seq: 1, Type: string(constants.TaskRenamed), Data: `{"name":"a"}`})
+		assert.EqualError(t, err, "event 1 of task task-1 TaskRenamed before the task was created")
+	})
+
+	t.Run("unknown type", func(t *testing.T) {
+		_, err := applyEvent(&models.Task{}, &models.TaskEvent{TaskID: "task-1", Seq: 2, Type: "TaskArchived", Data: `{}`})
+		assert.EqualError(t, err, "event 2 of task task-1 has unknown type TaskArchived")
+	})
+
+	t.Run("malformed data", func(t *testing.T) {
+		_, err := applyEvent(&models.Task{}, &models.TaskEvent{TaskID: "task-1", Seq: 3, Type: string(constants.TaskRenamed), Data: `{`})
+		assert.ErrorContains(t, err, "decode event 3 of task task-1")
+	})
+}
+
+func Test_eventTaskRepository(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewEventTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop(), 2)
+	ctx := audit.WithActor(context.Background(), "alice")
+
+	// expectState 預期由事件還原 task 目前的狀態
+	expectState := func(id string, events *sqlmock.Rows) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT " + snapshotColumns + " FROM task_snapshots WHERE task_id = ?")).
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows([]string{"task_id", "seq", "version", "state", "created_at"}))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT "+eventColumns+" FROM task_events WHERE task_id = ? AND seq > ? ORDER BY seq")).
+			WithArgs(id, int64(0)).
+			WillReturnRows(events)
+	}
+	created := `{"task":{"id":"task-1","name":"Task","status":0,"priority":1,"position":"00000001","version":1,` +
+		`"created_at":"2024-01-01T00:00:00Z","updated_at":"2024-01-01T00:00:00Z"}}`
+
+	t.Run("patch appends events before updating the projection", func(t *testing.T) {
+		name := "Renamed"
+		mock.ExpectBegin()
+		expectRecord(mock, "task-1", 1, nil)
+		expectState("task-1", sqlmock.NewRows(eventColumnNames).AddRow(1, "task-1", 1, string(constants.TaskCreated), 1, created, "alice", time.Now()))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(MAX(seq), 0) FROM task_events WHERE task_id = ?")).
+			WithArgs("task-1").
+			WillReturnRows(sqlmock.NewRows([]string{"seq"}).AddRow(1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO task_events (task_id, seq, type, version, data, actor, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)")).
+			WithArgs("task-1", int64(2), string(constants.TaskRenamed), 2, sqlmock.AnyArg(), "alice", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO task_snapshots (task_id, seq, version, state, created_at) VALUES (?, ?, ?, ?, ?)")).
+			WithArgs("task-1", int64(2), 2, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE tasks SET name = ?, status = ?, version = ?")).
+			WithArgs(name, 0, 2, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, nil, 1, "00000001", nil, nil, "task-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(" FROM tasks WHERE id = ?") + "$").
+			WithArgs("task-1").
+			WillReturnRows(sqlmock.NewRows(taskColumnNames).AddRow("task-1", name, 0, 2, time.Now(), time.Now(), nil, nil, nil, 1, "00000001", nil, nil, false))
+		expectAudit(mock, "task-1", constants.AuditUpdate)
+		mock.ExpectCommit()
+
+		err := repo.Patch(ctx, entities.TaskPatch{ID: "task-1", Name: &name}, 1)
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("stale version is rejected before any event is written", func(t *testing.T) {
+		name := "Renamed"
+		mock.ExpectBegin()
+		expectRecord(mock, "task-1", 1, nil)
+		expectState("task-1", sqlmock.NewRows(eventColumnNames).AddRow(1, "task-1", 1, string(constants.TaskCreated), 1, created, "alice", time.Now()))
+		mock.ExpectRollback()
+
+		err := repo.Patch(ctx, entities.TaskPatch{ID: "task-1", Name: &name}, 0)
+		assert.True(t, errors.Is(err, customError.TaskVersionConflict))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failed event leaves the projection untouched", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectState("task-1", sqlmock.NewRows(eventColumnNames))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(MAX(seq), 0) FROM task_events")).
+			WillReturnRows(sqlmock.NewRows([]string{"seq"}).AddRow(0))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO task_events")).
+			WillReturnError(errors.New("disk full"))
+		mock.ExpectRollback()
+
+		task := entities.NewTask("Task")
+		task.ID = "task-1"
+		err := repo.Create(ctx, task)
+		assert.EqualError(t, err, "disk full")
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("as-of version starts from the latest snapshot", func(t *testing.T) {
+		version := 3
+		state, _ := json.Marshal(&models.Task{ID: "task-1", Name: "Snapshot", Version: 2})
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT "+snapshotColumns+" FROM task_snapshots WHERE task_id = ? AND version <= ? ORDER BY seq DESC LIMIT 1")).
+			WithArgs("task-1", 3).
+			WillReturnRows(sqlmock.NewRows([]string{"task_id", "seq", "version", "state", "created_at"}).AddRow("task-1", 4, 2, string(state), time.Now()))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT "+eventColumns+" FROM task_events WHERE task_id = ? AND seq > ? AND version <= ? ORDER BY seq")).
+			WithArgs("task-1", int64(4), 3).
+			WillReturnRows(sqlmock.NewRows(eventColumnNames).
+				AddRow(5, "task-1", 5, string(constants.TaskRenamed), 3, `{"name":"Latest","updated_at":"2024-01-05T00:00:00Z"}`, "alice", time.Now()))
+
+		task, err := repo.FindAsOf(ctx, "task-1", entities.AsOf{Version: &version})
+		assert.NoError(t, err)
+		assert.Equal(t, "Latest", task.Name)
+		assert.Equal(t, 3, task.Version)
+		assert.Equal(t, "2024-01-05T00:00:00Z", task.UpdatedAt)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("as-of before the first event", func(t *testing.T) {
+		at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery(regexp.QuoteMeta("FROM task_snapshots WHERE task_id = ? AND created_at <= ?")).
+			WithArgs("task-1", at).
+			WillReturnRows(sqlmock.NewRows([]string{"task_id", "seq", "version", "state", "created_at"}))
+		mock.ExpectQuery(regexp.QuoteMeta("FROM task_events WHERE task_id = ? AND seq > ? AND created_at <= ?")).
+			WithArgs("task-1", int64(0), at).
+			WillReturnRows(sqlmock.NewRows(eventColumnNames))
+
+		_, err := repo.FindAsOf(ctx, "task-1", entities.AsOf{At: &at})
+		assert.True(t, errors.Is(err, customError.TaskNotFound))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("list events", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT "+eventColumns+" FROM task_events WHERE task_id = ? AND seq > ? ORDER BY seq LIMIT ?")).
+			WithArgs("task-1", int64(3), 2).
+			WillReturnRows(sqlmock.NewRows(eventColumnNames).
+				AddRow(4, "task-1", 4, string(constants.TaskDeleted), 3, `{}`, "alice", "2024-01-04 00:00:00+00:00"))
+
+		events, err := repo.ListEvents(ctx, "task-1", 3, 2)
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+		assert.Equal(t, int64(4), events[0].Seq)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
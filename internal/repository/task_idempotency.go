@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"tasks/domain/entities"
+	customError "tasks/errors"
+)
+
+// CompleteIdempotencyKey 與 task 的變更在同一個 transaction 中保存回應，rollback 時 key 仍是處理中
+func (t *taskRepository) CompleteIdempotencyKey(ctx context.Context, lease entities.IdempotencyLease, response entities.IdempotentResponse) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "complete_idempotency_key")
+	defer done(&err)
+	rows, err := t.conn.ExecContext(ctx, "UPDATE idempotency_keys SET status_code = ?, response = ?"+
+		" WHERE idempotency_key = ? AND lease_token = ? AND response IS NULL",
+		response.StatusCode, string(response.Body), lease.Key, lease.Token)
+	if err != nil {
+		t.logger.Error("Execute complete idempotency key error", zap.String("key", lease.Key), zap.Error(err))
+		return err
+	}
+	effectRows, err := rows.RowsAffected()
+	if err != nil {
+		t.logger.Error("Execute complete idempotency key error", zap.String("key", lease.Key), zap.Error(err))
+		return err
+	}
+	if effectRows == 0 {
+		return customError.IdempotencyKeyInProgress.Errorf("idempotency key %s was taken over by another request", lease.Key)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"tasks/domain/entities"
+	customError "tasks/errors"
+	"tasks/internal/dialect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func Test_taskRepository_CompleteIdempotencyKey(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	lease := entities.IdempotencyLease{Key: "key-1", Token: "token-1"}
+	response := entities.IdempotentResponse{StatusCode: 201, Body: []byte(`{"id":"task-1"}`)}
+	query := regexp.QuoteMeta("UPDATE idempotency_keys SET status_code = ?, response = ? WHERE idempotency_key = ? AND lease_token = ? AND response IS NULL")
+
+	t.Run("stores the response", func(t *testing.T) {
+		mock.ExpectExec(query).
+			WithArgs(201, `{"id":"task-1"}`, "key-1", "token-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.CompleteIdempotencyKey(ctx, lease, response))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("lease taken over", func(t *testing.T) {
+		mock.ExpectExec(query).
+			WithArgs(201, `{"id":"task-1"}`, "key-1", "token-1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.CompleteIdempotencyKey(ctx, lease, response)
+		assert.True(t, errors.Is(err, customError.IdempotencyKeyInProgress))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
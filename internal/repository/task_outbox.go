@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"strings"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	"tasks/internal/audit"
+)
+
+// enqueueWebhooks 在目前的 transaction 中將變更寫入 webhook outbox，每個訂閱該事件的 webhook 一筆，
+// task 的變更 rollback 時事件也不會送出
+func (t *taskRepository) enqueueWebhooks(ctx context.Context, action constants.AuditAction, before, after *models.Task, extra []entities.FieldChange) error {
+	event := constants.WebhookEventFor(action)
+	webhookIDs, err := t.subscribedWebhooks(ctx, event)
+	if err != nil || len(webhookIDs) == 0 {
+		return err
+	}
+	record := after
+	if record == nil {
+		record = before
+	}
+	task, err := json.Marshal(canonicalTask(record))
+	if err != nil {
+		return err
+	}
+	eventID, now := uuid.New().String(), audit.Now()
+	payload, err := json.Marshal(entities.WebhookPayload{
+		ID:         eventID,
+		Event:      event,
+		Action:     action,
+		Actor:      audit.Actor(ctx),
+		TaskID:     record.ID,
+		Version:    record.Version,
+		Task:       task,
+		Changes:    append(diffTask(before, after), extra...),
+		OccurredAt: now,
+	})
+	if err != nil {
+		return err
+	}
+	for _, webhookID := range webhookIDs {
+		_, err = t.conn.ExecContext(ctx, "INSERT INTO webhook_outbox (event_id, webhook_id, event, task_id, payload, status, attempts, next_attempt_at, created_at)"+
+			" VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)",
+			eventID, webhookID, string(event), record.ID, string(payload), string(constants.DeliveryPending), 0, now, now)
+		if err != nil {
+			t.logger.Error("Execute insert webhook outbox error", zap.String("id", record.ID), zap.String("webhook_id", webhookID), zap.Error(err))
+			return err
+		}
+	}
+	return nil
+}
+
+// subscribedWebhooks 啟用中且訂閱 event 的 webhook id
+func (t *taskRepository) subscribedWebhooks(ctx context.Context, event constants.WebhookEvent) ([]string, error) {
+	rows, err := t.conn.QueryContext(ctx, "SELECT id, events FROM webhooks WHERE active = 1 ORDER BY id")
+	if err != nil {
+		t.logger.Error("List subscribed webhooks error", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	result := make([]string, 0)
+	for rows.Next() {
+		var id, events string
+		if err = rows.Scan(&id, &events); err != nil {
+			t.logger.Error("Scan webhook error", zap.Error(err))
+			return nil, err
+		}
+		for _, subscribed := range strings.Split(events, ",") {
+			if subscribed == string(event) {
+				result = append(result, id)
+				break
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		t.logger.Error("Iterate webhook rows error", zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/internal/audit"
+	"tasks/internal/dialect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// payloadArg 解碼寫入 outbox 的事件內容供後續檢查
+type payloadArg struct {
+	payload *entities.WebhookPayload
+}
+
+func (a payloadArg) Match(value driver.Value) bool {
+	text, ok := value.(string)
+	return ok && json.Unmarshal([]byte(text), a.payload) == nil
+}
+
+func Test_taskRepository_enqueueWebhooks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := audit.WithActor(context.Background(), "alice")
+
+	t.Run("one row per subscribed webhook", func(t *testing.T) {
+		var payload entities.WebhookPayload
+		mock.ExpectBegin()
+		mock.ExpectPrepare("INSERT INTO tasks").
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectRecord(mock, "task-1", 0, nil)
+		expectAuditHead(mock, "")
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO task_audit")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectAuditTail(mock)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, events FROM webhooks WHERE active = 1")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "events"}).
+				AddRow("hook-1", "task.created,task.deleted").
+				AddRow("hook-2", "task.updated").
+				AddRow("hook-3", "task.created"))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO webhook_outbox (event_id, webhook_id, event, task_id, payload, status, attempts, next_attempt_at, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)")).
+			WithArgs(sqlmock.AnyArg(), "hook-1", "task.created", "task-1", payloadArg{&payload}, "pending", 0, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO webhook_outbox")).
+			WithArgs(sqlmock.AnyArg(), "hook-3", "task.created", "task-1", sqlmock.AnyArg(), "pending", 0, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		task := entities.NewTask("Task")
+		task.ID = "task-1"
+		err := repo.Create(ctx, task)
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.Equal(t, constants.WebhookTaskCreated, payload.Event)
+		assert.Equal(t, constants.AuditCreate, payload.Action)
+		assert.Equal(t, "alice", payload.Actor)
+		assert.Equal(t, "task-1", payload.TaskID)
+		assert.Len(t, payload.ID, 36)
+		assert.Contains(t, string(payload.Task), `"name":"Task"`)
+	})
+
+	t.Run("failed outbox write rolls back the change", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectRecord(mock, "task-1", 1, nil)
+		mock.ExpectPrepare("UPDATE tasks SET").
+			ExpectExec().
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectRecord(mock, "task-1", 2, nil)
+		expectAuditHead(mock, "")
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO task_audit")).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectAuditTail(mock)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, events FROM webhooks WHERE active = 1")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "events"}).AddRow("hook-2", "task.updated"))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO webhook_outbox")).
+			WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
+
+		name := "Renamed"
+		err := repo.Patch(ctx, entities.TaskPatch{ID: "task-1", Name: &name}, 1)
+		assert.EqualError(t, err, "db error")
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -1,27 +1,142 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"go.uber.org/zap"
+	"strings"
+	"tasks/constants"
 	"tasks/domain/entities"
 	"tasks/domain/models"
 	customError "tasks/errors"
+	"tasks/internal/audit"
+	"tasks/internal/dialect"
+	"time"
 )
 
+// dbConn *sql.DB 與 *sql.Tx 共用的操作
+type dbConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// reboundConn 執行前依 dialect 轉換 SQL 中的 placeholder
+type reboundConn struct {
+	conn    dbConn
+	dialect dialect.Dialect
+}
+
+func (c reboundConn) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return c.conn.ExecContext(ctx, c.dialect.Rebind(query), args...)
+}
+
+func (c reboundConn) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return c.conn.PrepareContext(ctx, c.dialect.Rebind(query))
+}
+
+func (c reboundConn) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return c.conn.QueryContext(ctx, c.dialect.Rebind(query), args...)
+}
+
+func (c reboundConn) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return c.conn.QueryRowContext(ctx, c.dialect.Rebind(query), args...)
+}
+
 type taskRepository struct {
-	conn   *sql.DB
-	logger *zap.Logger
+	db *sql.DB
+	// tx 不為 nil 表示 repository 綁定在 transaction 上
+	tx       *sql.Tx
+	conn     dbConn
+	dialect  dialect.Dialect
+	timeouts Timeouts
+	logger   *zap.Logger
+}
+
+func NewTaskRepository(conn *sql.DB, dialect dialect.Dialect, timeouts Timeouts, logger *zap.Logger) TaskRepository {
+	return &taskRepository{
+		db:       conn,
+		conn:     reboundConn{conn: conn, dialect: dialect},
+		dialect:  dialect,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+// WithTx 在同一個 transaction 中執行 fn，fn 回傳錯誤或 panic 時 rollback，
+// 已在 transaction 中時直接沿用外層的 transaction
+func (t *taskRepository) WithTx(ctx context.Context, fn func(repo TaskRepository) error) error {
+	return t.withTx(ctx, func(tx *taskRepository) error {
+		return fn(tx)
+	})
+}
+
+func (t *taskRepository) withTx(ctx context.Context, fn func(tx *taskRepository) error) (err error) {
+	if t.tx != nil {
+		return fn(t)
+	}
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.logger.Error("Begin tx error", zap.Error(err))
+		return err
+	}
+	txRepo := &taskRepository{
+		db:       t.db,
+		tx:       tx,
+		conn:     reboundConn{conn: tx, dialect: t.dialect},
+		dialect:  t.dialect,
+		timeouts: t.timeouts,
+		logger:   t.logger,
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				t.logger.Error("Rollback tx error", zap.Error(rollbackErr))
+			}
+			panic(p)
+		}
+	}()
+	if err = fn(txRepo); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			t.logger.Error("Rollback tx error", zap.Error(rollbackErr))
+		}
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		t.logger.Error("Commit tx error", zap.Error(err))
+		return err
+	}
+	return nil
 }
 
-func NewTaskRepository(conn *sql.DB, logger *zap.Logger) TaskRepository {
-	return &taskRepository{conn: conn, logger: logger}
+// blockedColumn task 是否有未完成且不在垃圾桶中的 blocker，status 1 為 constants.Complete
+const blockedColumn = "EXISTS (SELECT 1 FROM task_dependencies d JOIN tasks b ON b.id = d.blocker_id WHERE d.task_id = tasks.id AND b.status <> 1 AND b.deleted_at IS NULL)"
+
+const taskColumns = "id,name,status,version,created_at,COALESCE(updated_at,created_at),deleted_at,due_at,remind_at,priority,COALESCE(position,''),parent_id,recurrence," + blockedColumn
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
-func (t *taskRepository) Find(id string) (*models.Task, error) {
+// scanTask 依 taskColumns 的順序讀取，extra 為 taskColumns 之後額外查詢的欄位
+func scanTask(row rowScanner, extra ...interface{}) (*models.Task, error) {
 	task := models.Task{}
-	row := t.conn.QueryRow("SELECT id,name,status,version,created_at FROM tasks WHERE id = ?", id)
-	err := row.Scan(&task.ID, &task.Name, &task.Status, &task.Version, &task.CreatedAt)
+	dest := []interface{}{&task.ID, &task.Name, &task.Status, &task.Version, &task.CreatedAt, &task.UpdatedAt, &task.DeletedAt,
+		&task.DueAt, &task.RemindAt, &task.Priority, &task.Position, &task.ParentID, &task.Recurrence, &task.Blocked}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (t *taskRepository) Find(ctx context.Context, id string) (task *models.Task, err error) {
+	ctx, done := t.timeouts.apply(ctx, "find_task")
+	defer done(&err)
+	row := t.conn.QueryRowContext(ctx, "SELECT "+taskColumns+" FROM tasks WHERE id = ? AND deleted_at IS NULL", id)
+	task, err = scanTask(row)
 	if err != nil {
 		t.logger.Error("Find task error", zap.String("id", id), zap.Error(err))
 		if errors.Is(err, sql.ErrNoRows) {
@@ -29,73 +144,430 @@ func (t *taskRepository) Find(id string) (*models.Task, error) {
 		}
 		return nil, err
 	}
-	return &task, nil
+	return task, nil
 }
 
-func (t *taskRepository) List(param entities.TaskQueryParam) ([]*models.Task, error) {
-	rows, err := t.conn.Query("SELECT id,name,status,version FROM tasks LIMIT ? OFFSET ? ", param.Size, param.Offset)
-	defer rows.Close()
+func (t *taskRepository) List(ctx context.Context, param entities.TaskQueryParam) (result []*models.Task, err error) {
+	ctx, done := t.timeouts.apply(ctx, "list_tasks")
+	defer done(&err)
+	query, args := buildListQuery(t.dialect, param)
+	rows, err := t.conn.QueryContext(ctx, query, args...)
 	if err != nil {
 		t.logger.Error("List task error", zap.Any("param", param), zap.Error(err))
 		return nil, err
 	}
-	result := make([]*models.Task, 0)
+	defer rows.Close()
+	result = make([]*models.Task, 0)
 	for rows.Next() {
-		task := models.Task{}
-		err = rows.Scan(&task.ID, &task.Name, &task.Status, &task.Version)
+		task, err := scanTask(rows)
 		if err != nil {
 			t.logger.Error("Scan task error", zap.Any("param", param), zap.Error(err))
 			return nil, err
 		}
-		result = append(result, &task)
+		result = append(result, task)
+	}
+	if err = rows.Err(); err != nil {
+		t.logger.Error("Iterate task rows error", zap.Any("param", param), zap.Error(err))
+		return nil, err
 	}
 
 	return result, nil
 }
 
-func (t *taskRepository) Create(task entities.Task) error {
-	stmt, err := t.conn.Prepare("INSERT INTO tasks (id, name, status, version, created_at) VALUES (?, ?, ?, ?, ?)")
+func (t *taskRepository) Count(ctx context.Context, param entities.TaskQueryParam) (total int, err error) {
+	ctx, done := t.timeouts.apply(ctx, "count_tasks")
+	defer done(&err)
+	conditions, args := buildFilterConditions(t.dialect, param)
+	var query strings.Builder
+	query.WriteString("SELECT COUNT(*) FROM tasks")
+	writeWhere(&query, conditions)
+
+	if err = t.conn.QueryRowContext(ctx, query.String(), args...).Scan(&total); err != nil {
+		t.logger.Error("Count task error", zap.Any("param", param), zap.Error(err))
+		return 0, err
+	}
+	return total, nil
+}
+
+// sortColumns 允許排序的欄位白名單，避免將使用者輸入直接組進 SQL
+var sortColumns = map[constants.SortField]string{
+	constants.SortByCreatedAt: "created_at",
+	constants.SortByName:      "name",
+	constants.SortByStatus:    "status",
+	constants.SortByPriority:  "priority",
+	constants.SortByPosition:  "position",
+}
+
+func buildListQuery(dialect dialect.Dialect, param entities.TaskQueryParam) (string, []interface{}) {
+	conditions, args := buildFilterConditions(dialect, param)
+
+	column, ok := sortColumns[param.SortBy]
+	if !ok {
+		column = sortColumns[constants.SortByPosition]
+	}
+	ascending := param.Order != constants.OrderDesc
+	if param.Cursor != nil {
+		// 往前翻頁時反轉排序方向，結果由 service 再反轉回來
+		if param.Cursor.Backward {
+			ascending = !ascending
+		}
+		operator := "<"
+		if ascending {
+			operator = ">"
+		}
+		value := param.Cursor.Value
+		// 時間以 time.Time 綁定，交由 driver 轉成資料庫的時間格式
+		if text, ok := value.(string); ok && column == sortColumns[constants.SortByCreatedAt] {
+			value = models.ParseTime(text)
+		}
+		conditions = append(conditions, fmt.Sprintf("(%s %s ? OR (%s = ? AND id %s ?))", column, operator, column, operator))
+		args = append(args, value, value, param.Cursor.ID)
+	}
+	order := "DESC"
+	if ascending {
+		order = "ASC"
+	}
+
+	var query strings.Builder
+	query.WriteString("SELECT " + taskColumns + " FROM tasks")
+	writeWhere(&query, conditions)
+	query.WriteString(fmt.Sprintf(" ORDER BY %s %s, id %s LIMIT ? OFFSET ?", column, order, order))
+	args = append(args, param.Size, param.Offset)
+	return query.String(), args
+}
+
+func buildFilterConditions(dialect dialect.Dialect, param entities.TaskQueryParam) ([]string, []interface{}) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	if param.Trashed {
+		conditions = append(conditions, "deleted_at IS NOT NULL")
+	} else {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if len(param.Statuses) > 0 {
+		placeholders := make([]string, 0, len(param.Statuses))
+		for _, status := range param.Statuses {
+			placeholders = append(placeholders, "?")
+			args = append(args, status)
+		}
+		conditions = append(conditions, fmt.Sprintf("status IN (%s)", strings.Join(placeholders, ",")))
+	}
+	if len(param.Priorities) > 0 {
+		placeholders := make([]string, 0, len(param.Priorities))
+		for _, priority := range param.Priorities {
+			placeholders = append(placeholders, "?")
+			args = append(args, priority)
+		}
+		conditions = append(conditions, fmt.Sprintf("priority IN (%s)", strings.Join(placeholders, ",")))
+	}
+	if param.Keyword != "" {
+		conditions = append(conditions, dialect.Contains("name"))
+		args = append(args, "%"+escapeLike(param.Keyword)+"%")
+	}
+	if param.CreatedAfter != nil {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, *param.CreatedAfter)
+	}
+	if param.CreatedBefore != nil {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, *param.CreatedBefore)
+	}
+	if param.DueAfter != nil {
+		conditions = append(conditions, "due_at >= ?")
+		args = append(args, *param.DueAfter)
+	}
+	if param.DueBefore != nil {
+		conditions = append(conditions, "due_at < ?")
+		args = append(args, *param.DueBefore)
+	}
+	if names := distinct(param.TagsAny); len(names) > 0 {
+		in, values := inClause(names)
+		conditions = append(conditions, "id IN (SELECT tt.task_id FROM task_tags tt JOIN tags g ON g.id = tt.tag_id WHERE g.name IN "+in+")")
+		args = append(args, values...)
+	}
+	if names := distinct(param.TagsAll); len(names) > 0 {
+		in, values := inClause(names)
+		conditions = append(conditions, "id IN (SELECT tt.task_id FROM task_tags tt JOIN tags g ON g.id = tt.tag_id WHERE g.name IN "+in+
+			" GROUP BY tt.task_id HAVING COUNT(*) = ?)")
+		args = append(append(args, values...), len(names))
+	}
+	if param.ParentID != nil {
+		conditions = append(conditions, "parent_id = ?")
+		args = append(args, *param.ParentID)
+	}
+	if param.Overdue != nil {
+		// 與 entities.Task.IsOverdue 的判斷一致
+		if *param.Overdue {
+			conditions = append(conditions, "(status <> ? AND due_at < ?)")
+		} else {
+			conditions = append(conditions, "(status = ? OR due_at IS NULL OR due_at >= ?)")
+		}
+		args = append(args, constants.Complete, time.Now().UTC())
+	}
+	return conditions, args
+}
+
+// inClause 組出 IN 條件的 placeholder 與參數
+func inClause(values []string) (string, []interface{}) {
+	placeholders := make([]string, 0, len(values))
+	args := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		placeholders = append(placeholders, "?")
+		args = append(args, value)
+	}
+	return "(" + strings.Join(placeholders, ",") + ")", args
+}
+
+// distinct 去除重複的值並保留原本的順序
+func distinct(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// timeArg nil 時寫入 NULL
+func timeArg(value *time.Time) interface{} {
+	if value == nil {
+		return nil
+	}
+	return value.UTC()
+}
+
+// positionArg 空字串時寫入 NULL，position 有 unique index，讀取時以 COALESCE 轉回空字串
+func positionArg(position string) interface{} {
+	if position == "" {
+		return nil
+	}
+	return position
+}
+
+// sameTime 比較資料庫讀出的時間字串與新的時間是否相同
+func sameTime(stored *string, value *time.Time) bool {
+	if stored == nil || value == nil {
+		return stored == nil && value == nil
+	}
+	// mysql 只保存到 microsecond
+	return models.ParseTime(*stored).Truncate(time.Microsecond).Equal(value.Truncate(time.Microsecond))
+}
+
+func writeWhere(query *strings.Builder, conditions []string) {
+	if len(conditions) > 0 {
+		query.WriteString(" WHERE ")
+		query.WriteString(strings.Join(conditions, " AND "))
+	}
+}
+
+// escapeLike 以 ! 跳脫 LIKE 的萬用字元，避免 mysql 字串中反斜線的跳脫問題
+func escapeLike(s string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(s)
+}
+
+// Create 與 audit log 在同一個 transaction 中寫入
+func (t *taskRepository) Create(ctx context.Context, task entities.Task) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "create_task")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *taskRepository) error {
+		return tx.create(ctx, task)
+	})
+}
+
+func (t *taskRepository) create(ctx context.Context, task entities.Task) error {
+	stmt, err := t.conn.PrepareContext(ctx, "INSERT INTO tasks (id, parent_id, name, status, priority, position, version, created_at, updated_at, due_at, remind_at, recurrence) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
 	if err != nil {
 		t.logger.Error("Prepare insert stmt error", zap.Any("task", task), zap.Error(err))
 		return err
 	}
-	_, err = stmt.Exec(task.ID, task.Name, task.Status, task.Version, task.CreatedAt)
+	defer stmt.Close()
+	updatedAt := task.UpdatedAt
+	if updatedAt.IsZero() {
+		updatedAt = task.CreatedAt
+	}
+	_, err = stmt.ExecContext(ctx, task.ID, task.ParentID, task.Name, task.Status, task.Priority, positionArg(task.Position), task.Version, task.CreatedAt, updatedAt,
+		timeArg(task.DueAt), timeArg(task.RemindAt), task.Recurrence)
 	if err != nil {
+		// id 為 uuid，違反 unique index 只會是 position 被同時新增的 task 佔用
+		if t.dialect.IsUniqueViolation(err) {
+			return customError.TaskPositionConflict.Wrapf(err, "position %s of task %s is taken", task.Position, task.ID)
+		}
 		t.logger.Error("Execute insert stmt error", zap.Any("task", task), zap.Error(err))
 		return err
 	}
-	return nil
+	record, err := t.findRecord(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+	return t.record(ctx, constants.AuditCreate, nil, record)
 }
 
-func (t *taskRepository) Update(task entities.Task) error {
-	record, err := t.Find(task.ID)
+// Update 讀取與寫入在同一個 transaction 中完成
+func (t *taskRepository) Update(ctx context.Context, task entities.Task, expectedVersion *int) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "update_task")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *taskRepository) error {
+		return tx.update(ctx, task, expectedVersion)
+	})
+}
+
+func (t *taskRepository) update(ctx context.Context, task entities.Task, expectedVersion *int) error {
+	record, err := t.Find(ctx, task.ID)
 	if err != nil {
 		return err
 	}
+	if expectedVersion != nil && *expectedVersion != record.Version {
+		return customError.TaskVersionMismatch.Errorf("task %s version is %d, expected %d", task.ID, record.Version, *expectedVersion)
+	}
 	version := record.Version + 1
-	stmt, err := t.conn.Prepare("UPDATE tasks SET name = ?, status = ?, version = ? WHERE id = ? and version = ?")
+	query := "UPDATE tasks SET parent_id = ?, name = ?, status = ?, priority = ?, due_at = ?, remind_at = ?, recurrence = ?, version = ?, updated_at = ?"
+	// 提醒時間改變後需要重新提醒
+	if !sameTime(record.DueAt, task.DueAt) || !sameTime(record.RemindAt, task.RemindAt) {
+		query += ", reminded_at = NULL"
+	}
+	stmt, err := t.conn.PrepareContext(ctx, query+" WHERE id = ? and version = ?")
 	if err != nil {
 		t.logger.Error("Prepare update stmt error", zap.String("id", task.ID), zap.Error(err))
 		return err
 	}
-	name := task.Name
-	if task.Name == "" {
-		name = record.Name
+	defer stmt.Close()
+	rows, err := stmt.ExecContext(ctx, task.ParentID, task.Name, task.Status, task.Priority, timeArg(task.DueAt), timeArg(task.RemindAt), task.Recurrence,
+		version, audit.Now(), task.ID, record.Version)
+	if err != nil {
+		t.logger.Error("Execute update stmt error", zap.String("id", task.ID), zap.Error(err))
+		return err
 	}
-	_, err = stmt.Exec(name, task.Status, version, task.ID, record.Version)
+	effectRows, err := rows.RowsAffected()
 	if err != nil {
 		t.logger.Error("Execute update stmt error", zap.String("id", task.ID), zap.Error(err))
 		return err
 	}
-	return nil
+	if effectRows == 0 {
+		// 讀取後到寫入前已被其他請求修改
+		return customError.TaskVersionConflict.Errorf("task %s version %d is stale", task.ID, record.Version)
+	}
+	updated, err := t.findRecord(ctx, task.ID)
+	if err != nil {
+		return err
+	}
+	return t.record(ctx, constants.AuditUpdate, record, updated)
+}
+
+func (t *taskRepository) Patch(ctx context.Context, patch entities.TaskPatch, version int) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "patch_task")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *taskRepository) error {
+		return tx.patch(ctx, patch, version)
+	})
+}
+
+func (t *taskRepository) patch(ctx context.Context, patch entities.TaskPatch, version int) error {
+	// task 不存在時由下方的 UPDATE 回傳 TaskVersionConflict
+	record, err := t.findRecord(ctx, patch.ID)
+	if err != nil && !customError.TaskNotFound.Is(customError.CauseCustomError(err)) {
+		return err
+	}
+	var (
+		assignments []string
+		args        []interface{}
+	)
+	if patch.ParentID != nil {
+		assignments = append(assignments, "parent_id = ?")
+		args = append(args, *patch.ParentID)
+	}
+	if patch.Name != nil {
+		assignments = append(assignments, "name = ?")
+		args = append(args, *patch.Name)
+	}
+	if patch.Status != nil {
+		assignments = append(assignments, "status = ?")
+		args = append(args, *patch.Status)
+	}
+	if patch.Priority != nil {
+		assignments = append(assignments, "priority = ?")
+		args = append(args, *patch.Priority)
+	}
+	if patch.Position != nil {
+		assignments = append(assignments, "position = ?")
+		args = append(args, positionArg(*patch.Position))
+	}
+	if patch.DueAt != nil {
+		assignments = append(assignments, "due_at = ?")
+		args = append(args, timeArg(*patch.DueAt))
+	}
+	if patch.RemindAt != nil {
+		assignments = append(assignments, "remind_at = ?")
+		args = append(args, timeArg(*patch.RemindAt))
+	}
+	if patch.DueAt != nil || patch.RemindAt != nil {
+		assignments = append(assignments, "reminded_at = NULL")
+	}
+	if patch.Recurrence != nil {
+		assignments = append(assignments, "recurrence = ?")
+		args = append(args, *patch.Recurrence)
+	}
+	assignments = append(assignments, "version = ?", "updated_at = ?")
+	args = append(args, version+1, audit.Now(), patch.ID, version)
+
+	stmt, err := t.conn.PrepareContext(ctx, fmt.Sprintf("UPDATE tasks SET %s WHERE id = ? and version = ?", strings.Join(assignments, ", ")))
+	if err != nil {
+		t.logger.Error("Prepare patch stmt error", zap.String("id", patch.ID), zap.Error(err))
+		return err
+	}
+	defer stmt.Close()
+	rows, err := stmt.ExecContext(ctx, args...)
+	if err != nil {
+		if patch.Position != nil && t.dialect.IsUniqueViolation(err) {
+			return customError.TaskPositionConflict.Wrapf(err, "position %s of task %s is taken", *patch.Position, patch.ID)
+		}
+		t.logger.Error("Execute patch stmt error", zap.String("id", patch.ID), zap.Error(err))
+		return err
+	}
+	effectRows, err := rows.RowsAffected()
+	if err != nil {
+		t.logger.Error("Execute patch stmt error", zap.String("id", patch.ID), zap.Error(err))
+		return err
+	}
+	if effectRows == 0 {
+		return customError.TaskVersionConflict.Errorf("task %s version %d is stale", patch.ID, version)
+	}
+	updated, err := t.findRecord(ctx, patch.ID)
+	if err != nil {
+		return err
+	}
+	return t.record(ctx, constants.AuditUpdate, record, updated)
 }
 
-func (t *taskRepository) Delete(id string) error {
-	stmt, err := t.conn.Prepare("DELETE FROM tasks WHERE id = ?")
+// Delete 將 task 移到垃圾桶，cascade 為 true 時連同子孫 task 一起移到垃圾桶，
+// 否則還有未刪除的子 task 時回傳 TaskHasSubtasks
+func (t *taskRepository) Delete(ctx context.Context, id string, expectedVersion *int, cascade bool) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "delete_task")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *taskRepository) error {
+		return tx.delete(ctx, id, expectedVersion, cascade)
+	})
+}
+
+func (t *taskRepository) delete(ctx context.Context, id string, expectedVersion *int, cascade bool) error {
+	// 同一次刪除的 task 有相同的 deleted_at，還原時以此找出一起被刪除的子孫 task
+	now := audit.Now()
+	query := "UPDATE tasks SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL"
+	args := []interface{}{now, id}
+	if expectedVersion != nil {
+		query += " AND version = ?"
+		args = append(args, *expectedVersion)
+	}
+	stmt, err := t.conn.PrepareContext(ctx, query)
 	if err != nil {
 		return err
 	}
-	rows, err := stmt.Exec(id)
+	defer stmt.Close()
+	rows, err := stmt.ExecContext(ctx, args...)
 	if err != nil {
 		t.logger.Error("Execute delete stmt error", zap.String("id", id), zap.Error(err))
 		return err
@@ -106,7 +578,741 @@ func (t *taskRepository) Delete(id string) error {
 		return err
 	}
 	if effectRows == 0 {
-		return customError.TaskNotFound.New("task not found")
+		if expectedVersion == nil {
+			return customError.TaskNotFound.New("task not found")
+		}
+		record, err := t.Find(ctx, id)
+		if err != nil {
+			return err
+		}
+		return customError.TaskVersionMismatch.Errorf("task %s version is %d, expected %d", id, record.Version, *expectedVersion)
+	}
+	nodes, err := t.deleteTargets(ctx, id, cascade)
+	if err != nil {
+		return err
+	}
+	if descendants := nodeIDs(nodes[1:]); len(descendants) > 0 {
+		in, ids := inClause(descendants)
+		if _, err = t.conn.ExecContext(ctx, "UPDATE tasks SET deleted_at = ? WHERE deleted_at IS NULL AND id IN "+in, append([]interface{}{now}, ids...)...); err != nil {
+			t.logger.Error("Execute delete subtasks error", zap.String("id", id), zap.Error(err))
+			return err
+		}
+	}
+	// subtree 在刪除子孫 task 前查詢，只有 task 本身已帶有 deleted_at
+	deletedAt := now.Format(time.RFC3339Nano)
+	for _, node := range nodes {
+		before, after := node.Task, node.Task
+		before.DeletedAt, after.DeletedAt = nil, &deletedAt
+		if err = t.record(ctx, constants.AuditDelete, &before, &after); err != nil {
+			return err
+		}
 	}
 	return nil
 }
+
+// deleteTargets task 與其未刪除的子孫 task，有子孫 task 但 cascade 為 false 時回傳 TaskHasSubtasks
+func (t *taskRepository) deleteTargets(ctx context.Context, id string, cascade bool) ([]*models.TaskNode, error) {
+	nodes, err := t.subtree(ctx, id, constants.MaxTaskDepth, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) > 1 && !cascade {
+		return nil, customError.TaskHasSubtasks.Errorf("task %s has %d subtasks", id, len(nodes)-1)
+	}
+	return nodes, nil
+}
+
+// Restore 將 task 與跟它一起被刪除的子孫 task 移出垃圾桶，parent 仍在垃圾桶中時回傳 TaskParentDeleted
+func (t *taskRepository) Restore(ctx context.Context, id string) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "restore_task")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *taskRepository) error {
+		nodes, err := tx.restoreTargets(ctx, id)
+		if err != nil {
+			return err
+		}
+		in, ids := inClause(nodeIDs(nodes))
+		now := audit.Now()
+		_, err = tx.conn.ExecContext(ctx, "UPDATE tasks SET deleted_at = NULL, updated_at = ? WHERE deleted_at IS NOT NULL AND id IN "+in,
+			append([]interface{}{now}, ids...)...)
+		if err != nil {
+			t.logger.Error("Execute restore stmt error", zap.String("id", id), zap.Error(err))
+			return err
+		}
+		for _, node := range nodes {
+			after := node.Task
+			after.DeletedAt, after.UpdatedAt = nil, now.Format(time.RFC3339Nano)
+			if err = tx.record(ctx, constants.AuditRestore, &node.Task, &after); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// restoreTargets 垃圾桶中的 task 與跟它一起被刪除的子孫 task，parent 仍在垃圾桶中時回傳 TaskParentDeleted
+func (t *taskRepository) restoreTargets(ctx context.Context, id string) ([]*models.TaskNode, error) {
+	nodes, err := t.trashedSubtree(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	root := nodes[0]
+	if root.ParentID != nil {
+		var active int
+		if err = t.conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM tasks WHERE id = ? AND deleted_at IS NULL", *root.ParentID).Scan(&active); err != nil {
+			t.logger.Error("Find parent task error", zap.String("id", id), zap.Error(err))
+			return nil, err
+		}
+		if active == 0 {
+			return nil, customError.TaskParentDeleted.Errorf("parent task %s of task %s is deleted", *root.ParentID, id)
+		}
+	}
+	restored := []*models.TaskNode{root}
+	for _, node := range nodes[1:] {
+		if *node.DeletedAt == *root.DeletedAt {
+			restored = append(restored, node)
+		}
+	}
+	return restored, nil
+}
+
+// Purge 永久刪除垃圾桶中的 task 與其子孫 task，以及它們的 tag 與 dependency 關聯
+func (t *taskRepository) Purge(ctx context.Context, id string) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "purge_task")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *taskRepository) error {
+		nodes, err := tx.trashedSubtree(ctx, id)
+		if err != nil {
+			return err
+		}
+		in, ids := inClause(nodeIDs(nodes))
+		if _, err = tx.conn.ExecContext(ctx, "DELETE FROM task_tags WHERE task_id IN "+in, ids...); err != nil {
+			t.logger.Error("Execute purge task tags error", zap.String("id", id), zap.Error(err))
+			return err
+		}
+		if _, err = tx.conn.ExecContext(ctx, "DELETE FROM task_dependencies WHERE task_id IN "+in+" OR blocker_id IN "+in, append(ids, ids...)...); err != nil {
+			t.logger.Error("Execute purge task dependencies error", zap.String("id", id), zap.Error(err))
+			return err
+		}
+		if _, err = tx.conn.ExecContext(ctx, "DELETE FROM tasks WHERE deleted_at IS NOT NULL AND id IN "+in, ids...); err != nil {
+			t.logger.Error("Execute purge stmt error", zap.String("id", id), zap.Error(err))
+			return err
+		}
+		for _, node := range nodes {
+			if err = tx.record(ctx, constants.AuditPurge, &node.Task, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// trashedSubtree 垃圾桶中的 task 與其已刪除的子孫 task，task 不在垃圾桶中時回傳 TaskNotFound
+func (t *taskRepository) trashedSubtree(ctx context.Context, id string) ([]*models.TaskNode, error) {
+	nodes, err := t.subtree(ctx, id, constants.MaxTaskDepth, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(nodes) == 0 || nodes[0].DeletedAt == nil {
+		return nil, customError.TaskNotFound.New("task not found in trash")
+	}
+	return nodes, nil
+}
+
+func (t *taskRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (purged int64, err error) {
+	ctx, done := t.timeouts.apply(ctx, "purge_deleted_tasks")
+	defer done(&err)
+	err = t.withTx(ctx, func(tx *taskRepository) error {
+		expired, err := tx.queryTasks(ctx, "SELECT "+taskColumns+" FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?", before)
+		if err != nil {
+			return err
+		}
+		if len(expired) == 0 {
+			return nil
+		}
+		_, err = tx.conn.ExecContext(ctx, "DELETE FROM task_tags WHERE task_id IN (SELECT id FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?)", before)
+		if err != nil {
+			t.logger.Error("Execute purge expired task tags error", zap.Time("before", before), zap.Error(err))
+			return err
+		}
+		_, err = tx.conn.ExecContext(ctx, "DELETE FROM task_dependencies WHERE task_id IN (SELECT id FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?)"+
+			" OR blocker_id IN (SELECT id FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?)", before, before)
+		if err != nil {
+			t.logger.Error("Execute purge expired task dependencies error", zap.Time("before", before), zap.Error(err))
+			return err
+		}
+		rows, err := tx.conn.ExecContext(ctx, "DELETE FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?", before)
+		if err != nil {
+			t.logger.Error("Execute purge expired stmt error", zap.Time("before", before), zap.Error(err))
+			return err
+		}
+		if purged, err = rows.RowsAffected(); err != nil {
+			return err
+		}
+		for _, task := range expired {
+			if err = tx.record(ctx, constants.AuditPurge, task, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+// ListSubtree task 與其 depth 層內未刪除的子孫 task，第一筆為 task 本身
+func (t *taskRepository) ListSubtree(ctx context.Context, id string, depth int) (result []*models.TaskNode, err error) {
+	ctx, done := t.timeouts.apply(ctx, "list_subtree")
+	defer done(&err)
+	result, err = t.subtree(ctx, id, depth, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 || result[0].DeletedAt != nil {
+		return nil, customError.TaskNotFound.New("task not found")
+	}
+	return result, nil
+}
+
+// subtree 以 recursive CTE 取得 id 與其 depth 層內的子孫 task，依深度與 position 排序，
+// deleted 為 false 時只往下找未刪除的 task，為 true 時只找已刪除的 task，id 本身不論是否刪除都會回傳
+func (t *taskRepository) subtree(ctx context.Context, id string, depth int, deleted bool) ([]*models.TaskNode, error) {
+	condition := "c.deleted_at IS NULL"
+	if deleted {
+		condition = "c.deleted_at IS NOT NULL"
+	}
+	rows, err := t.conn.QueryContext(ctx, "WITH RECURSIVE subtree (task_id, depth) AS ("+
+		"SELECT id, 0 FROM tasks WHERE id = ?"+
+		" UNION ALL SELECT c.id, s.depth + 1 FROM tasks c JOIN subtree s ON c.parent_id = s.task_id WHERE s.depth < ? AND "+condition+
+		") SELECT "+taskColumns+", s.depth FROM tasks JOIN subtree s ON s.task_id = tasks.id ORDER BY s.depth, position, id", id, depth)
+	if err != nil {
+		t.logger.Error("List subtree error", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	result := make([]*models.TaskNode, 0)
+	for rows.Next() {
+		var level int
+		task, err := scanTask(rows, &level)
+		if err != nil {
+			t.logger.Error("Scan subtree error", zap.String("id", id), zap.Error(err))
+			return nil, err
+		}
+		result = append(result, &models.TaskNode{Task: *task, Depth: level})
+	}
+	if err = rows.Err(); err != nil {
+		t.logger.Error("Iterate subtree rows error", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+func nodeIDs(nodes []*models.TaskNode) []string {
+	ids := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.ID)
+	}
+	return ids
+}
+
+// ListAncestors task 所有上層 task 的 id，由 parent 開始往上排列
+func (t *taskRepository) ListAncestors(ctx context.Context, id string) (result []string, err error) {
+	ctx, done := t.timeouts.apply(ctx, "list_ancestors")
+	defer done(&err)
+	rows, err := t.conn.QueryContext(ctx, "WITH RECURSIVE ancestors (task_id, parent_id, depth) AS ("+
+		"SELECT id, parent_id, 0 FROM tasks WHERE id = ?"+
+		" UNION ALL SELECT p.id, p.parent_id, a.depth + 1 FROM tasks p JOIN ancestors a ON p.id = a.parent_id WHERE a.depth < ?"+
+		") SELECT task_id FROM ancestors WHERE depth > 0 ORDER BY depth", id, constants.MaxTaskDepth)
+	if err != nil {
+		t.logger.Error("List ancestors error", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	result = make([]string, 0)
+	for rows.Next() {
+		var ancestor string
+		if err = rows.Scan(&ancestor); err != nil {
+			t.logger.Error("Scan ancestor error", zap.String("id", id), zap.Error(err))
+			return nil, err
+		}
+		result = append(result, ancestor)
+	}
+	if err = rows.Err(); err != nil {
+		t.logger.Error("Iterate ancestor rows error", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+// CountChildren 未刪除的直接子 task 數量，以及其中已完成的數量
+func (t *taskRepository) CountChildren(ctx context.Context, id string) (total int, completed int, err error) {
+	ctx, done := t.timeouts.apply(ctx, "count_children")
+	defer done(&err)
+	err = t.conn.QueryRowContext(ctx, "SELECT COUNT(*), COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0)"+
+		" FROM tasks WHERE parent_id = ? AND deleted_at IS NULL", constants.Complete, id).Scan(&total, &completed)
+	if err != nil {
+		t.logger.Error("Count children error", zap.String("id", id), zap.Error(err))
+		return 0, 0, err
+	}
+	return total, completed, nil
+}
+
+// LastPosition 目前最後面的 position，包含垃圾桶中的 task，沒有任何 task 時回傳空字串
+func (t *taskRepository) LastPosition(ctx context.Context) (position string, err error) {
+	ctx, done := t.timeouts.apply(ctx, "last_position")
+	defer done(&err)
+	if err = t.conn.QueryRowContext(ctx, "SELECT COALESCE(MAX(position), '') FROM tasks").Scan(&position); err != nil {
+		t.logger.Error("Find last position error", zap.Error(err))
+		return "", err
+	}
+	return position, nil
+}
+
+// AdjacentPosition position 之後（after 為 false 時為之前）最接近的 position，略過 id 本身，沒有時回傳空字串
+func (t *taskRepository) AdjacentPosition(ctx context.Context, id string, position string, after bool) (adjacent string, err error) {
+	ctx, done := t.timeouts.apply(ctx, "adjacent_position")
+	defer done(&err)
+	query := "SELECT position FROM tasks WHERE id <> ? AND position < ? ORDER BY position DESC LIMIT 1"
+	if after {
+		query = "SELECT position FROM tasks WHERE id <> ? AND position > ? ORDER BY position LIMIT 1"
+	}
+	err = t.conn.QueryRowContext(ctx, query, id, position).Scan(&adjacent)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		t.logger.Error("Find adjacent position error", zap.String("id", id), zap.Error(err))
+		return "", err
+	}
+	return adjacent, nil
+}
+
+// ListDueReminders 未完成且已到提醒時間、尚未提醒過的 task，未設定 remind_at 時以 due_at 為提醒時間
+func (t *taskRepository) ListDueReminders(ctx context.Context, now time.Time, limit int) (result []*models.Task, err error) {
+	ctx, done := t.timeouts.apply(ctx, "list_due_reminders")
+	defer done(&err)
+	rows, err := t.conn.QueryContext(ctx, "SELECT "+taskColumns+" FROM tasks"+
+		" WHERE deleted_at IS NULL AND reminded_at IS NULL AND status <> ? AND COALESCE(remind_at, due_at) <= ?"+
+		" ORDER BY COALESCE(remind_at, due_at), id LIMIT ?", constants.Complete, now, limit)
+	if err != nil {
+		t.logger.Error("List due reminders error", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	result = make([]*models.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, task)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ClaimReminder 標記 task 已提醒，回傳 false 表示已被其他 instance 處理
+func (t *taskRepository) ClaimReminder(ctx context.Context, id string, now time.Time) (claimed bool, err error) {
+	ctx, done := t.timeouts.apply(ctx, "claim_reminder")
+	defer done(&err)
+	rows, err := t.conn.ExecContext(ctx, "UPDATE tasks SET reminded_at = ? WHERE id = ? AND reminded_at IS NULL", now, id)
+	if err != nil {
+		t.logger.Error("Execute claim reminder error", zap.String("id", id), zap.Error(err))
+		return false, err
+	}
+	effectRows, err := rows.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return effectRows > 0, nil
+}
+
+// ReleaseReminder 提醒送出失敗時取消標記，下次排程會再提醒
+func (t *taskRepository) ReleaseReminder(ctx context.Context, id string) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "release_reminder")
+	defer done(&err)
+	if _, err = t.conn.ExecContext(ctx, "UPDATE tasks SET reminded_at = NULL WHERE id = ?", id); err != nil {
+		t.logger.Error("Execute release reminder error", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ListTags 以一次查詢取得所有 task 的 tag，避免列表逐筆查詢
+func (t *taskRepository) ListTags(ctx context.Context, taskIDs []string) (result map[string][]*models.Tag, err error) {
+	ctx, done := t.timeouts.apply(ctx, "list_task_tags")
+	defer done(&err)
+	result = make(map[string][]*models.Tag)
+	if len(taskIDs) == 0 {
+		return result, nil
+	}
+	in, args := inClause(distinct(taskIDs))
+	rows, err := t.conn.QueryContext(ctx, "SELECT tt.task_id,g.id,g.name,g.color,g.created_at,g.updated_at"+
+		" FROM task_tags tt JOIN tags g ON g.id = tt.tag_id WHERE tt.task_id IN "+in+" ORDER BY g.name, g.id", args...)
+	if err != nil {
+		t.logger.Error("List task tags error", zap.Strings("task_ids", taskIDs), zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			taskID string
+			tag    models.Tag
+		)
+		if err = rows.Scan(&taskID, &tag.ID, &tag.Name, &tag.Color, &tag.CreatedAt, &tag.UpdatedAt); err != nil {
+			t.logger.Error("Scan task tag error", zap.Error(err))
+			return nil, err
+		}
+		result[taskID] = append(result[taskID], &tag)
+	}
+	if err = rows.Err(); err != nil {
+		t.logger.Error("Iterate task tag rows error", zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+// AttachTags 為 task 加上 tag，已加上的 tag 會略過，有變更時 task 的 version 加一
+func (t *taskRepository) AttachTags(ctx context.Context, taskID string, tagIDs []string) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "attach_tags")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *taskRepository) error {
+		return tx.attachTags(ctx, taskID, tagIDs, tx.touch)
+	})
+}
+
+func (t *taskRepository) attachTags(ctx context.Context, taskID string, tagIDs []string, touch touchFunc) error {
+	record, err := t.Find(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	tagIDs = distinct(tagIDs)
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	existing, err := t.queryStrings(ctx, "SELECT tag_id FROM task_tags WHERE task_id = ?", taskID)
+	if err != nil {
+		return err
+	}
+	in, args := inClause(tagIDs)
+	found, err := t.queryStrings(ctx, "SELECT id FROM tags WHERE id IN "+in, args...)
+	if err != nil {
+		return err
+	}
+	for _, id := range tagIDs {
+		if !found[id] {
+			return customError.TagNotFound.Errorf("tag %s not found", id)
+		}
+	}
+	now := time.Now().UTC()
+	attached := 0
+	for _, id := range tagIDs {
+		if existing[id] {
+			continue
+		}
+		if _, err = t.conn.ExecContext(ctx, "INSERT INTO task_tags (task_id, tag_id, created_at) VALUES (?, ?, ?)", taskID, id, now); err != nil {
+			t.logger.Error("Execute attach tag error", zap.String("id", taskID), zap.String("tag_id", id), zap.Error(err))
+			return err
+		}
+		attached++
+	}
+	if attached == 0 {
+		return nil
+	}
+	return touch(ctx, record, now, setChange("tags", existing, tagIDs, nil))
+}
+
+// DetachTags 移除 task 上的 tag，未加上的 tag 會略過，有變更時 task 的 version 加一
+func (t *taskRepository) DetachTags(ctx context.Context, taskID string, tagIDs []string) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "detach_tags")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *taskRepository) error {
+		return tx.detachTags(ctx, taskID, tagIDs, tx.touch)
+	})
+}
+
+func (t *taskRepository) detachTags(ctx context.Context, taskID string, tagIDs []string, touch touchFunc) error {
+	record, err := t.Find(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	existing, err := t.queryStrings(ctx, "SELECT tag_id FROM task_tags WHERE task_id = ?", taskID)
+	if err != nil {
+		return err
+	}
+	in, args := inClause(distinct(tagIDs))
+	rows, err := t.conn.ExecContext(ctx, "DELETE FROM task_tags WHERE task_id = ? AND tag_id IN "+in, append([]interface{}{taskID}, args...)...)
+	if err != nil {
+		t.logger.Error("Execute detach tags error", zap.String("id", taskID), zap.Error(err))
+		return err
+	}
+	effectRows, err := rows.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if effectRows == 0 {
+		return nil
+	}
+	return touch(ctx, record, time.Now().UTC(), setChange("tags", existing, nil, tagIDs))
+}
+
+// touchFunc 在 tag 或 blocker 變更後更新 task 並記錄 audit log
+type touchFunc func(ctx context.Context, record *models.Task, now time.Time, change entities.FieldChange) error
+
+// touch tag 屬於 task 的內容，變更後更新 version 讓 ETag 失效，並以 change 記錄 audit log
+func (t *taskRepository) touch(ctx context.Context, record *models.Task, now time.Time, change entities.FieldChange) error {
+	// 資料庫只保存到微秒，記錄的 updated_at 才會與寫入的相同
+	now = now.UTC().Truncate(time.Microsecond)
+	if _, err := t.conn.ExecContext(ctx, "UPDATE tasks SET version = version + 1, updated_at = ? WHERE id = ?", now, record.ID); err != nil {
+		t.logger.Error("Execute touch task error", zap.String("id", record.ID), zap.Error(err))
+		return err
+	}
+	return t.recordTouched(ctx, record, now, change)
+}
+
+// recordTouched 以 touch 後的 version 與 updated_at 記錄 audit log
+func (t *taskRepository) recordTouched(ctx context.Context, record *models.Task, now time.Time, change entities.FieldChange) error {
+	touched := *record
+	touched.Version++
+	touched.UpdatedAt = now.Format(time.RFC3339Nano)
+	return t.record(ctx, constants.AuditUpdate, record, &touched, change)
+}
+
+// queryTasks 查詢 taskColumns 並逐筆讀取
+func (t *taskRepository) queryTasks(ctx context.Context, query string, args ...interface{}) ([]*models.Task, error) {
+	rows, err := t.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		t.logger.Error("Query tasks error", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	result := make([]*models.Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			t.logger.Error("Scan task error", zap.Error(err))
+			return nil, err
+		}
+		result = append(result, task)
+	}
+	if err = rows.Err(); err != nil {
+		t.logger.Error("Iterate task rows error", zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+// queryStrings 查詢單一字串欄位並轉成集合
+func (t *taskRepository) queryStrings(ctx context.Context, query string, args ...interface{}) (map[string]bool, error) {
+	rows, err := t.conn.QueryContext(ctx, query, args...)
+	if err != nil {
+		t.logger.Error("Query error", zap.String("query", query), zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	result := make(map[string]bool)
+	for rows.Next() {
+		var value string
+		if err = rows.Scan(&value); err != nil {
+			return nil, err
+		}
+		result[value] = true
+	}
+	return result, rows.Err()
+}
+
+// FindWorkflow 所有狀態依 position 排序，轉換依 from_status、to_status 排序
+func (t *taskRepository) FindWorkflow(ctx context.Context) (workflow *models.Workflow, err error) {
+	ctx, done := t.timeouts.apply(ctx, "find_workflow")
+	defer done(&err)
+	workflow = &models.Workflow{}
+	rows, err := t.conn.QueryContext(ctx, "SELECT id, name FROM workflow_states ORDER BY position, id")
+	if err != nil {
+		t.logger.Error("List workflow states error", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var state models.WorkflowState
+		if err = rows.Scan(&state.ID, &state.Name); err != nil {
+			t.logger.Error("Scan workflow state error", zap.Error(err))
+			return nil, err
+		}
+		workflow.States = append(workflow.States, &state)
+	}
+	if err = rows.Err(); err != nil {
+		t.logger.Error("Iterate workflow state rows error", zap.Error(err))
+		return nil, err
+	}
+	transitions, err := t.conn.QueryContext(ctx, "SELECT from_status, to_status FROM workflow_transitions ORDER BY from_status, to_status")
+	if err != nil {
+		t.logger.Error("List workflow transitions error", zap.Error(err))
+		return nil, err
+	}
+	defer transitions.Close()
+	for transitions.Next() {
+		var transition models.WorkflowTransition
+		if err = transitions.Scan(&transition.From, &transition.To); err != nil {
+			t.logger.Error("Scan workflow transition error", zap.Error(err))
+			return nil, err
+		}
+		workflow.Transitions = append(workflow.Transitions, &transition)
+	}
+	if err = transitions.Err(); err != nil {
+		t.logger.Error("Iterate workflow transition rows error", zap.Error(err))
+		return nil, err
+	}
+	return workflow, nil
+}
+
+// ListBlockers 以一次查詢取得多個 task 直接的 blocker id，以 task id 分組
+func (t *taskRepository) ListBlockers(ctx context.Context, taskIDs []string) (result map[string][]string, err error) {
+	ctx, done := t.timeouts.apply(ctx, "list_blockers")
+	defer done(&err)
+	result = make(map[string][]string)
+	if len(taskIDs) == 0 {
+		return result, nil
+	}
+	in, args := inClause(distinct(taskIDs))
+	rows, err := t.conn.QueryContext(ctx, "SELECT task_id, blocker_id FROM task_dependencies WHERE task_id IN "+in+" ORDER BY task_id, blocker_id", args...)
+	if err != nil {
+		t.logger.Error("List blockers error", zap.Strings("task_ids", taskIDs), zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var taskID, blockerID string
+		if err = rows.Scan(&taskID, &blockerID); err != nil {
+			t.logger.Error("Scan blocker error", zap.Error(err))
+			return nil, err
+		}
+		result[taskID] = append(result[taskID], blockerID)
+	}
+	if err = rows.Err(); err != nil {
+		t.logger.Error("Iterate blocker rows error", zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+// ListAllBlockers 直接或間接 block 住 taskIDs 的所有 task id，包含已刪除的 task
+func (t *taskRepository) ListAllBlockers(ctx context.Context, taskIDs []string) (result map[string]bool, err error) {
+	ctx, done := t.timeouts.apply(ctx, "list_all_blockers")
+	defer done(&err)
+	if len(taskIDs) == 0 {
+		return make(map[string]bool), nil
+	}
+	in, args := inClause(distinct(taskIDs))
+	// UNION 會去除重複的列，即使資料中有環也會結束
+	return t.queryStrings(ctx, "WITH RECURSIVE blockers (id) AS ("+
+		"SELECT blocker_id FROM task_dependencies WHERE task_id IN "+in+
+		" UNION SELECT d.blocker_id FROM task_dependencies d JOIN blockers b ON d.task_id = b.id"+
+		") SELECT id FROM blockers", args...)
+}
+
+// LockDependencies 鎖住 task_dependency_lock，新增 dependency 的 transaction 依序執行，
+// 同時新增 A→B 與 B→A 時後執行的一方會看到先完成的關聯
+func (t *taskRepository) LockDependencies(ctx context.Context) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "lock_dependencies")
+	defer done(&err)
+	if _, err = t.conn.ExecContext(ctx, "UPDATE task_dependency_lock SET version = version + 1 WHERE id = 1"); err != nil {
+		t.logger.Error("Execute lock dependencies error", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// AddBlockers 讓 task 被 blockerIDs block 住，已存在的關聯會略過，有變更時 task 的 version 加一，
+// 不檢查是否形成環，需由呼叫端在同一個 transaction 中先呼叫 LockDependencies 再檢查
+func (t *taskRepository) AddBlockers(ctx context.Context, taskID string, blockerIDs []string) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "add_blockers")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *taskRepository) error {
+		return tx.addBlockers(ctx, taskID, blockerIDs, tx.touch)
+	})
+}
+
+func (t *taskRepository) addBlockers(ctx context.Context, taskID string, blockerIDs []string, touch touchFunc) error {
+	record, err := t.Find(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	blockerIDs = distinct(blockerIDs)
+	if len(blockerIDs) == 0 {
+		return nil
+	}
+	existing, err := t.queryStrings(ctx, "SELECT blocker_id FROM task_dependencies WHERE task_id = ?", taskID)
+	if err != nil {
+		return err
+	}
+	in, args := inClause(blockerIDs)
+	found, err := t.queryStrings(ctx, "SELECT id FROM tasks WHERE deleted_at IS NULL AND id IN "+in, args...)
+	if err != nil {
+		return err
+	}
+	for _, id := range blockerIDs {
+		if !found[id] {
+			return customError.TaskNotFound.Errorf("blocker %s not found", id)
+		}
+	}
+	now := time.Now().UTC()
+	added := 0
+	for _, id := range blockerIDs {
+		if existing[id] {
+			continue
+		}
+		if _, err = t.conn.ExecContext(ctx, "INSERT INTO task_dependencies (task_id, blocker_id, created_at) VALUES (?, ?, ?)", taskID, id, now); err != nil {
+			t.logger.Error("Execute add blocker error", zap.String("id", taskID), zap.String("blocker_id", id), zap.Error(err))
+			return err
+		}
+		added++
+	}
+	if added == 0 {
+		return nil
+	}
+	return touch(ctx, record, now, setChange("blockers", existing, blockerIDs, nil))
+}
+
+// RemoveBlockers 移除 task 的 blocker，不存在的關聯會略過，有變更時 task 的 version 加一
+func (t *taskRepository) RemoveBlockers(ctx context.Context, taskID string, blockerIDs []string) (err error) {
+	ctx, done := t.timeouts.apply(ctx, "remove_blockers")
+	defer done(&err)
+	return t.withTx(ctx, func(tx *taskRepository) error {
+		return tx.removeBlockers(ctx, taskID, blockerIDs, tx.touch)
+	})
+}
+
+func (t *taskRepository) removeBlockers(ctx context.Context, taskID string, blockerIDs []string, touch touchFunc) error {
+	record, err := t.Find(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if len(blockerIDs) == 0 {
+		return nil
+	}
+	existing, err := t.queryStrings(ctx, "SELECT blocker_id FROM task_dependencies WHERE task_id = ?", taskID)
+	if err != nil {
+		return err
+	}
+	in, args := inClause(distinct(blockerIDs))
+	rows, err := t.conn.ExecContext(ctx, "DELETE FROM task_dependencies WHERE task_id = ? AND blocker_id IN "+in, append([]interface{}{taskID}, args...)...)
+	if err != nil {
+		t.logger.Error("Execute remove blockers error", zap.String("id", taskID), zap.Error(err))
+		return err
+	}
+	effectRows, err := rows.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if effectRows == 0 {
+		return nil
+	}
+	return touch(ctx, record, time.Now().UTC(), setChange("blockers", existing, nil, blockerIDs))
+}
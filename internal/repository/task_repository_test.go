@@ -1,15 +1,20 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"regexp"
 	"tasks/constants"
 	"tasks/domain/entities"
+	"tasks/domain/models"
 	customError "tasks/errors"
+	"tasks/internal/dialect"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 )
@@ -21,15 +26,16 @@ func Test_taskRepository_Find(t *testing.T) {
 
 	logger := zap.NewNop()
 
-	repo := NewTaskRepository(db, logger)
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, logger)
+	ctx := context.Background()
 
 	t.Run("successfully find task", func(t *testing.T) {
-		columns := []string{"id", "name", "status", "version", "created_at"}
-		mock.ExpectQuery("SELECT id,name,status,version,created_at FROM tasks WHERE id = ?").
+		columns := []string{"id", "name", "status", "version", "created_at", "updated_at", "deleted_at", "due_at", "remind_at", "priority", "position", "parent_id", "recurrence", "blocked"}
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id,name,status,version,created_at,COALESCE(updated_at,created_at),deleted_at,due_at,remind_at,priority,COALESCE(position,''),parent_id,recurrence," + blockedColumn + " FROM tasks WHERE id = ? AND deleted_at IS NULL")).
 			WithArgs("task-123").
-			WillReturnRows(sqlmock.NewRows(columns).AddRow("task-123", "Test Task", 0, 1, time.Now()))
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("task-123", "Test Task", 0, 1, time.Now(), time.Now(), nil, nil, nil, 1, "00000001", nil, nil, false))
 
-		task, err := repo.Find("task-123")
+		task, err := repo.Find(ctx, "task-123")
 		assert.NoError(t, err)
 		assert.Equal(t, "task-123", task.ID)
 		assert.Equal(t, "Test Task", task.Name)
@@ -38,11 +44,11 @@ func Test_taskRepository_Find(t *testing.T) {
 	})
 
 	t.Run("task not found", func(t *testing.T) {
-		mock.ExpectQuery("SELECT id,name,status,version,created_at FROM tasks WHERE id = ?").
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id,name,status,version,created_at,COALESCE(updated_at,created_at),deleted_at,due_at,remind_at,priority,COALESCE(position,''),parent_id,recurrence," + blockedColumn + " FROM tasks WHERE id = ? AND deleted_at IS NULL")).
 			WithArgs("task-123").
 			WillReturnError(sql.ErrNoRows)
 
-		task, err := repo.Find("task-123")
+		task, err := repo.Find(ctx, "task-123")
 		assert.Nil(t, task)
 		assert.True(t, errors.Is(err, customError.TaskNotFound))
 
@@ -50,11 +56,11 @@ func Test_taskRepository_Find(t *testing.T) {
 	})
 
 	t.Run("find task error", func(t *testing.T) {
-		mock.ExpectQuery("SELECT id,name,status,version,created_at FROM tasks WHERE id = ?").
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id,name,status,version,created_at,COALESCE(updated_at,created_at),deleted_at,due_at,remind_at,priority,COALESCE(position,''),parent_id,recurrence," + blockedColumn + " FROM tasks WHERE id = ? AND deleted_at IS NULL")).
 			WithArgs("task-123").
 			WillReturnError(errors.New("db error"))
 
-		task, err := repo.Find("task-123")
+		task, err := repo.Find(ctx, "task-123")
 		assert.Nil(t, task)
 		assert.EqualError(t, err, "db error")
 
@@ -68,25 +74,233 @@ func Test_taskRepository_List(t *testing.T) {
 	defer db.Close()
 
 	logger := zap.NewNop() // 使用空的 logger
-	repo := NewTaskRepository(db, logger)
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, logger)
+	ctx := context.Background()
 
-	columns := []string{"id", "name", "status", "version"}
+	columns := []string{"id", "name", "status", "version", "created_at", "updated_at", "deleted_at", "due_at", "remind_at", "priority", "position", "parent_id", "recurrence", "blocked"}
 	t.Run("successfully list tasks", func(t *testing.T) {
 		mock.ExpectQuery("SELECT *").
-			WillReturnRows(sqlmock.NewRows(columns).AddRow("task-123", "Test Task", 0, 1))
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("task-123", "Test Task", 0, 1, time.Now(), time.Now(), nil, nil, nil, 1, "00000001", nil, nil, false))
 
 		param := entities.TaskQueryParam{
 			Size:   10,
 			Offset: 0,
 		}
 
-		tasks, err := repo.List(param)
+		tasks, err := repo.List(ctx, param)
 		assert.NoError(t, err)
 		assert.Len(t, tasks, 1)
 		assert.Equal(t, "task-123", tasks[0].ID)
 
 		mock.ExpectationsWereMet()
 	})
+
+	t.Run("list tasks with filters and sorting", func(t *testing.T) {
+		createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		createdBefore := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+		query := "SELECT id,name,status,version,created_at,COALESCE(updated_at,created_at),deleted_at,due_at,remind_at,priority,COALESCE(position,''),parent_id,recurrence," + blockedColumn + " FROM tasks " +
+			`WHERE deleted_at IS NULL AND status IN (?,?) AND name LIKE ? ESCAPE '!' AND created_at >= ? AND created_at < ? ` +
+			"ORDER BY name DESC, id DESC LIMIT ? OFFSET ?"
+		mock.ExpectQuery(regexp.QuoteMeta(query)).
+			WithArgs(constants.Incomplete, constants.Complete, `%50!%!_off%`, createdAfter, createdBefore, 5, 10).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("task-123", "50%_off", 0, 1, time.Now(), time.Now(), nil, nil, nil, 1, "00000001", nil, nil, false))
+
+		param := entities.TaskQueryParam{
+			Size:          5,
+			Offset:        10,
+			Statuses:      []constants.Status{constants.Incomplete, constants.Complete},
+			Keyword:       "50%_off",
+			CreatedAfter:  &createdAfter,
+			CreatedBefore: &createdBefore,
+			SortBy:        constants.SortByName,
+			Order:         constants.OrderDesc,
+		}
+
+		tasks, err := repo.List(ctx, param)
+		assert.NoError(t, err)
+		assert.Len(t, tasks, 1)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("priority filter sorted by priority", func(t *testing.T) {
+		query := "SELECT id,name,status,version,created_at,COALESCE(updated_at,created_at),deleted_at,due_at,remind_at,priority,COALESCE(position,''),parent_id,recurrence," + blockedColumn + " FROM tasks " +
+			"WHERE deleted_at IS NULL AND priority IN (?,?) ORDER BY priority DESC, id DESC LIMIT ? OFFSET ?"
+		mock.ExpectQuery(regexp.QuoteMeta(query)).
+			WithArgs(constants.PriorityHigh, constants.PriorityUrgent, 10, 0).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := repo.List(ctx, entities.TaskQueryParam{
+			Size:       10,
+			Priorities: []constants.Priority{constants.PriorityHigh, constants.PriorityUrgent},
+			SortBy:     constants.SortByPriority,
+			Order:      constants.OrderDesc,
+		})
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("tag filters", func(t *testing.T) {
+		query := "SELECT id,name,status,version,created_at,COALESCE(updated_at,created_at),deleted_at,due_at,remind_at,priority,COALESCE(position,''),parent_id,recurrence," + blockedColumn + " FROM tasks " +
+			"WHERE deleted_at IS NULL AND id IN (SELECT tt.task_id FROM task_tags tt JOIN tags g ON g.id = tt.tag_id WHERE g.name IN (?,?)) " +
+			"AND id IN (SELECT tt.task_id FROM task_tags tt JOIN tags g ON g.id = tt.tag_id WHERE g.name IN (?,?) GROUP BY tt.task_id HAVING COUNT(*) = ?) " +
+			"ORDER BY position ASC, id ASC LIMIT ? OFFSET ?"
+		mock.ExpectQuery(regexp.QuoteMeta(query)).
+			WithArgs("work", "home", "urgent", "later", 2, 10, 0).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := repo.List(ctx, entities.TaskQueryParam{
+			Size:    10,
+			TagsAny: []string{"work", "home"},
+			TagsAll: []string{"urgent", "later", "urgent"},
+		})
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unknown sort field falls back to position", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id,name,status,version,created_at,COALESCE(updated_at,created_at),deleted_at,due_at,remind_at,priority,COALESCE(position,''),parent_id,recurrence,"+blockedColumn+" FROM tasks WHERE deleted_at IS NULL ORDER BY position ASC, id ASC LIMIT ? OFFSET ?")).
+			WithArgs(10, 0).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		param := entities.TaskQueryParam{
+			Size:   10,
+			SortBy: "version; DROP TABLE tasks",
+		}
+
+		tasks, err := repo.List(ctx, param)
+		assert.NoError(t, err)
+		assert.Empty(t, tasks)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_taskRepository_ListWithCursor(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	columns := []string{"id", "name", "status", "version", "created_at", "updated_at", "deleted_at", "due_at", "remind_at", "priority", "position", "parent_id", "recurrence", "blocked"}
+
+	t.Run("forward cursor", func(t *testing.T) {
+		query := "SELECT id,name,status,version,created_at,COALESCE(updated_at,created_at),deleted_at,due_at,remind_at,priority,COALESCE(position,''),parent_id,recurrence," + blockedColumn + " FROM tasks " +
+			"WHERE deleted_at IS NULL AND status IN (?) AND (created_at > ? OR (created_at = ? AND id > ?)) " +
+			"ORDER BY created_at ASC, id ASC LIMIT ? OFFSET ?"
+		mock.ExpectQuery(regexp.QuoteMeta(query)).
+			WithArgs(constants.Incomplete, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "task-123", 11, 0).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := repo.List(ctx, entities.TaskQueryParam{
+			Size:     11,
+			Statuses: []constants.Status{constants.Incomplete},
+			SortBy:   constants.SortByCreatedAt,
+			Order:    constants.OrderAsc,
+			Cursor: &entities.Cursor{
+				SortBy: constants.SortByCreatedAt,
+				Order:  constants.OrderAsc,
+				Value:  "2024-01-01 00:00:00+00:00",
+				ID:     "task-123",
+			},
+		})
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("backward cursor over trash reverses order", func(t *testing.T) {
+		query := "SELECT id,name,status,version,created_at,COALESCE(updated_at,created_at),deleted_at,due_at,remind_at,priority,COALESCE(position,''),parent_id,recurrence," + blockedColumn + " FROM tasks " +
+			"WHERE deleted_at IS NOT NULL AND (name > ? OR (name = ? AND id > ?)) " +
+			"ORDER BY name ASC, id ASC LIMIT ? OFFSET ?"
+		mock.ExpectQuery(regexp.QuoteMeta(query)).
+			WithArgs("Task B", "Task B", "task-123", 3, 0).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := repo.List(ctx, entities.TaskQueryParam{
+			Size:    3,
+			SortBy:  constants.SortByName,
+			Order:   constants.OrderDesc,
+			Trashed: true,
+			Cursor: &entities.Cursor{
+				SortBy:   constants.SortByName,
+				Order:    constants.OrderDesc,
+				Value:    "Task B",
+				ID:       "task-123",
+				Backward: true,
+			},
+		})
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_taskRepository_ListDueFilters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	columns := []string{"id", "name", "status", "version", "created_at", "updated_at", "deleted_at", "due_at", "remind_at", "priority", "position", "parent_id", "recurrence", "blocked"}
+	dueAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	dueBefore := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("overdue within due range", func(t *testing.T) {
+		overdue := true
+		query := "SELECT id,name,status,version,created_at,COALESCE(updated_at,created_at),deleted_at,due_at,remind_at,priority,COALESCE(position,''),parent_id,recurrence," + blockedColumn + " FROM tasks " +
+			"WHERE deleted_at IS NULL AND due_at >= ? AND due_at < ? AND (status <> ? AND due_at < ?) " +
+			"ORDER BY position ASC, id ASC LIMIT ? OFFSET ?"
+		mock.ExpectQuery(regexp.QuoteMeta(query)).
+			WithArgs(dueAfter, dueBefore, constants.Complete, sqlmock.AnyArg(), 10, 0).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := repo.List(ctx, entities.TaskQueryParam{Size: 10, Overdue: &overdue, DueAfter: &dueAfter, DueBefore: &dueBefore})
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not overdue", func(t *testing.T) {
+		overdue := false
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE deleted_at IS NULL AND (status = ? OR due_at IS NULL OR due_at >= ?)")).
+			WithArgs(constants.Complete, sqlmock.AnyArg(), 10, 0).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := repo.List(ctx, entities.TaskQueryParam{Size: 10, Overdue: &overdue})
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_taskRepository_Count(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+
+	t.Run("count with filters ignores paging", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM tasks WHERE deleted_at IS NULL AND status IN (?)")).
+			WithArgs(constants.Complete).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(42))
+
+		total, err := repo.Count(ctx, entities.TaskQueryParam{
+			Size:     10,
+			Offset:   20,
+			Statuses: []constants.Status{constants.Complete},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 42, total)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func Test_taskRepository_Create(t *testing.T) {
@@ -95,13 +309,18 @@ func Test_taskRepository_Create(t *testing.T) {
 	defer db.Close()
 
 	logger := zap.NewNop()
-	repo := NewTaskRepository(db, logger)
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, logger)
+	ctx := context.Background()
 
 	t.Run("successfully create task", func(t *testing.T) {
+		mock.ExpectBegin()
 		mock.ExpectPrepare("INSERT INTO tasks").
 			ExpectExec().
-			WithArgs("task-123", "Test Task", 0, 0, sqlmock.AnyArg()).
+			WithArgs("task-123", nil, "Test Task", 0, 0, nil, 0, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, nil).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectRecord(mock, "task-123", 0, nil)
+		expectAudit(mock, "task-123", constants.AuditCreate)
+		mock.ExpectCommit()
 
 		task := entities.Task{
 			ID:        "task-123",
@@ -111,17 +330,19 @@ func Test_taskRepository_Create(t *testing.T) {
 			CreatedAt: time.Now().UTC(),
 		}
 
-		err := repo.Create(task)
+		err := repo.Create(ctx, task)
 		assert.NoError(t, err)
 
 		mock.ExpectationsWereMet()
 	})
 
 	t.Run("create task error", func(t *testing.T) {
+		mock.ExpectBegin()
 		mock.ExpectPrepare("INSERT INTO tasks").
 			ExpectExec().
-			WithArgs("task-123", "Test Task", 0, 0, sqlmock.AnyArg()).
+			WithArgs("task-123", nil, "Test Task", 0, 0, nil, 0, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, nil).
 			WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
 
 		task := entities.Task{
 			ID:        "task-123",
@@ -131,11 +352,33 @@ func Test_taskRepository_Create(t *testing.T) {
 			CreatedAt: time.Now().UTC(),
 		}
 
-		err := repo.Create(task)
+		err := repo.Create(ctx, task)
 		assert.EqualError(t, err, "db error")
 
 		mock.ExpectationsWereMet()
 	})
+
+	t.Run("position taken by a concurrent create", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare("INSERT INTO tasks").
+			ExpectExec().
+			WithArgs("task-123", nil, "Test Task", 0, 0, "00000002", 0, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, nil, nil).
+			WillReturnError(sqlite3.Error{Code: sqlite3.ErrConstraint, ExtendedCode: sqlite3.ErrConstraintUnique})
+		mock.ExpectRollback()
+
+		task := entities.Task{
+			ID:        "task-123",
+			Name:      "Test Task",
+			Status:    constants.Incomplete,
+			Position:  "00000002",
+			CreatedAt: time.Now().UTC(),
+		}
+
+		err := repo.Create(ctx, task)
+		assert.True(t, errors.Is(err, customError.TaskPositionConflict), err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
 }
 
 func Test_taskRepository_Update(t *testing.T) {
@@ -144,18 +387,23 @@ func Test_taskRepository_Update(t *testing.T) {
 	defer db.Close()
 
 	logger := zap.NewNop()
-	repo := NewTaskRepository(db, logger)
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, logger)
+	ctx := context.Background()
 
 	t.Run("successfully update task", func(t *testing.T) {
-		columns := []string{"id", "name", "status", "version", "created_at"}
+		columns := []string{"id", "name", "status", "version", "created_at", "updated_at", "deleted_at", "due_at", "remind_at", "priority", "position", "parent_id", "recurrence", "blocked"}
+		mock.ExpectBegin()
 		mock.ExpectQuery("SELECT *").
 			WithArgs("task-123").
-			WillReturnRows(sqlmock.NewRows(columns).AddRow("task-123", "Test Task", 0, 1, time.Now()))
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("task-123", "Test Task", 0, 1, time.Now(), time.Now(), nil, nil, nil, 1, "00000001", nil, nil, false))
 
 		mock.ExpectPrepare("UPDATE *").
 			ExpectExec().
-			WithArgs("Updated Task", 0, 2, "task-123", 1).
+			WithArgs(nil, "Updated Task", 0, 0, nil, nil, nil, 2, sqlmock.AnyArg(), "task-123", 1).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		expectRecord(mock, "task-123", 2, nil)
+		expectAudit(mock, "task-123", constants.AuditUpdate)
+		mock.ExpectCommit()
 
 		task := entities.Task{
 			ID:     "task-123",
@@ -163,16 +411,18 @@ func Test_taskRepository_Update(t *testing.T) {
 			Status: 0,
 		}
 
-		err := repo.Update(task)
+		err := repo.Update(ctx, task, nil)
 		assert.NoError(t, err)
 
 		mock.ExpectationsWereMet()
 	})
 
 	t.Run("update task error", func(t *testing.T) {
-		mock.ExpectQuery("SELECT id,name,status,version,created_at FROM tasks WHERE id = ?").
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id,name,status,version,created_at,COALESCE(updated_at,created_at),deleted_at,due_at,remind_at,priority,COALESCE(position,''),parent_id,recurrence," + blockedColumn + " FROM tasks WHERE id = ? AND deleted_at IS NULL")).
 			WithArgs("task-123").
 			WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
 
 		task := entities.Task{
 			ID:     "task-123",
@@ -180,11 +430,200 @@ func Test_taskRepository_Update(t *testing.T) {
 			Status: 0,
 		}
 
-		err := repo.Update(task)
+		err := repo.Update(ctx, task, nil)
 		assert.EqualError(t, err, "db error")
 
 		mock.ExpectationsWereMet()
 	})
+
+	t.Run("expected version does not match", func(t *testing.T) {
+		columns := []string{"id", "name", "status", "version", "created_at", "updated_at", "deleted_at", "due_at", "remind_at", "priority", "position", "parent_id", "recurrence", "blocked"}
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT *").
+			WithArgs("task-123").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("task-123", "Test Task", 0, 3, time.Now(), time.Now(), nil, nil, nil, 1, "00000001", nil, nil, false))
+		mock.ExpectRollback()
+
+		expectedVersion := 2
+		err := repo.Update(ctx, entities.Task{ID: "task-123", Name: "Updated Task"}, &expectedVersion)
+		assert.True(t, errors.Is(err, customError.TaskVersionMismatch))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("concurrent update loses the race", func(t *testing.T) {
+		columns := []string{"id", "name", "status", "version", "created_at", "updated_at", "deleted_at", "due_at", "remind_at", "priority", "position", "parent_id", "recurrence", "blocked"}
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT *").
+			WithArgs("task-123").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("task-123", "Test Task", 0, 1, time.Now(), time.Now(), nil, nil, nil, 1, "00000001", nil, nil, false))
+		mock.ExpectPrepare("UPDATE *").
+			ExpectExec().
+			WithArgs(nil, "Updated Task", 1, 0, nil, nil, nil, 2, sqlmock.AnyArg(), "task-123", 1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		expectedVersion := 1
+		err := repo.Update(ctx, entities.Task{ID: "task-123", Name: "Updated Task", Status: constants.Complete}, &expectedVersion)
+		assert.True(t, errors.Is(err, customError.TaskVersionConflict))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_taskRepository_UpdateDueAt(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	columns := []string{"id", "name", "status", "version", "created_at", "updated_at", "deleted_at", "due_at", "remind_at", "priority", "position", "parent_id", "recurrence", "blocked"}
+	dueAt := time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)
+
+	t.Run("changed due_at resets the reminder", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT *").
+			WithArgs("task-123").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("task-123", "Test Task", 0, 1, time.Now(), time.Now(), nil, "2024-01-01 09:00:00+00:00", nil, 1, "00000001", nil, nil, false))
+		mock.ExpectPrepare(regexp.QuoteMeta("UPDATE tasks SET parent_id = ?, name = ?, status = ?, priority = ?, due_at = ?, remind_at = ?, recurrence = ?, version = ?, updated_at = ?, reminded_at = NULL WHERE id = ? and version = ?")).
+			ExpectExec().
+			WithArgs(nil, "Test Task", 0, 0, dueAt, nil, nil, 2, sqlmock.AnyArg(), "task-123", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectRecord(mock, "task-123", 2, nil)
+		expectAudit(mock, "task-123", constants.AuditUpdate)
+		mock.ExpectCommit()
+
+		err := repo.Update(ctx, entities.Task{ID: "task-123", Name: "Test Task", DueAt: &dueAt}, nil)
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("unchanged due_at keeps the reminder", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT *").
+			WithArgs("task-123").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("task-123", "Test Task", 0, 1, time.Now(), time.Now(), nil, "2024-01-02 09:00:00+00:00", nil, 1, "00000001", nil, nil, false))
+		mock.ExpectPrepare(regexp.QuoteMeta("UPDATE tasks SET parent_id = ?, name = ?, status = ?, priority = ?, due_at = ?, remind_at = ?, recurrence = ?, version = ?, updated_at = ? WHERE id = ? and version = ?")).
+			ExpectExec().
+			WithArgs(nil, "Renamed", 0, 0, dueAt, nil, nil, 2, sqlmock.AnyArg(), "task-123", 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectRecord(mock, "task-123", 2, nil)
+		expectAudit(mock, "task-123", constants.AuditUpdate)
+		mock.ExpectCommit()
+
+		err := repo.Update(ctx, entities.Task{ID: "task-123", Name: "Renamed", DueAt: &dueAt}, nil)
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_taskRepository_Patch(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+
+	t.Run("only changed fields are written", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectRecord(mock, "task-123", 2, nil)
+		mock.ExpectPrepare(regexp.QuoteMeta("UPDATE tasks SET status = ?, version = ?, updated_at = ? WHERE id = ? and version = ?")).
+			ExpectExec().
+			WithArgs(constants.Complete, 3, sqlmock.AnyArg(), "task-123", 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectRecord(mock, "task-123", 3, nil)
+		expectAudit(mock, "task-123", constants.AuditUpdate)
+		mock.ExpectCommit()
+
+		status := constants.Complete
+		err := repo.Patch(ctx, entities.TaskPatch{ID: "task-123", Status: &status}, 2)
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("priority and position", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectRecord(mock, "task-123", 2, nil)
+		mock.ExpectPrepare(regexp.QuoteMeta("UPDATE tasks SET priority = ?, position = ?, version = ?, updated_at = ? WHERE id = ? and version = ?")).
+			ExpectExec().
+			WithArgs(constants.PriorityHigh, "00000002i", 3, sqlmock.AnyArg(), "task-123", 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectRecord(mock, "task-123", 3, nil)
+		expectAudit(mock, "task-123", constants.AuditUpdate)
+		mock.ExpectCommit()
+
+		priority := constants.PriorityHigh
+		position := "00000002i"
+		err := repo.Patch(ctx, entities.TaskPatch{ID: "task-123", Priority: &priority, Position: &position}, 2)
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectRecord(mock, "task-123", 2, nil)
+		mock.ExpectPrepare(regexp.QuoteMeta("UPDATE tasks SET name = ?, version = ?, updated_at = ? WHERE id = ? and version = ?")).
+			ExpectExec().
+			WithArgs("Renamed", 3, sqlmock.AnyArg(), "task-123", 2).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		name := "Renamed"
+		err := repo.Patch(ctx, entities.TaskPatch{ID: "task-123", Name: &name}, 2)
+		assert.True(t, errors.Is(err, customError.TaskVersionConflict))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("clear due_at and reset the reminder", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectRecord(mock, "task-123", 2, nil)
+		mock.ExpectPrepare(regexp.QuoteMeta("UPDATE tasks SET due_at = ?, reminded_at = NULL, version = ?, updated_at = ? WHERE id = ? and version = ?")).
+			ExpectExec().
+			WithArgs(nil, 3, sqlmock.AnyArg(), "task-123", 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectRecord(mock, "task-123", 3, nil)
+		expectAudit(mock, "task-123", constants.AuditUpdate)
+		mock.ExpectCommit()
+
+		var dueAt *time.Time
+		err := repo.Patch(ctx, entities.TaskPatch{ID: "task-123", DueAt: &dueAt}, 2)
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("recurrence", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectRecord(mock, "task-123", 2, nil)
+		mock.ExpectPrepare(regexp.QuoteMeta("UPDATE tasks SET status = ?, recurrence = ?, version = ?, updated_at = ? WHERE id = ? and version = ?")).
+			ExpectExec().
+			WithArgs(constants.Complete, nil, 3, sqlmock.AnyArg(), "task-123", 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectRecord(mock, "task-123", 3, nil)
+		expectAudit(mock, "task-123", constants.AuditUpdate)
+		mock.ExpectCommit()
+
+		status := constants.Complete
+		var recurrence *string
+		err := repo.Patch(ctx, entities.TaskPatch{ID: "task-123", Status: &status, Recurrence: &recurrence}, 2)
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// subtreeColumns recursive CTE 查詢回傳 taskColumns 與 depth
+var subtreeColumns = []string{"id", "name", "status", "version", "created_at", "updated_at", "deleted_at", "due_at", "remind_at", "priority", "position", "parent_id", "recurrence", "blocked", "depth"}
+
+func subtreeRow(rows *sqlmock.Rows, id string, parentID, deletedAt interface{}, depth int) *sqlmock.Rows {
+	return rows.AddRow(id, "Task", 0, 1, time.Now(), time.Now(), deletedAt, nil, nil, 1, "00000001", parentID, nil, false, depth)
 }
 
 func Test_taskRepository_Delete(t *testing.T) {
@@ -193,29 +632,737 @@ func Test_taskRepository_Delete(t *testing.T) {
 	defer db.Close()
 
 	logger := zap.NewNop() // 使用空的 logger
-	repo := NewTaskRepository(db, logger)
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, logger)
+	ctx := context.Background()
+	deletedAt := "2024-01-01 00:00:00+00:00"
 
 	t.Run("successfully delete task", func(t *testing.T) {
-		mock.ExpectPrepare("DELETE FROM tasks WHERE id = ?").
+		mock.ExpectBegin()
+		mock.ExpectPrepare(regexp.QuoteMeta("UPDATE tasks SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL")).
 			ExpectExec().
-			WithArgs("task-123").
+			WithArgs(sqlmock.AnyArg(), "task-123").
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("WITH RECURSIVE subtree (task_id, depth)")).
+			WithArgs("task-123", constants.MaxTaskDepth).
+			WillReturnRows(subtreeRow(sqlmock.NewRows(subtreeColumns), "task-123", nil, deletedAt, 0))
+		expectAudit(mock, "task-123", constants.AuditDelete)
+		mock.ExpectCommit()
 
-		err := repo.Delete("task-123")
+		err := repo.Delete(ctx, "task-123", nil, false)
 		assert.NoError(t, err)
 
-		mock.ExpectationsWereMet()
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 
 	t.Run("delete task error", func(t *testing.T) {
-		mock.ExpectPrepare("DELETE FROM tasks WHERE id = ?").
+		mock.ExpectBegin()
+		mock.ExpectPrepare(regexp.QuoteMeta("UPDATE tasks SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL")).
 			ExpectExec().
-			WithArgs("task-123").
+			WithArgs(sqlmock.AnyArg(), "task-123").
 			WillReturnError(errors.New("db error"))
+		mock.ExpectRollback()
 
-		err := repo.Delete("task-123")
+		err := repo.Delete(ctx, "task-123", nil, false)
 		assert.EqualError(t, err, "db error")
 
-		mock.ExpectationsWereMet()
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete missing task", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(regexp.QuoteMeta("UPDATE tasks SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL")).
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), "task-404").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectRollback()
+
+		err := repo.Delete(ctx, "task-404", nil, false)
+		assert.True(t, errors.Is(err, customError.TaskNotFound))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("delete with stale version", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(regexp.QuoteMeta("UPDATE tasks SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL AND version = ?")).
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), "task-123", 1).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		columns := []string{"id", "name", "status", "version", "created_at", "updated_at", "deleted_at", "due_at", "remind_at", "priority", "position", "parent_id", "recurrence", "blocked"}
+		mock.ExpectQuery("SELECT *").
+			WithArgs("task-123").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("task-123", "Test Task", 0, 2, time.Now(), time.Now(), nil, nil, nil, 1, "00000001", nil, nil, false))
+		mock.ExpectRollback()
+
+		expectedVersion := 1
+		err := repo.Delete(ctx, "task-123", &expectedVersion, false)
+		assert.True(t, errors.Is(err, customError.TaskVersionMismatch))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("task with subtasks needs cascade", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(regexp.QuoteMeta("UPDATE tasks SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL")).
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), "task-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		rows := subtreeRow(sqlmock.NewRows(subtreeColumns), "task-1", nil, deletedAt, 0)
+		mock.ExpectQuery(regexp.QuoteMeta("WITH RECURSIVE subtree (task_id, depth)")).
+			WithArgs("task-1", constants.MaxTaskDepth).
+			WillReturnRows(subtreeRow(rows, "task-2", "task-1", nil, 1))
+		mock.ExpectRollback()
+
+		err := repo.Delete(ctx, "task-1", nil, false)
+		assert.True(t, errors.Is(err, customError.TaskHasSubtasks))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("cascade delete moves subtasks to the trash", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectPrepare(regexp.QuoteMeta("UPDATE tasks SET deleted_at = ? WHERE id = ? AND deleted_at IS NULL")).
+			ExpectExec().
+			WithArgs(sqlmock.AnyArg(), "task-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		rows := subtreeRow(sqlmock.NewRows(subtreeColumns), "task-1", nil, deletedAt, 0)
+		rows = subtreeRow(rows, "task-2", "task-1", nil, 1)
+		mock.ExpectQuery(regexp.QuoteMeta("WITH RECURSIVE subtree (task_id, depth)")).
+			WithArgs("task-1", constants.MaxTaskDepth).
+			WillReturnRows(subtreeRow(rows, "task-3", "task-2", nil, 2))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE tasks SET deleted_at = ? WHERE deleted_at IS NULL AND id IN (?,?)")).
+			WithArgs(sqlmock.AnyArg(), "task-2", "task-3").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		for _, id := range []string{"task-1", "task-2", "task-3"} {
+			expectAudit(mock, id, constants.AuditDelete)
+		}
+		mock.ExpectCommit()
+
+		err := repo.Delete(ctx, "task-1", nil, true)
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_taskRepository_Restore(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	deletedAt := "2024-01-01 00:00:00+00:00"
+
+	t.Run("restore task with the subtasks deleted together", func(t *testing.T) {
+		mock.ExpectBegin()
+		rows := subtreeRow(sqlmock.NewRows(subtreeColumns), "task-1", nil, deletedAt, 0)
+		rows = subtreeRow(rows, "task-2", "task-1", deletedAt, 1)
+		mock.ExpectQuery(regexp.QuoteMeta("WITH RECURSIVE subtree (task_id, depth)")).
+			WithArgs("task-1", constants.MaxTaskDepth).
+			WillReturnRows(subtreeRow(rows, "task-3", "task-1", "2023-12-01 00:00:00+00:00", 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE tasks SET deleted_at = NULL, updated_at = ? WHERE deleted_at IS NOT NULL AND id IN (?,?)")).
+			WithArgs(sqlmock.AnyArg(), "task-1", "task-2").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		expectAudit(mock, "task-1", constants.AuditRestore)
+		expectAudit(mock, "task-2", constants.AuditRestore)
+		mock.ExpectCommit()
+
+		err := repo.Restore(ctx, "task-1")
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("task not in trash", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("WITH RECURSIVE subtree (task_id, depth)")).
+			WithArgs("task-123", constants.MaxTaskDepth).
+			WillReturnRows(subtreeRow(sqlmock.NewRows(subtreeColumns), "task-123", nil, nil, 0))
+		mock.ExpectRollback()
+
+		err := repo.Restore(ctx, "task-123")
+		assert.True(t, errors.Is(err, customError.TaskNotFound))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("parent is still in the trash", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta("WITH RECURSIVE subtree (task_id, depth)")).
+			WithArgs("task-2", constants.MaxTaskDepth).
+			WillReturnRows(subtreeRow(sqlmock.NewRows(subtreeColumns), "task-2", "task-1", deletedAt, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*) FROM tasks WHERE id = ? AND deleted_at IS NULL")).
+			WithArgs("task-1").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectRollback()
+
+		err := repo.Restore(ctx, "task-2")
+		assert.True(t, errors.Is(err, customError.TaskParentDeleted))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// expectPurge 預期 Purge 的查詢，found 為 false 時 task 不在垃圾桶中
+func expectPurge(mock sqlmock.Sqlmock, id string, found bool) {
+	rows := sqlmock.NewRows(subtreeColumns)
+	if found {
+		rows = subtreeRow(rows, id, nil, "2024-01-01 00:00:00+00:00", 0)
+		rows = subtreeRow(rows, id+"-child", id, "2024-01-01 00:00:00+00:00", 1)
+	}
+	mock.ExpectQuery(regexp.QuoteMeta("WITH RECURSIVE subtree (task_id, depth)")).
+		WithArgs(id, constants.MaxTaskDepth).
+		WillReturnRows(rows)
+	if !found {
+		return
+	}
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM task_tags WHERE task_id IN (?,?)")).
+		WithArgs(id, id+"-child").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM task_dependencies WHERE task_id IN (?,?) OR blocker_id IN (?,?)")).
+		WithArgs(id, id+"-child", id, id+"-child").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM tasks WHERE deleted_at IS NOT NULL AND id IN (?,?)")).
+		WithArgs(id, id+"-child").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	expectAudit(mock, id, constants.AuditPurge)
+	expectAudit(mock, id+"-child", constants.AuditPurge)
+}
+
+func Test_taskRepository_Purge(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+
+	t.Run("successfully purge task with its subtasks and tags", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectPurge(mock, "task-123", true)
+		mock.ExpectCommit()
+
+		err := repo.Purge(ctx, "task-123")
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("task not in trash", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectPurge(mock, "task-123", false)
+		mock.ExpectRollback()
+
+		err := repo.Purge(ctx, "task-123")
+		assert.True(t, errors.Is(err, customError.TaskNotFound))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("purge tasks deleted before retention", func(t *testing.T) {
+		before := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectBegin()
+		expired := sqlmock.NewRows(taskColumnNames)
+		for _, id := range []string{"task-1", "task-2", "task-3"} {
+			expired.AddRow(id, "Task", 0, 1, time.Now(), time.Now(), "2023-12-01 00:00:00+00:00", nil, nil, 1, "00000001", nil, nil, false)
+		}
+		mock.ExpectQuery(regexp.QuoteMeta("FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?")).
+			WithArgs(before).
+			WillReturnRows(expired)
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM task_tags WHERE task_id IN (SELECT id FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?)")).
+			WithArgs(before).
+			WillReturnResult(sqlmock.NewResult(0, 4))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM task_dependencies WHERE task_id IN (SELECT id FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?)"+
+			" OR blocker_id IN (SELECT id FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?)")).
+			WithArgs(before, before).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM tasks WHERE deleted_at IS NOT NULL AND deleted_at < ?")).
+			WithArgs(before).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		for _, id := range []string{"task-1", "task-2", "task-3"} {
+			expectAudit(mock, id, constants.AuditPurge)
+		}
+		mock.ExpectCommit()
+
+		purged, err := repo.PurgeDeletedBefore(ctx, before)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(3), purged)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_taskRepository_Tree(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+
+	t.Run("list subtree", func(t *testing.T) {
+		rows := subtreeRow(sqlmock.NewRows(subtreeColumns), "task-1", nil, nil, 0)
+		mock.ExpectQuery(regexp.QuoteMeta("WITH RECURSIVE subtree (task_id, depth) AS (SELECT id, 0 FROM tasks WHERE id = ?"+
+			" UNION ALL SELECT c.id, s.depth + 1 FROM tasks c JOIN subtree s ON c.parent_id = s.task_id WHERE s.depth < ? AND c.deleted_at IS NULL)")).
+			WithArgs("task-1", 2).
+			WillReturnRows(subtreeRow(rows, "task-2", "task-1", nil, 1))
+
+		nodes, err := repo.ListSubtree(ctx, "task-1", 2)
+		assert.NoError(t, err)
+		assert.Len(t, nodes, 2)
+		assert.Equal(t, "task-1", *nodes[1].ParentID)
+		assert.Equal(t, 1, nodes[1].Depth)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("subtree of a deleted task", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("WITH RECURSIVE subtree (task_id, depth)")).
+			WithArgs("task-1", 2).
+			WillReturnRows(subtreeRow(sqlmock.NewRows(subtreeColumns), "task-1", nil, "2024-01-01 00:00:00+00:00", 0))
+
+		_, err := repo.ListSubtree(ctx, "task-1", 2)
+		assert.True(t, errors.Is(err, customError.TaskNotFound))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("list ancestors from the parent up", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("WITH RECURSIVE ancestors (task_id, parent_id, depth)")).
+			WithArgs("task-3", constants.MaxTaskDepth).
+			WillReturnRows(sqlmock.NewRows([]string{"task_id"}).AddRow("task-2").AddRow("task-1"))
+
+		ancestors, err := repo.ListAncestors(ctx, "task-3")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"task-2", "task-1"}, ancestors)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("count children", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(*), COALESCE(SUM(CASE WHEN status = ? THEN 1 ELSE 0 END), 0) FROM tasks WHERE parent_id = ? AND deleted_at IS NULL")).
+			WithArgs(constants.Complete, "task-1").
+			WillReturnRows(sqlmock.NewRows([]string{"total", "completed"}).AddRow(3, 2))
+
+		total, completed, err := repo.CountChildren(ctx, "task-1")
+		assert.NoError(t, err)
+		assert.Equal(t, 3, total)
+		assert.Equal(t, 2, completed)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_taskRepository_WithTx(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+
+	t.Run("commit when fn succeeds", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectPurge(mock, "task-123", true)
+		mock.ExpectCommit()
+
+		err := repo.WithTx(ctx, func(tx TaskRepository) error {
+			return tx.Purge(ctx, "task-123")
+		})
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rollback when fn fails", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectPurge(mock, "task-123", false)
+		mock.ExpectRollback()
+
+		err := repo.WithTx(ctx, func(tx TaskRepository) error {
+			return tx.Purge(ctx, "task-123")
+		})
+		assert.True(t, errors.Is(err, customError.TaskNotFound))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+	t.Run("nested calls reuse the outer transaction", func(t *testing.T) {
+		mock.ExpectBegin()
+		expectPurge(mock, "task-123", true)
+		expectPurge(mock, "task-456", false)
+		mock.ExpectRollback()
+
+		err := repo.WithTx(ctx, func(tx TaskRepository) error {
+			if err := tx.Purge(ctx, "task-123"); err != nil {
+				return err
+			}
+			return tx.WithTx(ctx, func(nested TaskRepository) error {
+				return nested.Purge(ctx, "task-456")
+			})
+		})
+		assert.True(t, errors.Is(err, customError.TaskNotFound))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rollback and re-panic when fn panics", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		assert.PanicsWithValue(t, "boom", func() {
+			_ = repo.WithTx(ctx, func(tx TaskRepository) error {
+				panic("boom")
+			})
+		})
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_taskRepository_Reminders(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	t.Run("list due reminders", func(t *testing.T) {
+		columns := []string{"id", "name", "status", "version", "created_at", "updated_at", "deleted_at", "due_at", "remind_at", "priority", "position", "parent_id", "recurrence", "blocked"}
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE deleted_at IS NULL AND reminded_at IS NULL AND status <> ? AND COALESCE(remind_at, due_at) <= ? ORDER BY COALESCE(remind_at, due_at), id LIMIT ?")).
+			WithArgs(constants.Complete, now, 100).
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("task-123", "Test Task", 0, 1, time.Now(), time.Now(), nil, "2024-01-01 09:00:00+00:00", nil, 1, "00000001", nil, nil, false))
+
+		tasks, err := repo.ListDueReminders(ctx, now, 100)
+		assert.NoError(t, err)
+		assert.Len(t, tasks, 1)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("claim only once", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE tasks SET reminded_at = ? WHERE id = ? AND reminded_at IS NULL")).
+			WithArgs(now, "task-123").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE tasks SET reminded_at = ? WHERE id = ? AND reminded_at IS NULL")).
+			WithArgs(now, "task-123").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		claimed, err := repo.ClaimReminder(ctx, "task-123", now)
+		assert.NoError(t, err)
+		assert.True(t, claimed)
+		claimed, err = repo.ClaimReminder(ctx, "task-123", now)
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("release reminder", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE tasks SET reminded_at = NULL WHERE id = ?")).
+			WithArgs("task-123").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.ReleaseReminder(ctx, "task-123"))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_taskRepository_Positions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+
+	t.Run("last position", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COALESCE(MAX(position), '') FROM tasks")).
+			WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow("00000003"))
+
+		last, err := repo.LastPosition(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, "00000003", last)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("adjacent position after", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT position FROM tasks WHERE id <> ? AND position > ? ORDER BY position LIMIT 1")).
+			WithArgs("task-1", "00000003").
+			WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow("00000004"))
+
+		adjacent, err := repo.AdjacentPosition(ctx, "task-1", "00000003", true)
+		assert.NoError(t, err)
+		assert.Equal(t, "00000004", adjacent)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no adjacent position before", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT position FROM tasks WHERE id <> ? AND position < ? ORDER BY position DESC LIMIT 1")).
+			WithArgs("task-1", "00000001").
+			WillReturnRows(sqlmock.NewRows([]string{"position"}))
+
+		adjacent, err := repo.AdjacentPosition(ctx, "task-1", "00000001", false)
+		assert.NoError(t, err)
+		assert.Empty(t, adjacent)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_taskRepository_Tags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	taskColumns := []string{"id", "name", "status", "version", "created_at", "updated_at", "deleted_at", "due_at", "remind_at", "priority", "position", "parent_id", "recurrence", "blocked"}
+	findTask := func(id string) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id,name,status,version,created_at,COALESCE(updated_at,created_at),deleted_at,due_at,remind_at,priority,COALESCE(position,''),parent_id,recurrence," + blockedColumn + " FROM tasks WHERE id = ? AND deleted_at IS NULL")).
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows(taskColumns).AddRow(id, "Task", 0, 1, time.Now(), time.Now(), nil, nil, nil, 1, "00000001", nil, nil, false))
+	}
+
+	t.Run("list tags of many tasks in one query", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT tt.task_id,g.id,g.name,g.color,g.created_at,g.updated_at FROM task_tags tt JOIN tags g ON g.id = tt.tag_id WHERE tt.task_id IN (?,?) ORDER BY g.name, g.id")).
+			WithArgs("task-1", "task-2").
+			WillReturnRows(sqlmock.NewRows([]string{"task_id", "id", "name", "color", "created_at", "updated_at"}).
+				AddRow("task-1", "tag-2", "home", nil, "2024-01-01 00:00:00+00:00", "2024-01-01 00:00:00+00:00").
+				AddRow("task-2", "tag-2", "home", nil, "2024-01-01 00:00:00+00:00", "2024-01-01 00:00:00+00:00").
+				AddRow("task-1", "tag-1", "work", "#1e90ff", "2024-01-01 00:00:00+00:00", "2024-01-01 00:00:00+00:00"))
+
+		tags, err := repo.ListTags(ctx, []string{"task-1", "task-2", "task-1"})
+		assert.NoError(t, err)
+		assert.Len(t, tags["task-1"], 2)
+		assert.Equal(t, "#1e90ff", *tags["task-1"][1].Color)
+		assert.Len(t, tags["task-2"], 1)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("no tasks", func(t *testing.T) {
+		tags, err := repo.ListTags(ctx, nil)
+		assert.NoError(t, err)
+		assert.Empty(t, tags)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("attach skips attached tags and bumps version", func(t *testing.T) {
+		mock.ExpectBegin()
+		findTask("task-1")
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT tag_id FROM task_tags WHERE task_id = ?")).
+			WithArgs("task-1").
+			WillReturnRows(sqlmock.NewRows([]string{"tag_id"}).AddRow("tag-1"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM tags WHERE id IN (?,?)")).
+			WithArgs("tag-1", "tag-2").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("tag-1").AddRow("tag-2"))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO task_tags (task_id, tag_id, created_at) VALUES (?, ?, ?)")).
+			WithArgs("task-1", "tag-2", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE tasks SET version = version + 1, updated_at = ? WHERE id = ?")).
+			WithArgs(sqlmock.AnyArg(), "task-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectAudit(mock, "task-1", constants.AuditUpdate)
+		mock.ExpectCommit()
+
+		err := repo.AttachTags(ctx, "task-1", []string{"tag-1", "tag-2", "tag-2"})
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("attach unknown tag", func(t *testing.T) {
+		mock.ExpectBegin()
+		findTask("task-1")
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT tag_id FROM task_tags WHERE task_id = ?")).
+			WithArgs("task-1").
+			WillReturnRows(sqlmock.NewRows([]string{"tag_id"}))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM tags WHERE id IN (?)")).
+			WithArgs("tag-404").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		err := repo.AttachTags(ctx, "task-1", []string{"tag-404"})
+		assert.True(t, errors.Is(err, customError.TagNotFound))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("detach tag that is not attached", func(t *testing.T) {
+		mock.ExpectBegin()
+		findTask("task-1")
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT tag_id FROM task_tags WHERE task_id = ?")).
+			WithArgs("task-1").
+			WillReturnRows(sqlmock.NewRows([]string{"tag_id"}))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM task_tags WHERE task_id = ? AND tag_id IN (?)")).
+			WithArgs("task-1", "tag-1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := repo.DetachTags(ctx, "task-1", []string{"tag-1"})
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_taskRepository_Blockers(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	taskColumns := []string{"id", "name", "status", "version", "created_at", "updated_at", "deleted_at", "due_at", "remind_at", "priority", "position", "parent_id", "recurrence", "blocked"}
+	findTask := func(id string) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id,name,status,version,created_at,COALESCE(updated_at,created_at),deleted_at,due_at,remind_at,priority,COALESCE(position,''),parent_id,recurrence," + blockedColumn + " FROM tasks WHERE id = ? AND deleted_at IS NULL")).
+			WithArgs(id).
+			WillReturnRows(sqlmock.NewRows(taskColumns).AddRow(id, "Task", 0, 1, time.Now(), time.Now(), nil, nil, nil, 1, "00000001", nil, nil, true))
+	}
+
+	t.Run("lock dependencies", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE task_dependency_lock SET version = version + 1 WHERE id = 1")).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.LockDependencies(ctx))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("find reads the blocked flag", func(t *testing.T) {
+		findTask("task-1")
+
+		task, err := repo.Find(ctx, "task-1")
+		assert.NoError(t, err)
+		assert.True(t, task.Blocked)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("list direct blockers of many tasks", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT task_id, blocker_id FROM task_dependencies WHERE task_id IN (?,?) ORDER BY task_id, blocker_id")).
+			WithArgs("task-1", "task-2").
+			WillReturnRows(sqlmock.NewRows([]string{"task_id", "blocker_id"}).AddRow("task-1", "task-3").AddRow("task-1", "task-4").AddRow("task-2", "task-1"))
+
+		blockers, err := repo.ListBlockers(ctx, []string{"task-1", "task-2"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string][]string{"task-1": {"task-3", "task-4"}, "task-2": {"task-1"}}, blockers)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("list all blockers recursively", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("WITH RECURSIVE blockers (id) AS (SELECT blocker_id FROM task_dependencies WHERE task_id IN (?)" +
+			" UNION SELECT d.blocker_id FROM task_dependencies d JOIN blockers b ON d.task_id = b.id) SELECT id FROM blockers")).
+			WithArgs("task-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("task-2").AddRow("task-3"))
+
+		blockers, err := repo.ListAllBlockers(ctx, []string{"task-1"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]bool{"task-2": true, "task-3": true}, blockers)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("add skips existing blockers and bumps version", func(t *testing.T) {
+		mock.ExpectBegin()
+		findTask("task-1")
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT blocker_id FROM task_dependencies WHERE task_id = ?")).
+			WithArgs("task-1").
+			WillReturnRows(sqlmock.NewRows([]string{"blocker_id"}).AddRow("task-2"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM tasks WHERE deleted_at IS NULL AND id IN (?,?)")).
+			WithArgs("task-2", "task-3").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("task-2").AddRow("task-3"))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO task_dependencies (task_id, blocker_id, created_at) VALUES (?, ?, ?)")).
+			WithArgs("task-1", "task-3", sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE tasks SET version = version + 1, updated_at = ? WHERE id = ?")).
+			WithArgs(sqlmock.AnyArg(), "task-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectAudit(mock, "task-1", constants.AuditUpdate)
+		mock.ExpectCommit()
+
+		err := repo.AddBlockers(ctx, "task-1", []string{"task-2", "task-3", "task-3"})
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("add deleted blocker", func(t *testing.T) {
+		mock.ExpectBegin()
+		findTask("task-1")
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT blocker_id FROM task_dependencies WHERE task_id = ?")).
+			WithArgs("task-1").
+			WillReturnRows(sqlmock.NewRows([]string{"blocker_id"}))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM tasks WHERE deleted_at IS NULL AND id IN (?)")).
+			WithArgs("task-404").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		err := repo.AddBlockers(ctx, "task-1", []string{"task-404"})
+		assert.True(t, errors.Is(err, customError.TaskNotFound))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("remove blocker bumps version", func(t *testing.T) {
+		mock.ExpectBegin()
+		findTask("task-1")
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT blocker_id FROM task_dependencies WHERE task_id = ?")).
+			WithArgs("task-1").
+			WillReturnRows(sqlmock.NewRows([]string{"blocker_id"}).AddRow("task-2"))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM task_dependencies WHERE task_id = ? AND blocker_id IN (?)")).
+			WithArgs("task-1", "task-2").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE tasks SET version = version + 1, updated_at = ? WHERE id = ?")).
+			WithArgs(sqlmock.AnyArg(), "task-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectAudit(mock, "task-1", constants.AuditUpdate)
+		mock.ExpectCommit()
+
+		err := repo.RemoveBlockers(ctx, "task-1", []string{"task-2"})
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_taskRepository_FindWorkflow(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewTaskRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+
+	t.Run("states and transitions", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name FROM workflow_states ORDER BY position, id")).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(0, "todo").AddRow(2, "in_progress").AddRow(1, "done"))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT from_status, to_status FROM workflow_transitions ORDER BY from_status, to_status")).
+			WillReturnRows(sqlmock.NewRows([]string{"from_status", "to_status"}).AddRow(0, 2).AddRow(2, 1))
+
+		workflow, err := repo.FindWorkflow(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, []*models.WorkflowState{{ID: 0, Name: "todo"}, {ID: 2, Name: "in_progress"}, {ID: 1, Name: "done"}}, workflow.States)
+		assert.Equal(t, []*models.WorkflowTransition{{From: 0, To: 2}, {From: 2, To: 1}}, workflow.Transitions)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT id, name FROM workflow_states")).
+			WillReturnError(sql.ErrConnDone)
+
+		_, err := repo.FindWorkflow(ctx)
+		assert.Error(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
 	})
 }
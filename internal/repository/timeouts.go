@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	customError "tasks/errors"
+	"time"
+)
+
+// Timeouts 資料庫操作的逾時設定，Operations 以操作名稱覆寫 Default，0 表示不設逾時
+type Timeouts struct {
+	Default    time.Duration
+	Operations map[string]time.Duration
+}
+
+func (t Timeouts) For(operation string) time.Duration {
+	if timeout, ok := t.Operations[operation]; ok {
+		return timeout
+	}
+	return t.Default
+}
+
+// apply 依操作套用逾時，回傳的 done 需以 defer 呼叫，會將逾時錯誤轉為 customError.Timeout
+func (t Timeouts) apply(ctx context.Context, operation string) (context.Context, func(err *error)) {
+	cancel := context.CancelFunc(func() {})
+	if timeout := t.For(operation); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	return ctx, func(err *error) {
+		defer cancel()
+		if *err == nil || !customError.CauseCustomError(*err).IsEmpty() {
+			return
+		}
+		if errors.Is(*err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			*err = customError.Timeout.Wrapf(*err, "%s timed out", operation)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	customError "tasks/errors"
+	"tasks/internal/dialect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func TestTimeouts_For(t *testing.T) {
+	timeouts := Timeouts{
+		Default:    5 * time.Second,
+		Operations: map[string]time.Duration{"list_tasks": 10 * time.Second, "purge_deleted_tasks": 0},
+	}
+
+	assert.Equal(t, 5*time.Second, timeouts.For("find_task"))
+	assert.Equal(t, 10*time.Second, timeouts.For("list_tasks"))
+	assert.Equal(t, time.Duration(0), timeouts.For("purge_deleted_tasks"))
+}
+
+func TestTimeouts_apply(t *testing.T) {
+	t.Run("deadline is converted to timeout error", func(t *testing.T) {
+		ctx, done := Timeouts{Default: time.Millisecond}.apply(context.Background(), "find_task")
+		<-ctx.Done()
+		err := ctx.Err()
+		done(&err)
+		assert.True(t, errors.Is(err, customError.Timeout))
+	})
+
+	t.Run("custom error is kept", func(t *testing.T) {
+		ctx, done := Timeouts{Default: time.Millisecond}.apply(context.Background(), "find_task")
+		<-ctx.Done()
+		err := customError.TaskNotFound.New("task not found")
+		done(&err)
+		assert.True(t, errors.Is(err, customError.TaskNotFound))
+	})
+
+	t.Run("caller cancellation is not a timeout", func(t *testing.T) {
+		parent, cancel := context.WithCancel(context.Background())
+		ctx, done := Timeouts{Default: time.Minute}.apply(parent, "find_task")
+		cancel()
+		err := ctx.Err()
+		done(&err)
+		assert.False(t, errors.Is(err, customError.Timeout))
+		assert.True(t, errors.Is(err, context.Canceled))
+	})
+
+	t.Run("zero disables the deadline", func(t *testing.T) {
+		ctx, done := Timeouts{}.apply(context.Background(), "find_task")
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+		var err error
+		done(&err)
+		assert.NoError(t, err)
+	})
+}
+
+func Test_taskRepository_Timeout(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	timeouts := Timeouts{Default: time.Minute, Operations: map[string]time.Duration{"find_task": 20 * time.Millisecond}}
+	repo := NewTaskRepository(db, dialect.SQLite, timeouts, zap.NewNop())
+
+	mock.ExpectQuery(regexp.QuoteMeta("FROM tasks WHERE id = ?")).
+		WithArgs("task-123").
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	started := time.Now()
+	task, err := repo.Find(context.Background(), "task-123")
+	assert.Nil(t, task)
+	assert.True(t, errors.Is(err, customError.Timeout))
+	assert.Less(t, time.Since(started), time.Second)
+}
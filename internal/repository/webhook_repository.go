@@ -0,0 +1,351 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"go.uber.org/zap"
+	"strings"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"tasks/internal/dialect"
+	"time"
+)
+
+type webhookRepository struct {
+	db       *sql.DB
+	conn     dbConn
+	dialect  dialect.Dialect
+	timeouts Timeouts
+	logger   *zap.Logger
+}
+
+func NewWebhookRepository(conn *sql.DB, dialect dialect.Dialect, timeouts Timeouts, logger *zap.Logger) WebhookRepository {
+	return &webhookRepository{
+		db:       conn,
+		conn:     reboundConn{conn: conn, dialect: dialect},
+		dialect:  dialect,
+		timeouts: timeouts,
+		logger:   logger,
+	}
+}
+
+const (
+	webhookColumns  = "id,url,secret,events,active,created_at,updated_at"
+	deliveryColumns = "id,event_id,webhook_id,event,task_id,payload,status,attempts,next_attempt_at,last_status_code,last_error,created_at,delivered_at"
+)
+
+func scanWebhook(row rowScanner) (*models.Webhook, error) {
+	webhook := models.Webhook{}
+	if err := row.Scan(&webhook.ID, &webhook.URL, &webhook.Secret, &webhook.Events, &webhook.Active, &webhook.CreatedAt, &webhook.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func scanDelivery(row rowScanner, extra ...interface{}) (*models.WebhookDelivery, error) {
+	delivery := models.WebhookDelivery{}
+	dest := []interface{}{&delivery.ID, &delivery.EventID, &delivery.WebhookID, &delivery.Event, &delivery.TaskID, &delivery.Payload, &delivery.Status,
+		&delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastStatusCode, &delivery.LastError, &delivery.CreatedAt, &delivery.DeliveredAt}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// eventsArg 事件以逗號分隔保存
+func eventsArg(events []constants.WebhookEvent) string {
+	values := make([]string, 0, len(events))
+	for _, event := range events {
+		values = append(values, string(event))
+	}
+	return strings.Join(values, ",")
+}
+
+// activeArg active 欄位為整數，不是每個資料庫都接受 bool
+func activeArg(active bool) int {
+	if active {
+		return 1
+	}
+	return 0
+}
+
+func (r *webhookRepository) Find(ctx context.Context, id string) (webhook *models.Webhook, err error) {
+	ctx, done := r.timeouts.apply(ctx, "find_webhook")
+	defer done(&err)
+	webhook, err = scanWebhook(r.conn.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = ?", id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.WebhookNotFound.Wrap(err, "webhook not found")
+		}
+		r.logger.Error("Find webhook error", zap.String("id", id), zap.Error(err))
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (r *webhookRepository) List(ctx context.Context) (result []*models.Webhook, err error) {
+	ctx, done := r.timeouts.apply(ctx, "list_webhooks")
+	defer done(&err)
+	rows, err := r.conn.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks ORDER BY created_at, id")
+	if err != nil {
+		r.logger.Error("List webhook error", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	result = make([]*models.Webhook, 0)
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			r.logger.Error("Scan webhook error", zap.Error(err))
+			return nil, err
+		}
+		result = append(result, webhook)
+	}
+	if err = rows.Err(); err != nil {
+		r.logger.Error("Iterate webhook rows error", zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *webhookRepository) Create(ctx context.Context, webhook entities.Webhook) (err error) {
+	ctx, done := r.timeouts.apply(ctx, "create_webhook")
+	defer done(&err)
+	_, err = r.conn.ExecContext(ctx, "INSERT INTO webhooks (id, url, secret, events, active, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		webhook.ID, webhook.URL, webhook.Secret, eventsArg(webhook.Events), activeArg(webhook.Active), webhook.CreatedAt, webhook.UpdatedAt)
+	if err != nil {
+		r.logger.Error("Execute insert webhook error", zap.String("id", webhook.ID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Update secret 為空字串時保留原本的 secret
+func (r *webhookRepository) Update(ctx context.Context, webhook entities.Webhook) (err error) {
+	ctx, done := r.timeouts.apply(ctx, "update_webhook")
+	defer done(&err)
+	query := "UPDATE webhooks SET url = ?, events = ?, active = ?, updated_at = ?"
+	args := []interface{}{webhook.URL, eventsArg(webhook.Events), activeArg(webhook.Active), webhook.UpdatedAt}
+	if webhook.Secret != "" {
+		query += ", secret = ?"
+		args = append(args, webhook.Secret)
+	}
+	rows, err := r.conn.ExecContext(ctx, query+" WHERE id = ?", append(args, webhook.ID)...)
+	if err != nil {
+		r.logger.Error("Execute update webhook error", zap.String("id", webhook.ID), zap.Error(err))
+		return err
+	}
+	effectRows, err := rows.RowsAffected()
+	if err != nil {
+		r.logger.Error("Execute update webhook error", zap.String("id", webhook.ID), zap.Error(err))
+		return err
+	}
+	if effectRows == 0 {
+		return customError.WebhookNotFound.New("webhook not found")
+	}
+	return nil
+}
+
+// Delete 刪除 webhook 與其所有投遞，尚未送出的事件不再送出
+func (r *webhookRepository) Delete(ctx context.Context, id string) (err error) {
+	ctx, done := r.timeouts.apply(ctx, "delete_webhook")
+	defer done(&err)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("Begin tx error", zap.Error(err))
+		return err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+				r.logger.Error("Rollback tx error", zap.Error(rollbackErr))
+			}
+		}
+	}()
+	conn := reboundConn{conn: tx, dialect: r.dialect}
+	rows, err := conn.ExecContext(ctx, "DELETE FROM webhooks WHERE id = ?", id)
+	if err != nil {
+		r.logger.Error("Execute delete webhook error", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	effectRows, err := rows.RowsAffected()
+	if err != nil {
+		r.logger.Error("Execute delete webhook error", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	if effectRows == 0 {
+		return customError.WebhookNotFound.New("webhook not found")
+	}
+	if _, err = conn.ExecContext(ctx, "DELETE FROM webhook_outbox WHERE webhook_id = ?", id); err != nil {
+		r.logger.Error("Execute delete webhook outbox error", zap.String("id", id), zap.Error(err))
+		return err
+	}
+	if err = tx.Commit(); err != nil {
+		r.logger.Error("Commit tx error", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// ListDeliveries 依 id 由新到舊排列
+func (r *webhookRepository) ListDeliveries(ctx context.Context, param entities.DeliveryQueryParam) (result []*models.WebhookDelivery, err error) {
+	ctx, done := r.timeouts.apply(ctx, "list_deliveries")
+	defer done(&err)
+	conditions := []string{"webhook_id = ?"}
+	args := []interface{}{param.WebhookID}
+	if param.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, string(param.Status))
+	}
+	if param.Before > 0 {
+		conditions = append(conditions, "id < ?")
+		args = append(args, param.Before)
+	}
+	var query strings.Builder
+	query.WriteString("SELECT " + deliveryColumns + " FROM webhook_outbox")
+	writeWhere(&query, conditions)
+	query.WriteString(" ORDER BY id DESC LIMIT ?")
+	rows, err := r.conn.QueryContext(ctx, query.String(), append(args, param.Size)...)
+	if err != nil {
+		r.logger.Error("List deliveries error", zap.String("webhook_id", param.WebhookID), zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	result = make([]*models.WebhookDelivery, 0)
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			r.logger.Error("Scan delivery error", zap.Error(err))
+			return nil, err
+		}
+		result = append(result, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		r.logger.Error("Iterate delivery rows error", zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *webhookRepository) FindDelivery(ctx context.Context, webhookID string, id int64) (delivery *models.WebhookDelivery, err error) {
+	ctx, done := r.timeouts.apply(ctx, "find_delivery")
+	defer done(&err)
+	delivery, err = scanDelivery(r.conn.QueryRowContext(ctx, "SELECT "+deliveryColumns+" FROM webhook_outbox WHERE id = ? AND webhook_id = ?", id, webhookID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, customError.DeliveryNotFound.Wrap(err, "webhook delivery not found")
+		}
+		r.logger.Error("Find delivery error", zap.Int64("id", id), zap.Error(err))
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// ListDueDeliveries 已到投遞時間的 pending 投遞，依 id 由舊到新排列，停用中的 webhook 暫停投遞
+func (r *webhookRepository) ListDueDeliveries(ctx context.Context, now time.Time, limit int) (result []*models.DueDelivery, err error) {
+	ctx, done := r.timeouts.apply(ctx, "list_due_deliveries")
+	defer done(&err)
+	columns := make([]string, 0)
+	for _, column := range strings.Split(deliveryColumns, ",") {
+		columns = append(columns, "o."+column)
+	}
+	rows, err := r.conn.QueryContext(ctx, "SELECT "+strings.Join(columns, ",")+", w.url, w.secret FROM webhook_outbox o JOIN webhooks w ON w.id = o.webhook_id"+
+		" WHERE o.status = ? AND o.next_attempt_at <= ? AND w.active = 1 ORDER BY o.id LIMIT ?",
+		string(constants.DeliveryPending), now, limit)
+	if err != nil {
+		r.logger.Error("List due deliveries error", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	result = make([]*models.DueDelivery, 0)
+	for rows.Next() {
+		var due models.DueDelivery
+		delivery, err := scanDelivery(rows, &due.URL, &due.Secret)
+		if err != nil {
+			r.logger.Error("Scan due delivery error", zap.Error(err))
+			return nil, err
+		}
+		due.WebhookDelivery = *delivery
+		result = append(result, &due)
+	}
+	if err = rows.Err(); err != nil {
+		r.logger.Error("Iterate due delivery rows error", zap.Error(err))
+		return nil, err
+	}
+	return result, nil
+}
+
+// ClaimDelivery 將下次投遞時間延後到 until 並累計投遞次數，回傳 false 表示已被其他 instance 取走，
+// 投遞中途停止時 until 之後會再被取出
+func (r *webhookRepository) ClaimDelivery(ctx context.Context, id int64, now, until time.Time) (claimed bool, err error) {
+	ctx, done := r.timeouts.apply(ctx, "claim_delivery")
+	defer done(&err)
+	rows, err := r.conn.ExecContext(ctx, "UPDATE webhook_outbox SET next_attempt_at = ?, attempts = attempts + 1 WHERE id = ? AND status = ? AND next_attempt_at <= ?",
+		until, id, string(constants.DeliveryPending), now)
+	if err != nil {
+		r.logger.Error("Execute claim delivery error", zap.Int64("id", id), zap.Error(err))
+		return false, err
+	}
+	effectRows, err := rows.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return effectRows > 0, nil
+}
+
+// CompleteDelivery 標記為已送達
+func (r *webhookRepository) CompleteDelivery(ctx context.Context, id int64, statusCode int, now time.Time) (err error) {
+	ctx, done := r.timeouts.apply(ctx, "complete_delivery")
+	defer done(&err)
+	_, err = r.conn.ExecContext(ctx, "UPDATE webhook_outbox SET status = ?, last_status_code = ?, last_error = NULL, delivered_at = ? WHERE id = ?",
+		string(constants.DeliveryDelivered), statusCode, now, id)
+	if err != nil {
+		r.logger.Error("Execute complete delivery error", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// FailDelivery 記錄失敗原因，status 為 pending 時於 nextAttemptAt 重試，dead 時不再重試，statusCode 為 0 表示沒有回應
+func (r *webhookRepository) FailDelivery(ctx context.Context, id int64, status constants.DeliveryStatus, statusCode int, lastError string, nextAttemptAt time.Time) (err error) {
+	ctx, done := r.timeouts.apply(ctx, "fail_delivery")
+	defer done(&err)
+	var code interface{}
+	if statusCode > 0 {
+		code = statusCode
+	}
+	_, err = r.conn.ExecContext(ctx, "UPDATE webhook_outbox SET status = ?, last_status_code = ?, last_error = ?, next_attempt_at = ? WHERE id = ?",
+		string(status), code, lastError, nextAttemptAt, id)
+	if err != nil {
+		r.logger.Error("Execute fail delivery error", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// Redeliver 將已送達或 dead 的投遞重設為 pending 並立即投遞，投遞次數重新計算
+func (r *webhookRepository) Redeliver(ctx context.Context, webhookID string, id int64, now time.Time) (err error) {
+	ctx, done := r.timeouts.apply(ctx, "redeliver")
+	defer done(&err)
+	rows, err := r.conn.ExecContext(ctx, "UPDATE webhook_outbox SET status = ?, attempts = 0, next_attempt_at = ?, delivered_at = NULL WHERE id = ? AND webhook_id = ? AND status <> ?",
+		string(constants.DeliveryPending), now, id, webhookID, string(constants.DeliveryPending))
+	if err != nil {
+		r.logger.Error("Execute redeliver error", zap.Int64("id", id), zap.Error(err))
+		return err
+	}
+	effectRows, err := rows.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if effectRows > 0 {
+		return nil
+	}
+	// 沒有更新時區分投遞不存在或仍在等待投遞
+	if _, err = r.FindDelivery(ctx, webhookID, id); err != nil {
+		return err
+	}
+	return customError.DeliveryPending.Errorf("delivery %d is still pending", id)
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"tasks/constants"
+	"tasks/domain/entities"
+	customError "tasks/errors"
+	"tasks/internal/dialect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// deliveryColumnNames deliveryColumns 查詢回傳的欄位
+var deliveryColumnNames = []string{"id", "event_id", "webhook_id", "event", "task_id", "payload", "status", "attempts", "next_attempt_at", "last_status_code", "last_error", "created_at", "delivered_at"}
+
+func Test_webhookRepository_Update(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebhookRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []constants.WebhookEvent{constants.WebhookTaskCreated, constants.WebhookTaskDeleted}
+
+	t.Run("keeps the secret when it is empty", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE webhooks SET url = ?, events = ?, active = ?, updated_at = ? WHERE id = ?")).
+			WithArgs("https://example.com/hook", "task.created,task.deleted", 0, now, "hook-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Update(ctx, entities.Webhook{ID: "hook-1", URL: "https://example.com/hook", Events: events, UpdatedAt: now})
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("rotates the secret", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE webhooks SET url = ?, events = ?, active = ?, updated_at = ?, secret = ? WHERE id = ?")).
+			WithArgs("https://example.com/hook", "task.created,task.deleted", 1, now, "new-secret", "hook-1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.Update(ctx, entities.Webhook{ID: "hook-1", URL: "https://example.com/hook", Events: events, Active: true, Secret: "new-secret", UpdatedAt: now})
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mock.ExpectExec("UPDATE webhooks SET").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Update(ctx, entities.Webhook{ID: "hook-9", Events: events, UpdatedAt: now})
+		assert.True(t, errors.Is(err, customError.WebhookNotFound))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func Test_webhookRepository_Deliveries(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	defer db.Close()
+
+	repo := NewWebhookRepository(db, dialect.SQLite, Timeouts{}, zap.NewNop())
+	ctx := context.Background()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("list with filters", func(t *testing.T) {
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT "+deliveryColumns+" FROM webhook_outbox WHERE webhook_id = ? AND status = ? AND id < ? ORDER BY id DESC LIMIT ?")).
+			WithArgs("hook-1", "dead", int64(10), 51).
+			WillReturnRows(sqlmock.NewRows(deliveryColumnNames).
+				AddRow(7, "event-1", "hook-1", "task.created", "task-1", `{}`, "dead", 8, now, 503, "unexpected status 503", now, nil))
+
+		deliveries, err := repo.ListDeliveries(ctx, entities.DeliveryQueryParam{WebhookID: "hook-1", Status: constants.DeliveryDead, Before: 10, Size: 51})
+		assert.NoError(t, err)
+		assert.Len(t, deliveries, 1)
+		assert.Equal(t, 503, *deliveries[0].LastStatusCode)
+		assert.Nil(t, deliveries[0].DeliveredAt)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("claim is lost to another instance", func(t *testing.T) {
+		until := now.Add(time.Minute)
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE webhook_outbox SET next_attempt_at = ?, attempts = attempts + 1 WHERE id = ? AND status = ? AND next_attempt_at <= ?")).
+			WithArgs(until, int64(7), "pending", now).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		claimed, err := repo.ClaimDelivery(ctx, 7, now, until)
+		assert.NoError(t, err)
+		assert.False(t, claimed)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("failure without a response stores a null status code", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE webhook_outbox SET status = ?, last_status_code = ?, last_error = ?, next_attempt_at = ? WHERE id = ?")).
+			WithArgs("pending", nil, "connection refused", now, int64(7)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		err := repo.FailDelivery(ctx, 7, constants.DeliveryPending, 0, "connection refused", now)
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("redeliver a pending delivery", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE webhook_outbox SET status = ?, attempts = 0")).
+			WithArgs("pending", now, int64(7), "hook-1", "pending").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("FROM webhook_outbox WHERE id = ? AND webhook_id = ?")).
+			WithArgs(int64(7), "hook-1").
+			WillReturnRows(sqlmock.NewRows(deliveryColumnNames).
+				AddRow(7, "event-1", "hook-1", "task.created", "task-1", `{}`, "pending", 1, now, nil, nil, now, nil))
+
+		err := repo.Redeliver(ctx, "hook-1", 7, now)
+		assert.True(t, errors.Is(err, customError.DeliveryPending))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("redeliver an unknown delivery", func(t *testing.T) {
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE webhook_outbox SET status = ?, attempts = 0")).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta("FROM webhook_outbox WHERE id = ? AND webhook_id = ?")).
+			WillReturnRows(sqlmock.NewRows(deliveryColumnNames))
+
+		err := repo.Redeliver(ctx, "hook-2", 7, now)
+		assert.True(t, errors.Is(err, customError.DeliveryNotFound))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
@@ -0,0 +1,182 @@
+package search
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 搜尋字串以空白分隔為多個 term，所有 term 都需符合，
+// 以雙引號包住的文字為 phrase，結尾為 * 的 term 為 prefix，例如 "weekly report" plan*
+
+// MaxTerms 一次搜尋最多的 term 數
+const MaxTerms = 10
+
+// SnippetOpen 與 SnippetClose 為 FTS5 snippet 標示符合範圍用的字元，使用 Unicode 私用區，
+// 由 MarkSnippet 在 HTML escape 後換成真正的標籤
+const (
+	SnippetOpen  = "\uE000"
+	SnippetClose = "\uE001"
+)
+
+type Term struct {
+	// Text phrase 時包含空白
+	Text   string
+	Prefix bool
+}
+
+type Query []Term
+
+// Parse 解析搜尋字串，沒有任何文字或數字的 term 會被忽略
+func Parse(value string) (Query, error) {
+	var (
+		query   Query
+		current strings.Builder
+		quoted  bool
+	)
+	flush := func(prefix bool) {
+		text := strings.Join(strings.Fields(current.String()), " ")
+		current.Reset()
+		if strings.IndexFunc(text, isWordRune) >= 0 {
+			query = append(query, Term{Text: text, Prefix: prefix})
+		}
+	}
+	runes := []rune(value)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '"':
+			prefix := quoted && i+1 < len(runes) && runes[i+1] == '*'
+			if !quoted {
+				flush(false)
+			} else {
+				flush(prefix)
+				if prefix {
+					i++
+				}
+			}
+			quoted = !quoted
+		case quoted:
+			current.WriteRune(r)
+		case unicode.IsSpace(r):
+			flush(false)
+		case r == '*' && (i+1 == len(runes) || unicode.IsSpace(runes[i+1])):
+			flush(true)
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("search: phrase is missing a closing quote")
+	}
+	flush(false)
+	if len(query) == 0 {
+		return nil, fmt.Errorf("search: query is empty")
+	}
+	if len(query) > MaxTerms {
+		return nil, fmt.Errorf("search: at most %d terms are supported", MaxTerms)
+	}
+	return query, nil
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Match 轉為 FTS5 MATCH 使用的語法，每個 term 都以雙引號包住，避免被當作 FTS5 的運算子
+func (q Query) Match() string {
+	terms := make([]string, 0, len(q))
+	for _, term := range q {
+		match := `"` + strings.ReplaceAll(term.Text, `"`, `""`) + `"`
+		if term.Prefix {
+			match += "*"
+		}
+		terms = append(terms, match)
+	}
+	return strings.Join(terms, " ")
+}
+
+// Highlight 將 text 做 HTML escape 後以 open 與 close 標示不分大小寫符合任一 term 的部分，
+// 轉為小寫後長度改變的文字無法對應位置，只做 escape
+func (q Query) Highlight(text, open, close string) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		return html.EscapeString(text)
+	}
+	type span struct{ start, end int }
+	var spans []span
+	for _, term := range q {
+		needle := strings.ToLower(term.Text)
+		for offset := 0; offset < len(lower); {
+			index := strings.Index(lower[offset:], needle)
+			if index < 0 {
+				break
+			}
+			start := offset + index
+			spans = append(spans, span{start, start + len(needle)})
+			offset = start + len(needle)
+		}
+	}
+	if len(spans) == 0 {
+		return html.EscapeString(text)
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	var result strings.Builder
+	last := 0
+	for i := 0; i < len(spans); i++ {
+		current := spans[i]
+		// 合併重疊的部分
+		for i+1 < len(spans) && spans[i+1].start <= current.end {
+			if spans[i+1].end > current.end {
+				current.end = spans[i+1].end
+			}
+			i++
+		}
+		result.WriteString(html.EscapeString(text[last:current.start]))
+		result.WriteString(open)
+		result.WriteString(html.EscapeString(text[current.start:current.end]))
+		result.WriteString(close)
+		last = current.end
+	}
+	result.WriteString(html.EscapeString(text[last:]))
+	return result.String()
+}
+
+// MarkSnippet 將以 SnippetOpen 與 SnippetClose 標示的 snippet 做 HTML escape，再把標示換成 open 與 close，
+// 名稱本身含有標示字元時只保留成對的標示，其餘的標示字元會被移除
+func MarkSnippet(snippet, open, close string) string {
+	var (
+		result strings.Builder
+		text   strings.Builder
+		marked bool
+	)
+	flush := func() {
+		result.WriteString(html.EscapeString(text.String()))
+		text.Reset()
+	}
+	for _, r := range snippet {
+		switch string(r) {
+		case SnippetOpen:
+			if !marked {
+				flush()
+				result.WriteString(open)
+				marked = true
+			}
+		case SnippetClose:
+			if marked {
+				flush()
+				result.WriteString(close)
+				marked = false
+			}
+		default:
+			text.WriteRune(r)
+		}
+	}
+	flush()
+	if marked {
+		result.WriteString(close)
+	}
+	return result.String()
+}
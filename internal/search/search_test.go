@@ -0,0 +1,83 @@
+package search
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	t.Run("words prefixes and phrases", func(t *testing.T) {
+		query, err := Parse(`  plan*  "weekly   report" budget "q3 sa"* `)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Query{
+			{Text: "plan", Prefix: true},
+			{Text: "weekly report"},
+			{Text: "budget"},
+			{Text: "q3 sa", Prefix: true},
+		}, query)
+	})
+
+	t.Run("terms without letters or digits are ignored", func(t *testing.T) {
+		query, err := Parse(`- * "" report`)
+
+		assert.NoError(t, err)
+		assert.Equal(t, Query{{Text: "report"}}, query)
+	})
+
+	t.Run("invalid queries", func(t *testing.T) {
+		for _, value := range []string{"", "  ", `"weekly report`, "- *", "a b c d e f g h i j k"} {
+			_, err := Parse(value)
+			assert.Error(t, err, value)
+		}
+	})
+}
+
+func TestQuery_Match(t *testing.T) {
+	query, _ := Parse(`plan* "weekly report" NOT OR`)
+
+	// FTS5 的運算子與特殊字元都被當作一般文字
+	assert.Equal(t, `"plan"* "weekly report" "NOT" "OR"`, query.Match())
+	assert.Equal(t, `"a""b"`, Query{{Text: `a"b`}}.Match())
+}
+
+func TestQuery_Highlight(t *testing.T) {
+	t.Run("marks every match ignoring case", func(t *testing.T) {
+		query, _ := Parse(`report plan*`)
+
+		assert.Equal(t, "[Report] for the [plan]ning, [report] again", query.Highlight("Report for the planning, report again", "[", "]"))
+	})
+
+	t.Run("overlapping matches are merged", func(t *testing.T) {
+		query, _ := Parse(`"weekly rep" report`)
+
+		assert.Equal(t, "a [weekly report]", query.Highlight("a weekly report", "[", "]"))
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		query, _ := Parse(`budget`)
+
+		assert.Equal(t, "weekly report", query.Highlight("weekly report", "[", "]"))
+	})
+
+	t.Run("text is HTML escaped", func(t *testing.T) {
+		query, _ := Parse(`img`)
+
+		assert.Equal(t, `&lt;<mark>img</mark> src=x onerror=&#34;alert(1)&#34;&gt;`, query.Highlight(`<img src=x onerror="alert(1)">`, "<mark>", "</mark>"))
+		assert.Equal(t, "&lt;script&gt;", Query{{Text: "budget"}}.Highlight("<script>", "<mark>", "</mark>"))
+	})
+}
+
+func TestMarkSnippet(t *testing.T) {
+	t.Run("escapes the text and replaces the markers", func(t *testing.T) {
+		snippet := "..." + SnippetOpen + "report" + SnippetClose + ` <script>alert("x")</script>`
+
+		assert.Equal(t, `...<mark>report</mark> &lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;`, MarkSnippet(snippet, "<mark>", "</mark>"))
+	})
+
+	t.Run("unpaired markers are dropped", func(t *testing.T) {
+		snippet := SnippetClose + "a" + SnippetOpen + "b" + SnippetOpen + "c"
+
+		assert.Equal(t, "a<mark>bc</mark>", MarkSnippet(snippet, "<mark>", "</mark>"))
+	})
+}
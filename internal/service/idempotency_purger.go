@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+// IdempotencyPurger 定期刪除過期的 Idempotency-Key
+type IdempotencyPurger struct {
+	idempotencyService IdempotencyService
+	interval           time.Duration
+	logger             *zap.Logger
+}
+
+func NewIdempotencyPurger(idempotencyService IdempotencyService, interval time.Duration, logger *zap.Logger) *IdempotencyPurger {
+	return &IdempotencyPurger{
+		idempotencyService: idempotencyService,
+		interval:           interval,
+		logger:             logger,
+	}
+}
+
+func (p *IdempotencyPurger) Run(ctx context.Context) {
+	if p.interval <= 0 {
+		p.logger.Info("idempotency key purger disabled", zap.Duration("interval", p.interval))
+		return
+	}
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.purge(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *IdempotencyPurger) purge(ctx context.Context) {
+	purged, err := p.idempotencyService.PurgeExpiredKeys(ctx)
+	if err != nil {
+		p.logger.Error("purge expired idempotency keys error", zap.Error(err))
+		return
+	}
+	if purged > 0 {
+		p.logger.Info("purged expired idempotency keys", zap.Int64("count", purged))
+	}
+}
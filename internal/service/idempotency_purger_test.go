@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestIdempotencyPurger_Run(t *testing.T) {
+	t.Run("purges until context is cancelled", func(t *testing.T) {
+		mockRepo := new(MockIdempotencyRepository)
+		purged := make(chan struct{}, 10)
+		mockRepo.On("DeleteExpired", mock.Anything, mock.Anything).Return(int64(1), nil).Run(func(mock.Arguments) {
+			purged <- struct{}{}
+		})
+		purger := NewIdempotencyPurger(NewIdempotencyService(mockRepo, time.Hour, time.Minute), 10*time.Millisecond, zap.NewNop())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			purger.Run(ctx)
+			close(done)
+		}()
+		<-purged
+		<-purged
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("purger did not stop after cancel")
+		}
+	})
+
+	t.Run("disabled without interval", func(t *testing.T) {
+		mockRepo := new(MockIdempotencyRepository)
+		purger := NewIdempotencyPurger(NewIdempotencyService(mockRepo, time.Hour, time.Minute), 0, zap.NewNop())
+
+		purger.Run(context.Background())
+
+		mockRepo.AssertNotCalled(t, "DeleteExpired", mock.Anything)
+	})
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/google/uuid"
+	"tasks/domain/entities"
+	customError "tasks/errors"
+	"tasks/internal/repository"
+	"time"
+)
+
+type idempotencyService struct {
+	repo  repository.IdempotencyRepository
+	ttl   time.Duration
+	lease time.Duration
+}
+
+// NewIdempotencyService ttl 為保存回應的時間，lease 為處理中的 key 被其他 request 接手前的等待時間
+func NewIdempotencyService(repo repository.IdempotencyRepository, ttl, lease time.Duration) IdempotencyService {
+	return &idempotencyService{repo: repo, ttl: ttl, lease: lease}
+}
+
+// Begin 保留 key 並回傳 lease，若同一個 request 已完成則回傳先前保存的回應，
+// 處理中的 key 在 lease 過期後可由相同的 request 接手
+func (s *idempotencyService) Begin(ctx context.Context, key string, request []byte) (*entities.IdempotencyLease, *entities.IdempotentResponse, error) {
+	requestHash := hashRequest(request)
+	lease := entities.IdempotencyLease{Key: key, Token: uuid.New().String()}
+	now := time.Now().UTC()
+	reserved, err := s.repo.Reserve(ctx, key, requestHash, lease.Token, now, now.Add(s.lease), now.Add(s.ttl))
+	if err != nil {
+		return nil, nil, err
+	}
+	if reserved {
+		return &lease, nil, nil
+	}
+	record, err := s.repo.Find(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if record == nil {
+		return nil, nil, customError.IdempotencyKeyInProgress.Errorf("idempotency key %s is in progress", key)
+	}
+	if record.RequestHash != requestHash {
+		return nil, nil, customError.IdempotencyKeyReused.Errorf("idempotency key %s was used with a different request", key)
+	}
+	if record.Response != nil {
+		return nil, &entities.IdempotentResponse{StatusCode: record.StatusCode, Body: []byte(*record.Response)}, nil
+	}
+	taken, err := s.repo.TakeOver(ctx, key, requestHash, lease.Token, now, now.Add(s.lease))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !taken {
+		return nil, nil, customError.IdempotencyKeyInProgress.Errorf("idempotency key %s is in progress", key)
+	}
+	return &lease, nil, nil
+}
+
+func (s *idempotencyService) Release(ctx context.Context, lease entities.IdempotencyLease) error {
+	return s.repo.Release(ctx, lease.Key, lease.Token)
+}
+
+func (s *idempotencyService) PurgeExpiredKeys(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpired(ctx, time.Now().UTC())
+}
+
+func hashRequest(request []byte) string {
+	sum := sha256.Sum256(request)
+	return hex.EncodeToString(sum[:])
+}
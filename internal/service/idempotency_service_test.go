@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"testing"
+	"time"
+)
+
+type MockIdempotencyRepository struct {
+	mock.Mock
+}
+
+func (m *MockIdempotencyRepository) Reserve(ctx context.Context, key, requestHash, leaseToken string, now, leaseExpiresAt, expiresAt time.Time) (bool, error) {
+	args := m.Called(ctx, key, requestHash, leaseToken, now, leaseExpiresAt, expiresAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) TakeOver(ctx context.Context, key, requestHash, leaseToken string, now, leaseExpiresAt time.Time) (bool, error) {
+	args := m.Called(ctx, key, requestHash, leaseToken, now, leaseExpiresAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) Find(ctx context.Context, key string) (*models.IdempotencyKey, error) {
+	args := m.Called(ctx, key)
+	if record, ok := args.Get(0).(*models.IdempotencyKey); ok {
+		return record, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockIdempotencyRepository) Release(ctx context.Context, key, leaseToken string) error {
+	args := m.Called(ctx, key, leaseToken)
+	return args.Error(0)
+}
+
+func (m *MockIdempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func Test_idempotencyService_Begin(t *testing.T) {
+	request := []byte(`{"name":"Task 1"}`)
+	requestHash := hashRequest(request)
+	response := `{"id":"task-1"}`
+
+	t.Run("reserve new key", func(t *testing.T) {
+		mockRepo := new(MockIdempotencyRepository)
+		service := NewIdempotencyService(mockRepo, time.Hour, time.Minute)
+		mockRepo.On("Reserve", mock.Anything, "key-1", requestHash, mock.Anything, mock.Anything, mock.MatchedBy(func(leaseExpiresAt time.Time) bool {
+			return time.Until(leaseExpiresAt) <= time.Minute
+		}), mock.MatchedBy(func(expiresAt time.Time) bool {
+			return time.Until(expiresAt) > 59*time.Minute
+		})).Return(true, nil)
+
+		lease, replay, err := service.Begin(context.Background(), "key-1", request)
+
+		assert.NoError(t, err)
+		assert.Nil(t, replay)
+		assert.Equal(t, "key-1", lease.Key)
+		assert.Equal(t, mockRepo.Calls[0].Arguments.String(3), lease.Token)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("replay completed request", func(t *testing.T) {
+		mockRepo := new(MockIdempotencyRepository)
+		service := NewIdempotencyService(mockRepo, time.Hour, time.Minute)
+		mockRepo.On("Reserve", mock.Anything, "key-1", requestHash, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+		mockRepo.On("Find", mock.Anything, "key-1").Return(&models.IdempotencyKey{Key: "key-1", RequestHash: requestHash, StatusCode: 201, Response: &response}, nil)
+
+		lease, replay, err := service.Begin(context.Background(), "key-1", request)
+
+		assert.NoError(t, err)
+		assert.Nil(t, lease)
+		assert.Equal(t, &entities.IdempotentResponse{StatusCode: 201, Body: []byte(response)}, replay)
+	})
+
+	t.Run("key reused with a different request", func(t *testing.T) {
+		mockRepo := new(MockIdempotencyRepository)
+		service := NewIdempotencyService(mockRepo, time.Hour, time.Minute)
+		mockRepo.On("Reserve", mock.Anything, "key-1", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+		mockRepo.On("Find", mock.Anything, "key-1").Return(&models.IdempotencyKey{Key: "key-1", RequestHash: requestHash, StatusCode: 201, Response: &response}, nil)
+
+		_, replay, err := service.Begin(context.Background(), "key-1", []byte(`{"name":"Task 2"}`))
+
+		assert.True(t, errors.Is(err, customError.IdempotencyKeyReused))
+		assert.Nil(t, replay)
+	})
+
+	t.Run("key in progress reused with a different request", func(t *testing.T) {
+		mockRepo := new(MockIdempotencyRepository)
+		service := NewIdempotencyService(mockRepo, time.Hour, time.Minute)
+		mockRepo.On("Reserve", mock.Anything, "key-1", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+		mockRepo.On("Find", mock.Anything, "key-1").Return(&models.IdempotencyKey{Key: "key-1", RequestHash: requestHash}, nil)
+
+		_, _, err := service.Begin(context.Background(), "key-1", []byte(`{"name":"Task 2"}`))
+
+		assert.True(t, errors.Is(err, customError.IdempotencyKeyReused))
+		mockRepo.AssertNotCalled(t, "TakeOver", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("request still in progress", func(t *testing.T) {
+		mockRepo := new(MockIdempotencyRepository)
+		service := NewIdempotencyService(mockRepo, time.Hour, time.Minute)
+		mockRepo.On("Reserve", mock.Anything, "key-1", requestHash, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+		mockRepo.On("Find", mock.Anything, "key-1").Return(&models.IdempotencyKey{Key: "key-1", RequestHash: requestHash}, nil)
+		mockRepo.On("TakeOver", mock.Anything, "key-1", requestHash, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+
+		lease, replay, err := service.Begin(context.Background(), "key-1", request)
+
+		assert.True(t, errors.Is(err, customError.IdempotencyKeyInProgress))
+		assert.Nil(t, lease)
+		assert.Nil(t, replay)
+	})
+
+	t.Run("take over an expired lease", func(t *testing.T) {
+		mockRepo := new(MockIdempotencyRepository)
+		service := NewIdempotencyService(mockRepo, time.Hour, time.Minute)
+		mockRepo.On("Reserve", mock.Anything, "key-1", requestHash, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+		mockRepo.On("Find", mock.Anything, "key-1").Return(&models.IdempotencyKey{Key: "key-1", RequestHash: requestHash}, nil)
+		mockRepo.On("TakeOver", mock.Anything, "key-1", requestHash, mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+
+		lease, replay, err := service.Begin(context.Background(), "key-1", request)
+
+		assert.NoError(t, err)
+		assert.Nil(t, replay)
+		assert.Equal(t, "key-1", lease.Key)
+		assert.Equal(t, mockRepo.Calls[2].Arguments.String(3), lease.Token)
+	})
+}
+
+func Test_idempotencyService_Release(t *testing.T) {
+	mockRepo := new(MockIdempotencyRepository)
+	service := NewIdempotencyService(mockRepo, time.Hour, time.Minute)
+	mockRepo.On("Release", mock.Anything, "key-1", "token-1").Return(nil)
+
+	err := service.Release(context.Background(), entities.IdempotencyLease{Key: "key-1", Token: "token-1"})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
@@ -2,12 +2,80 @@ package service
 
 import (
 	"context"
+	"tasks/constants"
 	"tasks/domain/entities"
+	"tasks/internal/webhook"
+	"time"
 )
 
 type TaskService interface {
-	CreateTask(ctx context.Context, param entities.Task) error
-	UpdateTask(ctx context.Context, param entities.Task) error
-	DeleteTask(ctx context.Context, taskId string) error
+	// CreateTask 帶 lease 時在同一個 transaction 中保存 Idempotency-Key 的回應
+	CreateTask(ctx context.Context, param entities.Task, lease *entities.IdempotencyLease) (*entities.Task, error)
+	UpdateTask(ctx context.Context, param entities.Task, expectedVersion *int) (*entities.Task, error)
+	PatchTask(ctx context.Context, taskId string, patchType constants.PatchType, patch []byte, expectedVersion *int) (*entities.Task, error)
+	MoveTask(ctx context.Context, taskId string, move entities.TaskMove, expectedVersion *int) (*entities.Task, error)
+	DeleteTask(ctx context.Context, taskId string, expectedVersion *int, cascade bool) error
+	GetTask(ctx context.Context, taskId string) (*entities.Task, error)
+	GetSubtree(ctx context.Context, taskId string, depth int) (*entities.TaskNode, error)
 	GetTasks(ctx context.Context, param entities.TaskQueryParam) (*entities.Tasks, error)
+	GetTrash(ctx context.Context, param entities.TaskQueryParam) (*entities.Tasks, error)
+	RestoreTask(ctx context.Context, taskId string) (*entities.Task, error)
+	PurgeTask(ctx context.Context, taskId string) error
+	PurgeExpiredTasks(ctx context.Context, retention time.Duration) (int64, error)
+	BatchTasks(ctx context.Context, mode constants.BatchMode, operations []entities.BatchOperation) (*entities.BatchResults, error)
+	SendDueReminders(ctx context.Context, notifier ReminderNotifier) (int, error)
+	AttachTags(ctx context.Context, taskId string, tagIds []string) (*entities.Task, error)
+	DetachTags(ctx context.Context, taskId string, tagIds []string) (*entities.Task, error)
+	AddBlockers(ctx context.Context, taskId string, blockerIds []string) (*entities.Task, error)
+	RemoveBlockers(ctx context.Context, taskId string, blockerIds []string) (*entities.Task, error)
+	SortTasks(ctx context.Context, taskIds []string) (*entities.Tasks, error)
+	GetOccurrences(ctx context.Context, taskId string, count int) (*entities.Occurrences, error)
+	GetWorkflow(ctx context.Context) (*entities.Workflow, error)
+	GetHistory(ctx context.Context, taskId string, param entities.AuditQueryParam) (*entities.AuditLog, error)
+	GetAuditLog(ctx context.Context, param entities.AuditQueryParam) (*entities.AuditLog, error)
+	VerifyAudit(ctx context.Context) (*entities.AuditVerification, error)
+	GetEvents(ctx context.Context, taskId string, after int64, size int) (*entities.TaskEvents, error)
+	GetTaskAsOf(ctx context.Context, taskId string, asOf entities.AsOf) (*entities.Task, error)
+	RebuildProjection(ctx context.Context, dryRun bool) (*entities.ProjectionRebuild, error)
+	BackfillEvents(ctx context.Context, dryRun bool) (int, error)
+}
+
+type SearchService interface {
+	Search(ctx context.Context, value string, size, page int) (*entities.SearchResults, error)
+}
+
+type TagService interface {
+	CreateTag(ctx context.Context, tag entities.Tag) (*entities.Tag, error)
+	UpdateTag(ctx context.Context, tag entities.Tag) (*entities.Tag, error)
+	DeleteTag(ctx context.Context, tagId string) error
+	GetTag(ctx context.Context, tagId string) (*entities.Tag, error)
+	GetTags(ctx context.Context) (*entities.Tags, error)
+}
+
+// ReminderNotifier 送出 task 的提醒事件
+type ReminderNotifier interface {
+	Notify(ctx context.Context, reminder entities.Reminder) error
+}
+
+type IdempotencyService interface {
+	// Begin 回傳 lease 時由 TaskService.CreateTask 在建立 task 的 transaction 中保存回應，否則回傳先前保存的回應
+	Begin(ctx context.Context, key string, request []byte) (*entities.IdempotencyLease, *entities.IdempotentResponse, error)
+	Release(ctx context.Context, lease entities.IdempotencyLease) error
+	PurgeExpiredKeys(ctx context.Context) (int64, error)
+}
+
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, webhook entities.Webhook) (*entities.Webhook, error)
+	UpdateWebhook(ctx context.Context, webhook entities.Webhook) (*entities.Webhook, error)
+	DeleteWebhook(ctx context.Context, webhookId string) error
+	GetWebhook(ctx context.Context, webhookId string) (*entities.Webhook, error)
+	GetWebhooks(ctx context.Context) (*entities.Webhooks, error)
+	GetDeliveries(ctx context.Context, param entities.DeliveryQueryParam) (*entities.WebhookDeliveries, error)
+	Redeliver(ctx context.Context, webhookId string, deliveryId int64) (*entities.WebhookDelivery, error)
+	DispatchDue(ctx context.Context) (int, error)
+}
+
+// WebhookSender 投遞一次 webhook 事件，回傳接收端的 status code
+type WebhookSender interface {
+	Send(ctx context.Context, request webhook.Request) (int, error)
 }
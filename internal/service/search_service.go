@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"tasks/constants"
+	"tasks/domain/entities"
+	customError "tasks/errors"
+	"tasks/internal/repository"
+	"tasks/internal/search"
+)
+
+type searchService struct {
+	repo     repository.SearchRepository
+	taskRepo repository.TaskRepository
+}
+
+func NewSearchService(repo repository.SearchRepository, taskRepo repository.TaskRepository) SearchService {
+	return &searchService{repo: repo, taskRepo: taskRepo}
+}
+
+// Search 依 name 搜尋 task，語法見 search.Parse，page 從 1 開始
+func (s *searchService) Search(ctx context.Context, value string, size, page int) (*entities.SearchResults, error) {
+	if size < 1 || size > constants.MaxSearchSize {
+		return nil, customError.InvalidRequest.Errorf("size must be between 1 and %d", constants.MaxSearchSize)
+	}
+	if page < 1 {
+		return nil, customError.InvalidRequest.New("page must be at least 1")
+	}
+	query, err := search.Parse(value)
+	if err != nil {
+		return nil, customError.InvalidRequest.Wrap(err, "invalid search query")
+	}
+	matches, err := s.repo.Search(ctx, query, size, (page-1)*size)
+	if err != nil {
+		return nil, err
+	}
+	result := entities.SearchResults{Results: make([]entities.SearchResult, 0, len(matches)), Size: len(matches), Page: page}
+	for _, match := range matches {
+		result.Results = append(result.Results, entities.SearchResult{
+			Task:    toEntity(&match.Task),
+			Snippet: match.Snippet,
+			Rank:    match.Rank,
+		})
+	}
+	tasks := make([]*entities.Task, 0, len(result.Results))
+	for i := range result.Results {
+		tasks = append(tasks, &result.Results[i].Task)
+	}
+	if err = loadTags(ctx, s.taskRepo, tasks); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"tasks/constants"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"tasks/internal/search"
+	"testing"
+)
+
+type MockSearchRepository struct {
+	mock.Mock
+}
+
+func (m *MockSearchRepository) Prepare(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockSearchRepository) Search(ctx context.Context, query search.Query, limit, offset int) ([]*models.SearchResult, error) {
+	args := m.Called(ctx, query, limit, offset)
+	if results, ok := args.Get(0).([]*models.SearchResult); ok {
+		return results, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func Test_searchService_Search(t *testing.T) {
+	t.Run("search with tags", func(t *testing.T) {
+		mockRepo := new(MockSearchRepository)
+		mockTaskRepo := new(MockTaskRepository)
+		service := NewSearchService(mockRepo, mockTaskRepo)
+		query := search.Query{{Text: "weekly report"}, {Text: "plan", Prefix: true}}
+		mockRepo.On("Search", mock.Anything, query, 10, 20).Return([]*models.SearchResult{
+			{Task: models.Task{ID: "task-1", Name: "Weekly report planning", Priority: 1}, Snippet: "<mark>Weekly report</mark> <mark>planning</mark>", Rank: -1.5},
+			{Task: models.Task{ID: "task-2", Name: "Weekly report plan"}, Snippet: "<mark>Weekly report plan</mark>", Rank: -1.2},
+		}, nil)
+		mockTaskRepo.On("ListTags", mock.Anything, []string{"task-1", "task-2"}).Return(map[string][]*models.Tag{"task-2": {{ID: "tag-1", Name: "work"}}}, nil)
+
+		result, err := service.Search(context.Background(), `"weekly report" plan*`, 10, 3)
+
+		assert.NoError(t, err)
+		// size 為回傳的筆數，不是要求的筆數
+		assert.Equal(t, 2, result.Size)
+		assert.Equal(t, 3, result.Page)
+		assert.Len(t, result.Results, 2)
+		assert.Equal(t, "task-1", result.Results[0].Task.ID)
+		assert.Equal(t, constants.PriorityMedium, result.Results[0].Task.Priority)
+		assert.Equal(t, -1.5, result.Results[0].Rank)
+		assert.Equal(t, "work", result.Results[1].Task.Tags[0].Name)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		mockRepo := new(MockSearchRepository)
+		mockTaskRepo := new(MockTaskRepository)
+		service := NewSearchService(mockRepo, mockTaskRepo)
+		mockRepo.On("Search", mock.Anything, mock.Anything, 20, 0).Return([]*models.SearchResult{}, nil)
+
+		result, err := service.Search(context.Background(), "budget", 20, 1)
+
+		assert.NoError(t, err)
+		assert.Empty(t, result.Results)
+		mockTaskRepo.AssertNotCalled(t, "ListTags", mock.Anything, mock.Anything)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		mockRepo := new(MockSearchRepository)
+		service := NewSearchService(mockRepo, new(MockTaskRepository))
+		for _, tc := range []struct {
+			value      string
+			size, page int
+		}{
+			{`"weekly report`, 20, 1},
+			{"  ", 20, 1},
+			{"report", 0, 1},
+			{"report", constants.MaxSearchSize + 1, 1},
+			{"report", 20, 0},
+		} {
+			_, err := service.Search(context.Background(), tc.value, tc.size, tc.page)
+			assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)), tc)
+		}
+		mockRepo.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("search error", func(t *testing.T) {
+		mockRepo := new(MockSearchRepository)
+		service := NewSearchService(mockRepo, new(MockTaskRepository))
+		mockRepo.On("Search", mock.Anything, mock.Anything, 20, 0).Return(nil, errors.New("db error"))
+
+		_, err := service.Search(context.Background(), "report", 20, 1)
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"tasks/internal/repository"
+	"time"
+	"unicode/utf8"
+)
+
+// maxTagNameLength tag 名稱的字數上限，與資料表欄位長度一致
+const maxTagNameLength = 50
+
+var tagColorPattern = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+type tagService struct {
+	repo repository.TagRepository
+}
+
+func NewTagService(repo repository.TagRepository) TagService {
+	return &tagService{repo: repo}
+}
+
+func (s *tagService) CreateTag(ctx context.Context, tag entities.Tag) (*entities.Tag, error) {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.repo.Create(ctx, tag); err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// UpdateTag 整筆取代 name 與 color，未帶 color 時清除
+func (s *tagService) UpdateTag(ctx context.Context, tag entities.Tag) (*entities.Tag, error) {
+	tag, err := normalizeTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	tag.UpdatedAt = time.Now().UTC()
+	if err = s.repo.Update(ctx, tag); err != nil {
+		return nil, err
+	}
+	return s.GetTag(ctx, tag.ID)
+}
+
+func (s *tagService) DeleteTag(ctx context.Context, tagId string) error {
+	return s.repo.Delete(ctx, tagId)
+}
+
+func (s *tagService) GetTag(ctx context.Context, tagId string) (*entities.Tag, error) {
+	record, err := s.repo.Find(ctx, tagId)
+	if err != nil {
+		return nil, err
+	}
+	tag := toTagEntity(record)
+	return &tag, nil
+}
+
+func (s *tagService) GetTags(ctx context.Context) (*entities.Tags, error) {
+	records, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := entities.Tags{Tags: make([]entities.Tag, 0, len(records))}
+	for _, record := range records {
+		result.Tags = append(result.Tags, toTagEntity(record))
+	}
+	return &result, nil
+}
+
+// normalizeTag 去除名稱前後空白、color 轉小寫後檢查格式
+func normalizeTag(tag entities.Tag) (entities.Tag, error) {
+	tag.Name = strings.TrimSpace(tag.Name)
+	tag.Color = strings.ToLower(strings.TrimSpace(tag.Color))
+	if tag.Name == "" {
+		return tag, customError.InvalidRequest.New("name is required")
+	}
+	if utf8.RuneCountInString(tag.Name) > maxTagNameLength {
+		return tag, customError.InvalidRequest.Errorf("name must be at most %d characters", maxTagNameLength)
+	}
+	if tag.Color != "" && !tagColorPattern.MatchString(tag.Color) {
+		return tag, customError.InvalidRequest.New("color must be a hex color like #1e90ff")
+	}
+	return tag, nil
+}
+
+func toTagEntity(tag *models.Tag) entities.Tag {
+	result := entities.Tag{
+		ID:        tag.ID,
+		Name:      tag.Name,
+		CreatedAt: models.ParseTime(tag.CreatedAt),
+		UpdatedAt: models.ParseTime(tag.UpdatedAt),
+	}
+	if tag.Color != nil {
+		result.Color = *tag.Color
+	}
+	return result
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"strings"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"testing"
+	"time"
+)
+
+type MockTagRepository struct {
+	mock.Mock
+}
+
+func (m *MockTagRepository) Find(ctx context.Context, id string) (*models.Tag, error) {
+	args := m.Called(ctx, id)
+	if tag, ok := args.Get(0).(*models.Tag); ok {
+		return tag, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTagRepository) List(ctx context.Context) ([]*models.Tag, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*models.Tag), args.Error(1)
+}
+
+func (m *MockTagRepository) Create(ctx context.Context, tag entities.Tag) error {
+	args := m.Called(ctx, tag)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) Update(ctx context.Context, tag entities.Tag) error {
+	args := m.Called(ctx, tag)
+	return args.Error(0)
+}
+
+func (m *MockTagRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func Test_tagService_CreateTag(t *testing.T) {
+	t.Run("name and color are normalized", func(t *testing.T) {
+		mockRepo := new(MockTagRepository)
+		service := NewTagService(mockRepo)
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(tag entities.Tag) bool {
+			return tag.ID == "tag-1" && tag.Name == "work" && tag.Color == "#1e90ff"
+		})).Return(nil)
+
+		created, err := service.CreateTag(context.Background(), entities.Tag{ID: "tag-1", Name: "  work ", Color: "#1E90FF"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "work", created.Name)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid tags", func(t *testing.T) {
+		tags := map[string]entities.Tag{
+			"empty name":    {Name: " "},
+			"long name":     {Name: strings.Repeat("a", maxTagNameLength+1)},
+			"invalid color": {Name: "work", Color: "blue"},
+		}
+		for name, tag := range tags {
+			t.Run(name, func(t *testing.T) {
+				mockRepo := new(MockTagRepository)
+				service := NewTagService(mockRepo)
+
+				_, err := service.CreateTag(context.Background(), tag)
+
+				assert.True(t, errors.Is(err, customError.InvalidRequest))
+				mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("duplicate name", func(t *testing.T) {
+		mockRepo := new(MockTagRepository)
+		service := NewTagService(mockRepo)
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(customError.TagNameConflict.New("tag work already exists"))
+
+		_, err := service.CreateTag(context.Background(), entities.Tag{Name: "work"})
+
+		assert.True(t, errors.Is(err, customError.TagNameConflict))
+	})
+}
+
+func Test_tagService_UpdateTag(t *testing.T) {
+	t.Run("update and read back", func(t *testing.T) {
+		mockRepo := new(MockTagRepository)
+		service := NewTagService(mockRepo)
+		mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(tag entities.Tag) bool {
+			return tag.ID == "tag-1" && tag.Name == "home" && tag.Color == "" && !tag.UpdatedAt.IsZero()
+		})).Return(nil)
+		mockRepo.On("Find", mock.Anything, "tag-1").Return(&models.Tag{
+			ID:        "tag-1",
+			Name:      "home",
+			CreatedAt: "2024-01-01 00:00:00+00:00",
+			UpdatedAt: "2024-01-02 00:00:00+00:00",
+		}, nil)
+
+		updated, err := service.UpdateTag(context.Background(), entities.Tag{ID: "tag-1", Name: "home"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), updated.CreatedAt)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("tag not found", func(t *testing.T) {
+		mockRepo := new(MockTagRepository)
+		service := NewTagService(mockRepo)
+		mockRepo.On("Update", mock.Anything, mock.Anything).Return(customError.TagNotFound.New("tag not found"))
+
+		_, err := service.UpdateTag(context.Background(), entities.Tag{ID: "tag-404", Name: "home"})
+
+		assert.True(t, errors.Is(err, customError.TagNotFound))
+		mockRepo.AssertNotCalled(t, "Find", mock.Anything, mock.Anything)
+	})
+}
+
+func Test_tagService_GetTags(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	service := NewTagService(mockRepo)
+	color := "#ff0000"
+	mockRepo.On("List", mock.Anything).Return([]*models.Tag{
+		{ID: "tag-1", Name: "home"},
+		{ID: "tag-2", Name: "work", Color: &color},
+	}, nil)
+
+	tags, err := service.GetTags(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, tags.Tags, 2)
+	assert.Equal(t, "", tags.Tags[0].Color)
+	assert.Equal(t, "#ff0000", tags.Tags[1].Color)
+}
+
+func Test_tagService_DeleteTag(t *testing.T) {
+	mockRepo := new(MockTagRepository)
+	service := NewTagService(mockRepo)
+	mockRepo.On("Delete", mock.Anything, "tag-1").Return(nil)
+
+	err := service.DeleteTag(context.Background(), "tag-1")
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"tasks/internal/audit"
+)
+
+// GetHistory task 的變更紀錄，由新到舊排列，已永久刪除的 task 仍保有紀錄
+func (t *taskService) GetHistory(ctx context.Context, taskId string, param entities.AuditQueryParam) (*entities.AuditLog, error) {
+	param.TaskID = taskId
+	result, err := t.GetAuditLog(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+	// 沒有任何紀錄時確認 task 是否存在，例如 audit log 上線前建立的 task
+	if len(result.Entries) == 0 && param.Before == 0 && param.Actor == "" && len(param.Actions) == 0 && param.Since == nil && param.Until == nil {
+		if _, err = t.repo.Find(ctx, taskId); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// GetAuditLog 所有 task 的變更紀錄，由新到舊排列
+func (t *taskService) GetAuditLog(ctx context.Context, param entities.AuditQueryParam) (*entities.AuditLog, error) {
+	if param.Size < 1 || param.Size > constants.MaxAuditSize {
+		return nil, customError.InvalidRequest.Errorf("size must be between 1 and %d", constants.MaxAuditSize)
+	}
+	for _, action := range param.Actions {
+		if !action.IsValid() {
+			return nil, customError.InvalidRequest.Errorf("audit action %s not supported", action)
+		}
+	}
+	if param.Since != nil && param.Until != nil && !param.Since.Before(*param.Until) {
+		return nil, customError.InvalidRequest.New("since must be before until")
+	}
+	// 多取一筆用來判斷是否還有下一頁
+	query := param
+	query.Size = param.Size + 1
+	records, err := t.repo.ListAudit(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	result := entities.AuditLog{Entries: make([]entities.AuditEntry, 0, len(records))}
+	if len(records) > param.Size {
+		records = records[:param.Size]
+		result.NextBefore = records[len(records)-1].ID
+	}
+	result.Size = len(records)
+	for _, record := range records {
+		entry, err := toAuditEntity(record)
+		if err != nil {
+			return nil, err
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+	return &result, nil
+}
+
+func toAuditEntity(record *models.AuditEntry) (entities.AuditEntry, error) {
+	entry := entities.AuditEntry{
+		ID:        record.ID,
+		TaskID:    record.TaskID,
+		Action:    constants.AuditAction(record.Action),
+		Actor:     record.Actor,
+		Version:   record.Version,
+		CreatedAt: models.ParseTime(record.CreatedAt),
+		Hash:      record.Hash,
+	}
+	if err := json.Unmarshal([]byte(record.Changes), &entry.Changes); err != nil {
+		return entities.AuditEntry{}, customError.InternalServerError.Wrapf(err, "decode changes of audit entry %d", record.ID)
+	}
+	return entry, nil
+}
+
+// VerifyAudit 依 id 由舊到新重算 audit log 的 hash chain，回傳第一個斷開的位置
+func (t *taskService) VerifyAudit(ctx context.Context) (*entities.AuditVerification, error) {
+	head, err := t.repo.AuditHead(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &entities.AuditVerification{Valid: true, Head: head.Hash}
+	var (
+		prevHash string
+		afterID  int64
+		// unchained 為 id 大於 head 最後一筆的紀錄，可能是驗證期間新寫入的
+		unchained *models.AuditEntry
+	)
+	for unchained == nil {
+		records, err := t.repo.ListAuditChain(ctx, afterID, constants.AuditVerifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			if record.ID > head.LastID {
+				unchained = record
+				break
+			}
+			result.Checked++
+			if record.PrevHash != prevHash {
+				return result.Break(record.ID, record.TaskID, constants.AuditPrevHashMismatch, prevHash, record.PrevHash), nil
+			}
+			if hash := audit.Hash(prevHash, auditRecord(record)); hash != record.Hash {
+				return result.Break(record.ID, record.TaskID, constants.AuditHashMismatch, hash, record.Hash), nil
+			}
+			prevHash, afterID = record.Hash, record.ID
+		}
+		if len(records) < constants.AuditVerifyBatchSize {
+			break
+		}
+	}
+	if prevHash != head.Hash {
+		return result.Break(head.LastID, "", constants.AuditHeadMismatch, head.Hash, prevHash), nil
+	}
+	if result.Checked != head.Seq {
+		return result.Break(head.LastID, "", constants.AuditHeadMismatch,
+			strconv.FormatInt(head.Seq, 10), strconv.FormatInt(result.Checked, 10)), nil
+	}
+	if unchained != nil {
+		// 寫入 audit log 與更新 head 在同一個 transaction 中，讀到的紀錄若已串接，重新讀取的 head 一定包含它
+		if head, err = t.repo.AuditHead(ctx); err != nil {
+			return nil, err
+		}
+		if unchained.ID > head.LastID {
+			return result.Break(unchained.ID, unchained.TaskID, constants.AuditUnchained, "", unchained.Hash), nil
+		}
+	}
+	return result, nil
+}
+
+func auditRecord(record *models.AuditEntry) audit.Record {
+	return audit.Record{
+		TaskID:    record.TaskID,
+		Action:    record.Action,
+		Actor:     record.Actor,
+		Version:   record.Version,
+		Changes:   record.Changes,
+		CreatedAt: models.ParseTime(record.CreatedAt),
+	}
+}
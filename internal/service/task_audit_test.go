@@ -0,0 +1,258 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"tasks/internal/audit"
+	"testing"
+	"time"
+)
+
+func Test_taskService_GetAuditLog(t *testing.T) {
+	entries := []*models.AuditEntry{
+		{ID: 9, TaskID: "task-1", Action: "update", Actor: "alice", Version: 2, Changes: `[{"field":"name","before":"Draft","after":"Final"}]`, CreatedAt: "2024-01-02 09:00:00+00:00"},
+		{ID: 7, TaskID: "task-2", Action: "create", Actor: "bob", Version: 0, Changes: `[{"field":"name","before":null,"after":"Ship"}]`, CreatedAt: "2024-01-01 09:00:00+00:00"},
+		{ID: 3, TaskID: "task-1", Action: "create", Actor: "alice", Version: 0, Changes: `[]`, CreatedAt: "2024-01-01 08:00:00+00:00"},
+	}
+
+	t.Run("page with the next cursor", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("ListAudit", mock.Anything, entities.AuditQueryParam{Actor: "alice", Size: 3}).Return(entries, nil)
+
+		result, err := service.GetAuditLog(context.Background(), entities.AuditQueryParam{Actor: "alice", Size: 2})
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Entries, 2)
+		assert.Equal(t, int64(7), result.NextBefore)
+		assert.Equal(t, constants.AuditUpdate, result.Entries[0].Action)
+		assert.Equal(t, []entities.FieldChange{{Field: "name", Before: "Draft", After: "Final"}}, result.Entries[0].Changes)
+		assert.Equal(t, time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC), result.Entries[0].CreatedAt)
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("ListAudit", mock.Anything, entities.AuditQueryParam{Before: 7, Size: 11}).Return(entries[2:], nil)
+
+		result, err := service.GetAuditLog(context.Background(), entities.AuditQueryParam{Before: 7, Size: 10})
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Entries, 1)
+		// size 為回傳的筆數，不是要求的筆數
+		assert.Equal(t, 1, result.Size)
+		assert.Zero(t, result.NextBefore)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		since := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+		until := since.Add(-time.Hour)
+		for _, param := range []entities.AuditQueryParam{
+			{Size: 0},
+			{Size: constants.MaxAuditSize + 1},
+			{Size: 10, Actions: []constants.AuditAction{"rename"}},
+			{Size: 10, Since: &since, Until: &until},
+		} {
+			_, err := service.GetAuditLog(context.Background(), param)
+			assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)), param)
+		}
+		mockRepo.AssertNotCalled(t, "ListAudit", mock.Anything, mock.Anything)
+	})
+}
+
+func Test_taskService_GetHistory(t *testing.T) {
+	t.Run("history of a task", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("ListAudit", mock.Anything, entities.AuditQueryParam{TaskID: "task-1", Size: 51}).Return([]*models.AuditEntry{
+			{ID: 3, TaskID: "task-1", Action: "purge", Actor: "system", Version: 1, Changes: `[]`, CreatedAt: "2024-01-01 08:00:00+00:00"},
+		}, nil)
+
+		result, err := service.GetHistory(context.Background(), "task-1", entities.AuditQueryParam{Size: 50})
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Entries, 1)
+		mockRepo.AssertNotCalled(t, "Find", mock.Anything, mock.Anything)
+	})
+
+	t.Run("task without history", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("ListAudit", mock.Anything, entities.AuditQueryParam{TaskID: "task-1", Size: 51}).Return([]*models.AuditEntry{}, nil)
+		mockRepo.On("Find", mock.Anything, "task-1").Return(&models.Task{ID: "task-1"}, nil)
+
+		result, err := service.GetHistory(context.Background(), "task-1", entities.AuditQueryParam{Size: 50})
+
+		assert.NoError(t, err)
+		assert.Empty(t, result.Entries)
+	})
+
+	t.Run("task not found", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("ListAudit", mock.Anything, mock.Anything).Return([]*models.AuditEntry{}, nil)
+		mockRepo.On("Find", mock.Anything, "task-404").Return(nil, customError.TaskNotFound.New("task not found"))
+
+		_, err := service.GetHistory(context.Background(), "task-404", entities.AuditQueryParam{Size: 50})
+
+		assert.True(t, customError.TaskNotFound.Is(customError.CauseCustomError(err)))
+	})
+}
+
+// auditChain 產生 id 由 1 開始、hash 正確串接的紀錄
+func auditChain(size int) []*models.AuditEntry {
+	chain := make([]*models.AuditEntry, 0, size)
+	var prevHash string
+	for i := 1; i <= size; i++ {
+		entry := &models.AuditEntry{
+			ID:        int64(i),
+			TaskID:    "task-1",
+			Action:    "update",
+			Actor:     "alice",
+			Version:   i,
+			Changes:   `[]`,
+			CreatedAt: "2024-01-01 08:00:00.123456+00:00",
+			PrevHash:  prevHash,
+		}
+		entry.Hash = audit.Hash(prevHash, auditRecord(entry))
+		prevHash = entry.Hash
+		chain = append(chain, entry)
+	}
+	return chain
+}
+
+func Test_taskService_VerifyAudit(t *testing.T) {
+	verify := func(head *models.AuditHead, chain []*models.AuditEntry) (*MockTaskRepository, *entities.AuditVerification) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("AuditHead", mock.Anything).Return(head, nil)
+		for afterID := int64(0); ; afterID += constants.AuditVerifyBatchSize {
+			end := int(afterID) + constants.AuditVerifyBatchSize
+			if end > len(chain) {
+				end = len(chain)
+			}
+			mockRepo.On("ListAuditChain", mock.Anything, afterID, constants.AuditVerifyBatchSize).Return(chain[afterID:end], nil).Maybe()
+			if end == len(chain) {
+				break
+			}
+		}
+		result, err := service.VerifyAudit(context.Background())
+		assert.NoError(t, err)
+		return mockRepo, result
+	}
+
+	t.Run("intact chain across batches", func(t *testing.T) {
+		chain := auditChain(constants.AuditVerifyBatchSize + 3)
+		last := chain[len(chain)-1]
+
+		mockRepo, result := verify(&models.AuditHead{Seq: int64(len(chain)), LastID: last.ID, Hash: last.Hash}, chain)
+
+		assert.True(t, result.Valid)
+		assert.Equal(t, int64(len(chain)), result.Checked)
+		assert.Equal(t, last.Hash, result.Head)
+		assert.Nil(t, result.Broken)
+		mockRepo.AssertNumberOfCalls(t, "ListAuditChain", 2)
+	})
+
+	t.Run("empty log", func(t *testing.T) {
+		_, result := verify(&models.AuditHead{}, nil)
+
+		assert.True(t, result.Valid)
+		assert.Zero(t, result.Checked)
+	})
+
+	t.Run("modified record", func(t *testing.T) {
+		chain := auditChain(4)
+		last := chain[3]
+		chain[1].Actor = "mallory"
+
+		_, result := verify(&models.AuditHead{Seq: 4, LastID: 4, Hash: last.Hash}, chain)
+
+		assert.False(t, result.Valid)
+		assert.Equal(t, int64(2), result.Checked)
+		assert.Equal(t, int64(2), result.Broken.ID)
+		assert.Equal(t, "task-1", result.Broken.TaskID)
+		assert.Equal(t, constants.AuditHashMismatch, result.Broken.Reason)
+		assert.Equal(t, chain[1].Hash, result.Broken.Actual)
+	})
+
+	t.Run("deleted record", func(t *testing.T) {
+		chain := auditChain(4)
+		last := chain[3]
+
+		_, result := verify(&models.AuditHead{Seq: 4, LastID: 4, Hash: last.Hash}, append(chain[:1:1], chain[2:]...))
+
+		assert.False(t, result.Valid)
+		assert.Equal(t, int64(3), result.Broken.ID)
+		assert.Equal(t, constants.AuditPrevHashMismatch, result.Broken.Reason)
+		assert.Equal(t, chain[0].Hash, result.Broken.Expected)
+		assert.Equal(t, chain[1].Hash, result.Broken.Actual)
+	})
+
+	t.Run("deleted tail", func(t *testing.T) {
+		chain := auditChain(4)
+		last := chain[3]
+
+		_, result := verify(&models.AuditHead{Seq: 4, LastID: 4, Hash: last.Hash}, chain[:3])
+
+		assert.False(t, result.Valid)
+		assert.Equal(t, int64(3), result.Checked)
+		assert.Equal(t, int64(4), result.Broken.ID)
+		assert.Equal(t, constants.AuditHeadMismatch, result.Broken.Reason)
+		assert.Equal(t, last.Hash, result.Broken.Expected)
+		assert.Equal(t, chain[2].Hash, result.Broken.Actual)
+	})
+
+	t.Run("count does not match head", func(t *testing.T) {
+		chain := auditChain(2)
+
+		_, result := verify(&models.AuditHead{Seq: 3, LastID: 2, Hash: chain[1].Hash}, chain)
+
+		assert.False(t, result.Valid)
+		assert.Equal(t, constants.AuditHeadMismatch, result.Broken.Reason)
+		assert.Equal(t, "3", result.Broken.Expected)
+		assert.Equal(t, "2", result.Broken.Actual)
+	})
+
+	t.Run("record written after the head was read", func(t *testing.T) {
+		chain := auditChain(3)
+
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("AuditHead", mock.Anything).Return(&models.AuditHead{Seq: 2, LastID: 2, Hash: chain[1].Hash}, nil).Once()
+		mockRepo.On("AuditHead", mock.Anything).Return(&models.AuditHead{Seq: 3, LastID: 3, Hash: chain[2].Hash}, nil).Once()
+		mockRepo.On("ListAuditChain", mock.Anything, int64(0), constants.AuditVerifyBatchSize).Return(chain, nil)
+
+		result, err := service.VerifyAudit(context.Background())
+
+		assert.NoError(t, err)
+		assert.True(t, result.Valid)
+		assert.Equal(t, int64(2), result.Checked)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("record outside the chain", func(t *testing.T) {
+		chain := auditChain(3)
+		chain[2].PrevHash, chain[2].Hash = "", ""
+
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("AuditHead", mock.Anything).Return(&models.AuditHead{Seq: 2, LastID: 2, Hash: chain[1].Hash}, nil)
+		mockRepo.On("ListAuditChain", mock.Anything, int64(0), constants.AuditVerifyBatchSize).Return(chain, nil)
+
+		result, err := service.VerifyAudit(context.Background())
+
+		assert.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, int64(3), result.Broken.ID)
+		assert.Equal(t, constants.AuditUnchained, result.Broken.Reason)
+		mockRepo.AssertNumberOfCalls(t, "AuditHead", 2)
+	})
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"tasks/constants"
+	"tasks/domain/entities"
+	customError "tasks/errors"
+	"tasks/internal/repository"
+	"time"
+)
+
+// errBatchFailed 用來中斷 batch 的 transaction
+var errBatchFailed = customError.BatchAborted.New("batch operation failed")
+
+func (t *taskService) BatchTasks(ctx context.Context, mode constants.BatchMode, operations []entities.BatchOperation) (*entities.BatchResults, error) {
+	result := entities.BatchResults{
+		Mode:    mode,
+		Results: make([]entities.BatchResult, len(operations)),
+	}
+	if mode == constants.BatchBestEffort {
+		// 每個 operation 各自在一個 transaction 中執行
+		for i, operation := range operations {
+			err := t.repo.WithTx(ctx, func(repo repository.TaskRepository) error {
+				result.Results[i] = runBatchOperation(ctx, repo, i, operation)
+				if result.Results[i].Error != nil {
+					return errBatchFailed
+				}
+				return nil
+			})
+			if err != nil && result.Results[i].Error == nil {
+				result.Results[i] = newBatchErrorResult(i, operation, err)
+			}
+		}
+		result.Committed = true
+		return &result, nil
+	}
+
+	failed := -1
+	err := t.repo.WithTx(ctx, func(repo repository.TaskRepository) error {
+		for i, operation := range operations {
+			result.Results[i] = runBatchOperation(ctx, repo, i, operation)
+			if result.Results[i].Error != nil {
+				failed = i
+				return errBatchFailed
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if failed < 0 {
+			return nil, err
+		}
+		// 失敗項目保留原本的錯誤，其餘項目皆已 rollback
+		for i, operation := range operations {
+			if i != failed {
+				result.Results[i] = newBatchErrorResult(i, operation, customError.BatchAborted.New("rolled back"))
+			}
+		}
+		return &result, nil
+	}
+	result.Committed = true
+	return &result, nil
+}
+
+func runBatchOperation(ctx context.Context, repo repository.TaskRepository, index int, operation entities.BatchOperation) entities.BatchResult {
+	result := entities.BatchResult{Index: index, Op: operation.Op, ID: operation.ID}
+	if err := validateBatchOperation(operation); err != nil {
+		return newBatchErrorResult(index, operation, err)
+	}
+	switch operation.Op {
+	case constants.BatchCreate:
+		task := entities.NewTask(*operation.Name)
+		task.ParentID = operation.ParentID
+		if operation.Status != nil {
+			task.Status = *operation.Status
+		}
+		if operation.Priority != nil {
+			task.Priority = *operation.Priority
+		}
+		task.DueAt = operation.DueAt
+		task.RemindAt = operation.RemindAt
+		task.Recurrence = operation.Recurrence
+		if err := createTask(ctx, repo, &task); err != nil {
+			return newBatchErrorResult(index, operation, err)
+		}
+		task.Overdue = task.IsOverdue(time.Now().UTC())
+		result.ID = task.ID
+		result.Status = http.StatusCreated
+		result.Task = &task
+	case constants.BatchUpdate:
+		task := entities.Task{
+			ID:         operation.ID,
+			ParentID:   operation.ParentID,
+			Name:       *operation.Name,
+			Status:     *operation.Status,
+			Priority:   constants.PriorityMedium,
+			DueAt:      operation.DueAt,
+			RemindAt:   operation.RemindAt,
+			Recurrence: operation.Recurrence,
+		}
+		if operation.Priority != nil {
+			task.Priority = *operation.Priority
+		}
+		updated, err := updateTask(ctx, repo, task, operation.Version)
+		if err != nil {
+			return newBatchErrorResult(index, operation, err)
+		}
+		result.Status = http.StatusOK
+		result.Task = updated
+	case constants.BatchDelete:
+		if err := deleteTask(ctx, repo, operation.ID, operation.Version, false); err != nil {
+			return newBatchErrorResult(index, operation, err)
+		}
+		result.Status = http.StatusNoContent
+	}
+	return result
+}
+
+func validateBatchOperation(operation entities.BatchOperation) error {
+	switch operation.Op {
+	case constants.BatchCreate:
+		if operation.Name == nil || strings.TrimSpace(*operation.Name) == "" {
+			return customError.InvalidRequest.New("name is required")
+		}
+	case constants.BatchUpdate:
+		if operation.ID == "" {
+			return customError.InvalidRequest.New("id is required")
+		}
+		if operation.Name == nil || strings.TrimSpace(*operation.Name) == "" {
+			return customError.InvalidRequest.New("name is required")
+		}
+		if operation.Status == nil {
+			return customError.InvalidRequest.New("status is required")
+		}
+	case constants.BatchDelete:
+		if operation.ID == "" {
+			return customError.InvalidRequest.New("id is required")
+		}
+		return nil
+	default:
+		return customError.InvalidRequest.Errorf("op %s not supported", operation.Op)
+	}
+	if operation.Status != nil && *operation.Status < 0 {
+		return customError.InvalidRequest.New("status must not be negative")
+	}
+	if operation.Priority != nil && !operation.Priority.IsValid() {
+		return customError.InvalidRequest.New("priority not supported")
+	}
+	return validateSchedule(entities.Task{DueAt: operation.DueAt, RemindAt: operation.RemindAt, Recurrence: operation.Recurrence})
+}
+
+// newBatchErrorResult 以 CustomError 的 code/message/details 組成單筆結果，與錯誤 middleware 的回應格式一致
+func newBatchErrorResult(index int, operation entities.BatchOperation, err error) entities.BatchResult {
+	cause := customError.CauseCustomError(err)
+	if cause.IsEmpty() {
+		cause = customError.Internal
+	}
+	return entities.BatchResult{
+		Index:  index,
+		Op:     operation.Op,
+		ID:     operation.ID,
+		Status: cause.Status().ToHTTPStatus(),
+		Error:  &entities.BatchError{Code: cause.Code(), Message: cause.Message(), Details: cause.Details()},
+	}
+}
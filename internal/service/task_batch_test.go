@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"testing"
+)
+
+func Test_taskService_BatchTasks(t *testing.T) {
+	name := "Task 1"
+	complete := constants.Complete
+
+	t.Run("atomic batch commits all operations", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("ListTags", mock.Anything, mock.Anything).Return(map[string][]*models.Tag{}, nil)
+		mockRepo.On("LastPosition", mock.Anything).Return("", nil)
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(task entities.Task) bool {
+			return task.ID != "" && task.Name == name && task.Status == constants.Incomplete && task.Position == "00000001"
+		})).Return(nil)
+		mockRepo.On("Update", mock.Anything, entities.Task{ID: "task-2", Name: name, Status: complete, Priority: constants.PriorityMedium}, (*int)(nil)).Return(nil)
+		mockRepo.On("Find", mock.Anything, "task-2").Return(&models.Task{ID: "task-2", Name: name, Status: 1, Version: 1}, nil)
+		mockRepo.On("Find", mock.Anything, "task-3").Return(&models.Task{ID: "task-3", Version: 2}, nil)
+		mockRepo.On("Delete", mock.Anything, "task-3", (*int)(nil), false).Return(nil)
+
+		result, err := service.BatchTasks(context.Background(), constants.BatchAtomic, []entities.BatchOperation{
+			{Op: constants.BatchCreate, Name: &name},
+			{Op: constants.BatchUpdate, ID: "task-2", Name: &name, Status: &complete},
+			{Op: constants.BatchDelete, ID: "task-3"},
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, result.Committed)
+		assert.Equal(t, http.StatusCreated, result.Results[0].Status)
+		assert.NotEmpty(t, result.Results[0].ID)
+		assert.Equal(t, http.StatusOK, result.Results[1].Status)
+		assert.Equal(t, 1, result.Results[1].Task.Version)
+		assert.Equal(t, http.StatusNoContent, result.Results[2].Status)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("atomic batch aborts on first failure", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("LastPosition", mock.Anything).Return("", nil)
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		mockRepo.On("Find", mock.Anything, "task-2").Return(nil, customError.TaskNotFound.New("task not found"))
+
+		result, err := service.BatchTasks(context.Background(), constants.BatchAtomic, []entities.BatchOperation{
+			{Op: constants.BatchCreate, Name: &name},
+			{Op: constants.BatchDelete, ID: "task-2"},
+			{Op: constants.BatchDelete, ID: "task-3"},
+		})
+
+		assert.NoError(t, err)
+		assert.False(t, result.Committed)
+		assert.Equal(t, http.StatusFailedDependency, result.Results[0].Status)
+		assert.Equal(t, customError.BatchAborted.Code(), result.Results[0].Error.Code)
+		assert.Equal(t, http.StatusNotFound, result.Results[1].Status)
+		assert.Equal(t, customError.TaskNotFound.Code(), result.Results[1].Error.Code)
+		assert.Equal(t, customError.TaskNotFound.Message(), result.Results[1].Error.Message)
+		assert.Equal(t, http.StatusFailedDependency, result.Results[2].Status)
+		mockRepo.AssertNotCalled(t, "Find", mock.Anything, "task-3")
+	})
+
+	t.Run("best effort batch reports each operation", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		version := 2
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, "task-1").Return(&models.Task{ID: "task-1", Version: 3}, nil)
+		mockRepo.On("Delete", mock.Anything, "task-1", &version, false).Return(customError.TaskVersionMismatch.New("version mismatch"))
+		mockRepo.On("Find", mock.Anything, "task-2").Return(&models.Task{ID: "task-2", Version: 1}, nil)
+		mockRepo.On("Delete", mock.Anything, "task-2", (*int)(nil), false).Return(nil)
+
+		result, err := service.BatchTasks(context.Background(), constants.BatchBestEffort, []entities.BatchOperation{
+			{Op: constants.BatchDelete, ID: "task-1", Version: &version},
+			{Op: constants.BatchDelete, ID: "task-2"},
+			{Op: constants.BatchUpdate, ID: "task-3"},
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, result.Committed)
+		assert.Equal(t, http.StatusPreconditionFailed, result.Results[0].Status)
+		assert.Equal(t, http.StatusNoContent, result.Results[1].Status)
+		assert.Equal(t, http.StatusBadRequest, result.Results[2].Status)
+		assert.Equal(t, customError.InvalidRequest.Code(), result.Results[2].Error.Code)
+		// 每個 operation 各自在一個 transaction 中執行
+		mockRepo.AssertNumberOfCalls(t, "WithTx", 3)
+		mockRepo.AssertExpectations(t)
+	})
+}
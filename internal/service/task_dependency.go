@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"tasks/constants"
+	"tasks/domain/entities"
+	customError "tasks/errors"
+	"tasks/internal/repository"
+)
+
+// AddBlockers 讓 task 被 blockerIds block 住，形成環的 dependency 會被拒絕，回傳加上後的 task
+func (t *taskService) AddBlockers(ctx context.Context, taskId string, blockerIds []string) (*entities.Task, error) {
+	return t.changeLinks(ctx, taskId, "blocker", blockerIds, func(repo repository.TaskRepository) error {
+		// 先鎖住再檢查，避免同時新增的 dependency 都通過檢查而形成環
+		if err := repo.LockDependencies(ctx); err != nil {
+			return err
+		}
+		if err := validateBlockers(ctx, repo, taskId, blockerIds); err != nil {
+			return err
+		}
+		return repo.AddBlockers(ctx, taskId, blockerIds)
+	})
+}
+
+// RemoveBlockers 移除 task 的 blocker，回傳移除後的 task
+func (t *taskService) RemoveBlockers(ctx context.Context, taskId string, blockerIds []string) (*entities.Task, error) {
+	return t.changeLinks(ctx, taskId, "blocker", blockerIds, func(repo repository.TaskRepository) error {
+		return repo.RemoveBlockers(ctx, taskId, blockerIds)
+	})
+}
+
+// validateBlockers task 不能 block 自己，也不能被它直接或間接 block 住的 task 所 block，需在 transaction 中執行
+func validateBlockers(ctx context.Context, repo repository.TaskRepository, taskId string, blockerIds []string) error {
+	for _, id := range blockerIds {
+		if id == taskId {
+			return customError.InvalidRequest.New("task cannot block itself")
+		}
+	}
+	blockers, err := repo.ListAllBlockers(ctx, blockerIds)
+	if err != nil {
+		return err
+	}
+	if blockers[taskId] {
+		return customError.DependencyCycle.Errorf("task %s already blocks one of the blockers, the dependency would form a cycle", taskId)
+	}
+	return nil
+}
+
+// checkBlocked 還有未完成 blocker 的 task 不能改為完成
+func checkBlocked(current entities.Task, status constants.Status) error {
+	if status == constants.Complete && current.Status != constants.Complete && current.Blocked {
+		return customError.TaskBlocked.Errorf("task %s has incomplete blockers", current.ID)
+	}
+	return nil
+}
+
+// SortTasks 依 dependency 排序 task，blocker 排在被它 block 住的 task 之前，
+// 只考慮這些 task 之間的 dependency，沒有先後關係的 task 保持傳入的順序
+func (t *taskService) SortTasks(ctx context.Context, taskIds []string) (*entities.Tasks, error) {
+	ids := make([]string, 0, len(taskIds))
+	seen := make(map[string]bool, len(taskIds))
+	for _, id := range taskIds {
+		if strings.TrimSpace(id) == "" {
+			return nil, customError.InvalidRequest.New("task id is required")
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, customError.InvalidRequest.New("at least one task id is required")
+	}
+	tasks := make(map[string]entities.Task, len(ids))
+	for _, id := range ids {
+		record, err := t.repo.Find(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		tasks[id] = toEntity(record)
+	}
+	blockers, err := t.repo.ListBlockers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// pending 為每個 task 尚未排入的 blocker 數，blocks 為 blocker 到被 block 住的 task
+	pending := make(map[string]int, len(ids))
+	blocks := make(map[string][]string)
+	for _, id := range ids {
+		for _, blocker := range blockers[id] {
+			if seen[blocker] {
+				pending[id]++
+				blocks[blocker] = append(blocks[blocker], id)
+			}
+		}
+	}
+	result := entities.Tasks{Tasks: make([]entities.Task, 0, len(ids))}
+	sorted := make(map[string]bool, len(ids))
+	for len(result.Tasks) < len(ids) {
+		next := ""
+		for _, id := range ids {
+			if !sorted[id] && pending[id] == 0 {
+				next = id
+				break
+			}
+		}
+		if next == "" {
+			// 新增 dependency 時已檢查過環，不應該發生
+			return nil, customError.Internal.New("dependency cycle detected")
+		}
+		sorted[next] = true
+		result.Tasks = append(result.Tasks, tasks[next])
+		for _, id := range blocks[next] {
+			pending[id]--
+		}
+	}
+	page := make([]*entities.Task, 0, len(result.Tasks))
+	for i := range result.Tasks {
+		page = append(page, &result.Tasks[i])
+	}
+	if err = loadTags(ctx, t.repo, page); err != nil {
+		return nil, err
+	}
+	result.Size = len(result.Tasks)
+	return &result, nil
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"testing"
+)
+
+func Test_taskService_AddBlockers(t *testing.T) {
+	taskID := "task-1"
+
+	t.Run("add blockers and return the blocked task", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		blockers := []string{"task-2", "task-3"}
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("LockDependencies", mock.Anything).Return(nil)
+		mockRepo.On("ListAllBlockers", mock.Anything, blockers).Return(map[string]bool{"task-4": true}, nil)
+		mockRepo.On("AddBlockers", mock.Anything, taskID, blockers).Return(nil)
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "ship", Version: 2, Blocked: true}, nil)
+		mockRepo.On("ListTags", mock.Anything, []string{taskID}).Return(map[string][]*models.Tag{}, nil)
+
+		task, err := service.AddBlockers(context.Background(), taskID, blockers)
+
+		assert.NoError(t, err)
+		assert.True(t, task.Blocked)
+		assert.Equal(t, 2, task.Version)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("dependency would form a cycle", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("LockDependencies", mock.Anything).Return(nil)
+		// task-2 已經直接或間接被 task-1 block 住
+		mockRepo.On("ListAllBlockers", mock.Anything, []string{"task-2"}).Return(map[string]bool{taskID: true, "task-3": true}, nil)
+
+		_, err := service.AddBlockers(context.Background(), taskID, []string{"task-2"})
+
+		assert.True(t, errors.Is(err, customError.DependencyCycle))
+		assert.Equal(t, customError.StatusConflict, customError.CauseCustomError(err).Status())
+		mockRepo.AssertCalled(t, "LockDependencies", mock.Anything)
+		mockRepo.AssertNotCalled(t, "AddBlockers", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("task cannot block itself", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("LockDependencies", mock.Anything).Return(nil)
+
+		_, err := service.AddBlockers(context.Background(), taskID, []string{taskID})
+
+		assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)))
+		mockRepo.AssertNotCalled(t, "ListAllBlockers", mock.Anything, mock.Anything)
+	})
+
+	t.Run("empty blocker id", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+
+		_, err := service.AddBlockers(context.Background(), taskID, []string{" "})
+
+		assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)))
+		mockRepo.AssertNotCalled(t, "WithTx", mock.Anything)
+	})
+}
+
+func Test_taskService_RemoveBlockers(t *testing.T) {
+	mockRepo := new(MockTaskRepository)
+	service := NewTaskService(mockRepo)
+	mockRepo.On("WithTx", mock.Anything).Return()
+	mockRepo.On("RemoveBlockers", mock.Anything, "task-1", []string{"task-2"}).Return(nil)
+	mockRepo.On("Find", mock.Anything, "task-1").Return(&models.Task{ID: "task-1", Name: "ship", Version: 3}, nil)
+	mockRepo.On("ListTags", mock.Anything, []string{"task-1"}).Return(map[string][]*models.Tag{}, nil)
+
+	task, err := service.RemoveBlockers(context.Background(), "task-1", []string{"task-2"})
+
+	assert.NoError(t, err)
+	assert.False(t, task.Blocked)
+	mockRepo.AssertExpectations(t)
+}
+
+func Test_taskService_CompleteBlockedTask(t *testing.T) {
+	taskID, parentID := "task-1", "parent-1"
+
+	t.Run("update to complete is rejected", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "ship", Version: 1, Blocked: true}, nil)
+
+		_, err := service.UpdateTask(context.Background(), entities.Task{ID: taskID, Name: "ship", Status: constants.Complete}, nil)
+
+		assert.True(t, customError.TaskBlocked.Is(customError.CauseCustomError(err)))
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("update that keeps the task incomplete is allowed", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		task := entities.Task{ID: taskID, Name: "ship it", Status: constants.Incomplete}
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "ship", Version: 1, Blocked: true}, nil).Once()
+		mockRepo.On("Update", mock.Anything, task, (*int)(nil)).Return(nil)
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "ship it", Version: 2, Blocked: true}, nil).Once()
+		mockRepo.On("ListTags", mock.Anything, []string{taskID}).Return(map[string][]*models.Tag{}, nil)
+
+		updated, err := service.UpdateTask(context.Background(), task, nil)
+
+		assert.NoError(t, err)
+		assert.True(t, updated.Blocked)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("patch to complete is rejected", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "ship", Version: 1, Blocked: true}, nil)
+		mockRepo.On("ListTags", mock.Anything, []string{taskID}).Return(map[string][]*models.Tag{}, nil)
+
+		_, err := service.PatchTask(context.Background(), taskID, constants.MergePatch, []byte(`{"status":1}`), nil)
+
+		assert.True(t, customError.TaskBlocked.Is(customError.CauseCustomError(err)))
+		mockRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("blocked flag is read-only", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "ship", Version: 1, Blocked: true}, nil)
+		mockRepo.On("ListTags", mock.Anything, []string{taskID}).Return(map[string][]*models.Tag{}, nil)
+
+		_, err := service.PatchTask(context.Background(), taskID, constants.MergePatch, []byte(`{"blocked":false}`), nil)
+
+		assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)))
+	})
+
+	t.Run("blocked parent is not completed by the rollup", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		mockRepo.On("CountChildren", mock.Anything, parentID).Return(2, 2, nil)
+		mockRepo.On("Find", mock.Anything, parentID).Return(&models.Task{ID: parentID, Version: 3, Blocked: true}, nil)
+
+		err := completeAncestors(context.Background(), mockRepo, &parentID)
+
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func Test_taskService_SortTasks(t *testing.T) {
+	find := func(mockRepo *MockTaskRepository, ids ...string) {
+		for _, id := range ids {
+			mockRepo.On("Find", mock.Anything, id).Return(&models.Task{ID: id, Name: id}, nil)
+		}
+	}
+
+	t.Run("blockers come first and unrelated tasks keep their order", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		ids := []string{"ship", "docs", "build", "design"}
+		find(mockRepo, ids...)
+		// task-x 不在排序的範圍內，會被忽略
+		mockRepo.On("ListBlockers", mock.Anything, ids).Return(map[string][]string{
+			"ship":  {"build", "docs"},
+			"build": {"design", "task-x"},
+		}, nil)
+		mockRepo.On("ListTags", mock.Anything, []string{"docs", "design", "build", "ship"}).Return(map[string][]*models.Tag{}, nil)
+
+		result, err := service.SortTasks(context.Background(), []string{"ship", "docs", "build", "design", "docs"})
+
+		assert.NoError(t, err)
+		sorted := make([]string, 0, len(result.Tasks))
+		for _, task := range result.Tasks {
+			sorted = append(sorted, task.ID)
+		}
+		assert.Equal(t, []string{"docs", "design", "build", "ship"}, sorted)
+		assert.Equal(t, 4, result.Size)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("task not found", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		find(mockRepo, "task-1")
+		mockRepo.On("Find", mock.Anything, "task-404").Return(nil, customError.TaskNotFound.New("task not found"))
+
+		_, err := service.SortTasks(context.Background(), []string{"task-1", "task-404"})
+
+		assert.True(t, customError.TaskNotFound.Is(customError.CauseCustomError(err)))
+		mockRepo.AssertNotCalled(t, "ListBlockers", mock.Anything, mock.Anything)
+	})
+
+	t.Run("cycle in stored dependencies", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		find(mockRepo, "task-1", "task-2")
+		mockRepo.On("ListBlockers", mock.Anything, []string{"task-1", "task-2"}).Return(map[string][]string{
+			"task-1": {"task-2"},
+			"task-2": {"task-1"},
+		}, nil)
+
+		_, err := service.SortTasks(context.Background(), []string{"task-1", "task-2"})
+
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"tasks/internal/repository"
+)
+
+// eventRepository 只有 event sourcing 模式的 repository 提供事件查詢
+func (t *taskService) eventRepository() (repository.TaskEventRepository, error) {
+	repo, ok := t.repo.(repository.TaskEventRepository)
+	if !ok {
+		return nil, customError.EventSourcingDisabled.New("set events.enabled in config.yaml to use task events")
+	}
+	return repo, nil
+}
+
+// GetEvents task 的事件，依 seq 由舊到新排列，已永久刪除的 task 仍保有事件
+func (t *taskService) GetEvents(ctx context.Context, taskId string, after int64, size int) (*entities.TaskEvents, error) {
+	repo, err := t.eventRepository()
+	if err != nil {
+		return nil, err
+	}
+	if size < 1 || size > constants.MaxEventSize {
+		return nil, customError.InvalidRequest.Errorf("size must be between 1 and %d", constants.MaxEventSize)
+	}
+	if after < 0 {
+		return nil, customError.InvalidRequest.New("after must not be negative")
+	}
+	// 多取一筆用來判斷是否還有下一頁
+	records, err := repo.ListEvents(ctx, taskId, after, size+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 && after == 0 {
+		return nil, customError.TaskNotFound.Errorf("task %s has no events", taskId)
+	}
+	result := entities.TaskEvents{Events: make([]entities.TaskEvent, 0, len(records))}
+	if len(records) > size {
+		records = records[:size]
+		result.NextAfter = records[len(records)-1].Seq
+	}
+	result.Size = len(records)
+	for _, record := range records {
+		result.Events = append(result.Events, toEventEntity(record))
+	}
+	return &result, nil
+}
+
+func toEventEntity(record *models.TaskEvent) entities.TaskEvent {
+	return entities.TaskEvent{
+		ID:        record.ID,
+		TaskID:    record.TaskID,
+		Seq:       record.Seq,
+		Type:      constants.TaskEventType(record.Type),
+		Version:   record.Version,
+		Data:      json.RawMessage(record.Data),
+		Actor:     record.Actor,
+		CreatedAt: models.ParseTime(record.CreatedAt),
+	}
+}
+
+// GetTaskAsOf 由事件還原 task 在指定 version 或時間點的狀態，tags 等關聯不在還原範圍內
+func (t *taskService) GetTaskAsOf(ctx context.Context, taskId string, asOf entities.AsOf) (*entities.Task, error) {
+	repo, err := t.eventRepository()
+	if err != nil {
+		return nil, err
+	}
+	if (asOf.Version == nil) == (asOf.At == nil) {
+		return nil, customError.InvalidRequest.New("exactly one of version or at is required")
+	}
+	if asOf.Version != nil && *asOf.Version < 0 {
+		return nil, customError.InvalidRequest.New("version must not be negative")
+	}
+	record, err := repo.FindAsOf(ctx, taskId, asOf)
+	if err != nil {
+		return nil, err
+	}
+	task := toEntity(record)
+	if asOf.At != nil {
+		task.Overdue = task.IsOverdue(asOf.At.UTC())
+	}
+	return &task, nil
+}
+
+// RebuildProjection 以事件重建 tasks 表，dryRun 時只回傳會修正的筆數
+func (t *taskService) RebuildProjection(ctx context.Context, dryRun bool) (*entities.ProjectionRebuild, error) {
+	repo, err := t.eventRepository()
+	if err != nil {
+		return nil, err
+	}
+	return repo.RebuildProjection(ctx, dryRun)
+}
+
+// BackfillEvents 為啟用 event sourcing 前已存在的 task 補記事件，已有事件的 task 不會變更
+func (t *taskService) BackfillEvents(ctx context.Context, dryRun bool) (int, error) {
+	repo, err := t.eventRepository()
+	if err != nil {
+		return 0, err
+	}
+	return repo.BackfillEvents(ctx, dryRun)
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"testing"
+	"time"
+)
+
+// MockTaskEventRepository event sourcing 模式的 repository
+type MockTaskEventRepository struct {
+	MockTaskRepository
+}
+
+func (m *MockTaskEventRepository) ListEvents(ctx context.Context, id string, afterSeq int64, size int) ([]*models.TaskEvent, error) {
+	args := m.Called(ctx, id, afterSeq, size)
+	if events, ok := args.Get(0).([]*models.TaskEvent); ok {
+		return events, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskEventRepository) FindAsOf(ctx context.Context, id string, asOf entities.AsOf) (*models.Task, error) {
+	args := m.Called(ctx, id, asOf)
+	if task, ok := args.Get(0).(*models.Task); ok {
+		return task, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskEventRepository) RebuildProjection(ctx context.Context, dryRun bool) (*entities.ProjectionRebuild, error) {
+	args := m.Called(ctx, dryRun)
+	if result, ok := args.Get(0).(*entities.ProjectionRebuild); ok {
+		return result, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskEventRepository) BackfillEvents(ctx context.Context, dryRun bool) (int, error) {
+	args := m.Called(ctx, dryRun)
+	return args.Int(0), args.Error(1)
+}
+
+func Test_taskService_EventSourcingDisabled(t *testing.T) {
+	service := NewTaskService(new(MockTaskRepository))
+	version := 1
+
+	_, err := service.GetEvents(context.Background(), "task-1", 0, 10)
+	assert.True(t, customError.EventSourcingDisabled.Is(customError.CauseCustomError(err)))
+	_, err = service.GetTaskAsOf(context.Background(), "task-1", entities.AsOf{Version: &version})
+	assert.True(t, customError.EventSourcingDisabled.Is(customError.CauseCustomError(err)))
+	_, err = service.RebuildProjection(context.Background(), true)
+	assert.True(t, customError.EventSourcingDisabled.Is(customError.CauseCustomError(err)))
+	_, err = service.BackfillEvents(context.Background(), true)
+	assert.True(t, customError.EventSourcingDisabled.Is(customError.CauseCustomError(err)))
+}
+
+func Test_taskService_GetEvents(t *testing.T) {
+	events := []*models.TaskEvent{
+		{ID: 1, TaskID: "task-1", Seq: 1, Type: "TaskCreated", Version: 0, Data: `{"task":{"id":"task-1"}}`, Actor: "alice", CreatedAt: "2024-01-01 08:00:00+00:00"},
+		{ID: 4, TaskID: "task-1", Seq: 2, Type: "TaskRenamed", Version: 1, Data: `{"name":"Final"}`, Actor: "bob", CreatedAt: "2024-01-02 08:00:00+00:00"},
+		{ID: 9, TaskID: "task-1", Seq: 3, Type: "TaskDeleted", Version: 2, Data: `{}`, Actor: "bob", CreatedAt: "2024-01-03 08:00:00+00:00"},
+	}
+
+	t.Run("page with the next cursor", func(t *testing.T) {
+		mockRepo := new(MockTaskEventRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("ListEvents", mock.Anything, "task-1", int64(0), 3).Return(events, nil)
+
+		result, err := service.GetEvents(context.Background(), "task-1", 0, 2)
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Events, 2)
+		assert.Equal(t, int64(2), result.NextAfter)
+		assert.Equal(t, constants.TaskRenamed, result.Events[1].Type)
+		assert.JSONEq(t, `{"name":"Final"}`, string(result.Events[1].Data))
+		assert.Equal(t, time.Date(2024, 1, 2, 8, 0, 0, 0, time.UTC), result.Events[1].CreatedAt)
+	})
+
+	t.Run("last page", func(t *testing.T) {
+		mockRepo := new(MockTaskEventRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("ListEvents", mock.Anything, "task-1", int64(3), 11).Return([]*models.TaskEvent{}, nil)
+
+		result, err := service.GetEvents(context.Background(), "task-1", 3, 10)
+
+		assert.NoError(t, err)
+		assert.Empty(t, result.Events)
+		assert.Zero(t, result.Size)
+		assert.Zero(t, result.NextAfter)
+	})
+
+	t.Run("task without events", func(t *testing.T) {
+		mockRepo := new(MockTaskEventRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("ListEvents", mock.Anything, "task-9", int64(0), 11).Return([]*models.TaskEvent{}, nil)
+
+		_, err := service.GetEvents(context.Background(), "task-9", 0, 10)
+
+		assert.True(t, customError.TaskNotFound.Is(customError.CauseCustomError(err)))
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		mockRepo := new(MockTaskEventRepository)
+		service := NewTaskService(mockRepo)
+		for _, param := range []struct {
+			after int64
+			size  int
+		}{{0, 0}, {0, constants.MaxEventSize + 1}, {-1, 10}} {
+			_, err := service.GetEvents(context.Background(), "task-1", param.after, param.size)
+			assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)), param)
+		}
+		mockRepo.AssertNotCalled(t, "ListEvents", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func Test_taskService_GetTaskAsOf(t *testing.T) {
+	dueAt := "2024-01-05 00:00:00+00:00"
+	state := &models.Task{ID: "task-1", Name: "Draft", Version: 1, DueAt: &dueAt, CreatedAt: "2024-01-01 08:00:00+00:00", UpdatedAt: "2024-01-02 08:00:00+00:00"}
+
+	t.Run("as of a version", func(t *testing.T) {
+		mockRepo := new(MockTaskEventRepository)
+		service := NewTaskService(mockRepo)
+		version := 1
+		mockRepo.On("FindAsOf", mock.Anything, "task-1", entities.AsOf{Version: &version}).Return(state, nil)
+
+		task, err := service.GetTaskAsOf(context.Background(), "task-1", entities.AsOf{Version: &version})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Draft", task.Name)
+		assert.Equal(t, 1, task.Version)
+		assert.True(t, task.Overdue)
+	})
+
+	t.Run("overdue is evaluated at the requested time", func(t *testing.T) {
+		mockRepo := new(MockTaskEventRepository)
+		service := NewTaskService(mockRepo)
+		at := time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC)
+		mockRepo.On("FindAsOf", mock.Anything, "task-1", entities.AsOf{At: &at}).Return(state, nil)
+
+		task, err := service.GetTaskAsOf(context.Background(), "task-1", entities.AsOf{At: &at})
+
+		assert.NoError(t, err)
+		assert.False(t, task.Overdue)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockRepo := new(MockTaskEventRepository)
+		service := NewTaskService(mockRepo)
+		version := 0
+		mockRepo.On("FindAsOf", mock.Anything, "task-9", mock.Anything).Return(nil, customError.TaskNotFound.New("task not found"))
+
+		_, err := service.GetTaskAsOf(context.Background(), "task-9", entities.AsOf{Version: &version})
+
+		assert.True(t, customError.TaskNotFound.Is(customError.CauseCustomError(err)))
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		mockRepo := new(MockTaskEventRepository)
+		service := NewTaskService(mockRepo)
+		version, negative, at := 1, -1, time.Now()
+		for _, asOf := range []entities.AsOf{{}, {Version: &version, At: &at}, {Version: &negative}} {
+			_, err := service.GetTaskAsOf(context.Background(), "task-1", asOf)
+			assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)), asOf)
+		}
+		mockRepo.AssertNotCalled(t, "FindAsOf", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func Test_taskService_RebuildProjection(t *testing.T) {
+	mockRepo := new(MockTaskEventRepository)
+	service := NewTaskService(mockRepo)
+	mockRepo.On("RebuildProjection", mock.Anything, true).Return(&entities.ProjectionRebuild{Tasks: 3, Updated: 1, DryRun: true}, nil)
+
+	result, err := service.RebuildProjection(context.Background(), true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, &entities.ProjectionRebuild{Tasks: 3, Updated: 1, DryRun: true}, result)
+}
+
+func Test_taskService_BackfillEvents(t *testing.T) {
+	mockRepo := new(MockTaskEventRepository)
+	service := NewTaskService(mockRepo)
+	mockRepo.On("BackfillEvents", mock.Anything, false).Return(2, nil)
+
+	backfilled, err := service.BackfillEvents(context.Background(), false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, backfilled)
+}
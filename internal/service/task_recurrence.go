@@ -0,0 +1,68 @@
+package service
+
+import (
+	"context"
+	"tasks/constants"
+	"tasks/domain/entities"
+	customError "tasks/errors"
+	"tasks/internal/recurrence"
+	"tasks/internal/repository"
+)
+
+// createNextOccurrence 依 rule 建立已完成 task 的下一次 task，沿用名稱、優先度、parent 與 tag，
+// 提醒時間與到期時間保持相同的間隔，rule 已經沒有下一次時不建立，需在 transaction 中執行
+func createNextOccurrence(ctx context.Context, repo repository.TaskRepository, completed entities.Task, rule string) error {
+	parsed, err := recurrence.Parse(rule)
+	if err != nil {
+		return customError.InvalidRequest.Wrap(err, "invalid recurrence")
+	}
+	next, rest, ok := parsed.Next(*completed.DueAt)
+	if !ok {
+		return nil
+	}
+	task := entities.NewTask(completed.Name)
+	task.ParentID = completed.ParentID
+	task.Priority = completed.Priority
+	task.DueAt = &next
+	if completed.RemindAt != nil {
+		remindAt := next.Add(completed.RemindAt.Sub(*completed.DueAt))
+		task.RemindAt = &remindAt
+	}
+	restRule := rest.String()
+	task.Recurrence = &restRule
+	if err = createTask(ctx, repo, &task); err != nil {
+		return err
+	}
+	if len(completed.Tags) == 0 {
+		return nil
+	}
+	tagIds := make([]string, 0, len(completed.Tags))
+	for _, tag := range completed.Tags {
+		tagIds = append(tagIds, tag.ID)
+	}
+	return repo.AttachTags(ctx, task.ID, tagIds)
+}
+
+// GetOccurrences 列出 recurring task 接下來 count 次出現的時間，第一次為 task 的 due_at
+func (t *taskService) GetOccurrences(ctx context.Context, taskId string, count int) (*entities.Occurrences, error) {
+	if count < 1 || count > constants.MaxOccurrences {
+		return nil, customError.InvalidRequest.Errorf("count must be between 1 and %d", constants.MaxOccurrences)
+	}
+	record, err := t.repo.Find(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	task := toEntity(record)
+	if task.Recurrence == nil || task.DueAt == nil {
+		return nil, customError.InvalidRequest.Errorf("task %s is not recurring", taskId)
+	}
+	rule, err := recurrence.Parse(*task.Recurrence)
+	if err != nil {
+		return nil, customError.Internal.Wrap(err, "stored recurrence is invalid")
+	}
+	return &entities.Occurrences{
+		TaskID:      task.ID,
+		Recurrence:  rule.String(),
+		Occurrences: rule.Occurrences(*task.DueAt, count),
+	}, nil
+}
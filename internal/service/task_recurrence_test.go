@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"testing"
+	"time"
+)
+
+func Test_taskService_CompleteRecurringTask(t *testing.T) {
+	taskID := "task-1"
+	rule := "FREQ=WEEKLY;BYDAY=MO;COUNT=3"
+	dueAt := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+	remindAt := dueAt.Add(-time.Hour)
+	dueText, remindText := dueAt.Format(time.RFC3339), remindAt.Format(time.RFC3339)
+
+	isNext := func(task entities.Task) bool {
+		return task.ID != taskID && task.Name == "standup notes" && task.Status == constants.Incomplete &&
+			task.Priority == constants.PriorityHigh &&
+			task.DueAt.Equal(time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC)) &&
+			task.RemindAt.Equal(time.Date(2024, 1, 8, 8, 0, 0, 0, time.UTC)) &&
+			*task.Recurrence == "FREQ=WEEKLY;BYDAY=MO;COUNT=2"
+	}
+
+	t.Run("update to complete creates the next occurrence with the same tags", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		param := entities.Task{ID: taskID, Name: "standup notes", Status: constants.Complete, Priority: constants.PriorityHigh, DueAt: &dueAt, RemindAt: &remindAt, Recurrence: &rule}
+		stored := param
+		stored.Recurrence = nil
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("FindWorkflow", mock.Anything).Return(defaultWorkflow(), nil)
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "standup notes", Priority: 2, DueAt: &dueText, RemindAt: &remindText, Recurrence: &rule, Version: 1}, nil).Once()
+		mockRepo.On("Update", mock.Anything, stored, (*int)(nil)).Return(nil)
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "standup notes", Status: 1, Priority: 2, DueAt: &dueText, RemindAt: &remindText, Version: 2}, nil).Once()
+		mockRepo.On("ListTags", mock.Anything, []string{taskID}).Return(map[string][]*models.Tag{taskID: {{ID: "tag-1", Name: "work"}}}, nil)
+		mockRepo.On("LastPosition", mock.Anything).Return("00000001i", nil)
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(isNext)).Return(nil)
+		mockRepo.On("AttachTags", mock.Anything, mock.Anything, []string{"tag-1"}).Return(nil)
+
+		task, err := service.UpdateTask(context.Background(), param, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, constants.Complete, task.Status)
+		assert.Nil(t, task.Recurrence)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("patch to complete moves the rule to the next occurrence", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		var cleared *string
+		complete := constants.Complete
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("FindWorkflow", mock.Anything).Return(defaultWorkflow(), nil)
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "standup notes", Priority: 2, DueAt: &dueText, RemindAt: &remindText, Recurrence: &rule, Version: 1}, nil).Once()
+		mockRepo.On("ListTags", mock.Anything, []string{taskID}).Return(map[string][]*models.Tag{}, nil)
+		mockRepo.On("Patch", mock.Anything, entities.TaskPatch{ID: taskID, Status: &complete, Recurrence: &cleared}, 1).Return(nil)
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "standup notes", Status: 1, Priority: 2, DueAt: &dueText, RemindAt: &remindText, Version: 2}, nil).Once()
+		mockRepo.On("LastPosition", mock.Anything).Return("00000001i", nil)
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(isNext)).Return(nil)
+
+		task, err := service.PatchTask(context.Background(), taskID, constants.MergePatch, []byte(`{"status":1}`), nil)
+
+		assert.NoError(t, err)
+		assert.Nil(t, task.Recurrence)
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "AttachTags", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("last occurrence does not create another task", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		last := "FREQ=WEEKLY;COUNT=1"
+		param := entities.Task{ID: taskID, Name: "standup notes", Status: constants.Complete, DueAt: &dueAt, Recurrence: &last}
+		stored := param
+		stored.Recurrence = nil
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("FindWorkflow", mock.Anything).Return(defaultWorkflow(), nil)
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "standup notes", DueAt: &dueText, Recurrence: &last, Version: 1}, nil).Once()
+		mockRepo.On("Update", mock.Anything, stored, (*int)(nil)).Return(nil)
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "standup notes", Status: 1, DueAt: &dueText, Version: 2}, nil).Once()
+		mockRepo.On("ListTags", mock.Anything, []string{taskID}).Return(map[string][]*models.Tag{}, nil)
+
+		_, err := service.UpdateTask(context.Background(), param, nil)
+
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("recurrence requires due_at", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+
+		_, err := service.CreateTask(context.Background(), entities.Task{ID: taskID, Name: "standup notes", Recurrence: &rule}, nil)
+
+		assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)))
+		mockRepo.AssertNotCalled(t, "WithTx", mock.Anything)
+	})
+
+	t.Run("invalid rule", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		invalid := "FREQ=YEARLY"
+
+		_, err := service.CreateTask(context.Background(), entities.Task{ID: taskID, Name: "standup notes", DueAt: &dueAt, Recurrence: &invalid}, nil)
+
+		assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)))
+	})
+}
+
+func Test_taskService_GetOccurrences(t *testing.T) {
+	taskID := "task-1"
+	dueText := "2024-01-31T09:00:00Z"
+
+	t.Run("list occurrences from due_at", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		rule := "rrule:freq=monthly;bymonthday=-1"
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, DueAt: &dueText, Recurrence: &rule}, nil)
+
+		result, err := service.GetOccurrences(context.Background(), taskID, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "FREQ=MONTHLY;BYMONTHDAY=-1", result.Recurrence)
+		assert.Equal(t, []time.Time{
+			time.Date(2024, 1, 31, 9, 0, 0, 0, time.UTC),
+			time.Date(2024, 2, 29, 9, 0, 0, 0, time.UTC),
+			time.Date(2024, 3, 31, 9, 0, 0, 0, time.UTC),
+		}, result.Occurrences)
+	})
+
+	t.Run("task is not recurring", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, DueAt: &dueText}, nil)
+
+		_, err := service.GetOccurrences(context.Background(), taskID, 3)
+
+		assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)))
+	})
+
+	t.Run("count out of range", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+
+		_, err := service.GetOccurrences(context.Background(), taskID, constants.MaxOccurrences+1)
+
+		assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)))
+		mockRepo.AssertNotCalled(t, "Find", mock.Anything, mock.Anything)
+	})
+}
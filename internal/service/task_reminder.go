@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"tasks/domain/entities"
+	"time"
+)
+
+// reminderBatchSize 每次查詢到期提醒的數量
+const reminderBatchSize = 100
+
+// SendDueReminders 送出已到提醒時間的 task，先標記再送出，送出失敗時取消標記等下次重送
+func (t *taskService) SendDueReminders(ctx context.Context, notifier ReminderNotifier) (int, error) {
+	now := time.Now().UTC()
+	sent := 0
+	for {
+		tasks, err := t.repo.ListDueReminders(ctx, now, reminderBatchSize)
+		if err != nil {
+			return sent, err
+		}
+		for _, record := range tasks {
+			claimed, err := t.repo.ClaimReminder(ctx, record.ID, now)
+			if err != nil {
+				return sent, err
+			}
+			if !claimed {
+				continue
+			}
+			task := toEntity(record)
+			reminder := entities.Reminder{
+				TaskID:   task.ID,
+				Name:     task.Name,
+				DueAt:    task.DueAt,
+				RemindAt: task.RemindAt,
+				Overdue:  task.IsOverdue(now),
+				SentAt:   now,
+			}
+			if err = notifier.Notify(ctx, reminder); err != nil {
+				_ = t.repo.ReleaseReminder(ctx, record.ID)
+				return sent, err
+			}
+			sent++
+		}
+		if len(tasks) < reminderBatchSize {
+			return sent, nil
+		}
+	}
+}
+
+// LogReminderNotifier 將提醒事件寫入 log
+type LogReminderNotifier struct {
+	logger *zap.Logger
+}
+
+func NewLogReminderNotifier(logger *zap.Logger) *LogReminderNotifier {
+	return &LogReminderNotifier{logger: logger}
+}
+
+func (n *LogReminderNotifier) Notify(ctx context.Context, reminder entities.Reminder) error {
+	fields := []zap.Field{
+		zap.String("task_id", reminder.TaskID),
+		zap.String("name", reminder.Name),
+		zap.Bool("overdue", reminder.Overdue),
+	}
+	if reminder.DueAt != nil {
+		fields = append(fields, zap.Time("due_at", *reminder.DueAt))
+	}
+	if reminder.RemindAt != nil {
+		fields = append(fields, zap.Time("remind_at", *reminder.RemindAt))
+	}
+	n.logger.Info("task reminder", fields...)
+	return nil
+}
+
+// ReminderScheduler 定期檢查並送出到期的提醒
+type ReminderScheduler struct {
+	taskService TaskService
+	notifier    ReminderNotifier
+	interval    time.Duration
+	logger      *zap.Logger
+}
+
+func NewReminderScheduler(taskService TaskService, notifier ReminderNotifier, interval time.Duration, logger *zap.Logger) *ReminderScheduler {
+	return &ReminderScheduler{
+		taskService: taskService,
+		notifier:    notifier,
+		interval:    interval,
+		logger:      logger,
+	}
+}
+
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	if s.interval <= 0 {
+		s.logger.Info("reminder scheduler disabled", zap.Duration("interval", s.interval))
+		return
+	}
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.send(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *ReminderScheduler) send(ctx context.Context) {
+	sent, err := s.taskService.SendDueReminders(ctx, s.notifier)
+	if err != nil {
+		s.logger.Error("send due reminders error", zap.Int("sent", sent), zap.Error(err))
+		return
+	}
+	if sent > 0 {
+		s.logger.Info("sent due reminders", zap.Int("count", sent))
+	}
+}
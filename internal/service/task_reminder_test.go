@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	"testing"
+	"time"
+)
+
+type MockReminderNotifier struct {
+	mock.Mock
+}
+
+func (m *MockReminderNotifier) Notify(ctx context.Context, reminder entities.Reminder) error {
+	args := m.Called(ctx, reminder)
+	return args.Error(0)
+}
+
+func Test_taskService_SendDueReminders(t *testing.T) {
+	dueAt := "2024-01-01 09:00:00+00:00"
+	due := []*models.Task{
+		{ID: "task-1", Name: "Pay rent", DueAt: &dueAt},
+		{ID: "task-2", Name: "Call mom", DueAt: &dueAt},
+	}
+
+	t.Run("notifies claimed tasks only", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		notifier := new(MockReminderNotifier)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("ListDueReminders", mock.Anything, mock.Anything, reminderBatchSize).Return(due, nil)
+		mockRepo.On("ClaimReminder", mock.Anything, "task-1", mock.Anything).Return(true, nil)
+		mockRepo.On("ClaimReminder", mock.Anything, "task-2", mock.Anything).Return(false, nil)
+		notifier.On("Notify", mock.Anything, mock.MatchedBy(func(reminder entities.Reminder) bool {
+			return reminder.TaskID == "task-1" && reminder.Overdue && reminder.DueAt != nil
+		})).Return(nil)
+
+		sent, err := service.SendDueReminders(context.Background(), notifier)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, sent)
+		mockRepo.AssertExpectations(t)
+		notifier.AssertExpectations(t)
+	})
+
+	t.Run("releases the claim when notify fails", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		notifier := new(MockReminderNotifier)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("ListDueReminders", mock.Anything, mock.Anything, reminderBatchSize).Return(due[:1], nil)
+		mockRepo.On("ClaimReminder", mock.Anything, "task-1", mock.Anything).Return(true, nil)
+		mockRepo.On("ReleaseReminder", mock.Anything, "task-1").Return(nil)
+		notifier.On("Notify", mock.Anything, mock.Anything).Return(errors.New("notify error"))
+
+		sent, err := service.SendDueReminders(context.Background(), notifier)
+
+		assert.EqualError(t, err, "notify error")
+		assert.Equal(t, 0, sent)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestReminderScheduler_Run(t *testing.T) {
+	t.Run("sends until context is cancelled", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		checked := make(chan struct{}, 10)
+		mockRepo.On("ListDueReminders", mock.Anything, mock.Anything, reminderBatchSize).Return([]*models.Task{}, nil).Run(func(mock.Arguments) {
+			checked <- struct{}{}
+		})
+		scheduler := NewReminderScheduler(NewTaskService(mockRepo), NewLogReminderNotifier(zap.NewNop()), 10*time.Millisecond, zap.NewNop())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			scheduler.Run(ctx)
+			close(done)
+		}()
+		<-checked
+		<-checked
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("scheduler did not stop after cancel")
+		}
+	})
+
+	t.Run("disabled without interval", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		scheduler := NewReminderScheduler(NewTaskService(mockRepo), NewLogReminderNotifier(zap.NewNop()), 0, zap.NewNop())
+
+		scheduler.Run(context.Background())
+
+		mockRepo.AssertNotCalled(t, "ListDueReminders", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
@@ -1,12 +1,26 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"net/http"
+	"strings"
 	"tasks/constants"
 	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"tasks/internal/position"
+	"tasks/internal/recurrence"
 	"tasks/internal/repository"
+	"time"
 )
 
+// maxPositionAttempts 新增或移動 task 時 position 被同時寫入佔用的重試次數
+const maxPositionAttempts = 3
+
 type taskService struct {
 	repo repository.TaskRepository
 }
@@ -15,40 +29,573 @@ func NewTaskService(repo repository.TaskRepository) TaskService {
 	return &taskService{repo: repo}
 }
 
-func (t *taskService) CreateTask(ctx context.Context, param entities.Task) error {
-	return t.repo.Create(param)
+func (t *taskService) CreateTask(ctx context.Context, param entities.Task, lease *entities.IdempotencyLease) (*entities.Task, error) {
+	if err := validateSchedule(param); err != nil {
+		return nil, err
+	}
+	err := t.withPositionRetry(ctx, func(repo repository.TaskRepository) error {
+		if err := createTask(ctx, repo, &param); err != nil {
+			return err
+		}
+		param.Overdue = param.IsOverdue(time.Now().UTC())
+		if lease == nil {
+			return nil
+		}
+		body, err := json.Marshal(param)
+		if err != nil {
+			return err
+		}
+		return repo.CompleteIdempotencyKey(ctx, *lease, entities.IdempotentResponse{StatusCode: http.StatusCreated, Body: body})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &param, nil
+}
+
+// withPositionRetry position 被同時寫入的 task 佔用時 rollback 後重新執行 fn，重新計算 position
+func (t *taskService) withPositionRetry(ctx context.Context, fn func(repo repository.TaskRepository) error) error {
+	var err error
+	for attempt := 0; attempt < maxPositionAttempts; attempt++ {
+		if err = t.repo.WithTx(ctx, fn); !errors.Is(err, customError.TaskPositionConflict) {
+			return err
+		}
+	}
+	return err
 }
 
-func (t *taskService) UpdateTask(ctx context.Context, param entities.Task) error {
-	err := t.repo.Update(param)
+// createTask 新的 task 排在最後面，需在 transaction 中執行
+func createTask(ctx context.Context, repo repository.TaskRepository, task *entities.Task) error {
+	if err := checkState(ctx, repo, task.Status); err != nil {
+		return err
+	}
+	if err := validateParent(ctx, repo, task.ID, task.ParentID, false); err != nil {
+		return err
+	}
+	last, err := repo.LastPosition(ctx)
 	if err != nil {
 		return err
 	}
+	if task.Position, err = position.After(last); err != nil {
+		return err
+	}
+	if err = repo.Create(ctx, *task); err != nil {
+		return err
+	}
+	return rollupStatus(ctx, repo, nil, task)
+}
+
+// UpdateTask 更新與讀回在同一個 transaction 中，回傳的是這次更新的結果
+func (t *taskService) UpdateTask(ctx context.Context, param entities.Task, expectedVersion *int) (*entities.Task, error) {
+	if err := validateSchedule(param); err != nil {
+		return nil, err
+	}
+	var updated *entities.Task
+	err := t.repo.WithTx(ctx, func(repo repository.TaskRepository) error {
+		task, err := updateTask(ctx, repo, param, expectedVersion)
+		updated = task
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// updateTask 整筆更新後讀回，需在 transaction 中執行
+func updateTask(ctx context.Context, repo repository.TaskRepository, param entities.Task, expectedVersion *int) (*entities.Task, error) {
+	record, err := repo.Find(ctx, param.ID)
+	if err != nil {
+		return nil, err
+	}
+	before := toEntity(record)
+	if err = checkBlocked(before, param.Status); err != nil {
+		return nil, err
+	}
+	if err = checkTransition(ctx, repo, before, param.Status); err != nil {
+		return nil, err
+	}
+	if !equalID(before.ParentID, param.ParentID) {
+		if err = validateParent(ctx, repo, param.ID, param.ParentID, true); err != nil {
+			return nil, err
+		}
+	}
+	// 完成時 recurrence 移到下一次的 task 上，重複完成不會再產生新的 task
+	rule := param.Recurrence
+	recurring := rule != nil && param.Status == constants.Complete && before.Status != constants.Complete
+	if recurring {
+		param.Recurrence = nil
+	}
+	if err = repo.Update(ctx, param, expectedVersion); err != nil {
+		return nil, err
+	}
+	record, err = repo.Find(ctx, param.ID)
+	if err != nil {
+		return nil, err
+	}
+	task := toEntity(record)
+	if err = loadTags(ctx, repo, []*entities.Task{&task}); err != nil {
+		return nil, err
+	}
+	if recurring {
+		if err = createNextOccurrence(ctx, repo, task, *rule); err != nil {
+			return nil, err
+		}
+	}
+	if err = rollupStatus(ctx, repo, &before, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (t *taskService) PatchTask(ctx context.Context, taskId string, patchType constants.PatchType, patch []byte, expectedVersion *int) (*entities.Task, error) {
+	var result *entities.Task
+	err := t.repo.WithTx(ctx, func(repo repository.TaskRepository) error {
+		task, err := patchTask(ctx, repo, taskId, patchType, patch, expectedVersion)
+		result = task
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// patchTask 讀取、套用 patch 並寫回，需在 transaction 中執行
+func patchTask(ctx context.Context, repo repository.TaskRepository, taskId string, patchType constants.PatchType, patch []byte, expectedVersion *int) (*entities.Task, error) {
+	record, err := repo.Find(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != nil && *expectedVersion != record.Version {
+		return nil, customError.TaskVersionMismatch.Errorf("task %s version is %d, expected %d", taskId, record.Version, *expectedVersion)
+	}
+	current := toEntity(record)
+	if err = loadTags(ctx, repo, []*entities.Task{&current}); err != nil {
+		return nil, err
+	}
+	patched, err := applyPatch(current, patchType, patch)
+	if err != nil {
+		return nil, err
+	}
+	if err = validateTask(current, patched); err != nil {
+		return nil, err
+	}
+
+	change := entities.TaskPatch{ID: taskId}
+	if !equalID(patched.ParentID, current.ParentID) {
+		if err = validateParent(ctx, repo, taskId, patched.ParentID, true); err != nil {
+			return nil, err
+		}
+		change.ParentID = &patched.ParentID
+	}
+	if patched.Name != current.Name {
+		change.Name = &patched.Name
+	}
+	if patched.Status != current.Status {
+		if err = checkBlocked(current, patched.Status); err != nil {
+			return nil, err
+		}
+		if err = checkTransition(ctx, repo, current, patched.Status); err != nil {
+			return nil, err
+		}
+		change.Status = &patched.Status
+	}
+	if patched.Priority != current.Priority {
+		change.Priority = &patched.Priority
+	}
+	if !equalTime(patched.DueAt, current.DueAt) {
+		change.DueAt = &patched.DueAt
+	}
+	if !equalTime(patched.RemindAt, current.RemindAt) {
+		change.RemindAt = &patched.RemindAt
+	}
+	if !equalID(patched.Recurrence, current.Recurrence) {
+		change.Recurrence = &patched.Recurrence
+	}
+	// 與 updateTask 相同，完成時 recurrence 移到下一次的 task 上
+	recurring := patched.Recurrence != nil && change.Status != nil && patched.Status == constants.Complete
+	if recurring {
+		var cleared *string
+		change.Recurrence = &cleared
+	}
+	if change.ParentID == nil && change.Name == nil && change.Status == nil && change.Priority == nil && change.DueAt == nil && change.RemindAt == nil && change.Recurrence == nil {
+		return &current, nil
+	}
+	if err = repo.Patch(ctx, change, record.Version); err != nil {
+		return nil, err
+	}
+	record, err = repo.Find(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	task := toEntity(record)
+	// patch 不會改變 tag
+	task.Tags = current.Tags
+	if recurring {
+		if err = createNextOccurrence(ctx, repo, task, *patched.Recurrence); err != nil {
+			return nil, err
+		}
+	}
+	if err = rollupStatus(ctx, repo, &current, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// applyPatch 將 RFC 7396 merge patch 或 RFC 6902 json patch 套用在 task 的 JSON 表示上
+func applyPatch(task entities.Task, patchType constants.PatchType, patch []byte) (entities.Task, error) {
+	var patched entities.Task
+	document, err := json.Marshal(task)
+	if err != nil {
+		return patched, err
+	}
+	switch patchType {
+	case constants.MergePatch:
+		document, err = jsonpatch.MergePatch(document, patch)
+	case constants.JSONPatch:
+		var operations jsonpatch.Patch
+		operations, err = jsonpatch.DecodePatch(patch)
+		if err == nil {
+			document, err = operations.Apply(document)
+		}
+	default:
+		return patched, customError.UnsupportedMedia.Errorf("patch type %s not supported", patchType)
+	}
+	if err != nil {
+		return patched, customError.InvalidRequest.Wrap(err, "apply patch error")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(document))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&patched); err != nil {
+		return patched, customError.InvalidRequest.Wrap(err, "decode patched task error")
+	}
+	return patched, nil
+}
+
+func validateTask(current, patched entities.Task) error {
+	if patched.ID != current.ID || patched.Version != current.Version || patched.Position != current.Position || patched.Overdue != current.Overdue ||
+		patched.Blocked != current.Blocked || !patched.CreatedAt.Equal(current.CreatedAt) || !patched.UpdatedAt.Equal(current.UpdatedAt) ||
+		!sameTags(patched.Tags, current.Tags) {
+		return customError.InvalidRequest.New("id, version, position, created_at, updated_at, overdue, blocked and tags are read-only")
+	}
+	if strings.TrimSpace(patched.Name) == "" {
+		return customError.InvalidRequest.New("name is required")
+	}
+	if !patched.Priority.IsValid() {
+		return customError.InvalidRequest.New("priority not supported")
+	}
+	return validateSchedule(patched)
+}
+
+// validateSchedule 提醒時間不可晚於到期時間，recurrence 以 due_at 作為第一次出現的時間
+func validateSchedule(task entities.Task) error {
+	if task.DueAt != nil && task.RemindAt != nil && task.RemindAt.After(*task.DueAt) {
+		return customError.InvalidRequest.New("remind_at must not be after due_at")
+	}
+	if task.Recurrence != nil {
+		if task.DueAt == nil {
+			return customError.InvalidRequest.New("recurrence requires due_at")
+		}
+		if _, err := recurrence.Parse(*task.Recurrence); err != nil {
+			return customError.InvalidRequest.Wrap(err, "invalid recurrence")
+		}
+	}
+	return nil
+}
+
+// sameTags 只比較 tag 的 id、名稱與顏色
+func sameTags(a, b []entities.Tag) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].ID != b[i].ID || a[i].Name != b[i].Name || a[i].Color != b[i].Color {
+			return false
+		}
+	}
+	return true
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// MoveTask 將 task 移到另一個 task 的前面或後面，只改寫被移動 task 的 position
+func (t *taskService) MoveTask(ctx context.Context, taskId string, move entities.TaskMove, expectedVersion *int) (*entities.Task, error) {
+	if move.TargetID == taskId {
+		return nil, customError.InvalidRequest.New("task cannot be moved relative to itself")
+	}
+	var result *entities.Task
+	err := t.withPositionRetry(ctx, func(repo repository.TaskRepository) error {
+		record, err := repo.Find(ctx, taskId)
+		if err != nil {
+			return err
+		}
+		if expectedVersion != nil && *expectedVersion != record.Version {
+			return customError.TaskVersionMismatch.Errorf("task %s version is %d, expected %d", taskId, record.Version, *expectedVersion)
+		}
+		target, err := repo.Find(ctx, move.TargetID)
+		if err != nil {
+			return err
+		}
+		adjacent, err := repo.AdjacentPosition(ctx, taskId, target.Position, move.After)
+		if err != nil {
+			return err
+		}
+		var key string
+		switch {
+		case move.After && adjacent == "":
+			key, err = position.After(target.Position)
+		case move.After:
+			key, err = position.Between(target.Position, adjacent)
+		default:
+			key, err = position.Between(adjacent, target.Position)
+		}
+		if err != nil {
+			return err
+		}
+		if err = repo.Patch(ctx, entities.TaskPatch{ID: taskId, Position: &key}, record.Version); err != nil {
+			return err
+		}
+		record, err = repo.Find(ctx, taskId)
+		if err != nil {
+			return err
+		}
+		task := toEntity(record)
+		result = &task
+		return loadTags(ctx, repo, []*entities.Task{result})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// AttachTags 為 task 加上 tag，回傳加上後的 task
+func (t *taskService) AttachTags(ctx context.Context, taskId string, tagIds []string) (*entities.Task, error) {
+	return t.changeLinks(ctx, taskId, "tag", tagIds, func(repo repository.TaskRepository) error {
+		return repo.AttachTags(ctx, taskId, tagIds)
+	})
+}
+
+// DetachTags 移除 task 上的 tag，回傳移除後的 task
+func (t *taskService) DetachTags(ctx context.Context, taskId string, tagIds []string) (*entities.Task, error) {
+	return t.changeLinks(ctx, taskId, "tag", tagIds, func(repo repository.TaskRepository) error {
+		return repo.DetachTags(ctx, taskId, tagIds)
+	})
+}
+
+// changeLinks 在 transaction 中變更 task 的 tag 或 blocker 並讀回 task，kind 用於錯誤訊息
+func (t *taskService) changeLinks(ctx context.Context, taskId string, kind string, ids []string, change func(repo repository.TaskRepository) error) (*entities.Task, error) {
+	if len(ids) == 0 {
+		return nil, customError.InvalidRequest.Errorf("at least one %s id is required", kind)
+	}
+	for _, id := range ids {
+		if strings.TrimSpace(id) == "" {
+			return nil, customError.InvalidRequest.Errorf("%s id is required", kind)
+		}
+	}
+	var result *entities.Task
+	err := t.repo.WithTx(ctx, func(repo repository.TaskRepository) error {
+		if err := change(repo); err != nil {
+			return err
+		}
+		record, err := repo.Find(ctx, taskId)
+		if err != nil {
+			return err
+		}
+		task := toEntity(record)
+		result = &task
+		return loadTags(ctx, repo, []*entities.Task{result})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// loadTags 以一次查詢填入所有 task 的 tag
+func loadTags(ctx context.Context, repo repository.TaskRepository, tasks []*entities.Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	tags, err := repo.ListTags(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for _, task := range tasks {
+		task.Tags = nil
+		for _, tag := range tags[task.ID] {
+			task.Tags = append(task.Tags, toTagEntity(tag))
+		}
+	}
 	return nil
 }
 
-func (t *taskService) DeleteTask(ctx context.Context, taskId string) error {
-	return t.repo.Delete(taskId)
+func (t *taskService) DeleteTask(ctx context.Context, taskId string, expectedVersion *int, cascade bool) error {
+	return t.repo.WithTx(ctx, func(repo repository.TaskRepository) error {
+		return deleteTask(ctx, repo, taskId, expectedVersion, cascade)
+	})
+}
+
+// deleteTask 刪除後 parent 剩下的子 task 可能已全部完成，需在 transaction 中執行
+func deleteTask(ctx context.Context, repo repository.TaskRepository, taskId string, expectedVersion *int, cascade bool) error {
+	record, err := repo.Find(ctx, taskId)
+	if err != nil {
+		return err
+	}
+	if err = repo.Delete(ctx, taskId, expectedVersion, cascade); err != nil {
+		return err
+	}
+	before := toEntity(record)
+	return rollupStatus(ctx, repo, &before, nil)
+}
+
+func (t *taskService) GetTask(ctx context.Context, taskId string) (*entities.Task, error) {
+	record, err := t.repo.Find(ctx, taskId)
+	if err != nil {
+		return nil, err
+	}
+	task := toEntity(record)
+	if err = loadTags(ctx, t.repo, []*entities.Task{&task}); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (t *taskService) GetTrash(ctx context.Context, param entities.TaskQueryParam) (*entities.Tasks, error) {
+	param.Trashed = true
+	return t.GetTasks(ctx, param)
+}
+
+func (t *taskService) RestoreTask(ctx context.Context, taskId string) (*entities.Task, error) {
+	if err := t.repo.Restore(ctx, taskId); err != nil {
+		return nil, err
+	}
+	return t.GetTask(ctx, taskId)
+}
+
+func (t *taskService) PurgeTask(ctx context.Context, taskId string) error {
+	return t.repo.Purge(ctx, taskId)
+}
+
+func (t *taskService) PurgeExpiredTasks(ctx context.Context, retention time.Duration) (int64, error) {
+	return t.repo.PurgeDeletedBefore(ctx, time.Now().UTC().Add(-retention))
 }
 
 func (t *taskService) GetTasks(ctx context.Context, param entities.TaskQueryParam) (*entities.Tasks, error) {
 	var result entities.Tasks
-	tasks, err := t.repo.List(param)
+	// 多取一筆用來判斷是否還有下一頁
+	query := param
+	query.Size = param.Size + 1
+	tasks, err := t.repo.List(ctx, query)
 	if err != nil {
 		return nil, err
 	}
+	hasMore := len(tasks) > param.Size
+	if hasMore {
+		tasks = tasks[:param.Size]
+	}
+	backward := param.Cursor != nil && param.Cursor.Backward
+	if backward {
+		for i, j := 0, len(tasks)-1; i < j; i, j = i+1, j-1 {
+			tasks[i], tasks[j] = tasks[j], tasks[i]
+		}
+	}
 	result.Tasks = make([]entities.Task, 0)
 	for _, task := range tasks {
-		result.Tasks = append(result.Tasks, entities.Task{
-			ID:      task.ID,
-			Name:    task.Name,
-			Status:  constants.Status(task.Status),
-			Version: task.Version,
-		})
-
+		result.Tasks = append(result.Tasks, toEntity(task))
+	}
+	page := make([]*entities.Task, 0, len(result.Tasks))
+	for i := range result.Tasks {
+		page = append(page, &result.Tasks[i])
+	}
+	if err = loadTags(ctx, t.repo, page); err != nil {
+		return nil, err
+	}
+	if len(tasks) > 0 {
+		if hasMore || backward {
+			result.NextCursor = newCursor(param, tasks[len(tasks)-1], false).Encode()
+		}
+		if (backward && hasMore) || (!backward && (param.Cursor != nil || param.Offset > 0)) {
+			result.PrevCursor = newCursor(param, tasks[0], true).Encode()
+		}
+	}
+	if param.Cursor == nil {
+		result.Page = param.Offset/param.Size + 1
 	}
-	result.Page = param.Offset/param.Size + 1
 	result.Size = len(tasks)
+	if param.WithTotal {
+		total, err := t.repo.Count(ctx, param)
+		if err != nil {
+			return nil, err
+		}
+		result.Total = &total
+	}
 	return &result, nil
 
 }
+
+func newCursor(param entities.TaskQueryParam, task *models.Task, backward bool) entities.Cursor {
+	cursor := entities.Cursor{
+		SortBy:   param.SortBy,
+		Order:    param.Order,
+		ID:       task.ID,
+		Backward: backward,
+	}
+	switch param.SortBy {
+	case constants.SortByName:
+		cursor.Value = task.Name
+	case constants.SortByStatus:
+		cursor.Value = task.Status
+	case constants.SortByPriority:
+		cursor.Value = task.Priority
+	case constants.SortByCreatedAt:
+		cursor.Value = task.CreatedAt
+	default:
+		cursor.SortBy = constants.SortByPosition
+		cursor.Value = task.Position
+	}
+	if !cursor.Order.IsValid() {
+		cursor.Order = constants.OrderAsc
+	}
+	return cursor
+}
+
+func toEntity(task *models.Task) entities.Task {
+	result := entities.Task{
+		ID:         task.ID,
+		ParentID:   task.ParentID,
+		Name:       task.Name,
+		Status:     constants.Status(task.Status),
+		Priority:   constants.Priority(task.Priority),
+		Position:   task.Position,
+		Version:    task.Version,
+		Blocked:    task.Blocked,
+		Recurrence: task.Recurrence,
+		CreatedAt:  models.ParseTime(task.CreatedAt),
+		UpdatedAt:  models.ParseTime(task.UpdatedAt),
+	}
+	if task.DeletedAt != nil {
+		deletedAt := models.ParseTime(*task.DeletedAt)
+		result.DeletedAt = &deletedAt
+	}
+	if task.DueAt != nil {
+		dueAt := models.ParseTime(*task.DueAt)
+		result.DueAt = &dueAt
+	}
+	if task.RemindAt != nil {
+		remindAt := models.ParseTime(*task.RemindAt)
+		result.RemindAt = &remindAt
+	}
+	result.Overdue = result.IsOverdue(time.Now().UTC())
+	return result
+}
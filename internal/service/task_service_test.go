@@ -2,43 +2,211 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"net/http"
+	"tasks/constants"
 	"tasks/domain/entities"
 	"tasks/domain/models"
+	customError "tasks/errors"
+	"tasks/internal/repository"
 	"testing"
+	"time"
 )
 
 type MockTaskRepository struct {
 	mock.Mock
 }
 
-func (m *MockTaskRepository) Find(id string) (*models.Task, error) {
-	//TODO implement me
-	panic("implement me")
+func (m *MockTaskRepository) Find(ctx context.Context, id string) (*models.Task, error) {
+	args := m.Called(ctx, id)
+	if task, ok := args.Get(0).(*models.Task); ok {
+		return task, args.Error(1)
+	}
+	return nil, args.Error(1)
 }
 
-func (m *MockTaskRepository) Create(task entities.Task) error {
-	args := m.Called(task)
+func (m *MockTaskRepository) Create(ctx context.Context, task entities.Task) error {
+	args := m.Called(ctx, task)
 	return args.Error(0)
 }
 
-func (m *MockTaskRepository) Update(task entities.Task) error {
-	args := m.Called(task)
+func (m *MockTaskRepository) Update(ctx context.Context, task entities.Task, expectedVersion *int) error {
+	args := m.Called(ctx, task, expectedVersion)
 	return args.Error(0)
 }
 
-func (m *MockTaskRepository) Delete(taskID string) error {
-	args := m.Called(taskID)
+func (m *MockTaskRepository) Patch(ctx context.Context, patch entities.TaskPatch, version int) error {
+	args := m.Called(ctx, patch, version)
 	return args.Error(0)
 }
 
-func (m *MockTaskRepository) List(param entities.TaskQueryParam) ([]*models.Task, error) {
-	args := m.Called(param)
+func (m *MockTaskRepository) Delete(ctx context.Context, taskID string, expectedVersion *int, cascade bool) error {
+	args := m.Called(ctx, taskID, expectedVersion, cascade)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) List(ctx context.Context, param entities.TaskQueryParam) ([]*models.Task, error) {
+	args := m.Called(ctx, param)
+	return args.Get(0).([]*models.Task), args.Error(1)
+}
+
+func (m *MockTaskRepository) Count(ctx context.Context, param entities.TaskQueryParam) (int, error) {
+	args := m.Called(ctx, param)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTaskRepository) Restore(ctx context.Context, taskID string) error {
+	args := m.Called(ctx, taskID)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) Purge(ctx context.Context, taskID string) error {
+	args := m.Called(ctx, taskID)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaskRepository) ListSubtree(ctx context.Context, id string, depth int) ([]*models.TaskNode, error) {
+	args := m.Called(ctx, id, depth)
+	if nodes, ok := args.Get(0).([]*models.TaskNode); ok {
+		return nodes, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskRepository) ListAncestors(ctx context.Context, id string) ([]string, error) {
+	args := m.Called(ctx, id)
+	if ancestors, ok := args.Get(0).([]string); ok {
+		return ancestors, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskRepository) CountChildren(ctx context.Context, id string) (int, int, error) {
+	args := m.Called(ctx, id)
+	return args.Int(0), args.Int(1), args.Error(2)
+}
+
+func (m *MockTaskRepository) LastPosition(ctx context.Context) (string, error) {
+	args := m.Called(ctx)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTaskRepository) AdjacentPosition(ctx context.Context, id string, position string, after bool) (string, error) {
+	args := m.Called(ctx, id, position, after)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockTaskRepository) ListDueReminders(ctx context.Context, now time.Time, limit int) ([]*models.Task, error) {
+	args := m.Called(ctx, now, limit)
 	return args.Get(0).([]*models.Task), args.Error(1)
 }
 
+func (m *MockTaskRepository) ClaimReminder(ctx context.Context, id string, now time.Time) (bool, error) {
+	args := m.Called(ctx, id, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTaskRepository) ReleaseReminder(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) ListTags(ctx context.Context, taskIDs []string) (map[string][]*models.Tag, error) {
+	args := m.Called(ctx, taskIDs)
+	return args.Get(0).(map[string][]*models.Tag), args.Error(1)
+}
+
+func (m *MockTaskRepository) AttachTags(ctx context.Context, taskID string, tagIDs []string) error {
+	args := m.Called(ctx, taskID, tagIDs)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) DetachTags(ctx context.Context, taskID string, tagIDs []string) error {
+	args := m.Called(ctx, taskID, tagIDs)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) ListBlockers(ctx context.Context, taskIDs []string) (map[string][]string, error) {
+	args := m.Called(ctx, taskIDs)
+	return args.Get(0).(map[string][]string), args.Error(1)
+}
+
+func (m *MockTaskRepository) ListAllBlockers(ctx context.Context, taskIDs []string) (map[string]bool, error) {
+	args := m.Called(ctx, taskIDs)
+	return args.Get(0).(map[string]bool), args.Error(1)
+}
+
+func (m *MockTaskRepository) AddBlockers(ctx context.Context, taskID string, blockerIDs []string) error {
+	args := m.Called(ctx, taskID, blockerIDs)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) RemoveBlockers(ctx context.Context, taskID string, blockerIDs []string) error {
+	args := m.Called(ctx, taskID, blockerIDs)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) LockDependencies(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *MockTaskRepository) FindWorkflow(ctx context.Context) (*models.Workflow, error) {
+	args := m.Called(ctx)
+	if workflow, ok := args.Get(0).(*models.Workflow); ok {
+		return workflow, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskRepository) ListAudit(ctx context.Context, param entities.AuditQueryParam) ([]*models.AuditEntry, error) {
+	args := m.Called(ctx, param)
+	if entries, ok := args.Get(0).([]*models.AuditEntry); ok {
+		return entries, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskRepository) ListAuditChain(ctx context.Context, afterID int64, size int) ([]*models.AuditEntry, error) {
+	args := m.Called(ctx, afterID, size)
+	if entries, ok := args.Get(0).([]*models.AuditEntry); ok {
+		return entries, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskRepository) AuditHead(ctx context.Context) (*models.AuditHead, error) {
+	args := m.Called(ctx)
+	if head, ok := args.Get(0).(*models.AuditHead); ok {
+		return head, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTaskRepository) SealAudit(ctx context.Context) (int64, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockTaskRepository) CompleteIdempotencyKey(ctx context.Context, lease entities.IdempotencyLease, response entities.IdempotentResponse) error {
+	args := m.Called(ctx, lease, response)
+	return args.Error(0)
+}
+
+// WithTx 直接以 mock 本身執行 fn
+func (m *MockTaskRepository) WithTx(ctx context.Context, fn func(repo repository.TaskRepository) error) error {
+	m.Called(ctx)
+	return fn(m)
+}
+
 func Test_taskService_CreateTask(t *testing.T) {
 	mockRepo := new(MockTaskRepository)
 	service := NewTaskService(mockRepo)
@@ -48,14 +216,89 @@ func Test_taskService_CreateTask(t *testing.T) {
 		Name: "Test Task",
 	}
 
+	mockRepo.On("WithTx", mock.Anything).Return()
+	mockRepo.On("LastPosition", mock.Anything).Return("00000001i", nil)
+
 	t.Run("successfully create task", func(t *testing.T) {
-		mockRepo.On("Create", task).Return(nil)
+		positioned := task
+		positioned.Position = "00000002"
+		mockRepo.On("Create", mock.Anything, positioned).Return(nil)
 
-		err := service.CreateTask(context.Background(), task)
+		created, err := service.CreateTask(context.Background(), task, nil)
 
 		assert.NoError(t, err)
+		assert.Equal(t, positioned, *created)
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("overdue is computed", func(t *testing.T) {
+		dueAt := time.Now().UTC().Add(-time.Hour)
+		overdue := entities.Task{ID: "task-456", Name: "Late", DueAt: &dueAt}
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(task entities.Task) bool { return task.ID == "task-456" })).Return(nil)
+
+		created, err := service.CreateTask(context.Background(), overdue, nil)
+
+		assert.NoError(t, err)
+		assert.True(t, created.Overdue)
+	})
+
+	t.Run("response is stored for the idempotency key", func(t *testing.T) {
+		lease := entities.IdempotencyLease{Key: "key-1", Token: "token-1"}
+		keyed := entities.Task{ID: "task-321", Name: "Keyed"}
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(task entities.Task) bool { return task.ID == "task-321" })).Return(nil)
+		mockRepo.On("CompleteIdempotencyKey", mock.Anything, lease, mock.MatchedBy(func(response entities.IdempotentResponse) bool {
+			var stored entities.Task
+			return response.StatusCode == http.StatusCreated && json.Unmarshal(response.Body, &stored) == nil && stored.ID == "task-321"
+		})).Return(nil)
+
+		created, err := service.CreateTask(context.Background(), keyed, &lease)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "task-321", created.ID)
+		mockRepo.AssertCalled(t, "CompleteIdempotencyKey", mock.Anything, lease, mock.Anything)
+	})
+
+	t.Run("lease taken over rolls back the task", func(t *testing.T) {
+		lease := entities.IdempotencyLease{Key: "key-2", Token: "token-2"}
+		keyed := entities.Task{ID: "task-654", Name: "Keyed"}
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(task entities.Task) bool { return task.ID == "task-654" })).Return(nil)
+		mockRepo.On("CompleteIdempotencyKey", mock.Anything, lease, mock.Anything).Return(customError.IdempotencyKeyInProgress.New("taken over"))
+
+		created, err := service.CreateTask(context.Background(), keyed, &lease)
+
+		assert.True(t, errors.Is(err, customError.IdempotencyKeyInProgress))
+		assert.Nil(t, created)
+	})
+
+	t.Run("retries when the position is taken", func(t *testing.T) {
+		isRetried := mock.MatchedBy(func(task entities.Task) bool { return task.ID == "task-987" })
+		mockRepo.On("Create", mock.Anything, isRetried).Return(customError.TaskPositionConflict.New("position taken")).Once()
+		mockRepo.On("Create", mock.Anything, isRetried).Return(nil).Once()
+
+		created, err := service.CreateTask(context.Background(), entities.Task{ID: "task-987", Name: "Retried"}, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "task-987", created.ID)
+	})
+
+	t.Run("gives up after repeated position conflicts", func(t *testing.T) {
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(task entities.Task) bool { return task.ID == "task-988" })).
+			Return(customError.TaskPositionConflict.New("position taken")).Times(maxPositionAttempts)
+
+		_, err := service.CreateTask(context.Background(), entities.Task{ID: "task-988", Name: "Busy"}, nil)
+
+		assert.True(t, errors.Is(err, customError.TaskPositionConflict))
+	})
+
+	t.Run("remind_at after due_at", func(t *testing.T) {
+		dueAt := time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC)
+		remindAt := dueAt.Add(time.Minute)
+
+		_, err := service.CreateTask(context.Background(), entities.Task{ID: "task-789", Name: "Bad", DueAt: &dueAt, RemindAt: &remindAt}, nil)
+
+		assert.True(t, errors.Is(err, customError.InvalidRequest))
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.MatchedBy(func(task entities.Task) bool { return task.ID == "task-789" }))
+	})
 }
 
 func Test_taskService_UpdateTask(t *testing.T) {
@@ -68,13 +311,324 @@ func Test_taskService_UpdateTask(t *testing.T) {
 	}
 
 	t.Run("successfully update task", func(t *testing.T) {
-		mockRepo.On("Update", task).Return(nil)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("ListTags", mock.Anything, mock.Anything).Return(map[string][]*models.Tag{}, nil)
+		mockRepo.On("Update", mock.Anything, task, (*int)(nil)).Return(nil)
+		mockRepo.On("Find", mock.Anything, "task-123").Return(&models.Task{
+			ID:      "task-123",
+			Name:    "Test Task",
+			Version: 1,
+		}, nil)
+
+		updated, err := service.UpdateTask(context.Background(), task, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, updated.Version)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func Test_taskService_PatchTask(t *testing.T) {
+	record := &models.Task{
+		ID:        "task-123",
+		Name:      "Test Task",
+		Status:    0,
+		Version:   2,
+		CreatedAt: "2024-01-01 00:00:00+00:00",
+		UpdatedAt: "2024-01-01 00:00:00+00:00",
+	}
+	renamed := "Renamed"
+	complete := constants.Complete
+
+	t.Run("merge patch only writes changed fields", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("ListTags", mock.Anything, mock.Anything).Return(map[string][]*models.Tag{}, nil)
+		mockRepo.On("Find", mock.Anything, "task-123").Return(record, nil)
+		mockRepo.On("Patch", mock.Anything, entities.TaskPatch{ID: "task-123", Name: &renamed}, 2).Return(nil)
+
+		_, err := service.PatchTask(context.Background(), "task-123", constants.MergePatch, []byte(`{"name":"Renamed","status":0}`), nil)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("json patch with version test", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("ListTags", mock.Anything, mock.Anything).Return(map[string][]*models.Tag{}, nil)
+		mockRepo.On("Find", mock.Anything, "task-123").Return(record, nil)
+		mockRepo.On("FindWorkflow", mock.Anything).Return(defaultWorkflow(), nil)
+		mockRepo.On("Patch", mock.Anything, entities.TaskPatch{ID: "task-123", Status: &complete}, 2).Return(nil)
+
+		patch := `[{"op":"test","path":"/version","value":2},{"op":"replace","path":"/status","value":1}]`
+		_, err := service.PatchTask(context.Background(), "task-123", constants.JSONPatch, []byte(patch), nil)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("priority change", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		high := constants.PriorityHigh
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("ListTags", mock.Anything, mock.Anything).Return(map[string][]*models.Tag{}, nil)
+		mockRepo.On("Find", mock.Anything, "task-123").Return(record, nil)
+		mockRepo.On("Patch", mock.Anything, entities.TaskPatch{ID: "task-123", Priority: &high}, 2).Return(nil)
+
+		_, err := service.PatchTask(context.Background(), "task-123", constants.MergePatch, []byte(`{"priority":2}`), nil)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unchanged document is not written", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("ListTags", mock.Anything, mock.Anything).Return(map[string][]*models.Tag{}, nil)
+		mockRepo.On("Find", mock.Anything, "task-123").Return(record, nil)
+
+		task, err := service.PatchTask(context.Background(), "task-123", constants.MergePatch, []byte(`{"name":"Test Task"}`), nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, task.Version)
+		mockRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("invalid patched documents", func(t *testing.T) {
+		patches := map[string]struct {
+			patchType constants.PatchType
+			patch     string
+		}{
+			"read-only field":    {constants.MergePatch, `{"version":10}`},
+			"removed name":       {constants.MergePatch, `{"name":null}`},
+			"unknown field":      {constants.MergePatch, `{"assignee":"bob"}`},
+			"invalid status":     {constants.JSONPatch, `[{"op":"replace","path":"/status","value":7}]`},
+			"invalid priority":   {constants.MergePatch, `{"priority":9}`},
+			"read-only position": {constants.MergePatch, `{"position":"a"}`},
+			"read-only tags":     {constants.MergePatch, `{"tags":[{"id":"tag-1","name":"work"}]}`},
+			"failed test op":     {constants.JSONPatch, `[{"op":"test","path":"/version","value":1}]`},
+			"malformed patch":    {constants.JSONPatch, `{"op":"replace"}`},
+			"missing json path":  {constants.JSONPatch, `[{"op":"replace","path":"/due","value":1}]`},
+		}
+		for name, tt := range patches {
+			t.Run(name, func(t *testing.T) {
+				mockRepo := new(MockTaskRepository)
+				service := NewTaskService(mockRepo)
+				mockRepo.On("WithTx", mock.Anything).Return()
+				mockRepo.On("ListTags", mock.Anything, mock.Anything).Return(map[string][]*models.Tag{}, nil)
+				mockRepo.On("Find", mock.Anything, "task-123").Return(record, nil)
+				// status 7 不在 workflow 中
+				mockRepo.On("FindWorkflow", mock.Anything).Return(defaultWorkflow(), nil)
+
+				_, err := service.PatchTask(context.Background(), "task-123", tt.patchType, []byte(tt.patch), nil)
+
+				assert.True(t, errors.Is(err, customError.InvalidRequest), err)
+				mockRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("stale If-Match", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, "task-123").Return(record, nil)
+
+		version := 1
+		_, err := service.PatchTask(context.Background(), "task-123", constants.MergePatch, []byte(`{"name":"Renamed"}`), &version)
+
+		assert.True(t, errors.Is(err, customError.TaskVersionMismatch))
+	})
+
+	t.Run("set and clear due dates", func(t *testing.T) {
+		dueAt := "2024-01-02 09:00:00+00:00"
+		remindAt := "2024-01-02 08:00:00+00:00"
+		scheduled := *record
+		scheduled.DueAt = &dueAt
+		scheduled.RemindAt = &remindAt
+		expected := time.Date(2024, 1, 3, 9, 0, 0, 0, time.UTC)
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("ListTags", mock.Anything, mock.Anything).Return(map[string][]*models.Tag{}, nil)
+		mockRepo.On("Find", mock.Anything, "task-123").Return(&scheduled, nil)
+		mockRepo.On("Patch", mock.Anything, mock.MatchedBy(func(patch entities.TaskPatch) bool {
+			return patch.Name == nil && patch.DueAt != nil && (*patch.DueAt).Equal(expected) &&
+				patch.RemindAt != nil && *patch.RemindAt == nil
+		}), 2).Return(nil)
+
+		patch := `{"due_at":"2024-01-03T09:00:00Z","remind_at":null}`
+		_, err := service.PatchTask(context.Background(), "task-123", constants.MergePatch, []byte(patch), nil)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("tags are kept", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, "task-123").Return(record, nil)
+		mockRepo.On("ListTags", mock.Anything, []string{"task-123"}).Return(map[string][]*models.Tag{
+			"task-123": {{ID: "tag-1", Name: "work"}},
+		}, nil).Once()
+		mockRepo.On("Patch", mock.Anything, entities.TaskPatch{ID: "task-123", Name: &renamed}, 2).Return(nil)
+
+		patch := `[{"op":"test","path":"/tags/0/name","value":"work"},{"op":"replace","path":"/name","value":"Renamed"}]`
+		task, err := service.PatchTask(context.Background(), "task-123", constants.JSONPatch, []byte(patch), nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "work", task.Tags[0].Name)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("overdue is read-only", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("ListTags", mock.Anything, mock.Anything).Return(map[string][]*models.Tag{}, nil)
+		mockRepo.On("Find", mock.Anything, "task-123").Return(record, nil)
+
+		_, err := service.PatchTask(context.Background(), "task-123", constants.MergePatch, []byte(`{"overdue":true}`), nil)
+
+		assert.True(t, errors.Is(err, customError.InvalidRequest))
+	})
+}
+
+func Test_taskService_MoveTask(t *testing.T) {
+	moved := &models.Task{ID: "task-1", Name: "Task 1", Version: 3, Position: "00000001"}
+	target := &models.Task{ID: "task-2", Name: "Task 2", Position: "00000005"}
+
+	tests := []struct {
+		name     string
+		after    bool
+		adjacent string
+		want     string
+	}{
+		{name: "before target", after: false, adjacent: "00000003", want: "00000004"},
+		{name: "before first", after: false, adjacent: "", want: "00000002"},
+		{name: "after target", after: true, adjacent: "00000006", want: "00000005i"},
+		{name: "after last", after: true, adjacent: "", want: "00000006"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := new(MockTaskRepository)
+			service := NewTaskService(mockRepo)
+			mockRepo.On("WithTx", mock.Anything).Return()
+			mockRepo.On("ListTags", mock.Anything, mock.Anything).Return(map[string][]*models.Tag{}, nil)
+			mockRepo.On("Find", mock.Anything, "task-1").Return(moved, nil)
+			mockRepo.On("Find", mock.Anything, "task-2").Return(target, nil)
+			mockRepo.On("AdjacentPosition", mock.Anything, "task-1", "00000005", tt.after).Return(tt.adjacent, nil)
+			mockRepo.On("Patch", mock.Anything, entities.TaskPatch{ID: "task-1", Position: &tt.want}, 3).Return(nil)
+
+			_, err := service.MoveTask(context.Background(), "task-1", entities.TaskMove{TargetID: "task-2", After: tt.after}, nil)
+
+			assert.NoError(t, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+
+	t.Run("stale If-Match", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		version := 2
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, "task-1").Return(moved, nil)
+
+		_, err := service.MoveTask(context.Background(), "task-1", entities.TaskMove{TargetID: "task-2"}, &version)
+
+		assert.True(t, errors.Is(err, customError.TaskVersionMismatch))
+		mockRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("target not found", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, "task-1").Return(moved, nil)
+		mockRepo.On("Find", mock.Anything, "task-9").Return(nil, customError.TaskNotFound.New("task not found"))
+
+		_, err := service.MoveTask(context.Background(), "task-1", entities.TaskMove{TargetID: "task-9"}, nil)
 
-		err := service.UpdateTask(context.Background(), task)
+		assert.True(t, errors.Is(err, customError.TaskNotFound))
+		mockRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("relative to itself", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+
+		_, err := service.MoveTask(context.Background(), "task-1", entities.TaskMove{TargetID: "task-1"}, nil)
+
+		assert.True(t, errors.Is(err, customError.InvalidRequest))
+		mockRepo.AssertNotCalled(t, "WithTx", mock.Anything)
+	})
+}
+
+func Test_taskService_AttachTags(t *testing.T) {
+	record := &models.Task{ID: "task-1", Name: "Task 1", Version: 4}
+
+	t.Run("attach and read back with tags", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("AttachTags", mock.Anything, "task-1", []string{"tag-1", "tag-2"}).Return(nil)
+		mockRepo.On("Find", mock.Anything, "task-1").Return(record, nil)
+		mockRepo.On("ListTags", mock.Anything, []string{"task-1"}).Return(map[string][]*models.Tag{
+			"task-1": {{ID: "tag-2", Name: "home"}, {ID: "tag-1", Name: "work"}},
+		}, nil)
+
+		task, err := service.AttachTags(context.Background(), "task-1", []string{"tag-1", "tag-2"})
 
 		assert.NoError(t, err)
+		assert.Equal(t, 4, task.Version)
+		assert.Equal(t, []string{"tag-2", "tag-1"}, []string{task.Tags[0].ID, task.Tags[1].ID})
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("tag ids are required", func(t *testing.T) {
+		for _, ids := range [][]string{nil, {""}} {
+			mockRepo := new(MockTaskRepository)
+			service := NewTaskService(mockRepo)
+
+			_, err := service.AttachTags(context.Background(), "task-1", ids)
+
+			assert.True(t, errors.Is(err, customError.InvalidRequest))
+			mockRepo.AssertNotCalled(t, "AttachTags", mock.Anything, mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("unknown tag", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("AttachTags", mock.Anything, "task-1", []string{"tag-404"}).Return(customError.TagNotFound.New("tag tag-404 not found"))
+
+		_, err := service.AttachTags(context.Background(), "task-1", []string{"tag-404"})
+
+		assert.True(t, errors.Is(err, customError.TagNotFound))
+		mockRepo.AssertNotCalled(t, "Find", mock.Anything, mock.Anything)
+	})
+}
+
+func Test_taskService_DetachTags(t *testing.T) {
+	mockRepo := new(MockTaskRepository)
+	service := NewTaskService(mockRepo)
+	mockRepo.On("WithTx", mock.Anything).Return()
+	mockRepo.On("DetachTags", mock.Anything, "task-1", []string{"tag-1"}).Return(nil)
+	mockRepo.On("Find", mock.Anything, "task-1").Return(&models.Task{ID: "task-1", Name: "Task 1", Version: 5}, nil)
+	mockRepo.On("ListTags", mock.Anything, []string{"task-1"}).Return(map[string][]*models.Tag{}, nil)
+
+	task, err := service.DetachTags(context.Background(), "task-1", []string{"tag-1"})
+
+	assert.NoError(t, err)
+	assert.Empty(t, task.Tags)
+	mockRepo.AssertExpectations(t)
 }
 
 func Test_taskService_DeleteTask(t *testing.T) {
@@ -84,20 +638,94 @@ func Test_taskService_DeleteTask(t *testing.T) {
 	taskID := "task-123"
 
 	t.Run("successfully delete task", func(t *testing.T) {
-		mockRepo.On("Delete", taskID).Return(nil)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Version: 1}, nil)
+		mockRepo.On("Delete", mock.Anything, taskID, (*int)(nil), false).Return(nil)
+
+		err := service.DeleteTask(context.Background(), taskID, nil, false)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("task with subtasks", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Version: 1}, nil)
+		mockRepo.On("Delete", mock.Anything, taskID, (*int)(nil), false).Return(customError.TaskHasSubtasks.New("task has subtasks"))
+
+		err := service.DeleteTask(context.Background(), taskID, nil, false)
 
-		err := service.DeleteTask(context.Background(), taskID)
+		assert.True(t, customError.TaskHasSubtasks.Is(customError.CauseCustomError(err)))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("deleting the last incomplete subtask completes the parent", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		parentID := "parent-1"
+		complete := constants.Complete
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, ParentID: &parentID, Version: 1}, nil)
+		mockRepo.On("Delete", mock.Anything, taskID, (*int)(nil), true).Return(nil)
+		mockRepo.On("CountChildren", mock.Anything, parentID).Return(2, 2, nil)
+		mockRepo.On("Find", mock.Anything, parentID).Return(&models.Task{ID: parentID, Version: 4}, nil)
+		mockRepo.On("FindWorkflow", mock.Anything).Return(defaultWorkflow(), nil)
+		mockRepo.On("Patch", mock.Anything, entities.TaskPatch{ID: parentID, Status: &complete}, 4).Return(nil)
+
+		err := service.DeleteTask(context.Background(), taskID, nil, true)
 
 		assert.NoError(t, err)
 		mockRepo.AssertExpectations(t)
 	})
 }
 
+func Test_taskService_GetTask(t *testing.T) {
+	t.Run("successfully get task", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("Find", mock.Anything, "task-123").Return(&models.Task{
+			ID:        "task-123",
+			Name:      "Test Task",
+			Status:    1,
+			Version:   2,
+			CreatedAt: "2024-01-01 00:00:00+00:00",
+			UpdatedAt: "2024-01-02 03:04:05.5+00:00",
+		}, nil)
+		mockRepo.On("ListTags", mock.Anything, mock.Anything).Return(map[string][]*models.Tag{}, nil)
+
+		task, err := service.GetTask(context.Background(), "task-123")
+
+		assert.NoError(t, err)
+		assert.Equal(t, constants.Complete, task.Status)
+		assert.Equal(t, 2, task.Version)
+		assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), task.CreatedAt)
+		assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 500000000, time.UTC), task.UpdatedAt)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("task not found", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("Find", mock.Anything, "task-404").Return(nil, errors.New("task not found"))
+
+		task, err := service.GetTask(context.Background(), "task-404")
+
+		assert.Error(t, err)
+		assert.Nil(t, task)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
 func Test_taskService_GetTasks(t *testing.T) {
 	param := entities.TaskQueryParam{
 		Offset: 0,
 		Size:   10,
 	}
+	// service 會多取一筆判斷是否有下一頁
+	listParam := param
+	listParam.Size = param.Size + 1
 
 	mockTasks := []*models.Task{
 		{
@@ -115,19 +743,29 @@ func Test_taskService_GetTasks(t *testing.T) {
 	t.Run("successfully get tasks", func(t *testing.T) {
 		mockRepo := new(MockTaskRepository)
 		service := NewTaskService(mockRepo)
-		mockRepo.On("List", param).Return(mockTasks, nil)
+		mockRepo.On("List", mock.Anything, listParam).Return(mockTasks, nil)
+		color := "#1e90ff"
+		mockRepo.On("ListTags", mock.Anything, []string{"task-123", "task-124"}).Return(map[string][]*models.Tag{
+			"task-123": {{ID: "tag-1", Name: "work", Color: &color}, {ID: "tag-2", Name: "urgent"}},
+		}, nil).Once()
 
 		tasks, err := service.GetTasks(context.Background(), param)
 
 		assert.NoError(t, err)
 		assert.Equal(t, 2, len(tasks.Tasks))
+		assert.Equal(t, []string{"work", "urgent"}, []string{tasks.Tasks[0].Tags[0].Name, tasks.Tasks[0].Tags[1].Name})
+		assert.Equal(t, "#1e90ff", tasks.Tasks[0].Tags[0].Color)
+		assert.Empty(t, tasks.Tasks[1].Tags)
+		assert.Empty(t, tasks.NextCursor)
+		assert.Empty(t, tasks.PrevCursor)
+		assert.Nil(t, tasks.Total)
 		mockRepo.AssertExpectations(t)
 	})
 
 	t.Run("fail to get tasks", func(t *testing.T) {
 		mockRepo := new(MockTaskRepository)
 		service := NewTaskService(mockRepo)
-		mockRepo.On("List", param).Return([]*models.Task{}, errors.New("failed to list tasks"))
+		mockRepo.On("List", mock.Anything, listParam).Return([]*models.Task{}, errors.New("failed to list tasks"))
 
 		tasks, err := service.GetTasks(context.Background(), param)
 
@@ -135,4 +773,143 @@ func Test_taskService_GetTasks(t *testing.T) {
 		assert.Nil(t, tasks)
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("next cursor and total when more tasks exist", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		pageParam := entities.TaskQueryParam{
+			Size:      1,
+			SortBy:    constants.SortByCreatedAt,
+			Order:     constants.OrderAsc,
+			WithTotal: true,
+		}
+		pageListParam := pageParam
+		pageListParam.Size = 2
+		rows := []*models.Task{
+			{ID: "task-123", CreatedAt: "2024-01-01 00:00:00+00:00"},
+			{ID: "task-124", CreatedAt: "2024-01-02 00:00:00+00:00"},
+		}
+		mockRepo.On("List", mock.Anything, pageListParam).Return(rows, nil)
+		mockRepo.On("ListTags", mock.Anything, []string{"task-123"}).Return(map[string][]*models.Tag{}, nil)
+		mockRepo.On("Count", mock.Anything, pageParam).Return(5, nil)
+
+		tasks, err := service.GetTasks(context.Background(), pageParam)
+
+		assert.NoError(t, err)
+		assert.Len(t, tasks.Tasks, 1)
+		assert.Equal(t, 1, tasks.Page)
+		assert.Equal(t, 5, *tasks.Total)
+		assert.Empty(t, tasks.PrevCursor)
+		cursor, err := entities.DecodeCursor(tasks.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, "task-123", cursor.ID)
+		assert.Equal(t, "2024-01-01 00:00:00+00:00", cursor.Value)
+		assert.False(t, cursor.Backward)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("backward cursor reverses rows", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		pageParam := entities.TaskQueryParam{
+			Size:   2,
+			SortBy: constants.SortByStatus,
+			Order:  constants.OrderDesc,
+			Cursor: &entities.Cursor{
+				SortBy:   constants.SortByStatus,
+				Order:    constants.OrderDesc,
+				Value:    int64(0),
+				ID:       "task-200",
+				Backward: true,
+			},
+		}
+		pageListParam := pageParam
+		pageListParam.Size = 3
+		rows := []*models.Task{
+			{ID: "task-125", Status: 0},
+			{ID: "task-124", Status: 1},
+			{ID: "task-123", Status: 1},
+		}
+		mockRepo.On("List", mock.Anything, pageListParam).Return(rows, nil)
+		mockRepo.On("ListTags", mock.Anything, mock.Anything).Return(map[string][]*models.Tag{}, nil)
+
+		tasks, err := service.GetTasks(context.Background(), pageParam)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"task-124", "task-125"}, []string{tasks.Tasks[0].ID, tasks.Tasks[1].ID})
+		assert.Equal(t, 0, tasks.Page)
+		next, err := entities.DecodeCursor(tasks.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, "task-125", next.ID)
+		assert.Equal(t, int64(0), next.Value)
+		prev, err := entities.DecodeCursor(tasks.PrevCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, "task-124", prev.ID)
+		assert.True(t, prev.Backward)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func Test_taskService_GetTrash(t *testing.T) {
+	t.Run("lists soft-deleted tasks", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		deletedAt := "2024-01-03 00:00:00+00:00"
+		mockRepo.On("List", mock.Anything, entities.TaskQueryParam{Size: 11, Trashed: true}).Return([]*models.Task{
+			{ID: "task-123", Name: "Deleted Task", DeletedAt: &deletedAt},
+		}, nil)
+		mockRepo.On("ListTags", mock.Anything, mock.Anything).Return(map[string][]*models.Tag{}, nil)
+
+		tasks, err := service.GetTrash(context.Background(), entities.TaskQueryParam{Size: 10})
+
+		assert.NoError(t, err)
+		assert.Len(t, tasks.Tasks, 1)
+		assert.Equal(t, time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), *tasks.Tasks[0].DeletedAt)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func Test_taskService_RestoreTask(t *testing.T) {
+	t.Run("successfully restore task", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("Restore", mock.Anything, "task-123").Return(nil)
+		mockRepo.On("Find", mock.Anything, "task-123").Return(&models.Task{ID: "task-123", Name: "Test Task"}, nil)
+		mockRepo.On("ListTags", mock.Anything, mock.Anything).Return(map[string][]*models.Tag{}, nil)
+
+		task, err := service.RestoreTask(context.Background(), "task-123")
+
+		assert.NoError(t, err)
+		assert.Nil(t, task.DeletedAt)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("task not in trash", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("Restore", mock.Anything, "task-123").Return(customError.TaskNotFound.New("task not found in trash"))
+
+		task, err := service.RestoreTask(context.Background(), "task-123")
+
+		assert.True(t, errors.Is(err, customError.TaskNotFound))
+		assert.Nil(t, task)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func Test_taskService_PurgeExpiredTasks(t *testing.T) {
+	t.Run("purges tasks deleted before the retention window", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		retention := 24 * time.Hour
+		mockRepo.On("PurgeDeletedBefore", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+			return time.Since(before) >= retention && time.Since(before) < retention+time.Minute
+		})).Return(int64(2), nil)
+
+		purged, err := service.PurgeExpiredTasks(context.Background(), retention)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), purged)
+		mockRepo.AssertExpectations(t)
+	})
 }
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"tasks/constants"
+	"tasks/domain/entities"
+	customError "tasks/errors"
+	"tasks/internal/repository"
+)
+
+// GetSubtree task 與其 depth 層內的子孫 task，子 task 依 position 排序
+func (t *taskService) GetSubtree(ctx context.Context, taskId string, depth int) (*entities.TaskNode, error) {
+	if depth < 0 || depth > constants.MaxTaskDepth {
+		return nil, customError.InvalidRequest.Errorf("depth must be between 0 and %d", constants.MaxTaskDepth)
+	}
+	records, err := t.repo.ListSubtree(ctx, taskId, depth)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[string]*entities.TaskNode, len(records))
+	tasks := make([]*entities.Task, 0, len(records))
+	var root *entities.TaskNode
+	for _, record := range records {
+		node := &entities.TaskNode{Task: toEntity(&record.Task), Depth: record.Depth}
+		nodes[node.ID] = node
+		tasks = append(tasks, &node.Task)
+		if record.Depth == 0 {
+			root = node
+			continue
+		}
+		// 結果依深度排序，parent 一定已經建立
+		if parent, ok := nodes[*record.ParentID]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+	if err = loadTags(ctx, t.repo, tasks); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// validateParent parent 必須是未刪除的 task，不能是 task 本身或其子孫，且移動後的深度不能超過 constants.MaxTaskDepth，
+// existing 為 false 表示 task 尚未建立，沒有子孫 task
+func validateParent(ctx context.Context, repo repository.TaskRepository, taskId string, parentId *string, existing bool) error {
+	if parentId == nil {
+		return nil
+	}
+	if strings.TrimSpace(*parentId) == "" {
+		return customError.InvalidRequest.New("parent_id must not be empty")
+	}
+	if *parentId == taskId {
+		return customError.InvalidRequest.New("task cannot be its own parent")
+	}
+	if _, err := repo.Find(ctx, *parentId); err != nil {
+		return err
+	}
+	ancestors, err := repo.ListAncestors(ctx, *parentId)
+	if err != nil {
+		return err
+	}
+	for _, ancestor := range ancestors {
+		if ancestor == taskId {
+			return customError.InvalidRequest.Errorf("task %s cannot be moved under its own subtask %s", taskId, *parentId)
+		}
+	}
+	height := 0
+	if existing {
+		nodes, err := repo.ListSubtree(ctx, taskId, constants.MaxTaskDepth)
+		if err != nil {
+			return err
+		}
+		height = nodes[len(nodes)-1].Depth
+	}
+	if len(ancestors)+1+height > constants.MaxTaskDepth {
+		return customError.InvalidRequest.Errorf("tasks cannot be nested more than %d levels deep", constants.MaxTaskDepth)
+	}
+	return nil
+}
+
+// rollupStatus 在 task 完成、離開 parent 或被刪除後，將子 task 已全部完成的 parent 標為完成，
+// before 為 nil 表示新建立的 task，after 為 nil 表示已刪除的 task，需在 transaction 中執行
+func rollupStatus(ctx context.Context, repo repository.TaskRepository, before, after *entities.Task) error {
+	if before != nil && (after == nil || !equalID(before.ParentID, after.ParentID)) {
+		if err := completeAncestors(ctx, repo, before.ParentID); err != nil {
+			return err
+		}
+	}
+	if after == nil || after.Status != constants.Complete {
+		return nil
+	}
+	if before != nil && before.Status == constants.Complete && equalID(before.ParentID, after.ParentID) {
+		return nil
+	}
+	return completeAncestors(ctx, repo, after.ParentID)
+}
+
+// completeAncestors 由 parentId 往上，將子 task 全部完成的 task 標為完成，與使用者完成 task 一樣需通過 blocker 與 workflow 檢查，
+// recurring task 完成後建立下一次的 task，遇到不需變更、被 block 住或 workflow 不允許完成的 task 即停止
+func completeAncestors(ctx context.Context, repo repository.TaskRepository, parentId *string) error {
+	for parentId != nil {
+		total, completed, err := repo.CountChildren(ctx, *parentId)
+		if err != nil {
+			return err
+		}
+		if total == 0 || completed < total {
+			return nil
+		}
+		record, err := repo.Find(ctx, *parentId)
+		if err != nil {
+			return err
+		}
+		parent := toEntity(record)
+		if parent.Status == constants.Complete || checkBlocked(parent, constants.Complete) != nil {
+			return nil
+		}
+		if err = checkTransition(ctx, repo, parent, constants.Complete); err != nil {
+			if errors.Is(err, customError.TransitionNotAllowed) {
+				return nil
+			}
+			return err
+		}
+		status := constants.Complete
+		change := entities.TaskPatch{ID: parent.ID, Status: &status}
+		// 與 patchTask 相同，完成時 recurrence 移到下一次的 task 上
+		rule := parent.Recurrence
+		if rule != nil {
+			var cleared *string
+			change.Recurrence = &cleared
+		}
+		if err = repo.Patch(ctx, change, parent.Version); err != nil {
+			return err
+		}
+		if rule != nil {
+			if err = loadTags(ctx, repo, []*entities.Task{&parent}); err != nil {
+				return err
+			}
+			// 下一次的 task 未完成，會讓上一層的 parent 停止自動完成
+			if err = createNextOccurrence(ctx, repo, parent, *rule); err != nil {
+				return err
+			}
+		}
+		parentId = parent.ParentID
+	}
+	return nil
+}
+
+func equalID(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"testing"
+	"time"
+)
+
+func Test_taskService_GetSubtree(t *testing.T) {
+	root, child, grandchild := "task-1", "task-2", "task-3"
+
+	t.Run("nests children and loads tags once", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("ListSubtree", mock.Anything, root, 2).Return([]*models.TaskNode{
+			{Task: models.Task{ID: root, Name: "epic"}, Depth: 0},
+			{Task: models.Task{ID: child, ParentID: &root, Name: "story"}, Depth: 1},
+			{Task: models.Task{ID: "task-4", ParentID: &root, Name: "story 2"}, Depth: 1},
+			{Task: models.Task{ID: grandchild, ParentID: &child, Name: "subtask"}, Depth: 2},
+		}, nil)
+		mockRepo.On("ListTags", mock.Anything, []string{root, child, "task-4", grandchild}).Return(map[string][]*models.Tag{
+			grandchild: {{ID: "tag-1", Name: "work"}},
+		}, nil).Once()
+
+		tree, err := service.GetSubtree(context.Background(), root, 2)
+
+		assert.NoError(t, err)
+		assert.Equal(t, root, tree.ID)
+		assert.Len(t, tree.Children, 2)
+		assert.Equal(t, child, tree.Children[0].ID)
+		assert.Equal(t, "task-4", tree.Children[1].ID)
+		assert.Equal(t, grandchild, tree.Children[0].Children[0].ID)
+		assert.Equal(t, 2, tree.Children[0].Children[0].Depth)
+		assert.Equal(t, "work", tree.Children[0].Children[0].Tags[0].Name)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("depth out of range", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+
+		_, err := service.GetSubtree(context.Background(), root, constants.MaxTaskDepth+1)
+
+		assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)))
+		mockRepo.AssertNotCalled(t, "ListSubtree", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("task not found", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("ListSubtree", mock.Anything, root, constants.MaxTaskDepth).Return(nil, customError.TaskNotFound.New("task not found"))
+
+		_, err := service.GetSubtree(context.Background(), root, constants.MaxTaskDepth)
+
+		assert.True(t, customError.TaskNotFound.Is(customError.CauseCustomError(err)))
+	})
+}
+
+func Test_taskService_CreateSubtask(t *testing.T) {
+	parentID := "parent-1"
+
+	t.Run("create under a parent", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		task := entities.NewTask("subtask")
+		task.ParentID = &parentID
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, parentID).Return(&models.Task{ID: parentID}, nil)
+		mockRepo.On("ListAncestors", mock.Anything, parentID).Return([]string{"root"}, nil)
+		mockRepo.On("LastPosition", mock.Anything).Return("", nil)
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(created entities.Task) bool {
+			return created.ParentID != nil && *created.ParentID == parentID
+		})).Return(nil)
+
+		created, err := service.CreateTask(context.Background(), task, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, parentID, *created.ParentID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("parent not found", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		task := entities.NewTask("subtask")
+		task.ParentID = &parentID
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, parentID).Return(nil, customError.TaskNotFound.New("task not found"))
+
+		_, err := service.CreateTask(context.Background(), task, nil)
+
+		assert.True(t, customError.TaskNotFound.Is(customError.CauseCustomError(err)))
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("parent at the deepest level", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		task := entities.NewTask("subtask")
+		task.ParentID = &parentID
+		ancestors := make([]string, constants.MaxTaskDepth)
+		for i := range ancestors {
+			ancestors[i] = "ancestor"
+		}
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, parentID).Return(&models.Task{ID: parentID}, nil)
+		mockRepo.On("ListAncestors", mock.Anything, parentID).Return(ancestors, nil)
+
+		_, err := service.CreateTask(context.Background(), task, nil)
+
+		assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)))
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("empty parent id", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		task := entities.NewTask("subtask")
+		empty := " "
+		task.ParentID = &empty
+		mockRepo.On("WithTx", mock.Anything).Return()
+
+		_, err := service.CreateTask(context.Background(), task, nil)
+
+		assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)))
+	})
+}
+
+func Test_taskService_ReparentTask(t *testing.T) {
+	taskID, parentID := "task-1", "parent-1"
+
+	t.Run("cannot move under its own subtask", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "epic", Version: 1}, nil)
+		mockRepo.On("Find", mock.Anything, parentID).Return(&models.Task{ID: parentID, ParentID: &taskID}, nil)
+		mockRepo.On("ListAncestors", mock.Anything, parentID).Return([]string{taskID}, nil)
+
+		_, err := service.UpdateTask(context.Background(), entities.Task{ID: taskID, ParentID: &parentID, Name: "epic"}, nil)
+
+		assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)))
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("cannot be its own parent", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "epic", Version: 1}, nil)
+		mockRepo.On("ListTags", mock.Anything, []string{taskID}).Return(map[string][]*models.Tag{}, nil)
+
+		_, err := service.PatchTask(context.Background(), taskID, constants.MergePatch, []byte(`{"parent_id":"task-1"}`), nil)
+
+		assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)))
+		mockRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("subtree would be nested too deep", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "epic", Version: 1}, nil)
+		mockRepo.On("ListTags", mock.Anything, []string{taskID}).Return(map[string][]*models.Tag{}, nil)
+		mockRepo.On("Find", mock.Anything, parentID).Return(&models.Task{ID: parentID}, nil)
+		mockRepo.On("ListAncestors", mock.Anything, parentID).Return([]string{"root"}, nil)
+		mockRepo.On("ListSubtree", mock.Anything, taskID, constants.MaxTaskDepth).Return([]*models.TaskNode{
+			{Task: models.Task{ID: taskID}, Depth: 0},
+			{Task: models.Task{ID: "leaf"}, Depth: constants.MaxTaskDepth - 1},
+		}, nil)
+
+		_, err := service.PatchTask(context.Background(), taskID, constants.MergePatch, []byte(`{"parent_id":"parent-1"}`), nil)
+
+		assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)))
+		mockRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("move to another parent and back to the top level", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		var cleared *string
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, ParentID: &parentID, Name: "story", Version: 1}, nil).Once()
+		mockRepo.On("ListTags", mock.Anything, []string{taskID}).Return(map[string][]*models.Tag{}, nil)
+		mockRepo.On("Patch", mock.Anything, entities.TaskPatch{ID: taskID, ParentID: &cleared}, 1).Return(nil)
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "story", Version: 2}, nil).Once()
+		// 離開 parent 後檢查 parent 剩下的子 task
+		mockRepo.On("CountChildren", mock.Anything, parentID).Return(0, 0, nil)
+
+		patched, err := service.PatchTask(context.Background(), taskID, constants.MergePatch, []byte(`{"parent_id":null}`), nil)
+
+		assert.NoError(t, err)
+		assert.Nil(t, patched.ParentID)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func Test_taskService_RollupStatus(t *testing.T) {
+	taskID, parentID, rootID := "task-1", "parent-1", "root-1"
+	complete := constants.Complete
+
+	t.Run("completing the last subtask completes every ancestor", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("FindWorkflow", mock.Anything).Return(defaultWorkflow(), nil)
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, ParentID: &parentID, Name: "subtask", Version: 1}, nil).Once()
+		mockRepo.On("ListTags", mock.Anything, []string{taskID}).Return(map[string][]*models.Tag{}, nil)
+		mockRepo.On("Patch", mock.Anything, entities.TaskPatch{ID: taskID, Status: &complete}, 1).Return(nil)
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, ParentID: &parentID, Name: "subtask", Status: 1, Version: 2}, nil).Once()
+		mockRepo.On("CountChildren", mock.Anything, parentID).Return(3, 3, nil)
+		mockRepo.On("Find", mock.Anything, parentID).Return(&models.Task{ID: parentID, ParentID: &rootID, Version: 5}, nil)
+		mockRepo.On("Patch", mock.Anything, entities.TaskPatch{ID: parentID, Status: &complete}, 5).Return(nil)
+		mockRepo.On("CountChildren", mock.Anything, rootID).Return(1, 1, nil)
+		mockRepo.On("Find", mock.Anything, rootID).Return(&models.Task{ID: rootID, Version: 2}, nil)
+		mockRepo.On("Patch", mock.Anything, entities.TaskPatch{ID: rootID, Status: &complete}, 2).Return(nil)
+
+		patched, err := service.PatchTask(context.Background(), taskID, constants.MergePatch, []byte(`{"status":1}`), nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, constants.Complete, patched.Status)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("parent with incomplete subtasks is unchanged", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		task := entities.Task{ID: taskID, ParentID: &parentID, Name: "subtask", Status: constants.Complete, Priority: constants.PriorityMedium}
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("FindWorkflow", mock.Anything).Return(defaultWorkflow(), nil)
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, ParentID: &parentID, Name: "subtask", Version: 1}, nil).Once()
+		mockRepo.On("Update", mock.Anything, task, (*int)(nil)).Return(nil)
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, ParentID: &parentID, Name: "subtask", Status: 1, Priority: 1, Version: 2}, nil).Once()
+		mockRepo.On("ListTags", mock.Anything, []string{taskID}).Return(map[string][]*models.Tag{}, nil)
+		mockRepo.On("CountChildren", mock.Anything, parentID).Return(3, 2, nil)
+
+		_, err := service.UpdateTask(context.Background(), task, nil)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "Find", mock.Anything, parentID)
+	})
+
+	t.Run("already complete parent stops the rollup", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		mockRepo.On("CountChildren", mock.Anything, parentID).Return(2, 2, nil)
+		mockRepo.On("Find", mock.Anything, parentID).Return(&models.Task{ID: parentID, ParentID: &rootID, Status: 1, Version: 3}, nil)
+
+		err := completeAncestors(context.Background(), mockRepo, &parentID)
+
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "CountChildren", mock.Anything, rootID)
+	})
+
+	t.Run("parent the workflow cannot complete stops the rollup", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		workflow := defaultWorkflow()
+		// review 只能退回 in_progress
+		workflow.Transitions = []*models.WorkflowTransition{{From: 3, To: 2}}
+		mockRepo.On("CountChildren", mock.Anything, parentID).Return(2, 2, nil)
+		mockRepo.On("Find", mock.Anything, parentID).Return(&models.Task{ID: parentID, ParentID: &rootID, Status: 3, Version: 3}, nil)
+		mockRepo.On("FindWorkflow", mock.Anything).Return(workflow, nil)
+
+		err := completeAncestors(context.Background(), mockRepo, &parentID)
+
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "CountChildren", mock.Anything, rootID)
+	})
+
+	t.Run("recurring parent moves the rule to its next occurrence", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		rule := "FREQ=DAILY;COUNT=2"
+		dueText := "2024-01-01T09:00:00Z"
+		var cleared *string
+		isNext := func(task entities.Task) bool {
+			return task.Name == "release" && task.Status == constants.Incomplete && task.ParentID == nil &&
+				task.DueAt.Equal(time.Date(2024, 1, 2, 9, 0, 0, 0, time.UTC)) && *task.Recurrence == "FREQ=DAILY;COUNT=1"
+		}
+		mockRepo.On("CountChildren", mock.Anything, parentID).Return(2, 2, nil)
+		mockRepo.On("Find", mock.Anything, parentID).Return(&models.Task{ID: parentID, Name: "release", DueAt: &dueText, Recurrence: &rule, Version: 3}, nil)
+		mockRepo.On("FindWorkflow", mock.Anything).Return(defaultWorkflow(), nil)
+		mockRepo.On("Patch", mock.Anything, entities.TaskPatch{ID: parentID, Status: &complete, Recurrence: &cleared}, 3).Return(nil)
+		mockRepo.On("ListTags", mock.Anything, []string{parentID}).Return(map[string][]*models.Tag{parentID: {{ID: "tag-1", Name: "work"}}}, nil)
+		mockRepo.On("LastPosition", mock.Anything).Return("00000001i", nil)
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(isNext)).Return(nil)
+		mockRepo.On("AttachTags", mock.Anything, mock.Anything, []string{"tag-1"}).Return(nil)
+
+		err := completeAncestors(context.Background(), mockRepo, &parentID)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("editing a completed subtask does not roll up", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		before := entities.Task{ID: taskID, ParentID: &parentID, Status: constants.Complete}
+		after := entities.Task{ID: taskID, ParentID: &parentID, Status: constants.Complete, Name: "renamed"}
+
+		err := rollupStatus(context.Background(), mockRepo, &before, &after)
+
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "CountChildren", mock.Anything, mock.Anything)
+	})
+}
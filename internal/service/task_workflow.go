@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"tasks/constants"
+	"tasks/domain/entities"
+	customError "tasks/errors"
+	"tasks/internal/repository"
+)
+
+// GetWorkflow 回傳所有狀態以及每個狀態可以改為的狀態
+func (t *taskService) GetWorkflow(ctx context.Context) (*entities.Workflow, error) {
+	workflow, err := loadWorkflow(ctx, t.repo)
+	if err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+func loadWorkflow(ctx context.Context, repo repository.TaskRepository) (entities.Workflow, error) {
+	record, err := repo.FindWorkflow(ctx)
+	if err != nil {
+		return entities.Workflow{}, err
+	}
+	workflow := entities.Workflow{States: make([]entities.WorkflowState, 0, len(record.States))}
+	index := make(map[int]int, len(record.States))
+	for _, state := range record.States {
+		index[state.ID] = len(workflow.States)
+		workflow.States = append(workflow.States, entities.WorkflowState{
+			ID:          constants.Status(state.ID),
+			Name:        state.Name,
+			Transitions: make([]constants.Status, 0),
+		})
+	}
+	for _, transition := range record.Transitions {
+		// 忽略指向不存在狀態的轉換
+		from, ok := index[transition.From]
+		if _, exists := index[transition.To]; !ok || !exists {
+			continue
+		}
+		workflow.States[from].Transitions = append(workflow.States[from].Transitions, constants.Status(transition.To))
+	}
+	return workflow, nil
+}
+
+// checkState 新增的 task 可以是 workflow 中任何一個狀態，0 與 1 一定存在不需查詢
+func checkState(ctx context.Context, repo repository.TaskRepository, status constants.Status) error {
+	if status == constants.Incomplete || status == constants.Complete {
+		return nil
+	}
+	workflow, err := loadWorkflow(ctx, repo)
+	if err != nil {
+		return err
+	}
+	if _, ok := workflow.State(status); !ok {
+		return customError.InvalidRequest.Errorf("status %d is not defined in the workflow", status)
+	}
+	return nil
+}
+
+// checkTransition task 的狀態只能依 workflow 定義的轉換改變，狀態不變時不檢查
+func checkTransition(ctx context.Context, repo repository.TaskRepository, current entities.Task, status constants.Status) error {
+	if current.Status == status {
+		return nil
+	}
+	workflow, err := loadWorkflow(ctx, repo)
+	if err != nil {
+		return err
+	}
+	to, ok := workflow.State(status)
+	if !ok {
+		return customError.InvalidRequest.Errorf("status %d is not defined in the workflow", status)
+	}
+	if workflow.Allows(current.Status, status) {
+		return nil
+	}
+	details := entities.TransitionDetails{
+		From:    entities.StateRef{ID: current.Status},
+		To:      entities.StateRef{ID: to.ID, Name: to.Name},
+		Allowed: make([]entities.StateRef, 0),
+	}
+	from, ok := workflow.State(current.Status)
+	if !ok {
+		return customError.TransitionNotAllowed.WithDetails(details).Errorf("task %s has status %d which is not defined in the workflow", current.ID, current.Status)
+	}
+	details.From.Name = from.Name
+	allowed := make([]string, 0, len(from.Transitions))
+	for _, next := range from.Transitions {
+		if state, ok := workflow.State(next); ok {
+			details.Allowed = append(details.Allowed, entities.StateRef{ID: state.ID, Name: state.Name})
+			allowed = append(allowed, state.Name)
+		}
+	}
+	// 允許的狀態放在回應的 details 中，錯誤訊息只留在 log
+	if len(allowed) == 0 {
+		return customError.TransitionNotAllowed.WithDetails(details).Errorf("task %s cannot move from %s to %s, %s has no transitions", current.ID, from.Name, to.Name, from.Name)
+	}
+	return customError.TransitionNotAllowed.WithDetails(details).Errorf("task %s cannot move from %s to %s, allowed: %s", current.ID, from.Name, to.Name, strings.Join(allowed, ", "))
+}
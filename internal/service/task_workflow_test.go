@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"testing"
+)
+
+// defaultWorkflow 與 migration 預設的 workflow 相同
+func defaultWorkflow() *models.Workflow {
+	return &models.Workflow{
+		States: []*models.WorkflowState{{ID: 0, Name: "todo"}, {ID: 2, Name: "in_progress"}, {ID: 3, Name: "review"}, {ID: 1, Name: "done"}},
+		Transitions: []*models.WorkflowTransition{
+			{From: 0, To: 1}, {From: 0, To: 2}, {From: 1, To: 0}, {From: 2, To: 0},
+			{From: 2, To: 1}, {From: 2, To: 3}, {From: 3, To: 1}, {From: 3, To: 2},
+		},
+	}
+}
+
+func Test_taskService_GetWorkflow(t *testing.T) {
+	mockRepo := new(MockTaskRepository)
+	service := NewTaskService(mockRepo)
+	workflow := defaultWorkflow()
+	// 指向不存在狀態的轉換會被忽略
+	workflow.Transitions = append(workflow.Transitions, &models.WorkflowTransition{From: 3, To: 9})
+	mockRepo.On("FindWorkflow", mock.Anything).Return(workflow, nil)
+
+	result, err := service.GetWorkflow(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.WorkflowState{
+		{ID: 0, Name: "todo", Transitions: []constants.Status{1, 2}},
+		{ID: 2, Name: "in_progress", Transitions: []constants.Status{0, 1, 3}},
+		{ID: 3, Name: "review", Transitions: []constants.Status{1, 2}},
+		{ID: 1, Name: "done", Transitions: []constants.Status{0}},
+	}, result.States)
+}
+
+func Test_taskService_StatusTransitions(t *testing.T) {
+	taskID := "task-1"
+	review, inProgress := constants.Status(3), constants.Status(2)
+
+	t.Run("update along an allowed transition", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		task := entities.Task{ID: taskID, Name: "ship", Status: review}
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "ship", Status: 2, Version: 1}, nil).Once()
+		mockRepo.On("FindWorkflow", mock.Anything).Return(defaultWorkflow(), nil)
+		mockRepo.On("Update", mock.Anything, task, (*int)(nil)).Return(nil)
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "ship", Status: 3, Version: 2}, nil).Once()
+		mockRepo.On("ListTags", mock.Anything, []string{taskID}).Return(map[string][]*models.Tag{}, nil)
+
+		updated, err := service.UpdateTask(context.Background(), task, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, review, updated.Status)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("update along a transition that is not allowed", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "ship", Status: 0, Version: 1}, nil)
+		mockRepo.On("FindWorkflow", mock.Anything).Return(defaultWorkflow(), nil)
+
+		_, err := service.UpdateTask(context.Background(), entities.Task{ID: taskID, Name: "ship", Status: review}, nil)
+
+		assert.True(t, customError.TransitionNotAllowed.Is(customError.CauseCustomError(err)))
+		assert.Contains(t, err.Error(), "cannot move from todo to review, allowed: done, in_progress")
+		assert.Equal(t, entities.TransitionDetails{
+			From:    entities.StateRef{ID: constants.Incomplete, Name: "todo"},
+			To:      entities.StateRef{ID: review, Name: "review"},
+			Allowed: []entities.StateRef{{ID: constants.Complete, Name: "done"}, {ID: inProgress, Name: "in_progress"}},
+		}, customError.CauseCustomError(err).Details())
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("status that is not defined", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "ship", Status: 0, Version: 1}, nil)
+		mockRepo.On("FindWorkflow", mock.Anything).Return(defaultWorkflow(), nil)
+
+		_, err := service.UpdateTask(context.Background(), entities.Task{ID: taskID, Name: "ship", Status: 7}, nil)
+
+		assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)))
+	})
+
+	t.Run("same status skips the workflow", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		task := entities.Task{ID: taskID, Name: "ship it", Status: review}
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "ship", Status: 3, Version: 1}, nil)
+		mockRepo.On("Update", mock.Anything, task, (*int)(nil)).Return(nil)
+		mockRepo.On("ListTags", mock.Anything, []string{taskID}).Return(map[string][]*models.Tag{}, nil)
+
+		_, err := service.UpdateTask(context.Background(), task, nil)
+
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "FindWorkflow", mock.Anything)
+	})
+
+	t.Run("patch along a transition that is not allowed", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		service := NewTaskService(mockRepo)
+		mockRepo.On("WithTx", mock.Anything).Return()
+		mockRepo.On("Find", mock.Anything, taskID).Return(&models.Task{ID: taskID, Name: "ship", Status: 1, Version: 1}, nil)
+		mockRepo.On("ListTags", mock.Anything, []string{taskID}).Return(map[string][]*models.Tag{}, nil)
+		mockRepo.On("FindWorkflow", mock.Anything).Return(defaultWorkflow(), nil)
+
+		_, err := service.PatchTask(context.Background(), taskID, constants.MergePatch, []byte(`{"status":2}`), nil)
+
+		assert.True(t, customError.TransitionNotAllowed.Is(customError.CauseCustomError(err)))
+		assert.Contains(t, err.Error(), "cannot move from done to in_progress, allowed: todo")
+		mockRepo.AssertNotCalled(t, "Patch", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("batch create in a state that is not defined", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		mockRepo.On("FindWorkflow", mock.Anything).Return(defaultWorkflow(), nil)
+		task := entities.NewTask("ship")
+		task.Status = 9
+
+		err := createTask(context.Background(), mockRepo, &task)
+
+		assert.True(t, customError.InvalidRequest.Is(customError.CauseCustomError(err)))
+		mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	})
+
+	t.Run("create in a custom state", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		mockRepo.On("FindWorkflow", mock.Anything).Return(defaultWorkflow(), nil)
+		mockRepo.On("LastPosition", mock.Anything).Return("", nil)
+		mockRepo.On("Create", mock.Anything, mock.Anything).Return(nil)
+		task := entities.NewTask("ship")
+		task.Status = inProgress
+
+		err := createTask(context.Background(), mockRepo, &task)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+// TrashPurger 定期永久刪除在垃圾桶中超過保留期限的 task
+type TrashPurger struct {
+	taskService TaskService
+	retention   time.Duration
+	interval    time.Duration
+	logger      *zap.Logger
+}
+
+func NewTrashPurger(taskService TaskService, retention, interval time.Duration, logger *zap.Logger) *TrashPurger {
+	return &TrashPurger{
+		taskService: taskService,
+		retention:   retention,
+		interval:    interval,
+		logger:      logger,
+	}
+}
+
+func (p *TrashPurger) Run(ctx context.Context) {
+	if p.retention <= 0 || p.interval <= 0 {
+		p.logger.Info("trash purger disabled", zap.Duration("retention", p.retention), zap.Duration("interval", p.interval))
+		return
+	}
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.purge(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *TrashPurger) purge(ctx context.Context) {
+	purged, err := p.taskService.PurgeExpiredTasks(ctx, p.retention)
+	if err != nil {
+		p.logger.Error("purge expired tasks error", zap.Error(err))
+		return
+	}
+	if purged > 0 {
+		p.logger.Info("purged expired tasks", zap.Int64("count", purged), zap.Duration("retention", p.retention))
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+func TestTrashPurger_Run(t *testing.T) {
+	t.Run("purges until context is cancelled", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		purged := make(chan struct{}, 10)
+		mockRepo.On("PurgeDeletedBefore", mock.Anything, mock.Anything).Return(int64(1), nil).Run(func(mock.Arguments) {
+			purged <- struct{}{}
+		})
+		purger := NewTrashPurger(NewTaskService(mockRepo), time.Hour, 10*time.Millisecond, zap.NewNop())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			purger.Run(ctx)
+			close(done)
+		}()
+		<-purged
+		<-purged
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("purger did not stop after cancel")
+		}
+	})
+
+	t.Run("disabled without retention", func(t *testing.T) {
+		mockRepo := new(MockTaskRepository)
+		purger := NewTrashPurger(NewTaskService(mockRepo), 0, time.Millisecond, zap.NewNop())
+
+		purger.Run(context.Background())
+
+		mockRepo.AssertNotCalled(t, "PurgeDeletedBefore", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"time"
+)
+
+// WebhookDispatcher 定期投遞 webhook outbox 中到期的事件
+type WebhookDispatcher struct {
+	webhookService WebhookService
+	interval       time.Duration
+	logger         *zap.Logger
+}
+
+func NewWebhookDispatcher(webhookService WebhookService, interval time.Duration, logger *zap.Logger) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		webhookService: webhookService,
+		interval:       interval,
+		logger:         logger,
+	}
+}
+
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	if d.interval <= 0 {
+		d.logger.Info("webhook dispatcher disabled", zap.Duration("interval", d.interval))
+		return
+	}
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		d.dispatch(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *WebhookDispatcher) dispatch(ctx context.Context) {
+	dispatched, err := d.webhookService.DispatchDue(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			d.logger.Error("dispatch webhook deliveries error", zap.Int("dispatched", dispatched), zap.Error(err))
+		}
+		return
+	}
+	if dispatched > 0 {
+		d.logger.Info("dispatched webhook deliveries", zap.Int("count", dispatched))
+	}
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"tasks/domain/models"
+	"testing"
+	"time"
+)
+
+func TestWebhookDispatcher_Run(t *testing.T) {
+	t.Run("dispatches until context is cancelled", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		listed := make(chan struct{}, 10)
+		mockRepo.On("ListDueDeliveries", mock.Anything, mock.Anything, deliveryBatchSize).Return([]*models.DueDelivery{}, nil).Run(func(mock.Arguments) {
+			listed <- struct{}{}
+		})
+		dispatcher := NewWebhookDispatcher(NewWebhookService(mockRepo, new(MockWebhookSender), testRetry), 10*time.Millisecond, zap.NewNop())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			dispatcher.Run(ctx)
+			close(done)
+		}()
+		<-listed
+		<-listed
+		cancel()
+
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("dispatcher did not stop after cancel")
+		}
+	})
+
+	t.Run("disabled without interval", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		dispatcher := NewWebhookDispatcher(NewWebhookService(mockRepo, new(MockWebhookSender), testRetry), 0, zap.NewNop())
+
+		dispatcher.Run(context.Background())
+
+		mockRepo.AssertNotCalled(t, "ListDueDeliveries", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
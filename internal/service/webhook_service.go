@@ -0,0 +1,272 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"strings"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"tasks/internal/repository"
+	"tasks/internal/webhook"
+	"time"
+)
+
+const (
+	// deliveryBatchSize 每次查詢到期投遞的數量
+	deliveryBatchSize = 100
+	// deliveryLease 投遞前先保留的時間，程序在投遞途中中斷時 lease 到期後會重新投遞
+	deliveryLease = 5 * time.Minute
+	// maxWebhookURLLength、webhook secret 長度與資料表欄位長度一致
+	maxWebhookURLLength    = 2048
+	minWebhookSecretLength = 16
+	maxWebhookSecretLength = 255
+)
+
+type webhookService struct {
+	repo   repository.WebhookRepository
+	sender WebhookSender
+	retry  webhook.Retry
+}
+
+func NewWebhookService(repo repository.WebhookRepository, sender WebhookSender, retry webhook.Retry) WebhookService {
+	return &webhookService{repo: repo, sender: sender, retry: retry}
+}
+
+// CreateWebhook 回傳的 webhook 帶有 secret，之後查詢不再回傳
+func (s *webhookService) CreateWebhook(ctx context.Context, hook entities.Webhook) (*entities.Webhook, error) {
+	hook, err := normalizeWebhook(hook)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.repo.Create(ctx, hook); err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
+// UpdateWebhook 整筆取代 url、events 與 active，帶 secret 時一併更換並在回應中回傳
+func (s *webhookService) UpdateWebhook(ctx context.Context, hook entities.Webhook) (*entities.Webhook, error) {
+	hook, err := normalizeWebhook(hook)
+	if err != nil {
+		return nil, err
+	}
+	hook.UpdatedAt = time.Now().UTC()
+	if err = s.repo.Update(ctx, hook); err != nil {
+		return nil, err
+	}
+	updated, err := s.GetWebhook(ctx, hook.ID)
+	if err != nil {
+		return nil, err
+	}
+	updated.Secret = hook.Secret
+	return updated, nil
+}
+
+func (s *webhookService) DeleteWebhook(ctx context.Context, webhookId string) error {
+	return s.repo.Delete(ctx, webhookId)
+}
+
+func (s *webhookService) GetWebhook(ctx context.Context, webhookId string) (*entities.Webhook, error) {
+	record, err := s.repo.Find(ctx, webhookId)
+	if err != nil {
+		return nil, err
+	}
+	hook := toWebhookEntity(record)
+	return &hook, nil
+}
+
+func (s *webhookService) GetWebhooks(ctx context.Context) (*entities.Webhooks, error) {
+	records, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := entities.Webhooks{Webhooks: make([]entities.Webhook, 0, len(records))}
+	for _, record := range records {
+		result.Webhooks = append(result.Webhooks, toWebhookEntity(record))
+	}
+	return &result, nil
+}
+
+// GetDeliveries webhook 的投遞紀錄，依 id 由新到舊排列
+func (s *webhookService) GetDeliveries(ctx context.Context, param entities.DeliveryQueryParam) (*entities.WebhookDeliveries, error) {
+	if param.Size < 1 || param.Size > constants.MaxDeliverySize {
+		return nil, customError.InvalidRequest.Errorf("size must be between 1 and %d", constants.MaxDeliverySize)
+	}
+	if param.Before < 0 {
+		return nil, customError.InvalidRequest.New("before must not be negative")
+	}
+	if param.Status != "" && !param.Status.IsValid() {
+		return nil, customError.InvalidRequest.Errorf("invalid status %s", param.Status)
+	}
+	if _, err := s.repo.Find(ctx, param.WebhookID); err != nil {
+		return nil, err
+	}
+	size := param.Size
+	// 多取一筆用來判斷是否還有下一頁
+	param.Size++
+	records, err := s.repo.ListDeliveries(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+	result := entities.WebhookDeliveries{Deliveries: make([]entities.WebhookDelivery, 0, len(records))}
+	if len(records) > size {
+		records = records[:size]
+		result.NextBefore = records[len(records)-1].ID
+	}
+	result.Size = len(records)
+	for _, record := range records {
+		result.Deliveries = append(result.Deliveries, toDeliveryEntity(record))
+	}
+	return &result, nil
+}
+
+// Redeliver 將已送達或 dead 的投遞重新排入 outbox，重試次數重新計算
+func (s *webhookService) Redeliver(ctx context.Context, webhookId string, deliveryId int64) (*entities.WebhookDelivery, error) {
+	if err := s.repo.Redeliver(ctx, webhookId, deliveryId, time.Now().UTC()); err != nil {
+		return nil, err
+	}
+	record, err := s.repo.FindDelivery(ctx, webhookId, deliveryId)
+	if err != nil {
+		return nil, err
+	}
+	delivery := toDeliveryEntity(record)
+	return &delivery, nil
+}
+
+// DispatchDue 投遞到期的事件，先以 lease 保留再送出，回傳投遞的次數
+// 失敗時依 retry 策略排定下次投遞，次數用盡後標記為 dead
+func (s *webhookService) DispatchDue(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	dispatched := 0
+	for {
+		deliveries, err := s.repo.ListDueDeliveries(ctx, now, deliveryBatchSize)
+		if err != nil {
+			return dispatched, err
+		}
+		for _, delivery := range deliveries {
+			claimed, err := s.repo.ClaimDelivery(ctx, delivery.ID, now, now.Add(deliveryLease))
+			if err != nil {
+				return dispatched, err
+			}
+			if !claimed {
+				continue
+			}
+			if err = s.dispatch(ctx, delivery); err != nil {
+				return dispatched, err
+			}
+			dispatched++
+		}
+		if len(deliveries) < deliveryBatchSize {
+			return dispatched, nil
+		}
+	}
+}
+
+func (s *webhookService) dispatch(ctx context.Context, delivery *models.DueDelivery) error {
+	// ClaimDelivery 已將 attempts 加一
+	attempts := delivery.Attempts + 1
+	statusCode, sendErr := s.sender.Send(ctx, webhook.Request{
+		URL:        delivery.URL,
+		Secret:     delivery.Secret,
+		EventID:    delivery.EventID,
+		DeliveryID: delivery.ID,
+		Event:      delivery.Event,
+		Body:       []byte(delivery.Payload),
+	})
+	// 停止中斷的投遞不算失敗，lease 到期後重新投遞
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	now := time.Now().UTC()
+	if sendErr == nil {
+		return s.repo.CompleteDelivery(ctx, delivery.ID, statusCode, now)
+	}
+	if s.retry.Exhausted(attempts) {
+		return s.repo.FailDelivery(ctx, delivery.ID, constants.DeliveryDead, statusCode, sendErr.Error(), now)
+	}
+	return s.repo.FailDelivery(ctx, delivery.ID, constants.DeliveryPending, statusCode, sendErr.Error(), now.Add(s.retry.Delay(attempts)))
+}
+
+// normalizeWebhook 檢查 url 與 secret，事件去除重複後排序
+func normalizeWebhook(hook entities.Webhook) (entities.Webhook, error) {
+	hook.URL = strings.TrimSpace(hook.URL)
+	if hook.URL == "" {
+		return hook, customError.InvalidRequest.New("url is required")
+	}
+	if len(hook.URL) > maxWebhookURLLength {
+		return hook, customError.InvalidRequest.Errorf("url must be at most %d characters", maxWebhookURLLength)
+	}
+	parsed, err := url.Parse(hook.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return hook, customError.InvalidRequest.New("url must be an absolute http or https url")
+	}
+	if hook.Secret != "" && (len(hook.Secret) < minWebhookSecretLength || len(hook.Secret) > maxWebhookSecretLength) {
+		return hook, customError.InvalidRequest.Errorf("secret must be between %d and %d characters", minWebhookSecretLength, maxWebhookSecretLength)
+	}
+	if len(hook.Events) == 0 {
+		return hook, customError.InvalidRequest.New("events is required")
+	}
+	seen := make(map[constants.WebhookEvent]bool, len(hook.Events))
+	events := make([]constants.WebhookEvent, 0, len(hook.Events))
+	for _, event := range hook.Events {
+		if !event.IsValid() {
+			return hook, customError.InvalidRequest.Errorf("invalid event %s", event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i] < events[j] })
+	hook.Events = events
+	return hook, nil
+}
+
+func toWebhookEntity(hook *models.Webhook) entities.Webhook {
+	result := entities.Webhook{
+		ID:        hook.ID,
+		URL:       hook.URL,
+		Events:    []constants.WebhookEvent{},
+		Active:    hook.Active,
+		CreatedAt: models.ParseTime(hook.CreatedAt),
+		UpdatedAt: models.ParseTime(hook.UpdatedAt),
+	}
+	for _, event := range strings.Split(hook.Events, ",") {
+		if event != "" {
+			result.Events = append(result.Events, constants.WebhookEvent(event))
+		}
+	}
+	return result
+}
+
+func toDeliveryEntity(delivery *models.WebhookDelivery) entities.WebhookDelivery {
+	result := entities.WebhookDelivery{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		WebhookID:      delivery.WebhookID,
+		Event:          constants.WebhookEvent(delivery.Event),
+		TaskID:         delivery.TaskID,
+		Status:         constants.DeliveryStatus(delivery.Status),
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		Payload:        json.RawMessage(delivery.Payload),
+		CreatedAt:      models.ParseTime(delivery.CreatedAt),
+	}
+	// 只有等待投遞時下次投遞時間才有意義
+	if result.Status == constants.DeliveryPending {
+		nextAttemptAt := models.ParseTime(delivery.NextAttemptAt)
+		result.NextAttemptAt = &nextAttemptAt
+	}
+	if delivery.LastError != nil {
+		result.LastError = *delivery.LastError
+	}
+	if delivery.DeliveredAt != nil {
+		deliveredAt := models.ParseTime(*delivery.DeliveredAt)
+		result.DeliveredAt = &deliveredAt
+	}
+	return result
+}
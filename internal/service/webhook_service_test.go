@@ -0,0 +1,413 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"tasks/constants"
+	"tasks/domain/entities"
+	"tasks/domain/models"
+	customError "tasks/errors"
+	"tasks/internal/webhook"
+	"testing"
+	"time"
+)
+
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepository) Find(ctx context.Context, id string) (*models.Webhook, error) {
+	args := m.Called(ctx, id)
+	if hook, ok := args.Get(0).(*models.Webhook); ok {
+		return hook, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookRepository) List(ctx context.Context) ([]*models.Webhook, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]*models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) Create(ctx context.Context, hook entities.Webhook) error {
+	args := m.Called(ctx, hook)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) Update(ctx context.Context, hook entities.Webhook) error {
+	args := m.Called(ctx, hook)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) Delete(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) ListDeliveries(ctx context.Context, param entities.DeliveryQueryParam) ([]*models.WebhookDelivery, error) {
+	args := m.Called(ctx, param)
+	if deliveries, ok := args.Get(0).([]*models.WebhookDelivery); ok {
+		return deliveries, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookRepository) FindDelivery(ctx context.Context, webhookID string, id int64) (*models.WebhookDelivery, error) {
+	args := m.Called(ctx, webhookID, id)
+	if delivery, ok := args.Get(0).(*models.WebhookDelivery); ok {
+		return delivery, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookRepository) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*models.DueDelivery, error) {
+	args := m.Called(ctx, now, limit)
+	if deliveries, ok := args.Get(0).([]*models.DueDelivery); ok {
+		return deliveries, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookRepository) ClaimDelivery(ctx context.Context, id int64, now, until time.Time) (bool, error) {
+	args := m.Called(ctx, id, now, until)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWebhookRepository) CompleteDelivery(ctx context.Context, id int64, statusCode int, now time.Time) error {
+	args := m.Called(ctx, id, statusCode, now)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) FailDelivery(ctx context.Context, id int64, status constants.DeliveryStatus, statusCode int, lastError string, nextAttemptAt time.Time) error {
+	args := m.Called(ctx, id, status, statusCode, lastError, nextAttemptAt)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) Redeliver(ctx context.Context, webhookID string, id int64, now time.Time) error {
+	args := m.Called(ctx, webhookID, id, now)
+	return args.Error(0)
+}
+
+type MockWebhookSender struct {
+	mock.Mock
+}
+
+func (m *MockWebhookSender) Send(ctx context.Context, request webhook.Request) (int, error) {
+	args := m.Called(ctx, request)
+	return args.Int(0), args.Error(1)
+}
+
+var testRetry = webhook.Retry{MaxAttempts: 3, Backoff: time.Minute, MaxBackoff: time.Hour}
+
+func Test_webhookService_CreateWebhook(t *testing.T) {
+	t.Run("events are deduplicated and sorted", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		service := NewWebhookService(mockRepo, new(MockWebhookSender), testRetry)
+		mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(hook entities.Webhook) bool {
+			return hook.URL == "https://example.com/hook" &&
+				assert.ObjectsAreEqual([]constants.WebhookEvent{constants.WebhookTaskCreated, constants.WebhookTaskDeleted}, hook.Events)
+		})).Return(nil)
+		hook := entities.NewWebhook(" https://example.com/hook ", []constants.WebhookEvent{constants.WebhookTaskDeleted, constants.WebhookTaskCreated, constants.WebhookTaskDeleted}, "")
+
+		created, err := service.CreateWebhook(context.Background(), hook)
+
+		assert.NoError(t, err)
+		assert.Len(t, created.Secret, 64)
+		assert.True(t, created.Active)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("invalid webhooks", func(t *testing.T) {
+		events := []constants.WebhookEvent{constants.WebhookTaskCreated}
+		hooks := map[string]entities.Webhook{
+			"empty url":       {URL: " ", Events: events},
+			"relative url":    {URL: "/hook", Events: events},
+			"unsupported url": {URL: "ftp://example.com/hook", Events: events},
+			"long url":        {URL: "https://example.com/" + strings.Repeat("a", maxWebhookURLLength), Events: events},
+			"short secret":    {URL: "https://example.com/hook", Events: events, Secret: "short"},
+			"no events":       {URL: "https://example.com/hook"},
+			"unknown event":   {URL: "https://example.com/hook", Events: []constants.WebhookEvent{"task.moved"}},
+		}
+		for name, hook := range hooks {
+			t.Run(name, func(t *testing.T) {
+				mockRepo := new(MockWebhookRepository)
+				service := NewWebhookService(mockRepo, new(MockWebhookSender), testRetry)
+
+				_, err := service.CreateWebhook(context.Background(), hook)
+
+				assert.True(t, errors.Is(err, customError.InvalidRequest))
+				mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			})
+		}
+	})
+}
+
+func Test_webhookService_UpdateWebhook(t *testing.T) {
+	record := &models.Webhook{ID: "hook-1", URL: "https://example.com/v2", Secret: "stored-secret", Events: "task.updated", Active: false,
+		CreatedAt: "2024-01-01 08:00:00+00:00", UpdatedAt: "2024-01-02 08:00:00+00:00"}
+
+	t.Run("secret is kept", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		service := NewWebhookService(mockRepo, new(MockWebhookSender), testRetry)
+		mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(hook entities.Webhook) bool {
+			return hook.ID == "hook-1" && hook.Secret == "" && !hook.Active && !hook.UpdatedAt.IsZero()
+		})).Return(nil)
+		mockRepo.On("Find", mock.Anything, "hook-1").Return(record, nil)
+
+		updated, err := service.UpdateWebhook(context.Background(), entities.Webhook{ID: "hook-1", URL: "https://example.com/v2", Events: []constants.WebhookEvent{constants.WebhookTaskUpdated}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []constants.WebhookEvent{constants.WebhookTaskUpdated}, updated.Events)
+		assert.Empty(t, updated.Secret)
+		assert.Equal(t, time.Date(2024, 1, 2, 8, 0, 0, 0, time.UTC), updated.UpdatedAt)
+	})
+
+	t.Run("rotated secret is returned once", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		service := NewWebhookService(mockRepo, new(MockWebhookSender), testRetry)
+		mockRepo.On("Update", mock.Anything, mock.MatchedBy(func(hook entities.Webhook) bool {
+			return hook.Secret == "rotated-secret-value"
+		})).Return(nil)
+		mockRepo.On("Find", mock.Anything, "hook-1").Return(record, nil)
+
+		updated, err := service.UpdateWebhook(context.Background(), entities.Webhook{ID: "hook-1", URL: "https://example.com/v2", Events: []constants.WebhookEvent{constants.WebhookTaskUpdated}, Secret: "rotated-secret-value"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "rotated-secret-value", updated.Secret)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		service := NewWebhookService(mockRepo, new(MockWebhookSender), testRetry)
+		mockRepo.On("Update", mock.Anything, mock.Anything).Return(customError.WebhookNotFound.New("webhook not found"))
+
+		_, err := service.UpdateWebhook(context.Background(), entities.Webhook{ID: "hook-9", URL: "https://example.com", Events: []constants.WebhookEvent{constants.WebhookTaskUpdated}})
+
+		assert.True(t, errors.Is(err, customError.WebhookNotFound))
+	})
+}
+
+func Test_webhookService_GetWebhooks(t *testing.T) {
+	mockRepo := new(MockWebhookRepository)
+	service := NewWebhookService(mockRepo, new(MockWebhookSender), testRetry)
+	mockRepo.On("List", mock.Anything).Return([]*models.Webhook{
+		{ID: "hook-1", URL: "https://example.com", Secret: "secret", Events: "task.created,task.deleted", Active: true, CreatedAt: "2024-01-01 08:00:00+00:00", UpdatedAt: "2024-01-01 08:00:00+00:00"},
+	}, nil)
+
+	result, err := service.GetWebhooks(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Webhooks, 1)
+	assert.Equal(t, []constants.WebhookEvent{constants.WebhookTaskCreated, constants.WebhookTaskDeleted}, result.Webhooks[0].Events)
+	// secret 只在建立或更換時回傳
+	assert.Empty(t, result.Webhooks[0].Secret)
+}
+
+func Test_webhookService_GetDeliveries(t *testing.T) {
+	hook := &models.Webhook{ID: "hook-1", Events: "task.created"}
+	code := 500
+	lastError := "unexpected status 500"
+	deliveries := []*models.WebhookDelivery{
+		{ID: 9, WebhookID: "hook-1", Event: "task.created", Payload: `{"id":"e-9"}`, Status: "pending", Attempts: 1, NextAttemptAt: "2024-01-01 08:01:00+00:00",
+			LastStatusCode: &code, LastError: &lastError, CreatedAt: "2024-01-01 08:00:00+00:00"},
+		{ID: 7, WebhookID: "hook-1", Event: "task.created", Payload: `{"id":"e-7"}`, Status: "delivered", Attempts: 1, NextAttemptAt: "2024-01-01 07:00:00+00:00", CreatedAt: "2024-01-01 07:00:00+00:00"},
+		{ID: 4, WebhookID: "hook-1", Event: "task.created", Payload: `{"id":"e-4"}`, Status: "delivered", Attempts: 1, CreatedAt: "2024-01-01 06:00:00+00:00"},
+	}
+
+	t.Run("page with the next cursor", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		service := NewWebhookService(mockRepo, new(MockWebhookSender), testRetry)
+		mockRepo.On("Find", mock.Anything, "hook-1").Return(hook, nil)
+		mockRepo.On("ListDeliveries", mock.Anything, entities.DeliveryQueryParam{WebhookID: "hook-1", Size: 3}).Return(deliveries, nil)
+
+		result, err := service.GetDeliveries(context.Background(), entities.DeliveryQueryParam{WebhookID: "hook-1", Size: 2})
+
+		assert.NoError(t, err)
+		assert.Len(t, result.Deliveries, 2)
+		assert.Equal(t, 2, result.Size)
+		assert.Equal(t, int64(7), result.NextBefore)
+		assert.Equal(t, time.Date(2024, 1, 1, 8, 1, 0, 0, time.UTC), *result.Deliveries[0].NextAttemptAt)
+		assert.Equal(t, "unexpected status 500", result.Deliveries[0].LastError)
+		assert.JSONEq(t, `{"id":"e-9"}`, string(result.Deliveries[0].Payload))
+		assert.Nil(t, result.Deliveries[1].NextAttemptAt)
+	})
+
+	t.Run("unknown webhook", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		service := NewWebhookService(mockRepo, new(MockWebhookSender), testRetry)
+		mockRepo.On("Find", mock.Anything, "hook-9").Return(nil, customError.WebhookNotFound.New("webhook not found"))
+
+		_, err := service.GetDeliveries(context.Background(), entities.DeliveryQueryParam{WebhookID: "hook-9", Size: 10})
+
+		assert.True(t, errors.Is(err, customError.WebhookNotFound))
+		mockRepo.AssertNotCalled(t, "ListDeliveries", mock.Anything, mock.Anything)
+	})
+
+	t.Run("invalid requests", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		service := NewWebhookService(mockRepo, new(MockWebhookSender), testRetry)
+		for _, param := range []entities.DeliveryQueryParam{
+			{WebhookID: "hook-1", Size: 0},
+			{WebhookID: "hook-1", Size: constants.MaxDeliverySize + 1},
+			{WebhookID: "hook-1", Size: 10, Before: -1},
+			{WebhookID: "hook-1", Size: 10, Status: "failed"},
+		} {
+			_, err := service.GetDeliveries(context.Background(), param)
+			assert.True(t, errors.Is(err, customError.InvalidRequest), param)
+		}
+		mockRepo.AssertNotCalled(t, "Find", mock.Anything, mock.Anything)
+	})
+}
+
+func Test_webhookService_Redeliver(t *testing.T) {
+	t.Run("delivery is queued again", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		service := NewWebhookService(mockRepo, new(MockWebhookSender), testRetry)
+		mockRepo.On("Redeliver", mock.Anything, "hook-1", int64(4), mock.Anything).Return(nil)
+		mockRepo.On("FindDelivery", mock.Anything, "hook-1", int64(4)).Return(&models.WebhookDelivery{ID: 4, WebhookID: "hook-1", Status: "pending",
+			NextAttemptAt: "2024-01-01 08:00:00+00:00", CreatedAt: "2024-01-01 06:00:00+00:00"}, nil)
+
+		delivery, err := service.Redeliver(context.Background(), "hook-1", 4)
+
+		assert.NoError(t, err)
+		assert.Equal(t, constants.DeliveryPending, delivery.Status)
+		assert.Zero(t, delivery.Attempts)
+	})
+
+	t.Run("delivery is pending", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		service := NewWebhookService(mockRepo, new(MockWebhookSender), testRetry)
+		mockRepo.On("Redeliver", mock.Anything, "hook-1", int64(4), mock.Anything).Return(customError.DeliveryPending.New("delivery is pending"))
+
+		_, err := service.Redeliver(context.Background(), "hook-1", 4)
+
+		assert.True(t, errors.Is(err, customError.DeliveryPending))
+	})
+}
+
+func Test_webhookService_DispatchDue(t *testing.T) {
+	due := func(id int64, attempts int) *models.DueDelivery {
+		return &models.DueDelivery{
+			WebhookDelivery: models.WebhookDelivery{ID: id, EventID: "event-1", WebhookID: "hook-1", Event: "task.created", Payload: `{"id":"event-1"}`, Attempts: attempts},
+			URL:             "https://example.com/hook",
+			Secret:          "secret",
+		}
+	}
+
+	t.Run("delivered", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		mockSender := new(MockWebhookSender)
+		service := NewWebhookService(mockRepo, mockSender, testRetry)
+		mockRepo.On("ListDueDeliveries", mock.Anything, mock.Anything, deliveryBatchSize).Return([]*models.DueDelivery{due(1, 0)}, nil)
+		mockRepo.On("ClaimDelivery", mock.Anything, int64(1), mock.Anything, mock.MatchedBy(func(until time.Time) bool {
+			return time.Until(until) > deliveryLease-time.Minute
+		})).Return(true, nil)
+		mockSender.On("Send", mock.Anything, webhook.Request{URL: "https://example.com/hook", Secret: "secret", EventID: "event-1", DeliveryID: 1,
+			Event: "task.created", Body: []byte(`{"id":"event-1"}`)}).Return(204, nil)
+		mockRepo.On("CompleteDelivery", mock.Anything, int64(1), 204, mock.Anything).Return(nil)
+
+		dispatched, err := service.DispatchDue(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, dispatched)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("failure is retried with backoff", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		mockSender := new(MockWebhookSender)
+		service := NewWebhookService(mockRepo, mockSender, testRetry)
+		mockRepo.On("ListDueDeliveries", mock.Anything, mock.Anything, deliveryBatchSize).Return([]*models.DueDelivery{due(1, 1)}, nil)
+		mockRepo.On("ClaimDelivery", mock.Anything, int64(1), mock.Anything, mock.Anything).Return(true, nil)
+		mockSender.On("Send", mock.Anything, mock.Anything).Return(503, errors.New("unexpected status 503"))
+		// 第二次失敗後等待 Backoff * 2
+		mockRepo.On("FailDelivery", mock.Anything, int64(1), constants.DeliveryPending, 503, "unexpected status 503", mock.MatchedBy(func(next time.Time) bool {
+			wait := time.Until(next)
+			return wait > 2*time.Minute-time.Second && wait <= 2*time.Minute
+		})).Return(nil)
+
+		dispatched, err := service.DispatchDue(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, dispatched)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("dead after the last attempt", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		mockSender := new(MockWebhookSender)
+		service := NewWebhookService(mockRepo, mockSender, testRetry)
+		mockRepo.On("ListDueDeliveries", mock.Anything, mock.Anything, deliveryBatchSize).Return([]*models.DueDelivery{due(1, 2)}, nil)
+		mockRepo.On("ClaimDelivery", mock.Anything, int64(1), mock.Anything, mock.Anything).Return(true, nil)
+		mockSender.On("Send", mock.Anything, mock.Anything).Return(0, errors.New("connection refused"))
+		mockRepo.On("FailDelivery", mock.Anything, int64(1), constants.DeliveryDead, 0, "connection refused", mock.Anything).Return(nil)
+
+		_, err := service.DispatchDue(context.Background())
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("claimed by another dispatcher", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		mockSender := new(MockWebhookSender)
+		service := NewWebhookService(mockRepo, mockSender, testRetry)
+		mockRepo.On("ListDueDeliveries", mock.Anything, mock.Anything, deliveryBatchSize).Return([]*models.DueDelivery{due(1, 0)}, nil)
+		mockRepo.On("ClaimDelivery", mock.Anything, int64(1), mock.Anything, mock.Anything).Return(false, nil)
+
+		dispatched, err := service.DispatchDue(context.Background())
+
+		assert.NoError(t, err)
+		assert.Zero(t, dispatched)
+		mockSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	t.Run("cancelled delivery is left to the lease", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		mockSender := new(MockWebhookSender)
+		service := NewWebhookService(mockRepo, mockSender, testRetry)
+		ctx, cancel := context.WithCancel(context.Background())
+		mockRepo.On("ListDueDeliveries", mock.Anything, mock.Anything, deliveryBatchSize).Return([]*models.DueDelivery{due(1, 0)}, nil)
+		mockRepo.On("ClaimDelivery", mock.Anything, int64(1), mock.Anything, mock.Anything).Return(true, nil)
+		mockSender.On("Send", mock.Anything, mock.Anything).Return(0, context.Canceled).Run(func(mock.Arguments) { cancel() })
+
+		_, err := service.DispatchDue(ctx)
+
+		assert.True(t, errors.Is(err, context.Canceled))
+		mockRepo.AssertNotCalled(t, "FailDelivery", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("signed request reaches the receiver", func(t *testing.T) {
+		received := make(chan *http.Request, 1)
+		var body []byte
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ = io.ReadAll(r.Body)
+			received <- r
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer receiver.Close()
+		mockRepo := new(MockWebhookRepository)
+		service := NewWebhookService(mockRepo, webhook.NewSender(time.Second), testRetry)
+		delivery := due(1, 0)
+		delivery.URL = receiver.URL
+		mockRepo.On("ListDueDeliveries", mock.Anything, mock.Anything, deliveryBatchSize).Return([]*models.DueDelivery{delivery}, nil)
+		mockRepo.On("ClaimDelivery", mock.Anything, int64(1), mock.Anything, mock.Anything).Return(true, nil)
+		mockRepo.On("CompleteDelivery", mock.Anything, int64(1), http.StatusAccepted, mock.Anything).Return(nil)
+
+		dispatched, err := service.DispatchDue(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, dispatched)
+		request := <-received
+		assert.Equal(t, "task.created", request.Header.Get(webhook.EventHeader))
+		assert.Equal(t, "event-1", request.Header.Get(webhook.EventIDHeader))
+		assert.True(t, webhook.Verify("secret", request.Header.Get(webhook.SignatureHeader), request.Header.Get(webhook.TimestampHeader), body, time.Now(), 0))
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,31 @@
+package webhook
+
+import "time"
+
+// Retry 投遞失敗時的重試策略，第 n 次失敗後等待 Backoff * 2^(n-1)，最多等待 MaxBackoff
+type Retry struct {
+	// MaxAttempts 包含第一次投遞，用盡後標記為 dead
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+}
+
+// Exhausted 第 attempts 次投遞失敗後是否不再重試
+func (r Retry) Exhausted(attempts int) bool {
+	return attempts >= r.MaxAttempts
+}
+
+// Delay 第 attempts 次投遞失敗後到下次重試的等待時間
+func (r Retry) Delay(attempts int) time.Duration {
+	delay := r.Backoff
+	for i := 1; i < attempts; i++ {
+		if r.MaxBackoff > 0 && delay >= r.MaxBackoff {
+			break
+		}
+		delay *= 2
+	}
+	if r.MaxBackoff > 0 && delay > r.MaxBackoff {
+		return r.MaxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,22 @@
+package webhook
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	retry := Retry{MaxAttempts: 4, Backoff: 30 * time.Second, MaxBackoff: 2 * time.Minute}
+
+	assert.Equal(t, 30*time.Second, retry.Delay(1))
+	assert.Equal(t, time.Minute, retry.Delay(2))
+	assert.Equal(t, 2*time.Minute, retry.Delay(3))
+	assert.Equal(t, 2*time.Minute, retry.Delay(50))
+	assert.False(t, retry.Exhausted(3))
+	assert.True(t, retry.Exhausted(4))
+
+	t.Run("without a cap", func(t *testing.T) {
+		assert.Equal(t, 8*time.Second, Retry{Backoff: time.Second}.Delay(4))
+	})
+}
@@ -0,0 +1,65 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Request 一次投遞的內容，Body 為已編碼的事件 JSON
+type Request struct {
+	URL        string
+	Secret     string
+	EventID    string
+	DeliveryID int64
+	Event      string
+	Body       []byte
+}
+
+// Sender 以 HTTP POST 投遞事件，2xx 以外的回應都視為失敗
+type Sender struct {
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewSender(timeout time.Duration) *Sender {
+	return &Sender{
+		client: &http.Client{
+			Timeout: timeout,
+			// 不跟隨轉址，避免事件被送到註冊以外的位置
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		now: time.Now,
+	}
+}
+
+// Send 回傳接收端的 status code，連線失敗時為 0
+func (s *Sender) Send(ctx context.Context, request Request) (int, error) {
+	httpRequest, err := http.NewRequestWithContext(ctx, http.MethodPost, request.URL, bytes.NewReader(request.Body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := s.now().Unix()
+	httpRequest.Header.Set("Content-Type", "application/json")
+	httpRequest.Header.Set("User-Agent", "tasks-webhook/1.0")
+	httpRequest.Header.Set(EventIDHeader, request.EventID)
+	httpRequest.Header.Set(DeliveryHeader, strconv.FormatInt(request.DeliveryID, 10))
+	httpRequest.Header.Set(EventHeader, request.Event)
+	httpRequest.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	httpRequest.Header.Set(SignatureHeader, Sign(request.Secret, timestamp, request.Body))
+	response, err := s.client.Do(httpRequest)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	// 讀完回應讓連線可以重用，內容不使用
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
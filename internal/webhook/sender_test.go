@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSender_Send(t *testing.T) {
+	body := []byte(`{"id":"event-1","type":"task.created"}`)
+	request := Request{Secret: "s3cret", EventID: "event-1", DeliveryID: 7, Event: "task.created", Body: body}
+
+	t.Run("signed request", func(t *testing.T) {
+		var received *http.Request
+		var receivedBody []byte
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			receivedBody, _ = io.ReadAll(r.Body)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer receiver.Close()
+		request := request
+		request.URL = receiver.URL
+
+		status, err := NewSender(time.Second).Send(context.Background(), request)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, status)
+		assert.Equal(t, http.MethodPost, received.Method)
+		assert.Equal(t, "application/json", received.Header.Get("Content-Type"))
+		assert.Equal(t, "event-1", received.Header.Get(EventIDHeader))
+		assert.Equal(t, "7", received.Header.Get(DeliveryHeader))
+		assert.Equal(t, "task.created", received.Header.Get(EventHeader))
+		assert.Equal(t, body, receivedBody)
+		assert.True(t, Verify("s3cret", received.Header.Get(SignatureHeader), received.Header.Get(TimestampHeader), receivedBody, time.Now(), 0))
+	})
+
+	t.Run("non 2xx response", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer receiver.Close()
+		request := request
+		request.URL = receiver.URL
+
+		status, err := NewSender(time.Second).Send(context.Background(), request)
+
+		assert.EqualError(t, err, "unexpected status 503")
+		assert.Equal(t, http.StatusServiceUnavailable, status)
+	})
+
+	t.Run("redirects are not followed", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "http://example.com/", http.StatusFound)
+		}))
+		defer receiver.Close()
+		request := request
+		request.URL = receiver.URL
+
+		status, err := NewSender(time.Second).Send(context.Background(), request)
+
+		assert.EqualError(t, err, "unexpected status 302")
+		assert.Equal(t, http.StatusFound, status)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		}))
+		defer receiver.Close()
+		request := request
+		request.URL = receiver.URL
+
+		status, err := NewSender(50*time.Millisecond).Send(context.Background(), request)
+
+		assert.Error(t, err)
+		assert.Zero(t, status)
+	})
+}
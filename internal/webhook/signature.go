@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 投遞時帶入的 header，接收端以 SignatureHeader 與 TimestampHeader 驗證來源
+const (
+	EventIDHeader    = "X-Webhook-Id"
+	DeliveryHeader   = "X-Webhook-Delivery"
+	EventHeader      = "X-Webhook-Event"
+	TimestampHeader  = "X-Webhook-Timestamp"
+	SignatureHeader  = "X-Webhook-Signature"
+	signaturePrefix  = "sha256="
+	defaultTolerance = 5 * time.Minute
+)
+
+// Sign 以 secret 對 "timestamp.body" 計算 HMAC-SHA256，timestamp 納入簽章避免舊請求被重送
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify 接收端驗證簽章，timestamp 與 now 相差超過 tolerance 時視為無效，tolerance 為 0 時使用 5 分鐘
+func Verify(secret, signature, timestamp string, body []byte, now time.Time, tolerance time.Duration) bool {
+	if tolerance <= 0 {
+		tolerance = defaultTolerance
+	}
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if diff := now.Sub(time.Unix(seconds, 0)); diff > tolerance || diff < -tolerance {
+		return false
+	}
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, seconds, body)))
+}
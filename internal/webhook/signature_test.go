@@ -0,0 +1,38 @@
+package webhook
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"task.created"}`)
+	signature := Sign("s3cret", 1704067200, body)
+
+	// echo -n '1704067200.{"type":"task.created"}' | openssl dgst -sha256 -hmac s3cret
+	assert.Equal(t, "sha256=8f397b10ce4be1ebb2722ecc48e7366d8a05154c11cd74ba8fdbcbb88886bc8b", signature)
+	assert.NotEqual(t, signature, Sign("other", 1704067200, body))
+	assert.NotEqual(t, signature, Sign("s3cret", 1704067201, body))
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"type":"task.created"}`)
+	now := time.Unix(1704067200, 0)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	signature := Sign("s3cret", now.Unix(), body)
+
+	assert.True(t, Verify("s3cret", signature, timestamp, body, now.Add(time.Minute), 0))
+	assert.False(t, Verify("other", signature, timestamp, body, now, 0))
+	assert.False(t, Verify("s3cret", signature, timestamp, []byte(`{}`), now, 0))
+	assert.False(t, Verify("s3cret", signature, "1704067201", body, now, 0))
+	assert.False(t, Verify("s3cret", signature, "yesterday", body, now, 0))
+	assert.False(t, Verify("s3cret", signature[len("sha256="):], timestamp, body, now, 0))
+
+	t.Run("outside the tolerance", func(t *testing.T) {
+		assert.False(t, Verify("s3cret", signature, timestamp, body, now.Add(6*time.Minute), 0))
+		assert.True(t, Verify("s3cret", signature, timestamp, body, now.Add(6*time.Minute), 10*time.Minute))
+		assert.False(t, Verify("s3cret", signature, timestamp, body, now.Add(-6*time.Minute), 0))
+	})
+}
@@ -5,25 +5,43 @@ import (
 	"database/sql"
 	"fmt"
 	"github.com/gin-gonic/gin"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
-	"github.com/spf13/viper"
 	"go.uber.org/zap"
 	"os"
 	"os/signal"
 	"syscall"
 	"tasks/config"
+	"tasks/internal/dialect"
 	"tasks/internal/handler"
+	"tasks/internal/migration"
 	"tasks/internal/repository"
 	"tasks/internal/service"
+	"tasks/internal/webhook"
+	"tasks/migrations"
 	"tasks/router"
+	"tasks/router/middleware"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "verify-audit" {
+		os.Exit(runVerifyAudit(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "rebuild-projection" {
+		os.Exit(runRebuildProjection(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "backfill-events" {
+		os.Exit(runBackfillEvents(os.Args[2:]))
+	}
 	ctx := context.Background()
 	svcCtx, cancel := context.WithCancel(ctx)
 	logger, _ := zap.NewProduction()
 	conf := initConfig()
-	db := initStorage(conf)
+	db := initStorage(conf, logger)
 	finishChan := make(chan struct{})
 	attaches, server := initServer(db, logger, conf)
 	defer func() {
@@ -46,33 +64,91 @@ func main() {
 }
 
 func initConfig() config.Config {
-	v := viper.New()
-	v.SetConfigFile("config.yaml")
-	if err := v.ReadInConfig(); err != nil {
+	conf, err := config.Load("config.yaml")
+	if err != nil {
 		panic(fmt.Errorf("Fatal error config file: %s \n", err))
 	}
-	var conf config.Config
-	if err := v.Unmarshal(&conf); err != nil {
-		panic(fmt.Errorf("umarshal config error: %s \n", err))
-	}
-	v.WatchConfig()
 	return conf
 }
 
 func initServer(db *sql.DB, logger *zap.Logger, conf config.Config) ([]router.Attach, *router.Server) {
-	taskRepo := repository.NewTaskRepository(db, logger)
+	sqlDialect, err := dialect.ForDriver(conf.DB.Driver)
+	if err != nil {
+		panic(fmt.Errorf("db dialect error: %s \n", err))
+	}
+	timeouts := repository.Timeouts{Default: conf.DB.Timeout, Operations: conf.DB.Timeouts}
+	taskRepo := newTaskRepository(conf, db, sqlDialect, timeouts, logger)
+	// hash chain 上線前寫入的 audit log 在啟動時串接，之後寫入的紀錄才能接在後面
+	if _, err = taskRepo.SealAudit(context.Background()); err != nil {
+		panic(fmt.Errorf("seal audit error: %s \n", err))
+	}
 	taskService := service.NewTaskService(taskRepo)
-	taskHandler := handler.NewTaskHandler(taskService)
+	idempotencyRepo := repository.NewIdempotencyRepository(db, sqlDialect, timeouts, logger)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo, conf.Idempotency.TTL, conf.Idempotency.Lease)
+	searchRepo := repository.NewSearchRepository(db, sqlDialect, timeouts, logger)
+	if err = searchRepo.Prepare(context.Background()); err != nil {
+		panic(fmt.Errorf("prepare search error: %s \n", err))
+	}
+	searchService := service.NewSearchService(searchRepo, taskRepo)
+	taskHandler := handler.NewTaskHandler(taskService, idempotencyService, searchService)
+	tagService := service.NewTagService(repository.NewTagRepository(db, sqlDialect, timeouts, logger))
+	tagHandler := handler.NewTagHandler(tagService)
+	webhookService := service.NewWebhookService(
+		repository.NewWebhookRepository(db, sqlDialect, timeouts, logger),
+		webhook.NewSender(conf.Webhook.Timeout),
+		webhook.Retry{MaxAttempts: conf.Webhook.MaxAttempts, Backoff: conf.Webhook.Backoff, MaxBackoff: conf.Webhook.MaxBackoff},
+	)
+	// 未設定 admin.token 時管理 API 與 webhook 設定都無法使用，啟動時提醒
+	if conf.Admin.Token == "" {
+		logger.Warn("admin.token is empty, every /admin and /webhooks request will be rejected with 401")
+	}
+	adminMiddleware := middleware.NewAdminMiddleware(conf.Admin.Token).GetAdminHandler()
 	attaches := []router.Attach{
 		router.NewBaseRouter(),
 		router.NewTaskRouter(taskHandler, []gin.HandlerFunc{}),
+		router.NewTagRouter(tagHandler, []gin.HandlerFunc{}),
+		router.NewAdminRouter(handler.NewAdminHandler(taskService), []gin.HandlerFunc{adminMiddleware}),
+		router.NewWebhookRouter(handler.NewWebhookHandler(webhookService), []gin.HandlerFunc{adminMiddleware}),
 	}
 	server := router.NewServer(&conf, logger)
+	server.AddWorkers(
+		service.NewTrashPurger(taskService, conf.Trash.Retention, conf.Trash.PurgeInterval, logger),
+		service.NewIdempotencyPurger(idempotencyService, conf.Idempotency.PurgeInterval, logger),
+		service.NewReminderScheduler(taskService, service.NewLogReminderNotifier(logger), conf.Reminder.Interval, logger),
+		service.NewWebhookDispatcher(webhookService, conf.Webhook.Interval, logger),
+	)
 
 	return attaches, server
 }
 
-func initStorage(conf config.Config) *sql.DB {
+// newTaskRepository 依設定選擇一般或 event sourcing 模式的 repository，handler 與 service 不需改變
+func newTaskRepository(conf config.Config, db *sql.DB, sqlDialect dialect.Dialect, timeouts repository.Timeouts, logger *zap.Logger) repository.TaskRepository {
+	if conf.Events.Enabled {
+		return repository.NewEventTaskRepository(db, sqlDialect, timeouts, logger, conf.Events.SnapshotInterval)
+	}
+	return repository.NewTaskRepository(db, sqlDialect, timeouts, logger)
+}
+
+func initStorage(conf config.Config, logger *zap.Logger) *sql.DB {
+	db := openStorage(conf)
+	migrator, err := newMigrator(db, conf, logger)
+	if err != nil {
+		panic(fmt.Errorf("load migrations error: %s \n", err))
+	}
+	unversioned, err := migrator.Unversioned("tasks")
+	if err != nil {
+		panic(fmt.Errorf("migrate db error: %s \n", err))
+	}
+	if unversioned {
+		panic(fmt.Errorf("migrate db error: the database was created before migrations existed, run `./main migrate baseline` once first \n"))
+	}
+	if _, err = migrator.Up(0, false); err != nil {
+		panic(fmt.Errorf("migrate db error: %s \n", err))
+	}
+	return db
+}
+
+func openStorage(conf config.Config) *sql.DB {
 	db, err := sql.Open(conf.DB.Driver, conf.DB.Dsn)
 	if err != nil {
 		panic(fmt.Errorf("Fatal error db file: %s \n", err))
@@ -81,24 +157,17 @@ func initStorage(conf config.Config) *sql.DB {
 	if err = db.Ping(); err != nil {
 		panic(fmt.Errorf("ping db error: %s \n", err))
 	}
-	if err = checkTables(db); err != nil {
-		panic(fmt.Errorf("check tables error: %s \n", err))
-	}
 	return db
 }
 
-func checkTables(db *sql.DB) error {
-	_, err := db.Exec(`
-	CREATE TABLE IF NOT EXISTS tasks 
-		(id TEXT PRIMARY KEY NOT NULL, 
-		name TEXT NOT NULL, 
-		status INTEGER NOT NULL, 
-		version INTEGER, 
-		created_at TEXT
-		)
-	`)
+func newMigrator(db *sql.DB, conf config.Config, logger *zap.Logger) (*migration.Migrator, error) {
+	sqlDialect, err := dialect.ForDriver(conf.DB.Driver)
+	if err != nil {
+		return nil, err
+	}
+	source, err := migrations.Source(conf.DB.Driver)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	return nil
+	return migration.NewMigrator(db, sqlDialect, source, logger)
 }
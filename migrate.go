@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+	"tasks/internal/migration"
+	"text/tabwriter"
+)
+
+const migrateUsage = `usage: tasks migrate <command> [flags]
+
+commands:
+  status                      show applied and pending migrations
+  up [-to N] [-dry-run]       apply pending migrations, up to version N
+  down [-steps N] [-dry-run]  roll back the latest N migrations (default 1)
+  baseline [-to N]            record migrations up to N as applied without running them (default 4),
+                              for a database created before migrations existed
+  resolve -version N [-applied]
+                              clear a dirty migration after repairing the schema, -applied keeps it
+                              recorded as applied, otherwise it is pending again
+`
+
+// legacyVersion checkTables 建立的 schema 對應的最後一個 migration
+const legacyVersion = 4
+
+// runMigrate 執行 migrate 子命令，回傳 exit code
+func runMigrate(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+	flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+	target := flags.Int("to", 0, "target version, 0 applies all pending migrations")
+	steps := flags.Int("steps", 1, "number of migrations to roll back")
+	dryRun := flags.Bool("dry-run", false, "print the migrations without running them")
+	version := flags.Int("version", 0, "dirty migration version to resolve")
+	resolved := flags.Bool("applied", false, "record the resolved migration as applied")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	logger, _ := zap.NewProduction()
+	conf := initConfig()
+	db := openStorage(conf)
+	defer db.Close()
+	migrator, err := newMigrator(db, conf, logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch args[0] {
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state := "pending"
+			if status.Dirty {
+				state = "dirty"
+			} else if status.Applied {
+				state = "applied"
+			}
+			fmt.Fprintf(writer, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, status.AppliedAt)
+		}
+		_ = writer.Flush()
+	case "up":
+		applied, err := migrator.Up(*target, *dryRun)
+		printMigrations("up", applied, *dryRun)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "down":
+		if *steps < 1 {
+			fmt.Fprintln(os.Stderr, "steps must be at least 1")
+			return 2
+		}
+		rolledBack, err := migrator.Down(*steps, *dryRun)
+		printMigrations("down", rolledBack, *dryRun)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	case "baseline":
+		if *target == 0 {
+			*target = legacyVersion
+		}
+		baseline, err := migrator.Baseline(*target)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		printMigrations("baseline", baseline, false)
+	case "resolve":
+		if *version < 1 {
+			fmt.Fprintln(os.Stderr, "version is required")
+			return 2
+		}
+		if err := migrator.Resolve(*version, *resolved); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+	return 0
+}
+
+func printMigrations(direction string, migrations []migration.Migration, dryRun bool) {
+	if len(migrations) == 0 {
+		fmt.Println("no migrations to run")
+		return
+	}
+	for _, migration := range migrations {
+		if dryRun {
+			script := migration.Up
+			if direction == "down" {
+				script = migration.Down
+			}
+			fmt.Printf("-- %s %04d_%s (dry run)\n%s\n", direction, migration.Version, migration.Name, script)
+			continue
+		}
+		fmt.Printf("%s %04d_%s\n", direction, migration.Version, migration.Name)
+	}
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+)
+
+//go:embed sqlite/*.sql postgres/*.sql mysql/*.sql
+var files embed.FS
+
+// dialectDirs db.driver 對應的 migration 目錄
+var dialectDirs = map[string]string{
+	"sqlite3":  "sqlite",
+	"postgres": "postgres",
+	"mysql":    "mysql",
+}
+
+// Source 取得 driver 對應的 migration 檔案
+func Source(driver string) (fs.FS, error) {
+	dir, ok := dialectDirs[driver]
+	if !ok {
+		return nil, fmt.Errorf("no migrations for driver %s", driver)
+	}
+	return fs.Sub(files, dir)
+}
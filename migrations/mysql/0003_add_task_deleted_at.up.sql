@@ -0,0 +1,2 @@
+ALTER TABLE tasks ADD COLUMN deleted_at DATETIME(6);
+CREATE INDEX idx_tasks_deleted_at ON tasks (deleted_at);
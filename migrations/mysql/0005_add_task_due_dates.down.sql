@@ -0,0 +1,5 @@
+DROP INDEX idx_tasks_due_at ON tasks;
+DROP INDEX idx_tasks_reminder ON tasks;
+ALTER TABLE tasks DROP COLUMN reminded_at;
+ALTER TABLE tasks DROP COLUMN remind_at;
+ALTER TABLE tasks DROP COLUMN due_at;
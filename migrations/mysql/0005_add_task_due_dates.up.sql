@@ -0,0 +1,5 @@
+ALTER TABLE tasks ADD COLUMN due_at DATETIME(6);
+ALTER TABLE tasks ADD COLUMN remind_at DATETIME(6);
+ALTER TABLE tasks ADD COLUMN reminded_at DATETIME(6);
+CREATE INDEX idx_tasks_due_at ON tasks (due_at);
+CREATE INDEX idx_tasks_reminder ON tasks (reminded_at, status);
@@ -0,0 +1,6 @@
+ALTER TABLE tasks ADD COLUMN priority INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE tasks ADD COLUMN position VARCHAR(1024) CHARACTER SET ascii COLLATE ascii_bin;
+UPDATE tasks
+JOIN (SELECT id, ROW_NUMBER() OVER (ORDER BY created_at, id) AS n FROM tasks) ranked ON ranked.id = tasks.id
+SET tasks.position = CONCAT(LPAD(ranked.n, 8, '0'), 'i');
+CREATE UNIQUE INDEX idx_tasks_position ON tasks (position);
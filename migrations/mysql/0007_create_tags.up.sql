@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS tags
+    (id VARCHAR(36) PRIMARY KEY NOT NULL,
+    name VARCHAR(50) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL,
+    color VARCHAR(7),
+    created_at DATETIME(6) NOT NULL,
+    updated_at DATETIME(6) NOT NULL
+    );
+CREATE UNIQUE INDEX idx_tags_name ON tags (name);
+CREATE TABLE IF NOT EXISTS task_tags
+    (task_id VARCHAR(36) NOT NULL,
+    tag_id VARCHAR(36) NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    PRIMARY KEY (task_id, tag_id)
+    );
+CREATE INDEX idx_task_tags_tag_id ON task_tags (tag_id);
@@ -0,0 +1,2 @@
+ALTER TABLE tasks ADD COLUMN parent_id VARCHAR(36);
+CREATE INDEX idx_tasks_parent_id ON tasks (parent_id);
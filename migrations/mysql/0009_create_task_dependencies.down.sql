@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS task_dependency_lock;
+DROP TABLE IF EXISTS task_dependencies;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS task_dependencies
+    (task_id VARCHAR(36) NOT NULL,
+    blocker_id VARCHAR(36) NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    PRIMARY KEY (task_id, blocker_id)
+    );
+CREATE INDEX idx_task_dependencies_blocker_id ON task_dependencies (blocker_id);
+CREATE TABLE IF NOT EXISTS task_dependency_lock
+    (id INTEGER PRIMARY KEY NOT NULL,
+    version BIGINT NOT NULL
+    );
+INSERT INTO task_dependency_lock (id, version) VALUES (1, 0);
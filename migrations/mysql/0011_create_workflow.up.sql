@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS workflow_states
+    (id INTEGER PRIMARY KEY NOT NULL,
+    name VARCHAR(50) NOT NULL,
+    position INTEGER NOT NULL
+    );
+CREATE UNIQUE INDEX idx_workflow_states_name ON workflow_states (name);
+CREATE TABLE IF NOT EXISTS workflow_transitions
+    (from_status INTEGER NOT NULL,
+    to_status INTEGER NOT NULL,
+    PRIMARY KEY (from_status, to_status)
+    );
+INSERT INTO workflow_states (id, name, position) VALUES
+    (0, 'todo', 0),
+    (2, 'in_progress', 1),
+    (3, 'review', 2),
+    (1, 'done', 3);
+INSERT INTO workflow_transitions (from_status, to_status) VALUES
+    (0, 2),
+    (0, 1),
+    (2, 0),
+    (2, 3),
+    (2, 1),
+    (3, 2),
+    (3, 1),
+    (1, 0);
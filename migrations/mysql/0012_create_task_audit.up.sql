@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS task_audit
+    (id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    task_id VARCHAR(36) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    version INTEGER NOT NULL,
+    changes MEDIUMTEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL
+    );
+CREATE INDEX idx_task_audit_task_id ON task_audit (task_id, id);
+CREATE INDEX idx_task_audit_created_at ON task_audit (created_at);
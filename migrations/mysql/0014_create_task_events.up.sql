@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS task_events
+    (id BIGINT PRIMARY KEY AUTO_INCREMENT,
+    task_id VARCHAR(36) NOT NULL,
+    seq BIGINT NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    version INTEGER NOT NULL,
+    data MEDIUMTEXT NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    created_at DATETIME(6) NOT NULL
+    );
+CREATE UNIQUE INDEX idx_task_events_task_id_seq ON task_events (task_id, seq);
+CREATE TABLE IF NOT EXISTS task_snapshots
+    (task_id VARCHAR(36) NOT NULL,
+    seq BIGINT NOT NULL,
+    version INTEGER NOT NULL,
+    state MEDIUMTEXT NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    PRIMARY KEY (task_id, seq)
+    );
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys
+    (idempotency_key VARCHAR(255) PRIMARY KEY NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INTEGER NOT NULL,
+    response TEXT,
+    created_at TIMESTAMPTZ NOT NULL,
+    lease_token VARCHAR(36),
+    lease_expires_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ NOT NULL
+    );
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
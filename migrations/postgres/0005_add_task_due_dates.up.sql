@@ -0,0 +1,5 @@
+ALTER TABLE tasks ADD COLUMN due_at TIMESTAMPTZ;
+ALTER TABLE tasks ADD COLUMN remind_at TIMESTAMPTZ;
+ALTER TABLE tasks ADD COLUMN reminded_at TIMESTAMPTZ;
+CREATE INDEX IF NOT EXISTS idx_tasks_due_at ON tasks (due_at);
+CREATE INDEX IF NOT EXISTS idx_tasks_reminder ON tasks (reminded_at, status);
@@ -0,0 +1,9 @@
+ALTER TABLE task_audit ADD COLUMN prev_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE task_audit ADD COLUMN hash VARCHAR(64) NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS task_audit_head
+    (id INTEGER PRIMARY KEY NOT NULL,
+    seq BIGINT NOT NULL,
+    last_id BIGINT NOT NULL,
+    hash VARCHAR(64) NOT NULL
+    );
+INSERT INTO task_audit_head (id, seq, last_id, hash) VALUES (1, 0, 0, '');
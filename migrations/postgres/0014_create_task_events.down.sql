@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS task_snapshots;
+DROP TABLE IF EXISTS task_events;
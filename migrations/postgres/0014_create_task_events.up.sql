@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS task_events
+    (id BIGSERIAL PRIMARY KEY,
+    task_id VARCHAR(36) NOT NULL,
+    seq BIGINT NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    version INTEGER NOT NULL,
+    data TEXT NOT NULL,
+    actor VARCHAR(255) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL
+    );
+CREATE UNIQUE INDEX IF NOT EXISTS idx_task_events_task_id_seq ON task_events (task_id, seq);
+CREATE TABLE IF NOT EXISTS task_snapshots
+    (task_id VARCHAR(36) NOT NULL,
+    seq BIGINT NOT NULL,
+    version INTEGER NOT NULL,
+    state TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (task_id, seq)
+    );
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS webhooks
+    (id VARCHAR(36) PRIMARY KEY NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    secret VARCHAR(255) NOT NULL,
+    events VARCHAR(255) NOT NULL,
+    active INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL
+    );
+CREATE TABLE IF NOT EXISTS webhook_outbox
+    (id BIGSERIAL PRIMARY KEY,
+    event_id VARCHAR(36) NOT NULL,
+    webhook_id VARCHAR(36) NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    task_id VARCHAR(36) NOT NULL,
+    payload TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL,
+    last_status_code INTEGER,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL,
+    delivered_at TIMESTAMPTZ
+    );
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_status_next_attempt_at ON webhook_outbox (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_webhook_id ON webhook_outbox (webhook_id, id);
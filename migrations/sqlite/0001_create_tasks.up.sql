@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS tasks
+    (id TEXT PRIMARY KEY NOT NULL,
+    name TEXT NOT NULL,
+    status INTEGER NOT NULL,
+    version INTEGER,
+    created_at TEXT
+    );
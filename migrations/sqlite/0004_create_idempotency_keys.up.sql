@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS idempotency_keys
+    (idempotency_key TEXT PRIMARY KEY NOT NULL,
+    request_hash TEXT NOT NULL,
+    status_code INTEGER NOT NULL,
+    response TEXT,
+    created_at TEXT NOT NULL,
+    lease_token TEXT,
+    lease_expires_at TEXT,
+    expires_at TEXT NOT NULL
+    );
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
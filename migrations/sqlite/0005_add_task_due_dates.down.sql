@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_tasks_due_at;
+DROP INDEX IF EXISTS idx_tasks_reminder;
+ALTER TABLE tasks DROP COLUMN reminded_at;
+ALTER TABLE tasks DROP COLUMN remind_at;
+ALTER TABLE tasks DROP COLUMN due_at;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_tasks_position;
+ALTER TABLE tasks DROP COLUMN position;
+ALTER TABLE tasks DROP COLUMN priority;
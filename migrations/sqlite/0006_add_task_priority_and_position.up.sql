@@ -0,0 +1,8 @@
+ALTER TABLE tasks ADD COLUMN priority INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE tasks ADD COLUMN position TEXT;
+UPDATE tasks SET position = (
+    SELECT printf('%08d', ranked.n) || 'i'
+    FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY created_at, id) AS n FROM tasks) ranked
+    WHERE ranked.id = tasks.id
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tasks_position ON tasks (position);
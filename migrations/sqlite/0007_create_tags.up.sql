@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS tags
+    (id TEXT PRIMARY KEY NOT NULL,
+    name TEXT NOT NULL,
+    color TEXT,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+    );
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tags_name ON tags (name);
+CREATE TABLE IF NOT EXISTS task_tags
+    (task_id TEXT NOT NULL,
+    tag_id TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (task_id, tag_id)
+    );
+CREATE INDEX IF NOT EXISTS idx_task_tags_tag_id ON task_tags (tag_id);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS task_audit
+    (id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    changes TEXT NOT NULL,
+    created_at TEXT NOT NULL
+    );
+CREATE INDEX IF NOT EXISTS idx_task_audit_task_id ON task_audit (task_id, id);
+CREATE INDEX IF NOT EXISTS idx_task_audit_created_at ON task_audit (created_at);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS task_audit_head;
+ALTER TABLE task_audit DROP COLUMN hash;
+ALTER TABLE task_audit DROP COLUMN prev_hash;
@@ -0,0 +1,9 @@
+ALTER TABLE task_audit ADD COLUMN prev_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE task_audit ADD COLUMN hash TEXT NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS task_audit_head
+    (id INTEGER PRIMARY KEY NOT NULL,
+    seq INTEGER NOT NULL,
+    last_id INTEGER NOT NULL,
+    hash TEXT NOT NULL
+    );
+INSERT INTO task_audit_head (id, seq, last_id, hash) VALUES (1, 0, 0, '');
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS task_events
+    (id INTEGER PRIMARY KEY AUTOINCREMENT,
+    task_id TEXT NOT NULL,
+    seq INTEGER NOT NULL,
+    type TEXT NOT NULL,
+    version INTEGER NOT NULL,
+    data TEXT NOT NULL,
+    actor TEXT NOT NULL,
+    created_at TEXT NOT NULL
+    );
+CREATE UNIQUE INDEX IF NOT EXISTS idx_task_events_task_id_seq ON task_events (task_id, seq);
+CREATE TABLE IF NOT EXISTS task_snapshots
+    (task_id TEXT NOT NULL,
+    seq INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    state TEXT NOT NULL,
+    created_at TEXT NOT NULL,
+    PRIMARY KEY (task_id, seq)
+    );
@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS webhooks
+    (id TEXT PRIMARY KEY NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT NOT NULL,
+    active INTEGER NOT NULL DEFAULT 1,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+    );
+CREATE TABLE IF NOT EXISTS webhook_outbox
+    (id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id TEXT NOT NULL,
+    webhook_id TEXT NOT NULL,
+    event TEXT NOT NULL,
+    task_id TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TEXT NOT NULL,
+    last_status_code INTEGER,
+    last_error TEXT,
+    created_at TEXT NOT NULL,
+    delivered_at TEXT
+    );
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_status_next_attempt_at ON webhook_outbox (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_outbox_webhook_id ON webhook_outbox (webhook_id, id);
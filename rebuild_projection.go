@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+)
+
+const rebuildProjectionUsage = `usage: tasks rebuild-projection [-dry-run] [-json]
+
+replay the task events and overwrite the tasks table with the result,
+requires events.enabled, exits with 1 on errors
+`
+
+// runRebuildProjection 執行 rebuild-projection 子命令，回傳 exit code
+func runRebuildProjection(args []string) int {
+	flags := flag.NewFlagSet("rebuild-projection", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, rebuildProjectionUsage) }
+	dryRun := flags.Bool("dry-run", false, "only count the rows that would change")
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	logger, _ := zap.NewProduction()
+	conf := initConfig()
+	db := openStorage(conf)
+	defer db.Close()
+	taskService, err := newTaskService(conf, db, logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	rebuild, err := taskService.RebuildProjection(context.Background(), *dryRun)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "    ")
+		_ = encoder.Encode(rebuild)
+		return 0
+	}
+	verb := "rebuilt"
+	if rebuild.DryRun {
+		verb = "would rebuild"
+	}
+	fmt.Printf("%s %d tasks: %d inserted, %d updated, %d deleted\n", verb, rebuild.Tasks, rebuild.Inserted, rebuild.Updated, rebuild.Deleted)
+	return 0
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"strings"
+	"tasks/errors"
+	"tasks/internal/audit"
+	"unicode/utf8"
+)
+
+const (
+	actorHeaderKey = "X-User-Id"
+	// AnonymousActor 請求未帶 X-User-Id 時記錄的 actor
+	AnonymousActor = "anonymous"
+	// maxActorLength 與 task_audit 的 actor 欄位長度一致
+	maxActorLength = 255
+)
+
+type ActorMiddleware struct{}
+
+func NewActorMiddleware() *ActorMiddleware {
+	return &ActorMiddleware{}
+}
+
+// GetActorHandler 將 X-User-Id 放入 request context，寫入 task 時記錄在 audit log 中，
+// 服務本身不驗證身分，需由前方的 gateway 驗證後帶入
+func (m *ActorMiddleware) GetActorHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		actor := strings.TrimSpace(c.GetHeader(actorHeaderKey))
+		if actor == "" {
+			actor = AnonymousActor
+		}
+		if utf8.RuneCountInString(actor) > maxActorLength {
+			_ = c.Error(errors.InvalidRequest.Errorf("%s must be at most %d characters", actorHeaderKey, maxActorLength))
+			c.Abort()
+			return
+		}
+		c.Set(ContextKeyUserID, actor)
+		c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), actor))
+		c.Next()
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"tasks/errors"
+)
+
+const (
+	authorizationHeaderKey = "Authorization"
+	bearerPrefix           = "Bearer "
+)
+
+type AdminMiddleware struct {
+	token string
+}
+
+// NewAdminMiddleware token 為空時拒絕所有請求，未設定 admin.token 不會開放管理 API
+func NewAdminMiddleware(token string) *AdminMiddleware {
+	return &AdminMiddleware{token: token}
+}
+
+// GetAdminHandler 驗證 Authorization: Bearer <admin.token>
+func (m *AdminMiddleware) GetAdminHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader(authorizationHeaderKey)
+		token, ok := strings.CutPrefix(header, bearerPrefix)
+		if m.token == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(m.token)) != 1 {
+			_ = c.Error(errors.Unauthorized.New("admin token is required"))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
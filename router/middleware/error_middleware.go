@@ -59,7 +59,7 @@ func (m *ResponseMiddleware) GetResponseHandler() gin.HandlerFunc {
 
 				c.JSON(
 					panicErrorStatus,
-					m.makeErrorResp(panicErrorCode, panicErrorMessage, traceID),
+					m.makeErrorResp(panicErrorCode, panicErrorMessage, nil, traceID),
 				)
 				return
 			}
@@ -77,7 +77,7 @@ func (m *ResponseMiddleware) GetResponseHandler() gin.HandlerFunc {
 
 				c.JSON(
 					defaultErrorStatus,
-					m.makeErrorResp(defaultErrorCode, defaultErrorMessage, traceID),
+					m.makeErrorResp(defaultErrorCode, defaultErrorMessage, nil, traceID),
 				)
 				return
 			}
@@ -87,18 +87,22 @@ func (m *ResponseMiddleware) GetResponseHandler() gin.HandlerFunc {
 			c.Set(ContextKeyStackTrace, errors.CauseStackTrace(err))
 			c.JSON(
 				customError.Status().ToHTTPStatus(),
-				m.makeErrorResp(customError.Code(), customError.Message(), traceID),
+				m.makeErrorResp(customError.Code(), customError.Message(), customError.Details(), traceID),
 			)
 			return
 		}
 	}
 }
 
-func (m *ResponseMiddleware) makeErrorResp(code int, message string, traceID string) gin.H {
+func (m *ResponseMiddleware) makeErrorResp(code int, message string, details interface{}, traceID string) gin.H {
+	body := gin.H{
+		"code":    code,
+		"message": message,
+	}
+	if details != nil {
+		body["details"] = details
+	}
 	return gin.H{
-		"error": gin.H{
-			"code":    code,
-			"message": message,
-		},
+		"error": body,
 	}
 }
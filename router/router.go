@@ -5,6 +5,7 @@ import (
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 	"net/http"
+	"strings"
 	"tasks/internal/handler"
 )
 
@@ -44,9 +45,111 @@ func NewTaskRouter(taskHandler handler.TaskHandler, middleware []gin.HandlerFunc
 func (r *taskRouter) Attach(router *gin.Engine) {
 	group := router.Group(r.rootPath, r.middlewares...)
 	group.GET("/", r.handlers.GetTasks)
+	group.GET("/:id", r.handlers.GetTask)
+	group.GET("/:id/subtree", r.handlers.GetSubtree)
+	group.GET("/:id/occurrences", r.handlers.GetOccurrences)
 	group.POST("/", r.handlers.CreateTask)
 	group.PUT("/:id", r.handlers.UpdateTask)
+	group.PATCH("/:id", r.handlers.PatchTask)
 	group.DELETE("/:id", r.handlers.DeleteTask)
+	group.GET("/trash", r.handlers.GetTrash)
+	group.GET("/workflow", r.handlers.GetWorkflow)
+	group.GET("/search", r.handlers.SearchTasks)
+	group.GET("/audit", r.handlers.GetAuditLog)
+	group.GET("/:id/history", r.handlers.GetHistory)
+	group.GET("/:id/events", r.handlers.GetEvents)
+	group.GET("/:id/as-of", r.handlers.GetTaskAsOf)
+	group.POST("/:id/restore", r.handlers.RestoreTask)
+	group.POST("/:id/move", r.handlers.MoveTask)
+	group.DELETE("/trash/:id", r.handlers.PurgeTask)
+	group.POST("/:id/tags", r.handlers.AttachTags)
+	group.DELETE("/:id/tags/:tag_id", r.handlers.DetachTag)
+	group.POST("/:id/blockers", r.handlers.AddBlockers)
+	group.DELETE("/:id/blockers/:blocker_id", r.handlers.RemoveBlocker)
+
+	// custom method，例如 POST /tasks:batch
+	actions := map[string]gin.HandlerFunc{
+		"batch":    r.handlers.BatchTasks,
+		"toposort": r.handlers.SortTasks,
+	}
+	dispatch := func(c *gin.Context) {
+		action, ok := actions[strings.TrimPrefix(c.Param("action"), ":")]
+		if !ok {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		action(c)
+	}
+	handlers := append(append([]gin.HandlerFunc{}, r.middlewares...), dispatch)
+	router.POST(r.rootPath+":action", handlers...)
+}
+
+type tagRouter struct {
+	rootPath    string
+	middlewares []gin.HandlerFunc
+	handlers    handler.TagHandler
+}
+
+func NewTagRouter(tagHandler handler.TagHandler, middleware []gin.HandlerFunc) Attach {
+	return &tagRouter{
+		rootPath:    "/tags",
+		middlewares: middleware,
+		handlers:    tagHandler,
+	}
+}
+
+func (r *tagRouter) Attach(router *gin.Engine) {
+	group := router.Group(r.rootPath, r.middlewares...)
+	group.GET("/", r.handlers.GetTags)
+	group.GET("/:id", r.handlers.GetTag)
+	group.POST("/", r.handlers.CreateTag)
+	group.PUT("/:id", r.handlers.UpdateTag)
+	group.DELETE("/:id", r.handlers.DeleteTag)
+}
+
+type webhookRouter struct {
+	rootPath    string
+	middlewares []gin.HandlerFunc
+	handlers    handler.WebhookHandler
+}
+
+func NewWebhookRouter(webhookHandler handler.WebhookHandler, middleware []gin.HandlerFunc) Attach {
+	return &webhookRouter{
+		rootPath:    "/webhooks",
+		middlewares: middleware,
+		handlers:    webhookHandler,
+	}
+}
+
+func (r *webhookRouter) Attach(router *gin.Engine) {
+	group := router.Group(r.rootPath, r.middlewares...)
+	group.GET("/", r.handlers.GetWebhooks)
+	group.GET("/:id", r.handlers.GetWebhook)
+	group.POST("/", r.handlers.CreateWebhook)
+	group.PUT("/:id", r.handlers.UpdateWebhook)
+	group.DELETE("/:id", r.handlers.DeleteWebhook)
+	group.GET("/:id/deliveries", r.handlers.GetDeliveries)
+	group.POST("/:id/deliveries/:delivery_id/redeliver", r.handlers.Redeliver)
+}
+
+type adminRouter struct {
+	rootPath    string
+	middlewares []gin.HandlerFunc
+	handlers    handler.AdminHandler
+}
+
+func NewAdminRouter(adminHandler handler.AdminHandler, middleware []gin.HandlerFunc) Attach {
+	return &adminRouter{
+		rootPath:    "/admin",
+		middlewares: middleware,
+		handlers:    adminHandler,
+	}
+}
+
+func (r *adminRouter) Attach(router *gin.Engine) {
+	group := router.Group(r.rootPath, r.middlewares...)
+	group.GET("/audit/verify", r.handlers.VerifyAudit)
+	group.POST("/projection/rebuild", r.handlers.RebuildProjection)
 }
 
 type swaggerRouter struct {
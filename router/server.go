@@ -8,16 +8,26 @@ import (
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 	"net/http"
+	"sync"
 	"tasks/config"
 	"tasks/router/middleware"
 	"time"
 )
 
+// shutdownTimeout 關閉服務時等待進行中請求的時間，超過時強制關閉
+const shutdownTimeout = 30 * time.Second
+
+// Worker 隨 Server 生命週期執行的背景工作，ctx 結束時應返回
+type Worker interface {
+	Run(ctx context.Context)
+}
+
 type Server struct {
 	port       string
 	router     *gin.Engine
 	httpServer *http.Server
 	logger     *zap.Logger
+	workers    []Worker
 }
 
 func NewServer(conf *config.Config, logger *zap.Logger) *Server {
@@ -28,6 +38,7 @@ func NewServer(conf *config.Config, logger *zap.Logger) *Server {
 	router.Use(gin.Recovery())
 	errorMiddleware := middleware.NewResponseMiddleware()
 	router.Use(errorMiddleware.GetResponseHandler())
+	router.Use(middleware.NewActorMiddleware().GetActorHandler())
 	return &Server{
 		port:   serverConf.Port,
 		router: router,
@@ -35,14 +46,30 @@ func NewServer(conf *config.Config, logger *zap.Logger) *Server {
 	}
 }
 
+func (s *Server) AddWorkers(workers ...Worker) {
+	s.workers = append(s.workers, workers...)
+}
+
 func (s *Server) Run(ctx context.Context, cancel context.CancelFunc, finishChan chan struct{}, attaches ...Attach) {
+	// request context 不衍生自 ctx，收到關閉訊號後進行中的請求仍可完成寫入，由 Shutdown 等待
 	httpServer := &http.Server{
 		Addr:    fmt.Sprintf(":%s", s.port),
 		Handler: s.router,
 	}
+	var wg sync.WaitGroup
+	for _, w := range s.workers {
+		wg.Add(1)
+		go func(w Worker) {
+			defer wg.Done()
+			w.Run(ctx)
+		}(w)
+	}
 	go func() {
 		<-ctx.Done()
-		s.Shutdown(httpServer, ctx)
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancelShutdown()
+		s.Shutdown(httpServer, shutdownCtx)
+		wg.Wait()
 		finishChan <- struct{}{}
 	}()
 	for _, a := range attaches {
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+	"tasks/config"
+	"tasks/internal/dialect"
+	"tasks/internal/repository"
+	"tasks/internal/service"
+)
+
+const verifyAuditUsage = `usage: tasks verify-audit [-json]
+
+verify the hash chain of the audit log and report the first broken link,
+exits with 1 on errors and 3 when the chain is broken
+`
+
+// exitAuditBroken audit log 的 hash chain 斷開時的 exit code，與執行錯誤區分
+const exitAuditBroken = 3
+
+// runVerifyAudit 執行 verify-audit 子命令，回傳 exit code
+func runVerifyAudit(args []string) int {
+	flags := flag.NewFlagSet("verify-audit", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, verifyAuditUsage) }
+	asJSON := flags.Bool("json", false, "print the result as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	logger, _ := zap.NewProduction()
+	conf := initConfig()
+	db := openStorage(conf)
+	defer db.Close()
+	taskService, err := newTaskService(conf, db, logger)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	verification, err := taskService.VerifyAudit(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "    ")
+		_ = encoder.Encode(verification)
+	} else if verification.Valid {
+		fmt.Printf("audit log intact, %d entries checked, head %s\n", verification.Checked, verification.Head)
+	} else {
+		broken := verification.Broken
+		fmt.Printf("audit log broken at entry %d (task %s): %s\n", broken.ID, broken.TaskID, broken.Reason)
+		fmt.Printf("  expected %s\n  actual   %s\n", broken.Expected, broken.Actual)
+		fmt.Printf("%d entries checked\n", verification.Checked)
+	}
+	if !verification.Valid {
+		return exitAuditBroken
+	}
+	return 0
+}
+
+func newTaskService(conf config.Config, db *sql.DB, logger *zap.Logger) (service.TaskService, error) {
+	sqlDialect, err := dialect.ForDriver(conf.DB.Driver)
+	if err != nil {
+		return nil, err
+	}
+	timeouts := repository.Timeouts{Default: conf.DB.Timeout, Operations: conf.DB.Timeouts}
+	return service.NewTaskService(newTaskRepository(conf, db, sqlDialect, timeouts, logger)), nil
+}